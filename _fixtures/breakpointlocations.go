@@ -0,0 +1,15 @@
+package main
+
+import "fmt"
+
+func apply(f func(int) int, x int) int {
+	return f(x)
+}
+
+func main() {
+	a := apply(func(x int) int { return x + 1 }, 1); b := apply(func(y int) int { return y * 2 }, 2)
+	var c int
+
+	c = a + b
+	fmt.Println(a, b, c)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type Base struct {
+	ID int
+}
+
+func (b *Base) Identify() int { return b.ID }
+
+type Item struct {
+	Base
+	Name  string
+	Count int
+}
+
+func (it Item) Label() string { return fmt.Sprintf("%s:%d", it.Name, it.Count) }
+
+var itemTotal = 1
+
+func main() {
+	item := Item{Base{1}, "apple", 3}
+	var boxed interface{} = &item
+	itemCount := 2
+	runtime.Breakpoint()
+	fmt.Println(item.Label(), boxed, itemCount, itemTotal, item.Identify())
+}
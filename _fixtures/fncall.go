@@ -164,6 +164,18 @@ func (b *Base) Method() int {
 	return b.y
 }
 
+type Methoder interface {
+	Method() int
+}
+
+func callMethoder(m Methoder) int {
+	return m.Method()
+}
+
+func callVRcvrable(v VRcvrable) string {
+	return v.VRcvr(1)
+}
+
 type X int
 
 func (_ X) CallMe() {
@@ -220,6 +232,23 @@ type intpair struct {
 	X, Y int
 }
 
+func mapsum(m map[string]int) int {
+	r := 0
+	for _, v := range m {
+		r += v
+	}
+	return r
+}
+
+type strpair struct {
+	S string
+	N int
+}
+
+func mapdel(m map[string]int, k string) {
+	delete(m, k)
+}
+
 var m = map[intpair]string{
 	{1, 1}: "one,one",
 	{1, 2}: "one,two",
@@ -259,12 +288,19 @@ func main() {
 	fn2valmeth := pa.VRcvr
 	fn2ptrmeth := pa.PRcvr
 	var fn2nil func()
+	fnslice := []func(int) string{fn2valmeth, fn2ptrmeth}
+	fnmap := map[string]func(int) string{"clos": fn2clos}
 
 	d := &Derived{3, Base{4}}
+	var methoder Methoder = d
 
 	var ref strings.Builder
 	fmt.Fprintf(&ref, "blah")
 
+	smap := map[string]int{"one": 1, "two": 2}
+	var iface interface{} = one
+	sp := strpair{S: "old", N: 1}
+
 	runtime.Breakpoint() // breakpoint here
 	call1(one, two)
 	fn2clos(2)
@@ -272,5 +308,7 @@ func main() {
 	d.Method()
 	d.Base.Method()
 	x.CallMe()
-	fmt.Println(one, two, zero, call, call0, call2, callexit, callpanic, callbreak, callstacktrace, stringsJoin, intslice, stringslice, comma, a.VRcvr, a.PRcvr, pa, vable_a, vable_pa, pable_pa, fn2clos, fn2glob, fn2valmeth, fn2ptrmeth, fn2nil, ga, escapeArg, a2, square, intcallpanic, onetwothree, curriedAdd, getAStruct, getAStructPtr, getVRcvrableFromAStruct, getPRcvrableFromAStructPtr, getVRcvrableFromAStructPtr, pa2, noreturncall, str, d, x, x2.CallMe(5), longstrs, regabistacktest, regabistacktest2, issue2698.String(), issue3364.String(), regabistacktest3, rast3, floatsum, ref, mul2, mul2ptr, m)
+	callMethoder(methoder)
+	callVRcvrable(vable_a)
+	fmt.Println(one, two, zero, call, call0, call2, callexit, callpanic, callbreak, callstacktrace, stringsJoin, intslice, stringslice, comma, a.VRcvr, a.PRcvr, pa, vable_a, vable_pa, pable_pa, fn2clos, fn2glob, fn2valmeth, fn2ptrmeth, fn2nil, fnslice, fnmap, ga, escapeArg, a2, square, intcallpanic, onetwothree, curriedAdd, getAStruct, getAStructPtr, getVRcvrableFromAStruct, getPRcvrableFromAStructPtr, getVRcvrableFromAStructPtr, pa2, noreturncall, str, d, x, x2.CallMe(5), longstrs, regabistacktest, regabistacktest2, issue2698.String(), issue3364.String(), regabistacktest3, rast3, floatsum, ref, mul2, mul2ptr, m, mapsum, smap, iface, sp, mapdel)
 }
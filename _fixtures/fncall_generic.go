@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type astruct struct {
+	X, Y int
+}
+
+type MyInt int
+
+type Box[T any] struct {
+	val T
+}
+
+func (b Box[T]) Get() T {
+	return b.val
+}
+
+func (b *Box[T]) Set(v T) {
+	b.val = v
+}
+
+func Add[T ~int | ~float64](a, b T) T {
+	return a + b
+}
+
+func Pair[K comparable, V any](k K, v V) string {
+	return fmt.Sprintf("%v=%v", k, v)
+}
+
+func First[T any](s []T) T {
+	return s[0]
+}
+
+func main() {
+	bi := Box[int]{3}
+	bs := &Box[string]{"hello"}
+	bp := &Box[int]{1}
+	s := []*astruct{{1, 2}}
+	strs := []string{"a", "b"}
+	fmt.Println(Add(1, 2), Add(1.5, 2.5), Add[MyInt](3, 4), Pair("a", 1), Pair(astruct{}, "x"), First(s), First(strs))
+	bi.Set(4)
+	runtime.Breakpoint()
+	fmt.Println(bi.Get(), bs.Get(), bi, bs, bp, s, strs)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type Session struct {
+	id   int
+	name string
+	conn *Conn
+	next *Session
+}
+
+type Conn struct {
+	buf  [1024]byte
+	peer *Session
+}
+
+type Big struct {
+	data [100000]*int
+}
+
+var sessions []*Session
+var big *Big
+
+func main() {
+	for i := 0; i < 100; i++ {
+		s := &Session{id: i, name: fmt.Sprintf("session%d", i), conn: &Conn{}}
+		s.conn.peer = s
+		sessions = append(sessions, s)
+	}
+	var first *Session
+	for i := 0; i < 10; i++ {
+		first = &Session{id: -i, next: first}
+	}
+	big = &Big{}
+	runtime.Breakpoint()
+	fmt.Println(len(sessions), first.id, len(big.data))
+}
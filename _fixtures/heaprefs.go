@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type Conn struct {
+	fd  int
+	buf []byte
+}
+
+type Session struct {
+	id   int
+	conn *Conn
+}
+
+type resource struct {
+	name string
+	conn *Conn
+}
+
+var cache = map[string]*Session{}
+
+var sink *Conn
+
+//go:noinline
+func newResource(conn *Conn) {
+	r := &resource{name: "res", conn: conn}
+	runtime.SetFinalizer(r, func(r *resource) {})
+}
+
+//go:noinline
+func use(c *Conn) int {
+	sink = c
+	sink = nil
+	return c.fd
+}
+
+//go:noinline
+func stop() {
+	runtime.Breakpoint()
+}
+
+func main() {
+	conn := &Conn{fd: 3, buf: make([]byte, 16)}
+	cache["s1"] = &Session{id: 1, conn: conn}
+	for i := 0; i < 20; i++ {
+		cache[fmt.Sprintf("s%d", i+2)] = &Session{id: i + 2, conn: &Conn{fd: i}}
+	}
+	newResource(conn)
+	dead := &Conn{fd: 4}
+	use(dead)
+	stop()
+	fmt.Println(use(conn), len(cache))
+}
@@ -0,0 +1,33 @@
+package main
+
+import "fmt"
+
+func skip() int {
+	x := 1
+	x = 2
+	x = 3
+	return x
+}
+
+func deferred(n int) {
+	for i := 0; i < n; i++ {
+		defer fmt.Println(i)
+	}
+	fmt.Println("after defer")
+}
+
+func main() {
+	fmt.Println(skip())
+	f := func() int {
+		return 1
+	}
+	fmt.Println(f())
+	deferred(2)
+	opendefer()
+}
+
+func opendefer() {
+	fmt.Println("before defer")
+	defer fmt.Println("deferred")
+	fmt.Println("after defer")
+}
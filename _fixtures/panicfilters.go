@@ -0,0 +1,17 @@
+package main
+
+import "errors"
+
+func recovered(v any) {
+	defer func() {
+		recover()
+	}()
+	panic(v)
+}
+
+func main() {
+	recovered("first")
+	recovered(errors.New("second"))
+	recovered("third")
+	panic("unrecovered")
+}
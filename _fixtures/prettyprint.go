@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+func main() {
+	d := 90 * time.Second
+	n := big.NewInt(-1)
+	n.Lsh(n, 70)
+	ip4 := netip.MustParseAddr("192.168.0.1")
+	ip6 := netip.MustParseAddr("2001:db8::1%eth0")
+	ip4in6 := netip.MustParseAddr("::ffff:10.0.0.1")
+	var ipzero netip.Addr
+	ap := netip.MustParseAddrPort("[::1]:8080")
+	pfx := netip.MustParsePrefix("10.0.0.0/8")
+	var cnt atomic.Int64
+	cnt.Store(42)
+	var flag atomic.Bool
+	flag.Store(true)
+	runtime.Breakpoint()
+	fmt.Println(d, n, ip4, ip6, ip4in6, ipzero, ap, pfx, cnt.Load(), flag.Load())
+}
@@ -0,0 +1,18 @@
+package main
+
+import "fmt"
+
+func add(a, b int) int {
+	c := a + b
+	a = 10
+	return c + a
+}
+
+func outer(x int) int {
+	y := add(x, 2)
+	return y * 2
+}
+
+func main() {
+	fmt.Println(outer(1))
+}
@@ -0,0 +1,21 @@
+package main
+
+import "fmt"
+
+func g(x int) int {
+	return x + 1
+}
+
+func h(y int) int {
+	return y * 2
+}
+
+func f(a, b int) int {
+	return a + b
+}
+
+func main() {
+	x, y := 1, 2
+	r := f(g(x), h(y))
+	fmt.Println(r)
+}
@@ -376,7 +376,13 @@ The core command will open the specified core file and the associated
 executable and let you examine the state of the process when the
 core dump was taken.
 
-Currently supports linux/amd64 and linux/arm64 core files, windows/amd64 minidumps and core files generated by Delve's 'dump' command.`,
+Currently supports linux/amd64 and linux/arm64 core files, windows/amd64 minidumps and core files generated by Delve's 'dump' command.
+
+Core files compressed with gzip, zstd, xz or lz4 (for example the ones
+stored by systemd-coredump) are read directly, without decompressing them to
+disk first. A core file split into multiple pieces, named <core>.000,
+<core>.001, etc., can be opened by passing <core>, as long as a file with
+that name does not exist.`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 2 {
 				return errors.New("you must provide a core file and an executable")
@@ -583,11 +589,13 @@ func dapCmd(cmd *cobra.Command, args []string) {
 		cfg := &service.Config{
 			DisconnectChan: disconnectChan,
 			Debugger: debugger.Config{
-				Backend:              backend,
-				Foreground:           true, // server always runs without terminal client
-				DebugInfoDirectories: conf.DebugInfoDirectories,
-				CheckGoVersion:       checkGoVersion,
-				DisableASLR:          disableASLR,
+				Backend:                      backend,
+				Foreground:                   true, // server always runs without terminal client
+				DebugInfoDirectories:         conf.DebugInfoDirectories,
+				PrettyPrinters:               conf.PrettyPrinters,
+				DisableBuiltinPrettyPrinters: conf.DisableBuiltinPrettyPrinters,
+				CheckGoVersion:               checkGoVersion,
+				DisableASLR:                  disableASLR,
 			},
 			CheckLocalConnUser: checkLocalConnUser,
 		}
@@ -737,11 +745,13 @@ func traceCmd(cmd *cobra.Command, args []string, conf *config.Config) int {
 			ProcessArgs: processArgs,
 			APIVersion:  2,
 			Debugger: debugger.Config{
-				AttachPid:            traceAttachPid,
-				WorkingDir:           workingDir,
-				Backend:              backend,
-				CheckGoVersion:       checkGoVersion,
-				DebugInfoDirectories: conf.DebugInfoDirectories,
+				AttachPid:                    traceAttachPid,
+				WorkingDir:                   workingDir,
+				Backend:                      backend,
+				CheckGoVersion:               checkGoVersion,
+				DebugInfoDirectories:         conf.DebugInfoDirectories,
+				PrettyPrinters:               conf.PrettyPrinters,
+				DisableBuiltinPrettyPrinters: conf.DisableBuiltinPrettyPrinters,
 			},
 		})
 		if err := server.Run(); err != nil {
@@ -1120,25 +1130,27 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 			CheckLocalConnUser: checkLocalConnUser,
 			DisconnectChan:     disconnectChan,
 			Debugger: debugger.Config{
-				AttachPid:             attachPid,
-				WorkingDir:            workingDir,
-				Backend:               backend,
-				CoreFile:              coreFile,
-				Foreground:            headless && tty == "",
-				Packages:              dlvArgs,
-				BuildFlags:            buildFlags,
-				ExecuteKind:           kind,
-				DebugInfoDirectories:  conf.DebugInfoDirectories,
-				CheckGoVersion:        checkGoVersion,
-				TTY:                   tty,
-				Stdin:                 redirects[0],
-				Stdout:                proc.OutputRedirect{Path: redirects[1]},
-				Stderr:                proc.OutputRedirect{Path: redirects[2]},
-				DisableASLR:           disableASLR,
-				RrOnProcessPid:        rrOnProcessPid,
-				AttachWaitFor:         attachWaitFor,
-				AttachWaitForInterval: attachWaitForInterval,
-				AttachWaitForDuration: attachWaitForDuration,
+				AttachPid:                    attachPid,
+				WorkingDir:                   workingDir,
+				Backend:                      backend,
+				CoreFile:                     coreFile,
+				Foreground:                   headless && tty == "",
+				Packages:                     dlvArgs,
+				BuildFlags:                   buildFlags,
+				ExecuteKind:                  kind,
+				DebugInfoDirectories:         conf.DebugInfoDirectories,
+				PrettyPrinters:               conf.PrettyPrinters,
+				DisableBuiltinPrettyPrinters: conf.DisableBuiltinPrettyPrinters,
+				CheckGoVersion:               checkGoVersion,
+				TTY:                          tty,
+				Stdin:                        redirects[0],
+				Stdout:                       proc.OutputRedirect{Path: redirects[1]},
+				Stderr:                       proc.OutputRedirect{Path: redirects[2]},
+				DisableASLR:                  disableASLR,
+				RrOnProcessPid:               rrOnProcessPid,
+				AttachWaitFor:                attachWaitFor,
+				AttachWaitForInterval:        attachWaitForInterval,
+				AttachWaitForDuration:        attachWaitForDuration,
 			},
 		})
 	default:
@@ -114,6 +114,14 @@ type Config struct {
 	// TraceShowTimestamp controls whether to show timestamp in the trace
 	// output.
 	TraceShowTimestamp bool `yaml:"trace-show-timestamp"`
+
+	// PrettyPrinters is a list of Starlark scripts defining pretty printers
+	// for Go types, see Documentation/cli/prettyprinters.md.
+	PrettyPrinters []string `yaml:"pretty-printers"`
+
+	// DisableBuiltinPrettyPrinters disables the built-in pretty printers for
+	// types of the standard library.
+	DisableBuiltinPrettyPrinters bool `yaml:"disable-builtin-pretty-printers"`
 }
 
 func (c *Config) GetSourceListLineCount() int {
@@ -311,6 +319,14 @@ substitute-path:
 
 # List of directories to use when searching for separate debug info files.
 debug-info-directories: ["/usr/lib/debug/.build-id"]
+
+# List of Starlark scripts defining pretty printers for Go types.
+# See also Documentation/cli/prettyprinters.md.
+# pretty-printers: ["~/.config/dlv/printers.star"]
+
+# Uncomment the following line to disable the built-in pretty printers for
+# types of the standard library (time.Duration, math/big.Int, net/netip.Addr...).
+# disable-builtin-pretty-printers: true
 `)
 	return err
 }
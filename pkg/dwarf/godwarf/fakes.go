@@ -59,3 +59,19 @@ func FakePointerType(typ Type, ptrSize int64) *PtrType {
 	typename := "*" + typ.Common().Name
 	return &PtrType{CommonType: CommonType{ByteSize: ptrSize, Name: typename}, Type: typ}
 }
+
+// FakeArrayType synthesizes an array type of n elements of type fieldType.
+func FakeArrayType(n uint64, fieldType Type) *ArrayType {
+	stride := fieldType.Common().ByteSize
+	if align := fieldType.Align(); align > 1 {
+		stride = (stride + align - 1) &^ (align - 1)
+	}
+	return &ArrayType{
+		CommonType: CommonType{
+			ReflectKind: reflect.Array,
+			ByteSize:    int64(n) * stride,
+			Name:        fmt.Sprintf("[%d]%s", n, fieldType.String())},
+		Type:          fieldType,
+		StrideBitSize: stride * 8,
+		Count:         int64(n)}
+}
@@ -168,6 +168,43 @@ func (lineInfo *DebugLineInfo) AllPCsForFileLines(f string, m map[int][]uint64)
 	}
 }
 
+// StmtPosition is the address of an instruction with the is_stmt flag set
+// and the source position it is assigned to.
+type StmtPosition struct {
+	PC     uint64
+	Line   int
+	Column int // zero if the compiler did not record a column
+}
+
+// StmtsForFileLines returns the address, line and column of all the
+// instructions with the is_stmt flag set that belong to file f, between
+// lines startLine and endLine (both included).
+func (lineInfo *DebugLineInfo) StmtsForFileLines(f string, startLine, endLine int) []StmtPosition {
+	if lineInfo == nil {
+		return nil
+	}
+
+	var (
+		r        []StmtPosition
+		lastAddr uint64
+		sm       = newStateMachine(lineInfo, lineInfo.Instructions, lineInfo.ptrSize)
+	)
+
+	for {
+		if err := sm.next(); err != nil {
+			if lineInfo.Logf != nil && err != io.EOF {
+				lineInfo.Logf("StmtsForFileLines error: %v", err)
+			}
+			break
+		}
+		if sm.address != lastAddr && sm.isStmt && sm.valid && !sm.endSeq && sm.file == f && sm.line >= startLine && sm.line <= endLine {
+			r = append(r, StmtPosition{PC: sm.address, Line: sm.line, Column: int(sm.column)})
+			lastAddr = sm.address
+		}
+	}
+	return r
+}
+
 var ErrNoSource = errors.New("no source available")
 
 // AllPCsBetween returns all PC addresses between begin and end (including both begin and end)
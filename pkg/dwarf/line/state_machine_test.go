@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"testing"
 
 	pdwarf "github.com/go-delve/delve/pkg/dwarf"
@@ -259,3 +260,81 @@ func TestMultipleSequences(t *testing.T) {
 		}
 	}
 }
+
+func TestStmtsForFileLines(t *testing.T) {
+	// Check that StmtsForFileLines reports columns and skips instructions
+	// without is_stmt or outside of the requested range.
+
+	const thefile = "thefile.go"
+
+	instr := bytes.NewBuffer(nil)
+	ptrSize := ptrSizeByRuntimeArch()
+
+	instr.WriteByte(0)
+	leb128.EncodeUnsigned(instr, 9) // 1 + ptr_size
+	instr.WriteByte(DW_LINE_set_address)
+	pdwarf.WriteUint(instr, binary.LittleEndian, ptrSize, 0x400000)
+
+	instr.WriteByte(DW_LNS_copy) // thefile.go:1 0x400000
+	for _, op := range []struct {
+		advpc, advline, column uint64
+		negateStmt             bool
+	}{
+		{0x2, 1, 5, false},  // thefile.go:2:5 0x400002
+		{0x2, 0, 0, true},   // thefile.go:2:5 0x400004 (not a statement)
+		{0x2, 0, 20, true},  // thefile.go:2:20 0x400006
+		{0x2, 0, 41, false}, // thefile.go:2:41 0x400008
+		{0x2, 1, 0, false},  // thefile.go:3:41 0x40000a
+	} {
+		instr.WriteByte(DW_LNS_advance_pc)
+		leb128.EncodeUnsigned(instr, op.advpc)
+		if op.advline != 0 {
+			instr.WriteByte(DW_LNS_advance_line)
+			leb128.EncodeSigned(instr, int64(op.advline))
+		}
+		if op.column != 0 {
+			instr.WriteByte(DW_LNS_set_column)
+			leb128.EncodeUnsigned(instr, op.column)
+		}
+		if op.negateStmt {
+			instr.WriteByte(DW_LNS_negate_stmt)
+		}
+		instr.WriteByte(DW_LNS_copy)
+	}
+	instr.WriteByte(DW_LNS_advance_pc)
+	leb128.EncodeUnsigned(instr, 0x2)
+	instr.WriteByte(0)
+	leb128.EncodeUnsigned(instr, 1)
+	instr.WriteByte(DW_LINE_end_sequence)
+
+	lines := &DebugLineInfo{
+		Prologue: &DebugLinePrologue{
+			UnitLength:     1,
+			Version:        2,
+			MinInstrLength: 1,
+			InitialIsStmt:  1,
+			LineBase:       -3,
+			LineRange:      12,
+			OpcodeBase:     13,
+			StdOpLengths:   []uint8{0, 1, 1, 1, 1, 0, 0, 0, 1, 0, 0, 1},
+		},
+		IncludeDirs:  []string{},
+		FileNames:    []*FileEntry{{Path: thefile}},
+		Instructions: instr.Bytes(),
+		ptrSize:      ptrSize,
+	}
+
+	out := lines.StmtsForFileLines(thefile, 2, 2)
+	tgt := []StmtPosition{
+		{PC: 0x400002, Line: 2, Column: 5},
+		{PC: 0x400006, Line: 2, Column: 20},
+		{PC: 0x400008, Line: 2, Column: 41},
+	}
+	if !reflect.DeepEqual(out, tgt) {
+		t.Errorf("StmtsForFileLines(%q, 2, 2): expected %v got %v", thefile, tgt, out)
+	}
+
+	if out := lines.StmtsForFileLines("otherfile.go", 1, 3); len(out) != 0 {
+		t.Errorf("StmtsForFileLines(\"otherfile.go\", 1, 3): expected nothing got %v", out)
+	}
+}
@@ -1,16 +1,22 @@
 // elfwriter is a package to write ELF files without having their entire
 // contents in memory at any one time.
 // This package is incomplete, only features needed to write core files are
-// implemented, notably missing:
-// - program headers at the beginning of the file
+// implemented.
 package elfwriter
 
 import (
 	"debug/elf"
 	"encoding/binary"
+	"errors"
 	"io"
 )
 
+const (
+	ehsize    = 64
+	phentsize = 56
+	shentsize = 64
+)
+
 // WriteCloserSeeker is the union of io.Writer, io.Closer and io.Seeker.
 type WriteCloserSeeker interface {
 	io.Writer
@@ -20,7 +26,9 @@ type WriteCloserSeeker interface {
 
 // Writer writes ELF files.
 type Writer struct {
-	w        WriteCloserSeeker
+	w        io.Writer
+	seeker   io.Seeker // nil for writers created by NewStream
+	off      int64     // current offset from the start of the file
 	Err      error
 	Progs    []*elf.ProgHeader
 	Sections []*elf.SectionHeader
@@ -41,18 +49,43 @@ type Note struct {
 
 // New creates a new Writer.
 func New(w WriteCloserSeeker, fhdr *elf.FileHeader) *Writer {
-	const (
-		ehsize    = 64
-		phentsize = 56
-		shentsize = 64
-	)
-
 	if seek, _ := w.Seek(0, io.SeekCurrent); seek != 0 {
 		panic("can't write halfway through a file")
 	}
 
-	r := &Writer{w: w}
+	r := &Writer{w: w, seeker: w}
+	r.writeFileHeader(fhdr, 0, 0)
+	return r
+}
 
+// NewStream creates a new Writer that does not need to seek backwards,
+// which can be used to write to a compressed stream.
+// The file header and the program headers in progs are written
+// immediately, the caller must set the Off field of each program header and
+// then write the contents of each segment in order of increasing offset,
+// starting at offset ProgHeadersEnd(len(progs)).
+// WriteProgramHeaders and WriteSectionHeaders can not be called on the
+// returned Writer.
+func NewStream(w io.Writer, fhdr *elf.FileHeader, progs []*elf.ProgHeader) *Writer {
+	r := &Writer{w: w, Progs: progs}
+	phoff := int64(0)
+	if len(progs) > 0 {
+		phoff = ehsize
+	}
+	r.writeFileHeader(fhdr, phoff, len(progs))
+	for _, prog := range progs {
+		r.writeProgHeader(prog)
+	}
+	return r
+}
+
+// ProgHeadersEnd returns the offset of the end of the program headers in a
+// file written by NewStream with n program headers.
+func ProgHeadersEnd(n int) uint64 {
+	return ehsize + uint64(n)*phentsize
+}
+
+func (w *Writer) writeFileHeader(fhdr *elf.FileHeader, phoff int64, phnum int) {
 	if fhdr.Class != elf.ELFCLASS64 {
 		panic("unsupported")
 	}
@@ -62,33 +95,31 @@ func New(w WriteCloserSeeker, fhdr *elf.FileHeader) *Writer {
 	}
 
 	// e_ident
-	r.Write([]byte{0x7f, 'E', 'L', 'F', byte(fhdr.Class), byte(fhdr.Data), byte(fhdr.Version), byte(fhdr.OSABI), fhdr.ABIVersion, 0, 0, 0, 0, 0, 0, 0})
-
-	r.u16(uint16(fhdr.Type))    // e_type
-	r.u16(uint16(fhdr.Machine)) // e_machine
-	r.u32(uint32(fhdr.Version)) // e_version
-	r.u64(0)                    // e_entry
-	r.seekProgHeader = r.Here()
-	r.u64(0) // e_phoff
-	r.seekSectionHeader = r.Here()
-	r.u64(0)         // e_shoff
-	r.u32(0)         // e_flags
-	r.u16(ehsize)    // e_ehsize
-	r.u16(phentsize) // e_phentsize
-	r.seekProgNum = r.Here()
-	r.u16(0)         // e_phnum
-	r.u16(shentsize) // e_shentsize
-	r.seekSectionNum = r.Here()
-	r.u16(0) // e_shnum
-	r.seekShstrndx = r.Here()
-	r.u16(uint16(elf.SHN_UNDEF)) // e_shstrndx
+	w.Write([]byte{0x7f, 'E', 'L', 'F', byte(fhdr.Class), byte(fhdr.Data), byte(fhdr.Version), byte(fhdr.OSABI), fhdr.ABIVersion, 0, 0, 0, 0, 0, 0, 0})
+
+	w.u16(uint16(fhdr.Type))    // e_type
+	w.u16(uint16(fhdr.Machine)) // e_machine
+	w.u32(uint32(fhdr.Version)) // e_version
+	w.u64(0)                    // e_entry
+	w.seekProgHeader = w.Here()
+	w.u64(uint64(phoff)) // e_phoff
+	w.seekSectionHeader = w.Here()
+	w.u64(0)         // e_shoff
+	w.u32(0)         // e_flags
+	w.u16(ehsize)    // e_ehsize
+	w.u16(phentsize) // e_phentsize
+	w.seekProgNum = w.Here()
+	w.u16(uint16(phnum)) // e_phnum
+	w.u16(shentsize)     // e_shentsize
+	w.seekSectionNum = w.Here()
+	w.u16(0) // e_shnum
+	w.seekShstrndx = w.Here()
+	w.u16(uint16(elf.SHN_UNDEF)) // e_shstrndx
 
 	// Sanity check, size of file header should be the same as ehsize
-	if sz, _ := w.Seek(0, io.SeekCurrent); sz != ehsize {
+	if w.Here() != ehsize {
 		panic("internal error, ELF header size")
 	}
-
-	return r
 }
 
 // WriteNotes writes notes to the current location, returns a ProgHeader describing the
@@ -118,30 +149,45 @@ func (w *Writer) WriteNotes(notes []Note) *elf.ProgHeader {
 	return h
 }
 
+// NotesSize returns the size of the notes written by WriteNotes, when the
+// current location is aligned to 4 bytes.
+func NotesSize(notes []Note) uint64 {
+	align4 := func(n uint64) uint64 { return (n + 3) &^ 3 }
+	var sz uint64
+	for i := range notes {
+		sz = align4(sz) + 12 + align4(uint64(len(notes[i].Name))) + uint64(len(notes[i].Data))
+	}
+	return sz
+}
+
 // WriteProgramHeaders writes the program headers at the current location
 // and patches the file header accordingly.
 func (w *Writer) WriteProgramHeaders() {
 	phoff := w.Here()
 
 	// Patch File Header
-	w.w.Seek(w.seekProgHeader, io.SeekStart)
+	w.seek(w.seekProgHeader, io.SeekStart)
 	w.u64(uint64(phoff))
-	w.w.Seek(w.seekProgNum, io.SeekStart)
+	w.seek(w.seekProgNum, io.SeekStart)
 	w.u16(uint16(len(w.Progs)))
-	w.w.Seek(0, io.SeekEnd)
+	w.seek(0, io.SeekEnd)
 
 	for _, prog := range w.Progs {
-		w.u32(uint32(prog.Type))
-		w.u32(uint32(prog.Flags))
-		w.u64(prog.Off)
-		w.u64(prog.Vaddr)
-		w.u64(prog.Paddr)
-		w.u64(prog.Filesz)
-		w.u64(prog.Memsz)
-		w.u64(prog.Align)
+		w.writeProgHeader(prog)
 	}
 }
 
+func (w *Writer) writeProgHeader(prog *elf.ProgHeader) {
+	w.u32(uint32(prog.Type))
+	w.u32(uint32(prog.Flags))
+	w.u64(prog.Off)
+	w.u64(prog.Vaddr)
+	w.u64(prog.Paddr)
+	w.u64(prog.Filesz)
+	w.u64(prog.Memsz)
+	w.u64(prog.Align)
+}
+
 // WriteSectionHeaders writes the section headers at the current location
 // and patches the file header accordingly.
 func (w *Writer) WriteSectionHeaders() {
@@ -174,13 +220,13 @@ func (w *Writer) WriteSectionHeaders() {
 	shoff := w.Here()
 
 	// Patch File Header
-	w.w.Seek(w.seekSectionHeader, io.SeekStart)
+	w.seek(w.seekSectionHeader, io.SeekStart)
 	w.u64(uint64(shoff))
-	w.w.Seek(w.seekSectionNum, io.SeekStart)
+	w.seek(w.seekSectionNum, io.SeekStart)
 	w.u16(uint16(len(w.Sections)))
-	w.w.Seek(w.seekShstrndx, io.SeekStart)
+	w.seek(w.seekShstrndx, io.SeekStart)
 	w.u16(uint16(shstrndx))
-	w.w.Seek(0, io.SeekEnd)
+	w.seek(0, io.SeekEnd)
 
 	for _, sect := range w.Sections {
 		w.u32(uint32(strToIndex[sect.Name]))
@@ -198,11 +244,21 @@ func (w *Writer) WriteSectionHeaders() {
 
 // Here returns the current seek offset from the start of the file.
 func (w *Writer) Here() int64 {
-	r, err := w.w.Seek(0, io.SeekCurrent)
+	return w.off
+}
+
+func (w *Writer) seek(off int64, whence int) {
+	if w.seeker == nil {
+		if w.Err == nil {
+			w.Err = errors.New("seek on a stream")
+		}
+		return
+	}
+	r, err := w.seeker.Seek(off, whence)
 	if err != nil && w.Err == nil {
 		w.Err = err
 	}
-	return r
+	w.off = r
 }
 
 // Align writes as many padding bytes as needed to make the current file
@@ -216,29 +272,21 @@ func (w *Writer) Align(align int64) {
 }
 
 func (w *Writer) Write(buf []byte) {
-	_, err := w.w.Write(buf)
+	n, err := w.w.Write(buf)
+	w.off += int64(n)
 	if err != nil && w.Err == nil {
 		w.Err = err
 	}
 }
 
 func (w *Writer) u16(n uint16) {
-	err := binary.Write(w.w, binary.LittleEndian, n)
-	if err != nil && w.Err == nil {
-		w.Err = err
-	}
+	w.Write(binary.LittleEndian.AppendUint16(nil, n))
 }
 
 func (w *Writer) u32(n uint32) {
-	err := binary.Write(w.w, binary.LittleEndian, n)
-	if err != nil && w.Err == nil {
-		w.Err = err
-	}
+	w.Write(binary.LittleEndian.AppendUint32(nil, n))
 }
 
 func (w *Writer) u64(n uint64) {
-	err := binary.Write(w.w, binary.LittleEndian, n)
-	if err != nil && w.Err == nil {
-		w.Err = err
-	}
+	w.Write(binary.LittleEndian.AppendUint64(nil, n))
 }
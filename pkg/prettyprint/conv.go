@@ -0,0 +1,251 @@
+package prettyprint
+
+import (
+	"errors"
+	"fmt"
+	"go/constant"
+	"reflect"
+
+	"go.starlark.net/starlark"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// autoLoadConfig is the load configuration used to load the parts of a
+// variable that a pretty printer accesses but were not loaded.
+var autoLoadConfig = proc.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 1024, MaxArrayValues: 1024, MaxStructFields: -1}
+
+// env is the environment in which pretty printers are called.
+type env struct {
+	scope  *proc.EvalScope
+	thread *starlark.Thread
+}
+
+// load returns v, loading it again if its value, or the value of its
+// children, was not loaded.
+func (env *env) load(v *proc.Variable) *proc.Variable {
+	if !needsLoad(v) || env.scope == nil || v.Addr == 0 || v.Flags&proc.VariableFakeAddress != 0 {
+		return v
+	}
+	v2, err := env.scope.EvalExpression(fmt.Sprintf("*(*%q)(%#x)", v.TypeString(), v.Addr), autoLoadConfig)
+	if err != nil {
+		return v
+	}
+	v2.Name = v.Name
+	return v2
+}
+
+func needsLoad(v *proc.Variable) bool {
+	if v.OnlyAddr {
+		return true
+	}
+	switch v.Kind {
+	case reflect.Struct:
+		return v.Len != 0 && len(v.Children) == 0
+	case reflect.Slice, reflect.Array:
+		return v.Len != 0 && len(v.Children) == 0
+	case reflect.Map:
+		return v.Len != 0 && len(v.Children) == 0
+	case reflect.Ptr, reflect.Interface:
+		return len(v.Children) > 0 && v.Children[0].OnlyAddr && v.Children[0].Addr != 0
+	}
+	return false
+}
+
+// variableToStarlarkValue converts v into a starlark.Value. Basic types
+// are converted to the corresponding Starlark type, pointers and
+// interfaces are automatically dereferenced, nil pointers and interfaces
+// are converted to None.
+func (env *env) variableToStarlarkValue(v *proc.Variable) (starlark.Value, error) {
+	v = env.load(v)
+	if v.Unreadable != nil {
+		return nil, v.Unreadable
+	}
+	switch v.Kind {
+	case reflect.Struct:
+		return structVariable{v, env}, nil
+	case reflect.Slice, reflect.Array:
+		return sliceVariable{v, env}, nil
+	case reflect.Map:
+		var r starlark.Dict
+		for i := 0; i+1 < len(v.Children); i += 2 {
+			key, err := env.variableToStarlarkValue(&v.Children[i])
+			if err != nil {
+				return nil, err
+			}
+			val, err := env.variableToStarlarkValue(&v.Children[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if err := r.SetKey(key, val); err != nil {
+				return nil, err
+			}
+		}
+		return &r, nil
+	case reflect.Ptr, reflect.Interface:
+		if len(v.Children) == 0 || v.Children[0].Addr == 0 || v.Children[0].Kind == reflect.Invalid {
+			return starlark.None, nil
+		}
+		return env.variableToStarlarkValue(&v.Children[0])
+	case reflect.UnsafePointer:
+		if len(v.Children) == 0 {
+			return starlark.MakeInt(0), nil
+		}
+		return starlark.MakeUint64(v.Children[0].Addr), nil
+	case reflect.String, reflect.Func:
+		if v.Value == nil {
+			return starlark.None, nil
+		}
+		return starlark.String(constant.StringVal(v.Value)), nil
+	case reflect.Bool:
+		return starlark.Bool(constant.BoolVal(v.Value)), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, _ := constant.Int64Val(v.Value)
+		return starlark.MakeInt64(n), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, _ := constant.Uint64Val(v.Value)
+		return starlark.MakeUint64(n), nil
+	case reflect.Float32, reflect.Float64:
+		f, _ := constant.Float64Val(v.Value)
+		return starlark.Float(f), nil
+	case reflect.Complex64, reflect.Complex128:
+		return starlark.String(v.Value.String()), nil
+	}
+	return starlark.None, nil
+}
+
+// starlarkValueToVariable converts a value returned by a pretty printer
+// as a synthetic child into a variable. Values that were produced from a
+// variable are converted back to it, other values become constants.
+func starlarkValueToVariable(name string, val starlark.Value) proc.Variable {
+	var r proc.Variable
+	switch val := val.(type) {
+	case structVariable:
+		r = *val.v
+	case sliceVariable:
+		r = *val.v
+	case starlark.String:
+		r = proc.Variable{Kind: reflect.String, Value: constant.MakeString(string(val)), Len: int64(len(val))}
+	case starlark.Bool:
+		r = proc.Variable{Kind: reflect.Bool, Value: constant.MakeBool(bool(val))}
+	case starlark.Int:
+		r = proc.Variable{Kind: reflect.Int, Value: constant.Make(val.BigInt())}
+	case starlark.Float:
+		r = proc.Variable{Kind: reflect.Float64, Value: constant.MakeFloat64(float64(val))}
+	default:
+		s := val.String()
+		r = proc.Variable{Kind: reflect.String, Value: constant.MakeString(s), Len: int64(len(s))}
+	}
+	r.Name = name
+	if r.DwarfType == nil {
+		r.Flags |= proc.VariableConstant
+	}
+	return r
+}
+
+// structVariable wraps a variable of kind struct, its fields can be
+// accessed as attributes.
+type structVariable struct {
+	v   *proc.Variable
+	env *env
+}
+
+var _ starlark.HasAttrs = structVariable{}
+
+func (v structVariable) Freeze() {
+}
+
+func (v structVariable) Hash() (uint32, error) {
+	return 0, errors.New("not hashable")
+}
+
+func (v structVariable) String() string {
+	return v.v.TypeString()
+}
+
+func (v structVariable) Truth() starlark.Bool {
+	return true
+}
+
+func (v structVariable) Type() string {
+	return v.v.TypeString()
+}
+
+func (v structVariable) Attr(name string) (starlark.Value, error) {
+	for i := range v.v.Children {
+		if v.v.Children[i].Name == name {
+			return v.env.variableToStarlarkValue(&v.v.Children[i])
+		}
+	}
+	return nil, nil // no such field
+}
+
+func (v structVariable) AttrNames() []string {
+	r := make([]string, len(v.v.Children))
+	for i := range v.v.Children {
+		r[i] = v.v.Children[i].Name
+	}
+	return r
+}
+
+// sliceVariable wraps a variable of kind slice or array, its loaded
+// elements can be accessed by index.
+type sliceVariable struct {
+	v   *proc.Variable
+	env *env
+}
+
+var _ starlark.Indexable = sliceVariable{}
+var _ starlark.Sequence = sliceVariable{}
+
+func (v sliceVariable) Freeze() {
+}
+
+func (v sliceVariable) Hash() (uint32, error) {
+	return 0, errors.New("not hashable")
+}
+
+func (v sliceVariable) String() string {
+	return v.v.TypeString()
+}
+
+func (v sliceVariable) Truth() starlark.Bool {
+	return v.v.Len != 0
+}
+
+func (v sliceVariable) Type() string {
+	return v.v.TypeString()
+}
+
+func (v sliceVariable) Index(i int) starlark.Value {
+	r, err := v.env.variableToStarlarkValue(&v.v.Children[i])
+	if err != nil {
+		return starlark.None
+	}
+	return r
+}
+
+func (v sliceVariable) Len() int {
+	return len(v.v.Children)
+}
+
+func (v sliceVariable) Iterate() starlark.Iterator {
+	return &sliceVariableIterator{v: v}
+}
+
+type sliceVariableIterator struct {
+	v   sliceVariable
+	cur int
+}
+
+func (it *sliceVariableIterator) Next(p *starlark.Value) bool {
+	if it.cur >= it.v.Len() {
+		return false
+	}
+	*p = it.v.Index(it.cur)
+	it.cur++
+	return true
+}
+
+func (it *sliceVariableIterator) Done() {
+}
@@ -0,0 +1,302 @@
+// Package prettyprint implements pretty printers for Go types.
+//
+// A pretty printer is a Starlark function, registered for a type name,
+// that receives a value of that type and returns either a string, which
+// will be used to display the value, or a dictionary with the keys
+// "value", the display string, and "children", a list of synthetic
+// children for the value.
+// Pretty printers are evaluated by the debugger after variables are
+// loaded, their output is returned to every client (terminal, DAP and
+// JSON-RPC).
+package prettyprint
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+
+	"github.com/go-delve/delve/pkg/logflags"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+const (
+	registerBuiltinName = "pretty_printer"
+	durationBuiltinName = "duration_string"
+	wordSizeBuiltinName = "word_size"
+
+	envThreadLocal = "prettyprint.env"
+
+	// maxDepth is the maximum depth of nested synthetic children that are
+	// pretty printed.
+	maxDepth = 16
+
+	// maxExecutionSteps is the maximum number of Starlark computation steps
+	// that a single call to a pretty printer can execute.
+	maxExecutionSteps = 1_000_000
+)
+
+//go:embed builtin.star
+var builtinScript string
+
+var syntaxFileOpts = &syntax.FileOptions{
+	Set:             true,
+	While:           true,
+	TopLevelControl: true,
+	GlobalReassign:  true,
+	Recursion:       true,
+}
+
+// Registry contains the pretty printers for a target, indexed by the
+// name of the type they format.
+type Registry struct {
+	mu       sync.Mutex
+	printers map[string]starlark.Callable
+}
+
+// New returns a registry containing the built-in pretty printers, unless
+// disableBuiltin is set, and the pretty printers registered by the
+// Starlark scripts in paths.
+func New(paths []string, disableBuiltin bool) (*Registry, error) {
+	r := &Registry{printers: make(map[string]starlark.Callable)}
+	if !disableBuiltin {
+		if err := r.Load("<builtin>", builtinScript); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range paths {
+		if err := r.Load(path, nil); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Load executes the Starlark script at path, if src is nil, or the script
+// contained in src. The script can register pretty printers by calling
+// the pretty_printer builtin.
+func (r *Registry) Load(path string, src interface{}) error {
+	if src == nil {
+		if strings.HasPrefix(path, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, path[2:])
+			}
+		}
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read pretty printers file: %v", err)
+		}
+		src = buf
+	}
+	thread := &starlark.Thread{Name: path}
+	predeclared := starlark.StringDict{
+		registerBuiltinName: starlark.NewBuiltin(registerBuiltinName, r.register),
+		durationBuiltinName: starlark.NewBuiltin(durationBuiltinName, durationString),
+		wordSizeBuiltinName: starlark.NewBuiltin(wordSizeBuiltinName, wordSize),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := starlark.ExecFileOptions(syntaxFileOpts, thread, path, src, predeclared)
+	if err != nil {
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			return fmt.Errorf("error loading pretty printers: %s", evalErr.Backtrace())
+		}
+		return fmt.Errorf("error loading pretty printers: %v", err)
+	}
+	return nil
+}
+
+// register implements the pretty_printer builtin.
+func (r *Registry) register(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var typeName string
+	var fn starlark.Callable
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "type", &typeName, "fn", &fn); err != nil {
+		return nil, err
+	}
+	r.printers[typeName] = fn
+	return starlark.None, nil
+}
+
+// Types returns the names of the types that have a pretty printer.
+func (r *Registry) Types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]string, 0, len(r.printers))
+	for typeName := range r.printers {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Apply runs the pretty printers registered for the type of v and of its
+// loaded children, storing their output in the Formatted and
+// FormattedChildren fields of the variables they format.
+// If scope is not nil it will be used to load the parts of a variable
+// accessed by a pretty printer that were not loaded already.
+func (r *Registry) Apply(scope *proc.EvalScope, v *proc.Variable) {
+	if r == nil || v == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.printers) == 0 {
+		return
+	}
+	env := &env{scope: scope, thread: &starlark.Thread{Name: "pretty printer"}}
+	env.thread.SetLocal(envThreadLocal, env)
+	r.apply(env, v, 0)
+}
+
+func (r *Registry) apply(env *env, v *proc.Variable, depth int) {
+	if depth > maxDepth {
+		return
+	}
+	if v.Flags&proc.VariableFormatted == 0 && v.Unreadable == nil && !v.OnlyAddr {
+		if fn := r.printers[v.TypeString()]; fn != nil {
+			r.format(env, fn, v)
+		}
+	}
+	children := v.Children
+	if v.Flags&proc.VariableFormatted != 0 && v.FormattedChildren != nil {
+		children = v.FormattedChildren
+	}
+	for i := range children {
+		r.apply(env, &children[i], depth+1)
+	}
+}
+
+// format calls the pretty printer fn on v.
+func (r *Registry) format(env *env, fn starlark.Callable, v *proc.Variable) {
+	arg, err := env.variableToStarlarkValue(v)
+	if err != nil {
+		setFormatted(v, fmt.Sprintf("(pretty printer error: %v)", err), nil)
+		return
+	}
+	env.thread.Uncancel()
+	env.thread.SetMaxExecutionSteps(env.thread.ExecutionSteps() + maxExecutionSteps)
+	out, err := starlark.Call(env.thread, fn, starlark.Tuple{arg}, nil)
+	if err != nil {
+		logflags.DebuggerLogger().Debugf("pretty printer for %s: %v", v.TypeString(), err)
+		setFormatted(v, fmt.Sprintf("(pretty printer error: %v)", err), nil)
+		return
+	}
+	value, children, err := parseResult(out)
+	if err != nil {
+		setFormatted(v, fmt.Sprintf("(pretty printer error: %v)", err), nil)
+		return
+	}
+	if value == nil {
+		// the pretty printer declined to format this value
+		return
+	}
+	setFormatted(v, *value, children)
+}
+
+func setFormatted(v *proc.Variable, value string, children []proc.Variable) {
+	v.Flags |= proc.VariableFormatted
+	v.Formatted = value
+	v.FormattedChildren = children
+}
+
+// parseResult converts the value returned by a pretty printer into a
+// display string and a list of synthetic children. If the pretty printer
+// returned None the returned string is nil.
+func parseResult(out starlark.Value) (*string, []proc.Variable, error) {
+	switch out := out.(type) {
+	case starlark.NoneType:
+		return nil, nil, nil
+	case starlark.String:
+		s := string(out)
+		return &s, nil, nil
+	case *starlark.Dict:
+		var s string
+		if vv, found, _ := out.Get(starlark.String("value")); found {
+			vs, ok := vv.(starlark.String)
+			if !ok {
+				return nil, nil, fmt.Errorf("value must be a string, not %s", vv.Type())
+			}
+			s = string(vs)
+		}
+		cv, found, _ := out.Get(starlark.String("children"))
+		if !found {
+			return &s, nil, nil
+		}
+		children, err := parseChildren(cv)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &s, children, nil
+	default:
+		return nil, nil, fmt.Errorf("pretty printers must return a string, a dict or None, not %s", out.Type())
+	}
+}
+
+// parseChildren converts the synthetic children returned by a pretty
+// printer, either a dict or a list of (name, value) pairs, into variables.
+func parseChildren(cv starlark.Value) ([]proc.Variable, error) {
+	var names []string
+	var values []starlark.Value
+	switch cv := cv.(type) {
+	case *starlark.Dict:
+		for _, item := range cv.Items() {
+			name, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("child names must be strings, not %s", item[0].Type())
+			}
+			names = append(names, string(name))
+			values = append(values, item[1])
+		}
+	case starlark.Iterable:
+		it := cv.Iterate()
+		defer it.Done()
+		var item starlark.Value
+		for it.Next(&item) {
+			pair, ok := item.(starlark.Tuple)
+			if !ok || len(pair) != 2 {
+				return nil, errors.New("children must be (name, value) pairs")
+			}
+			name, ok := pair[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("child names must be strings, not %s", pair[0].Type())
+			}
+			names = append(names, string(name))
+			values = append(values, pair[1])
+		}
+	default:
+		return nil, fmt.Errorf("children must be a dict or a list, not %s", cv.Type())
+	}
+	children := make([]proc.Variable, len(values))
+	for i := range values {
+		children[i] = starlarkValueToVariable(names[i], values[i])
+	}
+	return children, nil
+}
+
+// durationString implements the duration_string builtin.
+func durationString(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var n int64
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "ns", &n); err != nil {
+		return nil, err
+	}
+	return starlark.String(time.Duration(n).String()), nil
+}
+
+// wordSize implements the word_size builtin.
+func wordSize(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return nil, err
+	}
+	if env, _ := thread.Local(envThreadLocal).(*env); env != nil && env.scope != nil {
+		return starlark.MakeInt(env.scope.BinInfo.Arch.PtrSize()), nil
+	}
+	return starlark.MakeInt(8), nil
+}
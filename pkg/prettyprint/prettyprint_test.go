@@ -0,0 +1,104 @@
+package prettyprint
+
+import (
+	"go/constant"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+func intVar(name string, n int64) proc.Variable {
+	return proc.Variable{Name: name, Kind: reflect.Int, Value: constant.MakeInt64(n)}
+}
+
+func TestBuiltinLoad(t *testing.T) {
+	r, err := New(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := r.Types()
+	for _, tgt := range []string{"time.Duration", "net/netip.Addr", "sync/atomic.Int64"} {
+		found := false
+		for _, typ := range types {
+			if typ == tgt {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("no builtin pretty printer for %s (%v)", tgt, types)
+		}
+	}
+	r, err = New(nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := r.Types(); len(types) != 0 {
+		t.Errorf("builtin pretty printers loaded when disabled: %v", types)
+	}
+}
+
+func TestApply(t *testing.T) {
+	r, err := New(nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Load("test.star", `
+def _int(v):
+	if v < 0:
+		return None
+	if v == 0:
+		return v.missing
+	if v == 1:
+		return {"value": "one", "children": [("double", 2), ("name", "one")]}
+	return "n=%d" % v
+
+pretty_printer("int", _int)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := intVar("a", 3)
+	r.Apply(nil, &v)
+	if v.Flags&proc.VariableFormatted == 0 || v.Formatted != "n=3" {
+		t.Errorf("wrong output for 3: %#x %q", v.Flags, v.Formatted)
+	}
+
+	v = intVar("a", -1)
+	r.Apply(nil, &v)
+	if v.Flags&proc.VariableFormatted != 0 {
+		t.Errorf("value formatted when the pretty printer returned None: %q", v.Formatted)
+	}
+
+	v = intVar("a", 0)
+	r.Apply(nil, &v)
+	if !strings.HasPrefix(v.Formatted, "(pretty printer error: ") {
+		t.Errorf("wrong output for failing pretty printer: %q", v.Formatted)
+	}
+
+	v = intVar("a", 1)
+	r.Apply(nil, &v)
+	if v.Formatted != "one" || len(v.FormattedChildren) != 2 {
+		t.Fatalf("wrong output for 1: %q %v", v.Formatted, v.FormattedChildren)
+	}
+	// synthetic children are pretty printed too
+	if c := v.FormattedChildren[0]; c.Name != "double" || c.Formatted != "n=2" {
+		t.Errorf("wrong first child: %q %q", c.Name, c.Formatted)
+	}
+	if c := v.FormattedChildren[1]; c.Name != "name" || constant.StringVal(c.Value) != "one" {
+		t.Errorf("wrong second child: %q %v", c.Name, c.Value)
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	r, err := New(nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Load("bad.star", `pretty_printer("int")`)
+	if err == nil || !strings.Contains(err.Error(), "bad.star") {
+		t.Errorf("expected error mentioning the script, got %v", err)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"go/token"
 	"hash/crc32"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"slices"
@@ -66,10 +67,18 @@ type BinaryInfo struct {
 	// lookupGenericFunc maps function names, with their type parameters removed, to functions.
 	// Functions that are not generic are not added to this map.
 	lookupGenericFunc map[string][]*Function
+	// completions is the index of package members and methods used by
+	// EvalScope.Completions, built the first time it is needed.
+	completions *completionIndex
 
 	// SymNames maps addr to a description *elf.Symbol of this addr.
 	SymNames map[uint64]*elf.Symbol
 
+	// dictionaries maps the name of the dictionary symbol of each
+	// instantiation of a generic function or type (for example
+	// "main..dict.F[int]") to its address.
+	dictionaries map[string]uint64
+
 	// Images is a list of loaded shared libraries (also known as
 	// shared objects on linux or DLLs on windows).
 	Images []*Image
@@ -963,6 +972,72 @@ func (bi *BinaryInfo) AllPCsForFileLines(filename string, linenos []int) map[int
 	return r
 }
 
+// StatementPosition is a source position, inside a concrete function, where
+// a statement begins.
+type StatementPosition struct {
+	Line   int
+	Column int // zero if the column is not recorded in debug_line
+	Fn     *Function
+	PCs    []uint64
+}
+
+// StatementPositions returns the positions of the statements of filename
+// between startLine and endLine (both included), sorted by line and column.
+// Instructions of the same line and column are grouped by the concrete
+// function containing them, this distinguishes function literals defined
+// on the same line even if the compiler did not record columns.
+func (bi *BinaryInfo) StatementPositions(filename string, startLine, endLine int) []StatementPosition {
+	var stmts []line.StmtPosition
+	for _, image := range bi.Images {
+		for _, cu := range image.compileUnits {
+			if cu.lineInfo != nil && cu.lineInfo.Lookup[filename] != nil {
+				stmts = append(stmts, cu.lineInfo.StmtsForFileLines(filename, startLine, endLine)...)
+			}
+		}
+	}
+	for fl, pcs := range bi.inlinedCallLines {
+		if fl.file == filename && fl.line >= startLine && fl.line <= endLine {
+			for _, pc := range pcs {
+				stmts = append(stmts, line.StmtPosition{PC: pc, Line: fl.line})
+			}
+		}
+	}
+	sort.Slice(stmts, func(i, j int) bool { return stmts[i].PC < stmts[j].PC })
+
+	type posKey struct {
+		line, column int
+		fn           *Function
+	}
+	idx := map[posKey]int{}
+	var r []StatementPosition
+	var fn *Function
+	for _, stmt := range stmts {
+		if fn == nil || stmt.PC < fn.Entry || stmt.PC >= fn.End {
+			fn = bi.PCToFunc(stmt.PC)
+		}
+		if fn == nil || strings.Contains(fn.Name, "·dwrap·") || fn.trampoline {
+			continue
+		}
+		k := posKey{stmt.Line, stmt.Column, fn}
+		i, ok := idx[k]
+		if !ok {
+			i = len(r)
+			idx[k] = i
+			r = append(r, StatementPosition{Line: stmt.Line, Column: stmt.Column, Fn: fn})
+		}
+		if n := len(r[i].PCs); n == 0 || r[i].PCs[n-1] != stmt.PC {
+			r[i].PCs = append(r[i].PCs, stmt.PC)
+		}
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		if r[i].Line != r[j].Line {
+			return r[i].Line < r[j].Line
+		}
+		return r[i].Column < r[j].Column
+	})
+	return r
+}
+
 // PCToFunc returns the concrete function containing the given PC address.
 // If the PC address belongs to an inlined call it will return the containing function.
 func (bi *BinaryInfo) PCToFunc(pc uint64) *Function {
@@ -1040,6 +1115,12 @@ func (image *Image) Stripped() bool {
 // the relocation offset) for all other images.
 // The first image added must be the executable file.
 func (bi *BinaryInfo) AddImage(path string, addr uint64) error {
+	return bi.AddImageWithBuildID(path, addr, "")
+}
+
+// AddImageWithBuildID is like AddImage, if path does not exist and buildID
+// is not empty a copy of the image is downloaded from debuginfod.
+func (bi *BinaryInfo) AddImageWithBuildID(path string, addr uint64, buildID string) error {
 	// Check if the image is already present.
 	if len(bi.Images) > 0 && !strings.HasPrefix(path, "/") {
 		return nil
@@ -1051,7 +1132,7 @@ func (bi *BinaryInfo) AddImage(path string, addr uint64) error {
 	}
 
 	// Actually add the image.
-	image := &Image{Path: path, addr: addr, typeCache: make(map[dwarf.Offset]godwarf.Type)}
+	image := &Image{Path: path, BuildID: buildID, addr: addr, typeCache: make(map[dwarf.Offset]godwarf.Type)}
 	image.dwarfTreeCache, _ = simplelru.NewLRU(dwarfTreeCacheSize, nil)
 
 	// add Image regardless of error so that we don't attempt to re-add it every time we stop
@@ -1561,6 +1642,9 @@ func (bi *BinaryInfo) openSeparateDebugInfo(image *Image, exe *elf.File, debugIn
 		var err error
 		debugFilePath, err = debuginfod.GetDebuginfo(image.BuildID)
 		if err != nil {
+			if !errors.Is(err, debuginfod.ErrNoServers) {
+				bi.logger.Debugf("debuginfod: %v", err)
+			}
 			return nil, nil, ErrNoDebugInfoFound
 		}
 	}
@@ -1588,7 +1672,22 @@ func (bi *BinaryInfo) openSeparateDebugInfo(image *Image, exe *elf.File, debugIn
 func loadBinaryInfoElf(bi *BinaryInfo, image *Image, path string, addr uint64, wg *sync.WaitGroup) error {
 	exe, err := os.OpenFile(path, 0, os.ModePerm)
 	if err != nil {
-		return err
+		if !errors.Is(err, fs.ErrNotExist) || image.BuildID == "" {
+			return err
+		}
+		// The image is not on this system, for example a shared library of a
+		// core file, try to download it from debuginfod.
+		exePath, derr := debuginfod.GetExecutable(image.BuildID)
+		if derr != nil {
+			if !errors.Is(derr, debuginfod.ErrNoServers) {
+				bi.logger.Debugf("debuginfod: %v", derr)
+			}
+			return err
+		}
+		exe, err = os.OpenFile(exePath, 0, os.ModePerm)
+		if err != nil {
+			return err
+		}
 	}
 	image.closer = exe
 	elfFile, err := elf.NewFile(exe)
@@ -1738,7 +1837,26 @@ func (bi *BinaryInfo) loadSymbolName(image *Image, file *elf.File, wg *sync.Wait
 			s := symSec
 			bi.SymNames[symSec.Value+image.StaticBase] = &s
 		}
+		if elf.ST_TYPE(symSec.Info) == elf.STT_OBJECT {
+			bi.addDictionary(symSec.Name, symSec.Value+image.StaticBase)
+		}
+	}
+}
+
+// dictSymbolSep separates the package path from the instantiation in the
+// name of the symbol of a generic dictionary.
+const dictSymbolSep = "..dict."
+
+// addDictionary records the address of symbol name, if it is the
+// dictionary of an instantiation of a generic function or type.
+func (bi *BinaryInfo) addDictionary(name string, addr uint64) {
+	if !strings.Contains(name, dictSymbolSep) {
+		return
+	}
+	if bi.dictionaries == nil {
+		bi.dictionaries = make(map[string]uint64)
 	}
+	bi.dictionaries[name] = addr
 }
 
 func (bi *BinaryInfo) loadBuildID(image *Image, file *elf.File) {
@@ -1921,6 +2039,12 @@ func loadBinaryInfoPE(bi *BinaryInfo, image *Image, path string, entryPoint uint
 		}
 	}
 
+	for _, s := range peFile.Symbols {
+		if i := int(s.SectionNumber) - 1; 0 <= i && i < len(peFile.Sections) {
+			bi.addDictionary(s.Name, opth.ImageBase+image.StaticBase+uint64(peFile.Sections[i].VirtualAddress)+uint64(s.Value))
+		}
+	}
+
 	image.dwarfReader = image.dwarf.Reader()
 
 	debugLineBytes, err := godwarf.GetDebugSectionPE(peFile, "line")
@@ -2052,6 +2176,12 @@ func loadBinaryInfoMacho(bi *BinaryInfo, image *Image, path string, entryPoint u
 		return err
 	}
 
+	if exe.Symtab != nil {
+		for _, s := range exe.Symtab.Syms {
+			bi.addDictionary(strings.TrimPrefix(s.Name, "_"), s.Value+image.StaticBase)
+		}
+	}
+
 	image.dwarfReader = image.dwarf.Reader()
 
 	debugLineBytes, err := godwarf.GetDebugSectionMacho(exe, "line")
@@ -2584,6 +2714,7 @@ func (bi *BinaryInfo) loadDebugInfoMaps(image *Image, debugInfoBytes, debugLineB
 
 	bi.lookupFunc = nil
 	bi.lookupGenericFunc = nil
+	bi.completions = nil
 
 	for _, cu := range image.compileUnits {
 		if cu.lineInfo != nil {
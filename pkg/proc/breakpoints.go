@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"go/ast"
 	"go/constant"
-	"go/parser"
 	"go/printer"
 	"go/token"
 	"reflect"
@@ -629,22 +628,69 @@ func (t *Target) SetWatchpoint(logicalID int, scope *EvalScope, expr string, wty
 		return nil, errors.New("at least one of read and write must be set for watchpoint")
 	}
 
-	n, err := parser.ParseExpr(expr)
+	xv, expr, stackWatch, err := t.watchpointTarget(scope, expr)
 	if err != nil {
 		return nil, err
 	}
+
+	if stackWatch && wtype&WatchRead != 0 {
+		// In theory this would work except for the fact that the runtime will
+		// read them randomly to resize stacks so it doesn't make sense to do
+		// this.
+		return nil, errors.New("can not watch stack allocated variable for reads")
+	}
+
+	bp, err := t.setBreakpointInternal(logicalID, xv.Addr, UserBreakpoint, wtype.withSize(uint8(xv.DwarfType.Size())), cond)
+	if err != nil {
+		return bp, err
+	}
+	bp.WatchExpr = expr
+
+	if stackWatch {
+		bp.watchStackOff = int64(bp.Addr) - int64(scope.g.stack.hi)
+		err := t.setStackWatchBreakpoints(scope, bp)
+		if err != nil {
+			return bp, err
+		}
+	}
+
+	return bp, nil
+}
+
+// WatchpointTypes returns the types of watchpoint that can be set on expr,
+// evaluated in scope, or an error if expr can not be watched.
+func (t *Target) WatchpointTypes(scope *EvalScope, expr string) (WatchType, error) {
+	_, _, stackWatch, err := t.watchpointTarget(scope, expr)
+	if err != nil {
+		return 0, err
+	}
+	if stackWatch {
+		return WatchWrite, nil
+	}
+	return WatchRead | WatchWrite, nil
+}
+
+// watchpointTarget evaluates expr in scope and returns the variable that a
+// watchpoint on expr would watch, the description of the watched
+// expression and whether the variable is allocated on the stack of the
+// goroutine of scope.
+func (t *Target) watchpointTarget(scope *EvalScope, expr string) (*Variable, string, bool, error) {
+	n, err := evalop.ParseExpr(expr)
+	if err != nil {
+		return nil, "", false, err
+	}
 	xv, err := scope.evalAST(n)
 	if err != nil {
-		return nil, err
+		return nil, "", false, err
 	}
 	if xv.Addr == 0 || xv.Flags&VariableFakeAddress != 0 || xv.DwarfType == nil {
-		return nil, fmt.Errorf("can not watch %q", expr)
+		return nil, "", false, fmt.Errorf("can not watch %q", expr)
 	}
 	if xv.Unreadable != nil {
-		return nil, fmt.Errorf("expression %q is unreadable: %v", expr, xv.Unreadable)
+		return nil, "", false, fmt.Errorf("expression %q is unreadable: %v", expr, xv.Unreadable)
 	}
 	if xv.Kind == reflect.UnsafePointer || xv.Kind == reflect.Invalid {
-		return nil, fmt.Errorf("can not watch variable of type %s", xv.Kind.String())
+		return nil, "", false, fmt.Errorf("can not watch variable of type %s", xv.Kind.String())
 	}
 
 	// Special handling for interface types
@@ -653,10 +699,10 @@ func (t *Target) SetWatchpoint(logicalID int, scope *EvalScope, expr string, wty
 		// Read the interface to get the data pointer
 		_, data, _ := xv.readInterface()
 		if xv.Unreadable != nil {
-			return nil, fmt.Errorf("error reading interface %q: %v", expr, xv.Unreadable)
+			return nil, "", false, fmt.Errorf("error reading interface %q: %v", expr, xv.Unreadable)
 		}
 		if data == nil {
-			return nil, fmt.Errorf("invalid interface %q", expr)
+			return nil, "", false, fmt.Errorf("invalid interface %q", expr)
 		}
 
 		// Use the data field as our watch target
@@ -668,33 +714,12 @@ func (t *Target) SetWatchpoint(logicalID int, scope *EvalScope, expr string, wty
 	if sz <= 0 || sz > int64(t.BinInfo().Arch.PtrSize()) {
 		//TODO(aarzilli): it is reasonable to expect to be able to watch string
 		//variables and we could support it by watching certain member fields here.
-		return nil, fmt.Errorf("can not watch variable of type %s", xv.DwarfType.String())
+		return nil, "", false, fmt.Errorf("can not watch variable of type %s", xv.DwarfType.String())
 	}
 
 	stackWatch := scope.g != nil && !scope.g.SystemStack && xv.Addr >= scope.g.stack.lo && xv.Addr < scope.g.stack.hi
 
-	if stackWatch && wtype&WatchRead != 0 {
-		// In theory this would work except for the fact that the runtime will
-		// read them randomly to resize stacks so it doesn't make sense to do
-		// this.
-		return nil, errors.New("can not watch stack allocated variable for reads")
-	}
-
-	bp, err := t.setBreakpointInternal(logicalID, xv.Addr, UserBreakpoint, wtype.withSize(uint8(sz)), cond)
-	if err != nil {
-		return bp, err
-	}
-	bp.WatchExpr = expr
-
-	if stackWatch {
-		bp.watchStackOff = int64(bp.Addr) - int64(scope.g.stack.hi)
-		err := t.setStackWatchBreakpoints(scope, bp)
-		if err != nil {
-			return bp, err
-		}
-	}
-
-	return bp, nil
+	return xv, expr, stackWatch, nil
 }
 
 func (t *Target) setBreakpointInternal(logicalID int, addr uint64, kind BreakpointKind, wtype WatchType, cond ast.Expr) (*Breakpoint, error) {
@@ -0,0 +1,231 @@
+package proc
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+)
+
+// CompletionKind describes what a name returned by Completions refers to.
+type CompletionKind uint8
+
+const (
+	CompletionVariable CompletionKind = iota // local, argument or package variable
+	CompletionField                          // struct field
+	CompletionMethod                         // method
+	CompletionFunction                       // package level function
+	CompletionType                           // type name
+	CompletionPackage                        // package name
+)
+
+// Completion is a name that can be used at some point of an expression.
+type Completion struct {
+	Name string
+	Kind CompletionKind
+}
+
+// maxEmbeddedDepth is the maximum depth of embedded structs that
+// Completions will look into for promoted fields and methods.
+const maxEmbeddedDepth = 5
+
+// Completions returns the names starting with prefix that can be used
+// in an expression evaluated in scope.
+// If operand is empty the names are the local variables, the arguments,
+// the package variables and functions of the current package and the
+// package names.
+// Otherwise operand is the expression to the left of a selector and the
+// names are the members of the package called operand or the fields and
+// methods of the value of operand.
+// The returned completions are sorted by name.
+func (scope *EvalScope) Completions(operand, prefix string) ([]Completion, error) {
+	c := &completer{prefix: prefix, seen: make(map[string]bool)}
+	if operand == "" {
+		if err := scope.completeNames(c); err != nil {
+			return nil, err
+		}
+	} else if err := scope.completeMembers(c, operand); err != nil {
+		return nil, err
+	}
+	sort.Slice(c.r, func(i, j int) bool {
+		return c.r[i].Name < c.r[j].Name
+	})
+	return c.r, nil
+}
+
+type completer struct {
+	prefix string
+	seen   map[string]bool
+	r      []Completion
+}
+
+func (c *completer) add(name string, kind CompletionKind) {
+	if name == "" || c.seen[name] || !strings.HasPrefix(name, c.prefix) {
+		return
+	}
+	c.seen[name] = true
+	c.r = append(c.r, Completion{Name: name, Kind: kind})
+}
+
+func (scope *EvalScope) completeNames(c *completer) error {
+	if scope.Fn != nil {
+		vars, err := scope.Locals(0, "")
+		if err != nil {
+			return err
+		}
+		for _, v := range vars {
+			if v.Flags&VariableShadowed != 0 || strings.HasPrefix(v.Name, "~") || strings.HasPrefix(v.Name, ".") {
+				continue
+			}
+			c.add(v.Name, CompletionVariable)
+		}
+		scope.completePackageMembers(c, scope.Fn.PackageName())
+	}
+	for name := range scope.BinInfo.PackageMap {
+		c.add(name, CompletionPackage)
+	}
+	return nil
+}
+
+func (scope *EvalScope) completeMembers(c *completer, operand string) error {
+	if pkgpaths, ok := scope.BinInfo.PackageMap[operand]; ok {
+		// A local variable with the same name as a package shadows it.
+		vars, err := scope.Locals(0, operand)
+		if err != nil || len(vars) == 0 {
+			for _, pkgpath := range pkgpaths {
+				scope.completePackageMembers(c, pkgpath)
+			}
+			return nil
+		}
+	}
+
+	v, err := scope.EvalExpression(operand, LoadConfig{MaxVariableRecurse: 1})
+	if err != nil {
+		return err
+	}
+	if v.Unreadable != nil {
+		return v.Unreadable
+	}
+	typ := v.DwarfType
+	if v.Kind == reflect.Interface && len(v.Children) > 0 && v.Children[0].Kind != reflect.Invalid {
+		// Complete using the concrete type of the value.
+		typ = v.Children[0].DwarfType
+	}
+	scope.completeTypeMembers(c, typ, make(map[godwarf.Type]bool), 0)
+	return nil
+}
+
+// completePackageMembers adds the package variables, functions and types
+// of the package with path pkgpath.
+func (scope *EvalScope) completePackageMembers(c *completer, pkgpath string) {
+	for _, member := range scope.BinInfo.completionIndex().members[pkgpath] {
+		c.add(member.Name, member.Kind)
+	}
+}
+
+// completionIndex contains the names used by completePackageMembers and
+// completeMethods.
+type completionIndex struct {
+	members map[string][]Completion // package path -> package variables, functions and types
+	methods map[string][]string     // qualified type name -> method names
+}
+
+// completionIndex returns the completion index of bi, building it if
+// needed.
+func (bi *BinaryInfo) completionIndex() *completionIndex {
+	if bi.completions != nil {
+		return bi.completions
+	}
+	idx := &completionIndex{members: make(map[string][]Completion), methods: make(map[string][]string)}
+	addMember := func(name string, kind CompletionKind) {
+		dot := strings.LastIndex(name, ".")
+		if dot <= 0 || strings.Contains(name[dot+1:], "-") {
+			return
+		}
+		pkgpath, name := name[:dot], name[dot+1:]
+		if kind == CompletionFunction && isInitFunction(name) {
+			return
+		}
+		idx.members[pkgpath] = append(idx.members[pkgpath], Completion{Name: name, Kind: kind})
+	}
+	for _, pkgvar := range bi.packageVars {
+		addMember(pkgvar.name, CompletionVariable)
+	}
+	for i := range bi.Functions {
+		fn := &bi.Functions[i]
+		addMember(fn.NameWithoutTypeParams(), CompletionFunction)
+		recv := fn.ReceiverName()
+		if i := strings.Index(recv, "["); i >= 0 {
+			recv = recv[:i]
+		}
+		recv = strings.TrimSuffix(strings.TrimPrefix(recv, "(*"), ")")
+		if recv == "" || strings.Contains(fn.BaseName(), "-") {
+			continue
+		}
+		typename := fn.PackageName() + "." + recv
+		idx.methods[typename] = append(idx.methods[typename], fn.BaseName())
+	}
+	for name := range bi.types {
+		if i := strings.Index(name, "["); i >= 0 {
+			name = name[:i]
+		}
+		addMember(name, CompletionType)
+	}
+	// Instantiations of generic functions and types share the same name.
+	for pkgpath, members := range idx.members {
+		sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+		idx.members[pkgpath] = slices.CompactFunc(members, func(a, b Completion) bool { return a.Name == b.Name })
+	}
+	for typename, methods := range idx.methods {
+		sort.Strings(methods)
+		idx.methods[typename] = slices.Compact(methods)
+	}
+	bi.completions = idx
+	return idx
+}
+
+// isInitFunction returns true if name is the name of a package
+// initialization function.
+func isInitFunction(name string) bool {
+	return name == "init" || strings.HasPrefix(name, "init.")
+}
+
+// completeTypeMembers adds the fields and methods of typ, including the
+// ones promoted from embedded fields. If typ is a pointer the fields and
+// methods of the type it points to are used.
+func (scope *EvalScope) completeTypeMembers(c *completer, typ godwarf.Type, visited map[godwarf.Type]bool, depth int) {
+	if typ == nil || visited[typ] || depth > maxEmbeddedDepth {
+		return
+	}
+	visited[typ] = true
+	if ptyp, isptr := godwarf.ResolveTypedef(typ).(*godwarf.PtrType); isptr {
+		typ = ptyp.Type
+	}
+	if typ == nil {
+		return
+	}
+	if styp, isstruct := godwarf.ResolveTypedef(typ).(*godwarf.StructType); isstruct {
+		for _, field := range styp.Field {
+			c.add(field.Name, CompletionField)
+		}
+		for _, field := range styp.Field {
+			if field.Embedded {
+				scope.completeTypeMembers(c, field.Type, visited, depth+1)
+			}
+		}
+	}
+	scope.completeMethods(c, typ.Common().Name)
+}
+
+// completeMethods adds the methods of the named type typename, with
+// receivers of both value and pointer type.
+func (scope *EvalScope) completeMethods(c *completer, typename string) {
+	if i := strings.Index(typename, "["); i >= 0 {
+		typename = typename[:i]
+	}
+	for _, name := range scope.BinInfo.completionIndex().methods[typename] {
+		c.add(name, CompletionMethod)
+	}
+}
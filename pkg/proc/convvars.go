@@ -0,0 +1,182 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/go-delve/delve/pkg/proc/evalop"
+)
+
+// ConvenienceVariables is the set of convenience variables defined by the
+// user. Convenience variables are referenced in expressions as $name, they
+// hold either a snapshot of a value, taken when the variable was set, or a
+// live expression that is evaluated every time the variable is used.
+// It is safe for concurrent use.
+type ConvenienceVariables struct {
+	mu   sync.Mutex
+	vars map[string]*convVar
+}
+
+type convVar struct {
+	v    *Variable // snapshot of the value, nil for live expressions
+	expr string    // source of the live expression
+	t    ast.Expr  // parsed live expression
+}
+
+// convVarLoadConfig is the configuration used to load the value of a
+// convenience variable when its snapshot is taken.
+var convVarLoadConfig = loadFullValueLongerStrings
+
+// NewConvenienceVariables returns an empty set of convenience variables.
+func NewConvenienceVariables() *ConvenienceVariables {
+	return &ConvenienceVariables{vars: make(map[string]*convVar)}
+}
+
+// SetLive sets the convenience variable $name to the live expression expr.
+func (cv *ConvenienceVariables) SetLive(name, expr string) error {
+	if !token.IsIdentifier(name) {
+		return fmt.Errorf("invalid convenience variable name %q", name)
+	}
+	t, err := evalop.ParseExpr(expr)
+	if err != nil {
+		return err
+	}
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.vars[name] = &convVar{expr: expr, t: t}
+	return nil
+}
+
+// setSnapshot sets the convenience variable $name to a copy of v.
+func (cv *ConvenienceVariables) setSnapshot(name string, v *Variable) error {
+	if v.Flags&(VariableFakeAddress|VariableCPURegister|variableSaved) == 0 && v.Unreadable == nil && v.RealType != nil && v.Addr != 0 {
+		saveVariable(v)
+	}
+	v.loadValue(convVarLoadConfig)
+	if v.Unreadable != nil {
+		return fmt.Errorf("can not set $%s: %v", name, v.Unreadable)
+	}
+	if v != nilVariable {
+		v = cloneSnapshot(v)
+		v.Name = "$" + name
+	}
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.vars[name] = &convVar{v: v}
+	return nil
+}
+
+func (cv *ConvenienceVariables) get(name string) (*convVar, bool) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	v, ok := cv.vars[name]
+	return v, ok
+}
+
+// restore copies the convenience variables of old that survive a restart
+// of the target into cv: live expressions and snapshots of plain values
+// (booleans, numbers and strings). Snapshots that reference the memory of
+// the old target are discarded.
+func (cv *ConvenienceVariables) restore(old *ConvenienceVariables) {
+	if old == nil || old == cv {
+		return
+	}
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	for name, v := range old.vars {
+		switch {
+		case v.v == nil:
+			cv.vars[name] = v
+		case isPlainValue(v.v):
+			// keep the value and its type, but not the memory of the old
+			// target
+			c := newConstant(v.v.Value, nil, nil)
+			c.Name = v.v.Name
+			c.Kind = v.v.Kind
+			c.DwarfType = v.v.DwarfType
+			c.RealType = v.v.RealType
+			c.Flags = v.v.Flags
+			cv.vars[name] = &convVar{v: c}
+		}
+	}
+}
+
+// isPlainValue returns true if v is a boolean, a number or a completely
+// loaded string.
+func isPlainValue(v *Variable) bool {
+	if v.Unreadable != nil || v.Value == nil {
+		return false
+	}
+	switch v.Kind {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.String:
+		return v.Value.Kind() == constant.String && v.Len == int64(len(constant.StringVal(v.Value)))
+	}
+	return false
+}
+
+// cloneSnapshot returns a copy of v and of all its loaded children, so that
+// the copy can be modified (for example by pretty printers) without
+// changing the snapshot.
+func cloneSnapshot(v *Variable) *Variable {
+	if v == nilVariable {
+		return v
+	}
+	r := v.clone()
+	r.Children = slices.Clone(v.Children)
+	for i := range r.Children {
+		r.Children[i] = *cloneSnapshot(&r.Children[i])
+	}
+	return r
+}
+
+// convVar returns the value of the convenience variable $name.
+func (scope *EvalScope) convVar(name string) (*Variable, error) {
+	if scope.target == nil || scope.target.convVars == nil {
+		return nil, errors.New("convenience variables are not available")
+	}
+	cv, ok := scope.target.convVars.get(name)
+	if !ok {
+		return nil, fmt.Errorf("convenience variable $%s is not set", name)
+	}
+	if cv.v != nil {
+		v := cloneSnapshot(cv.v)
+		if v.bi == nil && v != nilVariable {
+			v.bi = scope.BinInfo
+			v.mem = scope.Mem
+		}
+		return v, nil
+	}
+	if slices.Contains(scope.liveConvVars, name) {
+		return nil, fmt.Errorf("convenience variable $%s refers to itself", name)
+	}
+	// Function calls are not allowed in live expressions, their evaluation
+	// is not part of the call injection protocol of the containing
+	// expression.
+	callCtx := scope.callCtx
+	scope.callCtx = nil
+	scope.liveConvVars = append(scope.liveConvVars, name)
+	defer func() {
+		scope.callCtx = callCtx
+		scope.liveConvVars = scope.liveConvVars[:len(scope.liveConvVars)-1]
+	}()
+	v, err := scope.evalAST(cv.t)
+	if err != nil {
+		return nil, fmt.Errorf("evaluating $%s: %v", name, err)
+	}
+	if v != nilVariable {
+		v.Name = "$" + name
+	}
+	return v, nil
+}
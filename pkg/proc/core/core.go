@@ -164,6 +164,8 @@ type process struct {
 
 	bi          *proc.BinaryInfo
 	breakpoints proc.BreakpointMap
+
+	coreFile *coreFile
 }
 
 // thread represents a thread in the core file being debugged.
@@ -193,7 +195,7 @@ var (
 	ErrChangeRegisterCore = errors.New("can not change register values of core process")
 )
 
-type openFn func(string, string) (*process, proc.Thread, error)
+type openFn func(*coreFile, string) (*process, proc.Thread, error)
 
 var openFns = []openFn{readLinuxOrPlatformIndependentCore, readAMD64Minidump}
 
@@ -204,21 +206,30 @@ var ErrUnrecognizedFormat = errors.New("unrecognized core format")
 // OpenCore will open the core file and return a *proc.TargetGroup.
 // If the DWARF information cannot be found in the binary, Delve will look
 // for external debug files in the directories passed in.
+// Core files compressed with gzip, zstd, xz or lz4 and, if corePath does
+// not exist, core files split into multiple pieces named corePath.000,
+// corePath.001, etc., are read directly, without decompressing them to disk.
 func OpenCore(corePath, exePath string, debugInfoDirs []string) (*proc.TargetGroup, error) {
+	cf, err := openCoreFile(corePath)
+	if err != nil {
+		return nil, err
+	}
 	var p *process
 	var currentThread proc.Thread
-	var err error
 	for _, openFn := range openFns {
-		p, currentThread, err = openFn(corePath, exePath)
+		p, currentThread, err = openFn(cf, exePath)
 		if err != ErrUnrecognizedFormat {
 			break
 		}
 	}
 	if err != nil {
+		cf.Close()
 		return nil, err
 	}
+	p.coreFile = cf
 
 	if currentThread == nil {
+		cf.Close()
 		return nil, ErrNoThreads
 	}
 
@@ -431,6 +442,9 @@ func (p *process) Detach(int, bool) error {
 }
 
 func (p *process) Close() error {
+	if p.coreFile != nil {
+		return p.coreFile.Close()
+	}
 	return nil
 }
 
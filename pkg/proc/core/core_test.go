@@ -2,18 +2,25 @@ package core
 
 import (
 	"bytes"
+	"compress/gzip"
 	"flag"
 	"fmt"
 	"go/constant"
+	"io"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+
 	"github.com/go-delve/delve/pkg/goversion"
 	"github.com/go-delve/delve/pkg/proc"
 	"github.com/go-delve/delve/pkg/proc/test"
@@ -197,6 +204,125 @@ func TestSplicedReader(t *testing.T) {
 	}
 }
 
+func TestCompressedCoreFile(t *testing.T) {
+	// Contents are not random so that they can be compressed, but do not
+	// repeat with a period that divides the size of the pieces.
+	data := make([]byte, 5<<20+123)
+	for i := range data {
+		data[i] = byte(i/7) ^ byte(i>>13)
+	}
+
+	compress := map[string]func(w io.Writer) io.WriteCloser{
+		"plain": func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} },
+		"gzip":  func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"zstd": func(w io.Writer) io.WriteCloser {
+			zw, _ := zstd.NewWriter(w)
+			return zw
+		},
+		"zstd-frames": func(w io.Writer) io.WriteCloser {
+			zw, _ := zstd.NewWriter(nil)
+			return &zstdFramesWriter{w: w, enc: zw}
+		},
+		"xz": func(w io.Writer) io.WriteCloser {
+			xw, _ := xz.NewWriter(w)
+			return xw
+		},
+		"lz4": func(w io.Writer) io.WriteCloser { return lz4.NewWriter(w) },
+	}
+
+	for name, newWriter := range compress {
+		for _, split := range []bool{false, true} {
+			dir := t.TempDir()
+			corePath := filepath.Join(dir, "core")
+			buf := new(bytes.Buffer)
+			w := newWriter(buf)
+			_, err := w.Write(data)
+			assertNoError(err, t, "Write")
+			assertNoError(w.Close(), t, "Close")
+			if split {
+				const pieceSize = 1 << 20
+				for i := 0; buf.Len() > 0; i++ {
+					err := os.WriteFile(fmt.Sprintf("%s.%03d", corePath, i), buf.Next(pieceSize), 0o600)
+					assertNoError(err, t, "WriteFile")
+				}
+			} else {
+				assertNoError(os.WriteFile(corePath, buf.Bytes(), 0o600), t, "WriteFile")
+			}
+
+			cf, err := openCoreFile(corePath)
+			assertNoError(err, t, fmt.Sprintf("openCoreFile (%s split=%v)", name, split))
+			if cf.size != int64(len(data)) {
+				t.Errorf("%s split=%v: wrong size %d (expected %d)", name, split, cf.size, len(data))
+			}
+			for _, off := range []int64{0, 1<<20 - 3, 4<<20 + 17, int64(len(data)) - 10} {
+				got := make([]byte, 1<<20)
+				n, err := cf.ReadAt(got, off)
+				want := data[off:min(off+int64(len(got)), int64(len(data)))]
+				if (err != nil && err != io.EOF) || !bytes.Equal(got[:n], want) {
+					t.Errorf("%s split=%v: ReadAt(%#x) = %d, %v", name, split, off, n, err)
+				}
+			}
+			assertNoError(cf.Close(), t, "Close")
+		}
+	}
+}
+
+func TestCorePieces(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"core.12345", "core.12346", "split.000", "split.001", "split.003"} {
+		assertNoError(os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600), t, "WriteFile")
+	}
+	for _, tc := range []struct {
+		path string
+		want []string
+	}{
+		// existing files are never combined with other files
+		{"core.12345", []string{"core.12345"}},
+		{"split.000", []string{"split.000"}},
+		{"split", []string{"split.000", "split.001"}},
+		{"nonexistent", nil},
+	} {
+		got, err := corePieces(filepath.Join(dir, tc.path))
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("%s: expected error, got %v", tc.path, got)
+			}
+			continue
+		}
+		assertNoError(err, t, tc.path)
+		var want []string
+		for _, name := range tc.want {
+			want = append(want, filepath.Join(dir, name))
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %v, expected %v", tc.path, got, want)
+		}
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// zstdFramesWriter writes zstd frames of known size, without a seek table.
+type zstdFramesWriter struct {
+	w   io.Writer
+	enc *zstd.Encoder
+}
+
+func (w *zstdFramesWriter) Write(p []byte) (int, error) {
+	for i := 0; i < len(p); i += 1 << 20 {
+		if _, err := w.w.Write(w.enc.EncodeAll(p[i:min(i+1<<20, len(p))], nil)); err != nil {
+			return i, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *zstdFramesWriter) Close() error { return nil }
+
 func withCoreFile(t *testing.T, name, args string) *proc.TargetGroup {
 	// This is all very fragile and won't work on hosts with non-default core patterns.
 	// Might be better to check in the binary and core?
@@ -442,6 +568,88 @@ mainSearch:
 	t.Logf("s = %#v\n", v2)
 }
 
+func TestCoreHeapObjects(t *testing.T) {
+	mustSupportCore(t)
+
+	// the fixture crashes when it reaches runtime.Breakpoint
+	grp := withCoreFile(t, "heapobjs", "")
+	p := grp.Selected
+
+	objs, err := proc.HeapObjects(p)
+	assertNoError(err, t, "HeapObjects()")
+	count := map[string]int{}
+	for _, obj := range objs {
+		if obj.Type != nil {
+			count[obj.Type.String()]++
+		}
+	}
+	for _, typ := range []string{"main.Session", "main.Conn", "main.Big"} {
+		if count[typ] == 0 {
+			t.Errorf("no objects of type %s found in %d objects", typ, len(objs))
+		}
+	}
+}
+
+func TestCoreObjectReferences(t *testing.T) {
+	mustSupportCore(t)
+
+	// the fixture crashes when it reaches runtime.Breakpoint
+	grp := withCoreFile(t, "heaprefs", "")
+	p := grp.Selected
+
+	gs, _, err := proc.GoroutinesInfo(p, 0, 0)
+	assertNoError(err, t, "GoroutinesInfo()")
+	var scope *proc.EvalScope
+	for _, g := range gs {
+		frames, err := proc.GoroutineStacktrace(p, g, 20, 0)
+		if err != nil {
+			continue
+		}
+		for i := range frames {
+			if frames[i].Current.Fn != nil && frames[i].Current.Fn.Name == "main.main" {
+				scope = proc.FrameToScope(p, p.Memory(), g, 0, frames[i:]...)
+			}
+		}
+	}
+	if scope == nil {
+		t.Fatal("could not find main.main")
+	}
+
+	for _, tc := range []struct {
+		expr string
+		refs []string // prefixes of the expected retention paths
+	}{
+		{"conn", []string{"main.cache -> map bucket -> *main.Session.conn", "finalizer -> *main.resource.conn", "conn in main.main (goroutine 1 frame "}},
+		{"dead", nil},
+	} {
+		v, err := scope.EvalExpression(tc.expr, proc.LoadConfig{})
+		assertNoError(err, t, "EvalExpression("+tc.expr+")")
+		addr, err := proc.ReferencedObjectAddress(v)
+		assertNoError(err, t, "ReferencedObjectAddress("+tc.expr+")")
+		_, refs, err := proc.ObjectReferences(p, addr)
+		assertNoError(err, t, "ObjectReferences("+tc.expr+")")
+		var paths []string
+		for _, ref := range refs {
+			if ref.Reachable {
+				paths = append(paths, strings.Join(ref.Path, " -> "))
+			}
+		}
+		t.Logf("%s: %q", tc.expr, paths)
+		for _, path := range tc.refs {
+			found := false
+			for _, p := range paths {
+				found = found || strings.HasPrefix(p, path)
+			}
+			if !found {
+				t.Errorf("%s: reference %q not found", tc.expr, path)
+			}
+		}
+		if len(paths) != len(tc.refs) {
+			t.Errorf("%s: expected %d references, got %d", tc.expr, len(tc.refs), len(paths))
+		}
+	}
+}
+
 func TestMinidump(t *testing.T) {
 	if runtime.GOOS != "windows" || runtime.GOARCH != "amd64" {
 		t.Skip("minidumps can only be produced on windows/amd64")
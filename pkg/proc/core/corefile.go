@@ -0,0 +1,473 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+// coreFile is the contents of a core file, which can be split into
+// multiple pieces and compressed.
+type coreFile struct {
+	io.ReaderAt       // uncompressed contents of the core file
+	size        int64 // size of the uncompressed contents
+	closers     []io.Closer
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	lz4Magic  = []byte{0x04, 0x22, 0x4d, 0x18}
+)
+
+const (
+	// streamChunkSize is the size of the chunks in which the contents of
+	// compressed core files that can not be accessed randomly are stored in
+	// memory.
+	streamChunkSize = 1 << 20
+	// maxFrameSize is the maximum size of the frames of a compressed core
+	// file that is accessed randomly, larger frames are read as a stream.
+	maxFrameSize = 64 << 20
+	// chunkCacheSize is the number of decompressed chunks kept in memory.
+	chunkCacheSize = 16
+
+	zstdFrameMagic         = 0xfd2fb528
+	zstdSkippableMagicMask = 0xfffffff0
+	zstdSkippableMagic     = 0x184d2a50
+	zstdSeekTableMagic     = 0x8f92eab1
+	zstdSeekTableFooterLen = 9
+)
+
+// openCoreFile opens the core file at corePath.
+// If corePath does not exist, the pieces of a core file split into
+// corePath.000, corePath.001, etc. are read as if they were concatenated.
+// Core files compressed with gzip, zstd, xz or lz4 are decompressed on
+// demand: zstd files made of small frames, for example files in the zstd
+// seekable format, are accessed randomly, other compressed files are
+// decompressed once and kept in memory, recompressed in chunks that can be
+// accessed independently, so that memory usage is proportional to the size
+// of the compressed file.
+func openCoreFile(corePath string) (*coreFile, error) {
+	pieces, err := corePieces(corePath)
+	if err != nil {
+		return nil, err
+	}
+	cf := &coreFile{}
+	var raw io.ReaderAt
+	var rawSize int64
+	if len(pieces) == 1 {
+		fh, err := os.Open(pieces[0])
+		if err != nil {
+			return nil, err
+		}
+		cf.closers = append(cf.closers, fh)
+		fi, err := fh.Stat()
+		if err != nil {
+			cf.Close()
+			return nil, err
+		}
+		raw, rawSize = fh, fi.Size()
+	} else {
+		mr := &multiReaderAt{}
+		for _, piece := range pieces {
+			fh, err := os.Open(piece)
+			if err != nil {
+				cf.Close()
+				return nil, err
+			}
+			cf.closers = append(cf.closers, fh)
+			fi, err := fh.Stat()
+			if err != nil {
+				cf.Close()
+				return nil, err
+			}
+			mr.add(fh, fi.Size())
+		}
+		raw, rawSize = mr, mr.size
+	}
+
+	var magic [6]byte
+	n, _ := raw.ReadAt(magic[:], 0)
+	hasMagic := func(m []byte) bool { return bytes.HasPrefix(magic[:n], m) }
+
+	src := io.NewSectionReader(raw, 0, rawSize)
+	var rd io.Reader
+	switch {
+	case hasMagic(zstdMagic):
+		if cr, err := newZstdFrameReader(raw, rawSize); err == nil {
+			cf.ReaderAt, cf.size = cr, cr.size
+			return cf, nil
+		}
+		zrd, err := zstd.NewReader(src)
+		if err != nil {
+			cf.Close()
+			return nil, err
+		}
+		defer zrd.Close()
+		rd = zrd
+	case hasMagic(gzipMagic):
+		gzrd, err := gzip.NewReader(src)
+		if err != nil {
+			cf.Close()
+			return nil, err
+		}
+		defer gzrd.Close()
+		rd = gzrd
+	case hasMagic(xzMagic):
+		xzrd, err := xz.NewReader(src)
+		if err != nil {
+			cf.Close()
+			return nil, err
+		}
+		rd = xzrd
+	case hasMagic(lz4Magic):
+		rd = lz4.NewReader(src)
+	default:
+		cf.ReaderAt, cf.size = raw, rawSize
+		return cf, nil
+	}
+
+	cr, err := newStreamChunkReader(rd)
+	if err != nil {
+		cf.Close()
+		return nil, fmt.Errorf("could not decompress %s: %v", corePath, err)
+	}
+	cf.ReaderAt, cf.size = cr, cr.size
+	return cf, nil
+}
+
+// Close closes the files of the core file.
+func (cf *coreFile) Close() error {
+	var err error
+	for _, closer := range cf.closers {
+		if err2 := closer.Close(); err == nil {
+			err = err2
+		}
+	}
+	cf.closers = nil
+	return err
+}
+
+// corePieces returns the list of files containing the core file at
+// corePath. If corePath does not exist but corePath.000 does, the core file
+// is split into multiple pieces, named corePath.000, corePath.001, etc.
+// An existing corePath is never combined with other files.
+func corePieces(corePath string) ([]string, error) {
+	_, err := os.Stat(corePath)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return []string{corePath}, nil
+	}
+	var pieces []string
+	for i := 0; ; i++ {
+		piece := fmt.Sprintf("%s.%03d", corePath, i)
+		if _, err := os.Stat(piece); err != nil {
+			break
+		}
+		pieces = append(pieces, piece)
+	}
+	if len(pieces) == 0 {
+		return nil, err
+	}
+	return pieces, nil
+}
+
+// multiReaderAt is the concatenation of multiple io.ReaderAt.
+type multiReaderAt struct {
+	parts []multiReaderAtPart
+	size  int64
+}
+
+type multiReaderAtPart struct {
+	r        io.ReaderAt
+	off, len int64
+}
+
+func (mr *multiReaderAt) add(r io.ReaderAt, size int64) {
+	mr.parts = append(mr.parts, multiReaderAtPart{r, mr.size, size})
+	mr.size += size
+}
+
+func (mr *multiReaderAt) ReadAt(buf []byte, off int64) (int, error) {
+	i := sort.Search(len(mr.parts), func(i int) bool { return mr.parts[i].off+mr.parts[i].len > off })
+	n := 0
+	for ; i < len(mr.parts) && n < len(buf); i++ {
+		part := &mr.parts[i]
+		partOff := off + int64(n) - part.off
+		m := int(min(int64(len(buf)-n), part.len-partOff))
+		k, err := part.r.ReadAt(buf[n:n+m], partOff)
+		n += k
+		if err != nil && (err != io.EOF || k < m) {
+			return n, err
+		}
+	}
+	if n < len(buf) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// chunkReader is an io.ReaderAt that reads the uncompressed contents of a
+// file from a list of chunks that can be decompressed independently.
+type chunkReader struct {
+	chunks []chunk // sorted by uoff
+	size   int64
+	decode func(c *chunk) ([]byte, error)
+
+	mu    sync.Mutex
+	cache *simplelru.LRU // decompressed chunks
+}
+
+// chunk is a compressed chunk of a file, stored either in data or at offset
+// coff of the compressed file.
+type chunk struct {
+	uoff, usize int64 // offset and size of the uncompressed data
+	coff, csize int64
+	data        []byte
+}
+
+func newChunkReader(chunks []chunk, decode func(c *chunk) ([]byte, error)) *chunkReader {
+	cr := &chunkReader{chunks: chunks, decode: decode}
+	if n := len(chunks); n > 0 {
+		cr.size = chunks[n-1].uoff + chunks[n-1].usize
+	}
+	cr.cache, _ = simplelru.NewLRU(chunkCacheSize, nil)
+	return cr
+}
+
+func (cr *chunkReader) ReadAt(buf []byte, off int64) (int, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	i := sort.Search(len(cr.chunks), func(i int) bool { return cr.chunks[i].uoff+cr.chunks[i].usize > off })
+	n := 0
+	for ; i < len(cr.chunks) && n < len(buf); i++ {
+		c := &cr.chunks[i]
+		var data []byte
+		if v, ok := cr.cache.Get(i); ok {
+			data = v.([]byte)
+		} else {
+			var err error
+			data, err = cr.decode(c)
+			if err != nil {
+				return n, err
+			}
+			if int64(len(data)) != c.usize {
+				return n, fmt.Errorf("wrong size for chunk at %#x: %#x (expected %#x)", c.uoff, len(data), c.usize)
+			}
+			cr.cache.Add(i, data)
+		}
+		n += copy(buf[n:], data[off+int64(n)-c.uoff:])
+	}
+	if n < len(buf) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// newStreamChunkReader decompresses rd and stores its contents in memory
+// in chunks compressed with s2.
+func newStreamChunkReader(rd io.Reader) (*chunkReader, error) {
+	var chunks []chunk
+	buf := make([]byte, streamChunkSize)
+	off := int64(0)
+	for {
+		n, err := io.ReadFull(rd, buf)
+		if n > 0 {
+			chunks = append(chunks, chunk{uoff: off, usize: int64(n), data: s2.Encode(nil, buf[:n])})
+			off += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newChunkReader(chunks, func(c *chunk) ([]byte, error) {
+		return s2.Decode(nil, c.data)
+	}), nil
+}
+
+// newZstdFrameReader returns a chunkReader reading each frame of the zstd
+// compressed file src directly from src.
+// The size of the frames is read from the seek table of files in the zstd
+// seekable format or from the frame headers. An error is returned if the
+// uncompressed size of a frame is larger than maxFrameSize.
+func newZstdFrameReader(src io.ReaderAt, size int64) (*chunkReader, error) {
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxFrameSize))
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := zstdSeekTable(src, size)
+	if err != nil {
+		return nil, err
+	}
+	if chunks == nil {
+		chunks, err = zstdFrames(src, size, dec)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range chunks {
+		if chunks[i].usize > maxFrameSize {
+			return nil, errors.New("zstd frame too large")
+		}
+	}
+	return newChunkReader(chunks, func(c *chunk) ([]byte, error) {
+		buf := make([]byte, c.csize)
+		if _, err := src.ReadAt(buf, c.coff); err != nil {
+			return nil, err
+		}
+		return dec.DecodeAll(buf, make([]byte, 0, c.usize))
+	}), nil
+}
+
+// zstdSeekTable reads the seek table of a file in the zstd seekable
+// format, see:
+// https://github.com/facebook/zstd/blob/dev/contrib/seekable_format/zstd_seekable_compression_format.md
+// Returns nil if the file does not have a seek table.
+func zstdSeekTable(src io.ReaderAt, size int64) ([]chunk, error) {
+	if size < zstdSeekTableFooterLen {
+		return nil, nil
+	}
+	var footer [zstdSeekTableFooterLen]byte
+	if _, err := src.ReadAt(footer[:], size-zstdSeekTableFooterLen); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(footer[5:]) != zstdSeekTableMagic {
+		return nil, nil
+	}
+	nframes := int64(binary.LittleEndian.Uint32(footer[:]))
+	entrySize := int64(8)
+	if footer[4]&0x80 != 0 {
+		// checksums
+		entrySize = 12
+	}
+	tableSize := nframes * entrySize
+	if tableSize+zstdSeekTableFooterLen+8 > size {
+		return nil, errors.New("malformed zstd seek table")
+	}
+	table := make([]byte, tableSize)
+	if _, err := src.ReadAt(table, size-zstdSeekTableFooterLen-tableSize); err != nil {
+		return nil, err
+	}
+	chunks := make([]chunk, nframes)
+	var coff, uoff int64
+	for i := range chunks {
+		entry := table[int64(i)*entrySize:]
+		csize, usize := int64(binary.LittleEndian.Uint32(entry)), int64(binary.LittleEndian.Uint32(entry[4:]))
+		chunks[i] = chunk{uoff: uoff, usize: usize, coff: coff, csize: csize}
+		coff += csize
+		uoff += usize
+	}
+	if coff > size {
+		return nil, errors.New("malformed zstd seek table")
+	}
+	return chunks, nil
+}
+
+// zstdFrames returns the list of frames of a zstd compressed file, reading
+// the size of each frame from its header and the headers of its blocks, see:
+// https://github.com/facebook/zstd/blob/dev/doc/zstd_compression_format.md
+// Frames that do not record their uncompressed size, for example the last
+// frame of files written in chunks, are decompressed with dec to find it.
+func zstdFrames(src io.ReaderAt, size int64, dec *zstd.Decoder) ([]chunk, error) {
+	var chunks []chunk
+	var buf [18]byte
+	var uoff int64
+	for off := int64(0); off < size; {
+		if _, err := src.ReadAt(buf[:8], off); err != nil {
+			return nil, err
+		}
+		magic := binary.LittleEndian.Uint32(buf[:])
+		if magic&zstdSkippableMagicMask == zstdSkippableMagic {
+			off += 8 + int64(binary.LittleEndian.Uint32(buf[4:]))
+			continue
+		}
+		if magic != zstdFrameMagic {
+			return nil, errors.New("malformed zstd frame")
+		}
+		start := off
+		desc := buf[4]
+		fcsFlag, singleSegment, hasChecksum, dictIDFlag := desc>>6, desc&0x20 != 0, desc&0x04 != 0, desc&0x03
+		off += 5
+		if !singleSegment {
+			off++ // window descriptor
+		}
+		off += []int64{0, 1, 2, 4}[dictIDFlag]
+		fcsSize := []int{0, 2, 4, 8}[fcsFlag]
+		if fcsFlag == 0 && singleSegment {
+			fcsSize = 1
+		}
+		if _, err := src.ReadAt(buf[:fcsSize], off); err != nil {
+			return nil, err
+		}
+		usize := int64(-1)
+		switch fcsSize {
+		case 1:
+			usize = int64(buf[0])
+		case 2:
+			usize = int64(binary.LittleEndian.Uint16(buf[:])) + 256
+		case 4:
+			usize = int64(binary.LittleEndian.Uint32(buf[:]))
+		case 8:
+			usize = int64(binary.LittleEndian.Uint64(buf[:]))
+		}
+		off += int64(fcsSize)
+		for {
+			if _, err := src.ReadAt(buf[:3], off); err != nil {
+				return nil, err
+			}
+			hdr := uint32(buf[0]) | uint32(buf[1])<<8 | uint32(buf[2])<<16
+			last, typ, bsize := hdr&1 != 0, (hdr>>1)&3, int64(hdr>>3)
+			off += 3
+			switch typ {
+			case 0, 2: // raw and compressed blocks
+				off += bsize
+			case 1: // RLE block
+				off++
+			default:
+				return nil, errors.New("malformed zstd block")
+			}
+			if last {
+				break
+			}
+		}
+		if hasChecksum {
+			off += 4
+		}
+		if off > size {
+			return nil, errors.New("truncated zstd frame")
+		}
+		if usize < 0 {
+			if off-start > maxFrameSize {
+				return nil, errors.New("zstd frame too large")
+			}
+			cbuf := make([]byte, off-start)
+			if _, err := src.ReadAt(cbuf, start); err != nil {
+				return nil, err
+			}
+			ubuf, err := dec.DecodeAll(cbuf, nil)
+			if err != nil {
+				return nil, err
+			}
+			usize = int64(len(ubuf))
+		}
+		chunks = append(chunks, chunk{uoff: uoff, usize: usize, coff: start, csize: off - start})
+		uoff += usize
+	}
+	return chunks, nil
+}
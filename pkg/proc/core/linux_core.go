@@ -101,15 +101,15 @@ var supportedLinuxMachines = map[elf.Machine]string{
 	_EM_LOONGARCH: "loong64",
 }
 
-// readLinuxOrPlatformIndependentCore reads a core file from corePath
+// readLinuxOrPlatformIndependentCore reads a core file from cf
 // corresponding to the executable at exePath. For details on the Linux ELF
 // core format, see:
 // https://www.gabriel.urdhr.fr/2015/05/29/core-file/,
 // https://uhlo.blogspot.com/2012/05/brief-look-into-core-dumps.html,
 // elf_core_dump in https://elixir.bootlin.com/linux/v4.20.17/source/fs/binfmt_elf.c,
 // and, if absolutely desperate, readelf.c from the binutils source.
-func readLinuxOrPlatformIndependentCore(corePath, exePath string) (*process, proc.Thread, error) {
-	coreFile, err := elf.Open(corePath)
+func readLinuxOrPlatformIndependentCore(cf *coreFile, exePath string) (*process, proc.Thread, error) {
+	coreFile, err := elf.NewFile(cf)
 	if err != nil {
 		if _, isfmterr := err.(*elf.FormatError); isfmterr && (strings.Contains(err.Error(), elfErrorBadMagicNumber) || strings.Contains(err.Error(), " at offset 0x0: too short")) {
 			// Go >=1.11 and <1.11 produce different errors when reading a non-elf file.
@@ -322,7 +322,12 @@ func Open(path string, logfn func(fmt string, args ...interface{})) (*Minidump,
 	if err != nil {
 		return nil, err
 	}
+	return Parse(rawbuf, logfn)
+}
 
+// Parse parses the contents of a minidump file and returns it as a Minidump
+// structure.
+func Parse(rawbuf []byte, logfn func(fmt string, args ...interface{})) (*Minidump, error) {
 	buf := &minidumpBuf{buf: rawbuf, kind: "file"}
 
 	var mdmp Minidump
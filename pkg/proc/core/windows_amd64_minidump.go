@@ -1,19 +1,25 @@
 package core
 
 import (
+	"io"
+
 	"github.com/go-delve/delve/pkg/logflags"
 	"github.com/go-delve/delve/pkg/proc"
 	"github.com/go-delve/delve/pkg/proc/core/minidump"
 	"github.com/go-delve/delve/pkg/proc/winutil"
 )
 
-func readAMD64Minidump(minidumpPath, exePath string) (*process, proc.Thread, error) {
+func readAMD64Minidump(cf *coreFile, exePath string) (*process, proc.Thread, error) {
 	var logfn func(string, ...interface{})
 	if logflags.Minidump() {
 		logfn = logflags.MinidumpLogger().Infof
 	}
 
-	mdmp, err := minidump.Open(minidumpPath, logfn)
+	rawbuf, err := io.ReadAll(io.NewSectionReader(cf, 0, cf.size))
+	if err != nil {
+		return nil, nil, err
+	}
+	mdmp, err := minidump.Parse(rawbuf, logfn)
 	if err != nil {
 		if _, isNotAMinidump := err.(minidump.ErrNotAMinidump); isNotAMinidump {
 			return nil, nil, ErrUnrecognizedFormat
@@ -1,28 +1,449 @@
+// Package debuginfod implements a client for the debuginfod protocol,
+// used to download debug information, executables and source files from
+// a debuginfod server given the build ID of an executable.
+//
+// The client is configured using the same environment variables used by
+// the debuginfod client library of elfutils:
+//
+//   - DEBUGINFOD_URLS: space separated list of server URLs, all servers
+//     are queried concurrently
+//   - DEBUGINFOD_CACHE_PATH: directory where downloaded files are cached,
+//     defaults to $XDG_CACHE_HOME/debuginfod_client
+//   - DEBUGINFOD_TIMEOUT: timeout, in seconds, for connecting to each
+//     server and receiving the headers of its response
+//
+// The files max_unused_age_s and cache_miss_s in the cache directory can
+// be used to change how long downloaded files and failed lookups are
+// cached, respectively.
 package debuginfod
 
 import (
-	"os/exec"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-delve/delve/pkg/logflags"
 )
 
-const debuginfodFind = "debuginfod-find"
+const (
+	urlsEnv      = "DEBUGINFOD_URLS"
+	cachePathEnv = "DEBUGINFOD_CACHE_PATH"
+	timeoutEnv   = "DEBUGINFOD_TIMEOUT"
 
-func execFind(args ...string) (string, error) {
-	if _, err := exec.LookPath(debuginfodFind); err != nil {
-		return "", err
+	maxAgeFile       = "max_unused_age_s"
+	cacheMissAgeFile = "cache_miss_s"
+
+	defaultTimeout      = 90 * time.Second
+	defaultMaxAge       = 7 * 24 * time.Hour
+	defaultCacheMissAge = 10 * time.Minute
+
+	userAgent = "delve"
+)
+
+// ErrNoServers is returned when no debuginfod server is configured.
+var ErrNoServers = errors.New("no debuginfod servers configured (" + urlsEnv + " is not set)")
+
+// ErrNotFound is returned when none of the servers has the requested file.
+var ErrNotFound = errors.New("not found")
+
+// Client is a debuginfod client.
+type Client struct {
+	// URLs is the list of servers to query.
+	URLs []string
+	// CacheDir is the directory where downloaded files are stored.
+	CacheDir string
+	// Timeout is the maximum time to wait for the headers of the response
+	// to each request, the download of the response body is not limited.
+	// If it is zero requests do not time out.
+	Timeout time.Duration
+	// MaxAge is the duration after which cached files are downloaded
+	// again, if it is zero cached files never expire.
+	MaxAge time.Duration
+	// CacheMissAge is the duration for which a failed lookup is remembered
+	// and the servers are not queried again for the same file.
+	CacheMissAge time.Duration
+	// HTTPClient is the client used to send requests, if nil
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+	// Progress, if not nil, is called periodically while a file is being
+	// downloaded, total is -1 if the size of the file is not known.
+	Progress func(url string, done, total int64)
+
+	mu    sync.Mutex           // protects locks
+	locks map[string]*fileLock // locks for the cache files being downloaded
+}
+
+// fileLock serializes lookups of the same cache file, so that concurrent
+// lookups of a file only download it once.
+type fileLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewClient returns a client configured using the environment.
+func NewClient() *Client {
+	c := &Client{
+		URLs:         strings.Fields(os.Getenv(urlsEnv)),
+		CacheDir:     defaultCacheDir(),
+		Timeout:      defaultTimeout,
+		MaxAge:       defaultMaxAge,
+		CacheMissAge: defaultCacheMissAge,
 	}
-	cmd := exec.Command(debuginfodFind, args...)
-	out, err := cmd.Output() // ignore stderr
+	if s := os.Getenv(timeoutEnv); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+			c.Timeout = time.Duration(n) * time.Second
+		}
+	}
+	if c.CacheDir != "" {
+		c.MaxAge = readCacheSetting(c.CacheDir, maxAgeFile, c.MaxAge)
+		c.CacheMissAge = readCacheSetting(c.CacheDir, cacheMissAgeFile, c.CacheMissAge)
+	}
+	return c
+}
+
+func defaultCacheDir() string {
+	if dir := os.Getenv(cachePathEnv); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
 	if err != nil {
-		return "", err
+		return ""
 	}
-	return strings.TrimSpace(string(out)), err
+	return filepath.Join(dir, "debuginfod_client")
 }
 
+// readCacheSetting reads a duration, in seconds, from the file name in
+// the cache directory.
+func readCacheSetting(cacheDir, name string, def time.Duration) time.Duration {
+	buf, err := os.ReadFile(filepath.Join(cacheDir, name))
+	if err != nil {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(buf)))
+	if err != nil || n < 0 {
+		return def
+	}
+	return time.Duration(n) * time.Second
+}
+
+var (
+	defaultClientOnce sync.Once
+	defaultClient     *Client
+)
+
+func getDefaultClient() *Client {
+	defaultClientOnce.Do(func() {
+		defaultClient = NewClient()
+		if logflags.Debugger() {
+			defaultClient.Progress = logProgress(logflags.DebuggerLogger())
+		}
+	})
+	return defaultClient
+}
+
+// GetSource returns the path of a local copy of the source file filename
+// of the executable with the specified build ID, using the client
+// configured by the environment.
 func GetSource(buildid, filename string) (string, error) {
-	return execFind("source", buildid, filename)
+	return getDefaultClient().GetSource(context.Background(), buildid, filename)
 }
 
+// GetDebuginfo returns the path of a local copy of the debug information
+// for the executable with the specified build ID, using the client
+// configured by the environment.
 func GetDebuginfo(buildid string) (string, error) {
-	return execFind("debuginfo", buildid)
+	return getDefaultClient().GetDebuginfo(context.Background(), buildid)
+}
+
+// GetExecutable returns the path of a local copy of the executable with
+// the specified build ID, using the client configured by the environment.
+func GetExecutable(buildid string) (string, error) {
+	return getDefaultClient().GetExecutable(context.Background(), buildid)
+}
+
+// logProgress returns a progress function that logs the state of each
+// download, at most once per second.
+func logProgress(logger logflags.Logger) func(url string, done, total int64) {
+	var mu sync.Mutex
+	var last time.Time
+	return func(url string, done, total int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		if done != total && time.Since(last) < time.Second {
+			return
+		}
+		last = time.Now()
+		if total < 0 {
+			logger.Debugf("debuginfod: downloading %s: %d bytes", url, done)
+		} else {
+			logger.Debugf("debuginfod: downloading %s: %d/%d bytes", url, done, total)
+		}
+	}
+}
+
+// GetDebuginfo returns the path of a local copy of the debug information
+// for the executable with the specified build ID.
+func (c *Client) GetDebuginfo(ctx context.Context, buildid string) (string, error) {
+	return c.get(ctx, buildid, "debuginfo", "debuginfo")
+}
+
+// GetExecutable returns the path of a local copy of the executable with
+// the specified build ID.
+func (c *Client) GetExecutable(ctx context.Context, buildid string) (string, error) {
+	return c.get(ctx, buildid, "executable", "executable")
+}
+
+// GetSource returns the path of a local copy of the source file filename
+// of the executable with the specified build ID. Filename must be the
+// absolute path of the file, as recorded in the debug information.
+func (c *Client) GetSource(ctx context.Context, buildid, filename string) (string, error) {
+	filename = filepath.ToSlash(filename)
+	if !strings.HasPrefix(filename, "/") {
+		return "", fmt.Errorf("source file path %q is not absolute", filename)
+	}
+	// the path of the file is escaped, in the cache, in the same way elfutils does
+	return c.get(ctx, buildid, "source"+(&url.URL{Path: filename}).EscapedPath(), "source"+strings.ReplaceAll(filename, "/", "#"))
+}
+
+// get returns the path of the cached copy of the file at
+// /buildid/<buildid>/<kind> downloading it, if necessary.
+func (c *Client) get(ctx context.Context, buildid, kind, cacheName string) (string, error) {
+	if !validBuildID(buildid) {
+		return "", fmt.Errorf("invalid build ID %q", buildid)
+	}
+	if len(c.URLs) == 0 {
+		return "", ErrNoServers
+	}
+	if c.CacheDir == "" {
+		return "", errors.New("no debuginfod cache directory")
+	}
+
+	dir := filepath.Join(c.CacheDir, buildid)
+	path := filepath.Join(dir, cacheName)
+
+	unlock := c.lock(path)
+	defer unlock()
+
+	if fi, err := os.Stat(path); err == nil {
+		age := time.Since(fi.ModTime())
+		if fi.Size() == 0 {
+			// a previous lookup failed
+			if age < c.CacheMissAge {
+				return "", ErrNotFound
+			}
+		} else if c.MaxAge == 0 || age < c.MaxAge {
+			return path, nil
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	err := c.download(ctx, "/buildid/"+buildid+"/"+kind, path)
+	if err == ErrNotFound {
+		// remember that no server has this file, errors that only wrap
+		// ErrNotFound mean that some server failed and are not cached
+		os.Remove(path)
+		if fh, err := os.Create(path); err == nil {
+			fh.Close()
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// lock acquires the lock for the cache file path, lookups of different
+// files proceed concurrently. Files are always written to a temporary file
+// first and then renamed, so that other processes sharing the cache never
+// see partial downloads.
+func (c *Client) lock(path string) (unlock func()) {
+	c.mu.Lock()
+	if c.locks == nil {
+		c.locks = make(map[string]*fileLock)
+	}
+	l := c.locks[path]
+	if l == nil {
+		l = &fileLock{}
+		c.locks[path] = l
+	}
+	l.refs++
+	c.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		c.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(c.locks, path)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// validBuildID returns true if buildid is a non empty hex string.
+func validBuildID(buildid string) bool {
+	if buildid == "" {
+		return false
+	}
+	for _, ch := range buildid {
+		if (ch < '0' || ch > '9') && (ch < 'a' || ch > 'f') && (ch < 'A' || ch > 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// download queries all servers concurrently for urlPath and saves the
+// first successful response to dst.
+func (c *Client) download(ctx context.Context, urlPath, dst string) error {
+	type response struct {
+		idx  int
+		url  string
+		resp *http.Response
+		err  error
+	}
+
+	ch := make(chan response, len(c.URLs))
+	cancels := make([]context.CancelCauseFunc, len(c.URLs))
+	defer func() {
+		for _, cancel := range cancels {
+			cancel(nil)
+		}
+	}()
+	for i, server := range c.URLs {
+		var reqctx context.Context
+		reqctx, cancels[i] = context.WithCancelCause(ctx)
+		u := strings.TrimSuffix(server, "/") + urlPath
+		go func(i int) {
+			resp, err := c.request(reqctx, cancels[i], u)
+			ch <- response{i, u, resp, err}
+		}(i)
+	}
+
+	var errs []error
+	notFound := true
+	for n := range c.URLs {
+		r := <-ch
+		if r.err != nil {
+			if !errors.Is(r.err, ErrNotFound) {
+				notFound = false
+			}
+			errs = append(errs, r.err)
+			continue
+		}
+		// r is the first successful response, cancel all other requests.
+		for i := range cancels {
+			if i != r.idx {
+				cancels[i](nil)
+			}
+		}
+		go func(pending int) {
+			for ; pending > 0; pending-- {
+				if r := <-ch; r.resp != nil {
+					r.resp.Body.Close()
+				}
+			}
+		}(len(c.URLs) - n - 1)
+		return c.save(r.url, r.resp, dst)
+	}
+
+	if notFound {
+		return ErrNotFound
+	}
+	return errors.Join(errs...)
+}
+
+// request sends a GET request for u, non-200 responses are returned as
+// errors. If the headers of the response are not received within
+// c.Timeout the request is canceled, using cancel, the body of the
+// response can take longer to download.
+func (c *Client) request(ctx context.Context, cancel context.CancelCauseFunc, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if c.Timeout > 0 {
+		timer := time.AfterFunc(c.Timeout, func() { cancel(context.DeadlineExceeded) })
+		defer timer.Stop()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if cause := context.Cause(ctx); cause == context.DeadlineExceeded {
+			return nil, fmt.Errorf("%s: %w", u, cause)
+		}
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", u, ErrNotFound)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+}
+
+// save writes the body of resp to dst, the body is first written to a
+// temporary file so that partial downloads are never visible in the
+// cache.
+func (c *Client) save(u string, resp *http.Response, dst string) error {
+	defer resp.Body.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".tmp-"+filepath.Base(dst))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	total := resp.ContentLength
+	if s := resp.Header.Get("X-Debuginfod-Size"); s != "" {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			total = n
+		}
+	}
+	var w io.Writer = tmp
+	if c.Progress != nil {
+		w = &progressWriter{w: tmp, url: u, total: total, fn: c.Progress}
+	}
+	_, err = io.Copy(w, resp.Body)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", u, err)
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+type progressWriter struct {
+	w     io.Writer
+	url   string
+	done  int64
+	total int64
+	fn    func(url string, done, total int64)
+}
+
+func (pw *progressWriter) Write(buf []byte) (int, error) {
+	n, err := pw.w.Write(buf)
+	pw.done += int64(n)
+	pw.fn(pw.url, pw.done, pw.total)
+	return n, err
 }
@@ -0,0 +1,313 @@
+package debuginfod
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testBuildID = "0123456789abcdef"
+
+type testServer struct {
+	*httptest.Server
+	requests atomic.Int32
+	paths    chan string
+}
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *testServer {
+	ts := &testServer{paths: make(chan string, 10)}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.requests.Add(1)
+		select {
+		case ts.paths <- r.URL.EscapedPath():
+		default:
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newTestClient(t *testing.T, servers ...*testServer) *Client {
+	c := &Client{
+		CacheDir:     t.TempDir(),
+		Timeout:      5 * time.Second,
+		MaxAge:       time.Hour,
+		CacheMissAge: time.Hour,
+	}
+	for _, ts := range servers {
+		c.URLs = append(c.URLs, ts.URL)
+	}
+	return c
+}
+
+func fileServer(content string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}
+}
+
+func assertFile(t *testing.T, path, content string) {
+	t.Helper()
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != content {
+		t.Fatalf("wrong content of %s: %q (expected %q)", path, buf, content)
+	}
+}
+
+func TestGetDebuginfo(t *testing.T) {
+	ts := newTestServer(t, fileServer("debuginfo contents"))
+	c := newTestClient(t, ts)
+
+	var progressDone, progressTotal int64
+	c.Progress = func(url string, done, total int64) {
+		progressDone, progressTotal = done, total
+	}
+
+	path, err := c.GetDebuginfo(context.Background(), testBuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path, "debuginfo contents")
+	if p := <-ts.paths; p != "/buildid/"+testBuildID+"/debuginfo" {
+		t.Errorf("wrong request path %q", p)
+	}
+	if n := int64(len("debuginfo contents")); progressDone != n || progressTotal != n {
+		t.Errorf("wrong progress %d/%d (expected %d/%d)", progressDone, progressTotal, n, n)
+	}
+
+	// the second request must be served from the cache
+	path2, err := c.GetDebuginfo(context.Background(), testBuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path2 != path {
+		t.Errorf("path mismatch %q %q", path, path2)
+	}
+	if n := ts.requests.Load(); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+
+	// expired files are downloaded again
+	old := time.Now().Add(-2 * c.MaxAge)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetDebuginfo(context.Background(), testBuildID); err != nil {
+		t.Fatal(err)
+	}
+	if n := ts.requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestGetSource(t *testing.T) {
+	ts := newTestServer(t, fileServer("package main"))
+	c := newTestClient(t, ts)
+
+	path, err := c.GetSource(context.Background(), testBuildID, "/home/user/my project/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path, "package main")
+	if p := <-ts.paths; p != "/buildid/"+testBuildID+"/source/home/user/my%20project/main.go" {
+		t.Errorf("wrong request path %q", p)
+	}
+	if base := filepath.Base(path); base != "source#home#user#my project#main.go" {
+		t.Errorf("wrong cache file name %q", base)
+	}
+
+	if _, err := c.GetSource(context.Background(), testBuildID, "main.go"); err == nil {
+		t.Errorf("no error for relative path")
+	}
+}
+
+func TestGetExecutable(t *testing.T) {
+	ts := newTestServer(t, fileServer("executable contents"))
+	c := newTestClient(t, ts)
+
+	path, err := c.GetExecutable(context.Background(), testBuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path, "executable contents")
+	if p := <-ts.paths; p != "/buildid/"+testBuildID+"/executable" {
+		t.Errorf("wrong request path %q", p)
+	}
+}
+
+func TestConcurrentLookups(t *testing.T) {
+	// lookups of the same file are only downloaded once, lookups of
+	// different files do not wait for each other
+	release := make(chan struct{})
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/debuginfo") {
+			<-release
+		}
+		w.Write([]byte("contents"))
+	})
+	c := newTestClient(t, ts)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetDebuginfo(context.Background(), testBuildID)
+			errs <- err
+		}()
+	}
+
+	path, err := c.GetExecutable(context.Background(), testBuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path, "contents")
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := ts.requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestMultipleServers(t *testing.T) {
+	notfound := newTestServer(t, http.NotFound)
+	slow := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	})
+	good := newTestServer(t, fileServer("debuginfo contents"))
+	c := newTestClient(t, notfound, slow, good)
+
+	start := time.Now()
+	path, err := c.GetDebuginfo(context.Background(), testBuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path, "debuginfo contents")
+	if d := time.Since(start); d >= c.Timeout {
+		t.Errorf("download waited for the slow server (%v)", d)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	slow := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	})
+	c := newTestClient(t, slow)
+	c.Timeout = 100 * time.Millisecond
+
+	_, err := c.GetDebuginfo(context.Background(), testBuildID)
+	if err == nil {
+		t.Fatal("no error")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error %v", err)
+	}
+	// timeouts are not cached as failed lookups
+	if _, err := os.Stat(filepath.Join(c.CacheDir, testBuildID, "debuginfo")); err == nil {
+		t.Errorf("timeout cached as a failed lookup")
+	}
+}
+
+func TestSlowDownload(t *testing.T) {
+	// the timeout does not apply to the download of the response body
+	ts := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("debuginfo "))
+		w.(http.Flusher).Flush()
+		time.Sleep(500 * time.Millisecond)
+		w.Write([]byte("contents"))
+	})
+	c := newTestClient(t, ts)
+	c.Timeout = 100 * time.Millisecond
+
+	path, err := c.GetDebuginfo(context.Background(), testBuildID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path, "debuginfo contents")
+}
+
+func TestNotFound(t *testing.T) {
+	ts1 := newTestServer(t, http.NotFound)
+	ts2 := newTestServer(t, http.NotFound)
+	c := newTestClient(t, ts1, ts2)
+
+	for i := 0; i < 2; i++ {
+		_, err := c.GetDebuginfo(context.Background(), testBuildID)
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	}
+	// the failed lookup is cached
+	if n := ts1.requests.Load() + ts2.requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+
+	c.CacheMissAge = 0
+	if _, err := c.GetDebuginfo(context.Background(), testBuildID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if n := ts1.requests.Load() + ts2.requests.Load(); n != 4 {
+		t.Errorf("expected 4 requests, got %d", n)
+	}
+}
+
+func TestServerError(t *testing.T) {
+	notfound := newTestServer(t, http.NotFound)
+	broken := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	})
+	c := newTestClient(t, notfound, broken)
+
+	_, err := c.GetDebuginfo(context.Background(), testBuildID)
+	if err == nil {
+		t.Fatal("no error")
+	}
+	// server errors are not cached as failed lookups
+	if _, err := os.Stat(filepath.Join(c.CacheDir, testBuildID, "debuginfo")); err == nil {
+		t.Errorf("server error cached as a failed lookup")
+	}
+}
+
+func TestInvalidRequests(t *testing.T) {
+	c := newTestClient(t)
+	if _, err := c.GetDebuginfo(context.Background(), testBuildID); !errors.Is(err, ErrNoServers) {
+		t.Errorf("expected ErrNoServers, got %v", err)
+	}
+
+	ts := newTestServer(t, fileServer("contents"))
+	c = newTestClient(t, ts)
+	for _, buildid := range []string{"", "../../etc", "xyz"} {
+		if _, err := c.GetDebuginfo(context.Background(), buildid); err == nil {
+			t.Errorf("no error for build ID %q", buildid)
+		}
+	}
+	if n := ts.requests.Load(); n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+}
@@ -2,13 +2,19 @@ package proc
 
 import (
 	"bytes"
+	"compress/gzip"
 	"debug/elf"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"runtime"
+	"sort"
 	"sync"
 
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
 	"github.com/go-delve/delve/pkg/elfwriter"
 	"github.com/go-delve/delve/pkg/version"
 )
@@ -37,6 +43,40 @@ type DumpFlags uint16
 
 const (
 	DumpPlatformIndependent DumpFlags = 1 << iota // always use platform-independent notes format
+	DumpExcludeFileBacked                         // do not dump read-only mappings backed by a file
+	DumpReachableOnly                             // only dump stacks, global variables and the memory reachable from them
+)
+
+// DumpCompression is the compression format of a core dump.
+type DumpCompression uint8
+
+const (
+	DumpUncompressed DumpCompression = iota
+	DumpGzip
+	DumpZstd
+)
+
+// DumpOptions is used to configure (*Target).Dump
+type DumpOptions struct {
+	Flags DumpFlags
+	// MaxMappingSize, if not zero, excludes anonymous mappings larger than
+	// MaxMappingSize bytes from the core dump.
+	MaxMappingSize uint64
+	// Compression is the compression format of the core dump.
+	Compression DumpCompression
+}
+
+const (
+	// dumpPageSize is the granularity of the memory ranges written by
+	// DumpReachableOnly dumps.
+	dumpPageSize = 4096
+	// dumpMaxLoadSegments is the maximum number of PT_LOAD segments in a
+	// core dump, the ELF header can not describe more than 0xfffe program
+	// headers, one of them is used for the notes.
+	dumpMaxLoadSegments = 0xfffe - 1
+	// dumpThreadStackSize is the size of the stack that is dumped for
+	// threads whose system stack could not be determined.
+	dumpThreadStackSize = 64 * 1024
 )
 
 // MemoryMapEntry represent a memory mapping in the target process.
@@ -93,7 +133,7 @@ func (state *DumpState) isCanceled() bool {
 }
 
 // Dump writes a core dump to out. State is updated as the core dump is written.
-func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *DumpState) {
+func (t *Target) Dump(out io.WriteCloser, opts DumpOptions, state *DumpState) {
 	defer func() {
 		state.Mutex.Lock()
 		if ierr := recover(); ierr != nil {
@@ -148,7 +188,21 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 
 	fhdr.Entry = 0
 
-	w := elfwriter.New(out, &fhdr)
+	switch opts.Compression {
+	case DumpUncompressed:
+	case DumpGzip:
+		out = &compressedWriter{gzip.NewWriter(out), out}
+	case DumpZstd:
+		zw, err := newSeekableZstdWriter(out)
+		if err != nil {
+			state.setErr(err)
+			return
+		}
+		out = &compressedWriter{zw, out}
+	default:
+		state.setErr(fmt.Errorf("unknown compression format %d", opts.Compression))
+		return
+	}
 
 	notes := []elfwriter.Note{}
 
@@ -169,7 +223,7 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 
 	var threadsDone bool
 
-	if flags&DumpPlatformIndependent == 0 {
+	if opts.Flags&DumpPlatformIndependent == 0 {
 		threadsDone, notes, err = t.proc.DumpProcessNotes(notes, state.threadDone)
 		if err != nil {
 			state.setErr(err)
@@ -179,10 +233,6 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 
 	if !threadsDone {
 		for _, th := range threads {
-			if w.Err != nil {
-				state.setErr(fmt.Errorf("error writing to output file: %v", w.Err))
-				return
-			}
 			if state.isCanceled() {
 				return
 			}
@@ -198,17 +248,50 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 	}
 
 	memmapFilter := make([]MemoryMapEntry, 0, len(memmap))
-	memtot := uint64(0)
 	for i := range memmap {
 		mme := &memmap[i]
-		if t.shouldDumpMemory(mme) {
+		if t.shouldDumpMemory(mme, &opts) {
 			memmapFilter = append(memmapFilter, *mme)
-			memtot += mme.Size
 		}
 	}
 
+	if opts.Flags&DumpReachableOnly != 0 {
+		memmapFilter, err = t.reachableMemoryMap(memmapFilter)
+		if err != nil {
+			state.setErr(err)
+			return
+		}
+	}
+
+	memtot := uint64(0)
+	for i := range memmapFilter {
+		memtot += memmapFilter[i].Size
+	}
+
 	state.setMemTotal(memtot)
 
+	// The layout of the file is computed in advance so that it can be
+	// written sequentially: file header, program headers, notes and then the
+	// contents of memory.
+	progs := make([]*elf.ProgHeader, 0, len(memmapFilter)+1)
+	off := elfwriter.ProgHeadersEnd(len(memmapFilter) + 1)
+	notesSize := elfwriter.NotesSize(notes)
+	progs = append(progs, &elf.ProgHeader{
+		Type:   elf.PT_NOTE,
+		Off:    off,
+		Filesz: notesSize,
+		Align:  4,
+	})
+	off += notesSize
+	for i := range memmapFilter {
+		mme := &memmapFilter[i]
+		progs = append(progs, memoryProgHeader(mme, off))
+		off += mme.Size
+	}
+
+	w := elfwriter.NewStream(out, &fhdr, progs)
+	w.WriteNotes(notes)
+
 	for i := range memmapFilter {
 		mme := &memmapFilter[i]
 		if w.Err != nil {
@@ -221,9 +304,6 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 		t.dumpMemory(state, w, mme)
 	}
 
-	notesProg := w.WriteNotes(notes)
-	w.Progs = append(w.Progs, notesProg)
-	w.WriteProgramHeaders()
 	if w.Err != nil {
 		state.setErr(fmt.Errorf("error writing to output file: %v", w.Err))
 	}
@@ -294,7 +374,9 @@ func (t *Target) dumpThreadNotes(notes []elfwriter.Note, state *DumpState, th Th
 	})
 }
 
-func (t *Target) dumpMemory(state *DumpState, w *elfwriter.Writer, mme *MemoryMapEntry) {
+// memoryProgHeader returns the program header for the contents of mme
+// written at offset off.
+func memoryProgHeader(mme *MemoryMapEntry, off uint64) *elf.ProgHeader {
 	var flags elf.ProgFlag
 	if mme.Read {
 		flags |= elf.PF_R
@@ -306,17 +388,19 @@ func (t *Target) dumpMemory(state *DumpState, w *elfwriter.Writer, mme *MemoryMa
 		flags |= elf.PF_X
 	}
 
-	w.Progs = append(w.Progs, &elf.ProgHeader{
+	return &elf.ProgHeader{
 		Type:   elf.PT_LOAD,
 		Flags:  flags,
-		Off:    uint64(w.Here()),
+		Off:    off,
 		Vaddr:  mme.Addr,
 		Paddr:  0,
 		Filesz: mme.Size,
 		Memsz:  mme.Size,
 		Align:  0,
-	})
+	}
+}
 
+func (t *Target) dumpMemory(state *DumpState, w *elfwriter.Writer, mme *MemoryMapEntry) {
 	buf := make([]byte, 1024*1024)
 	addr := mme.Addr
 	sz := mme.Size
@@ -351,10 +435,18 @@ func (t *Target) dumpMemory(state *DumpState, w *elfwriter.Writer, mme *MemoryMa
 	}
 }
 
-func (t *Target) shouldDumpMemory(mme *MemoryMapEntry) bool {
+func (t *Target) shouldDumpMemory(mme *MemoryMapEntry, opts *DumpOptions) bool {
 	if !mme.Read {
 		return false
 	}
+	if mme.Filename == "" {
+		if opts.MaxMappingSize != 0 && mme.Size > opts.MaxMappingSize {
+			return false
+		}
+	} else if !mme.Write && opts.Flags&DumpExcludeFileBacked != 0 {
+		// the contents of read-only mappings can be read back from the file
+		return false
+	}
 	exeimg := t.BinInfo().Images[0]
 	if mme.Write || mme.Filename == "" || mme.Filename != exeimg.Path {
 		return true
@@ -387,6 +479,365 @@ func (t *Target) shouldDumpMemory(mme *MemoryMapEntry) bool {
 	return true
 }
 
+// compressedWriter is an io.WriteCloser that compresses its output.
+type compressedWriter struct {
+	io.WriteCloser // compressor
+	out            io.Closer
+}
+
+func (w *compressedWriter) Close() error {
+	err := w.WriteCloser.Close()
+	if err2 := w.out.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+const (
+	// dumpZstdFrameSize is the size of the uncompressed contents of each
+	// frame of a zstd compressed core dump.
+	dumpZstdFrameSize = 4 << 20
+
+	zstdSkippableSeekTableMagic = 0x184d2a5e
+	zstdSeekTableFooterMagic    = 0x8f92eab1
+)
+
+// seekableZstdWriter writes a zstd compressed file in the zstd seekable
+// format, made of independent frames followed by a seek table, so that the
+// core dump can be read without decompressing it entirely, see:
+// https://github.com/facebook/zstd/blob/dev/contrib/seekable_format/zstd_seekable_compression_format.md
+type seekableZstdWriter struct {
+	out    io.Writer
+	enc    *zstd.Encoder
+	buf    []byte
+	cbuf   []byte
+	frames [][2]uint32 // compressed and uncompressed size of each frame
+}
+
+func newSeekableZstdWriter(out io.Writer) (*seekableZstdWriter, error) {
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &seekableZstdWriter{out: out, enc: enc, buf: make([]byte, 0, dumpZstdFrameSize)}, nil
+}
+
+func (w *seekableZstdWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		m := min(len(p), dumpZstdFrameSize-len(w.buf))
+		w.buf = append(w.buf, p[:m]...)
+		p = p[m:]
+		n += m
+		if len(w.buf) == dumpZstdFrameSize {
+			if err := w.flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *seekableZstdWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	w.cbuf = w.enc.EncodeAll(w.buf, w.cbuf[:0])
+	if _, err := w.out.Write(w.cbuf); err != nil {
+		return err
+	}
+	w.frames = append(w.frames, [2]uint32{uint32(len(w.cbuf)), uint32(len(w.buf))})
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close writes the last frame and the seek table.
+func (w *seekableZstdWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	const footerSize = 9
+	table := make([]byte, 0, 8+len(w.frames)*8+footerSize)
+	table = binary.LittleEndian.AppendUint32(table, zstdSkippableSeekTableMagic)
+	table = binary.LittleEndian.AppendUint32(table, uint32(len(w.frames)*8+footerSize))
+	for _, frame := range w.frames {
+		table = binary.LittleEndian.AppendUint32(table, frame[0])
+		table = binary.LittleEndian.AppendUint32(table, frame[1])
+	}
+	table = binary.LittleEndian.AppendUint32(table, uint32(len(w.frames)))
+	table = append(table, 0) // seek table descriptor, no checksums
+	table = binary.LittleEndian.AppendUint32(table, zstdSeekTableFooterMagic)
+	_, err := w.out.Write(table)
+	return err
+}
+
+// reachableMemoryMap returns the portions of the mappings in memmap
+// containing the stacks of goroutines and threads, global variables, the
+// heap objects reachable from them and the memory outside of the heap that
+// is pointed to by them.
+func (t *Target) reachableMemoryMap(memmap []MemoryMapEntry) ([]MemoryMapEntry, error) {
+	sort.Slice(memmap, func(i, j int) bool { return memmap[i].Addr < memmap[j].Addr })
+	findMapping := func(addr uint64) int {
+		i := sort.Search(len(memmap), func(i int) bool { return memmap[i].Addr > addr }) - 1
+		if i < 0 || addr >= memmap[i].Addr+memmap[i].Size {
+			return -1
+		}
+		return i
+	}
+
+	h, err := newHeapScanner(t)
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := h.reachableMemory(func(addr uint64) bool { return findMapping(addr) >= 0 })
+	if err != nil {
+		return nil, err
+	}
+
+	// round ranges to page boundaries and merge them
+	for i := range ranges {
+		ranges[i][0] &^= dumpPageSize - 1
+		ranges[i][1] = (ranges[i][1] + dumpPageSize - 1) &^ (dumpPageSize - 1)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := ranges[:0]
+	for _, rng := range ranges {
+		if rng[0] >= rng[1] {
+			continue
+		}
+		if n := len(merged); n > 0 && rng[0] <= merged[n-1][1] {
+			merged[n-1][1] = max(merged[n-1][1], rng[1])
+			continue
+		}
+		merged = append(merged, rng)
+	}
+
+	// split the ranges between the mappings containing them
+	var r []MemoryMapEntry
+	var mappingIdx []int
+	for _, rng := range merged {
+		start := rng[0]
+		for start < rng[1] {
+			i := findMapping(start)
+			if i < 0 {
+				j := sort.Search(len(memmap), func(i int) bool { return memmap[i].Addr > start })
+				if j >= len(memmap) || memmap[j].Addr >= rng[1] {
+					break
+				}
+				start = memmap[j].Addr
+				continue
+			}
+			mme := memmap[i]
+			end := min(rng[1], mme.Addr+mme.Size)
+			if mme.Filename != "" {
+				mme.Offset += start - mme.Addr
+			}
+			mme.Addr, mme.Size = start, end-start
+			r = append(r, mme)
+			mappingIdx = append(mappingIdx, i)
+			start = end
+		}
+	}
+
+	if len(r) <= dumpMaxLoadSegments {
+		return r, nil
+	}
+
+	// too many segments, merge the ones separated by the smallest gaps
+	// inside the same mapping.
+	var gaps []uint64
+	for i := 1; i < len(r); i++ {
+		if mappingIdx[i] == mappingIdx[i-1] {
+			gaps = append(gaps, r[i].Addr-(r[i-1].Addr+r[i-1].Size))
+		}
+	}
+	if len(gaps) == 0 {
+		return r, nil
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	maxGap := gaps[min(len(r)-dumpMaxLoadSegments, len(gaps))-1]
+	r2 := r[:1]
+	for i := 1; i < len(r); i++ {
+		prev := &r2[len(r2)-1]
+		if mappingIdx[i] == mappingIdx[i-1] && r[i].Addr-(prev.Addr+prev.Size) <= maxGap {
+			prev.Size = r[i].Addr + r[i].Size - prev.Addr
+			continue
+		}
+		r2 = append(r2, r[i])
+	}
+	return r2, nil
+}
+
+// reachableMemory returns the address ranges of global variables, of the
+// used portion of the stacks of goroutines and threads, of the heap objects
+// reachable from them and of the data structures describing the heap.
+// Pointers to memory outside of the heap, for which isMapped returns true,
+// are followed one page at a time and the contents of those pages are
+// scanned conservatively for more pointers outside of the heap.
+func (h *heapScanner) reachableMemory(isMapped func(addr uint64) bool) ([][2]uint64, error) {
+	if err := h.scanRoots(); err != nil {
+		return nil, err
+	}
+	if err := h.propagateTypes(); err != nil {
+		return nil, err
+	}
+
+	ranges := append([][2]uint64(nil), h.metadata...)
+	visited := make([]bool, len(h.objs))
+	var queue []int
+	pages := make(map[uint64]bool)
+	var pageQueue []uint64
+
+	visitOffHeap := func(p uint64) {
+		if h.inSpans(p) || !isMapped(p) {
+			return
+		}
+		page := p &^ (dumpPageSize - 1)
+		if !pages[page] {
+			pages[page] = true
+			pageQueue = append(pageQueue, page)
+		}
+	}
+	visit := func(_ int64, p uint64, _ godwarf.Type) {
+		if i := h.findObject(p); i >= 0 {
+			if !visited[i] {
+				visited[i] = true
+				queue = append(queue, i)
+			}
+			return
+		}
+		visitOffHeap(p)
+	}
+	scanMemory := func(start, end uint64, fn func(off int64, p uint64, typ godwarf.Type)) {
+		buf := make([]byte, dumpPageSize)
+		for addr := start &^ (dumpPageSize - 1); addr < end; addr += dumpPageSize {
+			if _, err := h.mem.ReadMemory(buf, addr); err != nil {
+				continue
+			}
+			lo, hi := max(addr, start)-addr, min(addr+dumpPageSize, end)-addr
+			h.scanWords(buf[lo:hi], fn)
+		}
+	}
+
+	for i := range h.roots {
+		root := &h.roots[i]
+		if root.addr != 0 && len(root.buf) > 0 {
+			ranges = append(ranges, [2]uint64{root.addr, root.addr + uint64(len(root.buf))})
+		}
+		h.rootPointers(root, visit)
+	}
+
+	stacks, regs, err := h.stacks()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range regs {
+		visit(0, p, nil)
+	}
+	for _, stk := range stacks {
+		if h.t.canceled() {
+			return nil, ErrCanceled
+		}
+		ranges = append(ranges, stk)
+		// stacks are scanned conservatively because they can contain pointers
+		// that are not described by the debug symbols, for example values
+		// spilled by assembly functions.
+		scanMemory(stk[0], stk[1], visit)
+	}
+
+	for n := 0; n < len(queue); n++ {
+		if n%4096 == 0 && h.t.canceled() {
+			return nil, ErrCanceled
+		}
+		i := queue[n]
+		ranges = append(ranges, [2]uint64{h.objs[i].base, h.objs[i].base + h.objs[i].size})
+		h.objectPointers(i, visit)
+	}
+
+	for n := 0; n < len(pageQueue); n++ {
+		if n%4096 == 0 && h.t.canceled() {
+			return nil, ErrCanceled
+		}
+		page := pageQueue[n]
+		ranges = append(ranges, [2]uint64{page, page + dumpPageSize})
+		// memory outside of the heap can contain pointers to objects that are
+		// no longer reachable, only pointers outside of the heap are followed.
+		scanMemory(page, page+dumpPageSize, func(_ int64, p uint64, _ godwarf.Type) {
+			visitOffHeap(p)
+		})
+	}
+
+	return ranges, nil
+}
+
+// stacks returns the address ranges of the used portion of the stacks of
+// all goroutines and threads and the values of the registers of all
+// threads.
+func (h *heapScanner) stacks() (stacks [][2]uint64, regvals []uint64, err error) {
+	addStack := func(sp, lo, hi uint64) {
+		if sp < lo || sp >= hi {
+			sp = lo
+		}
+		if lo < hi {
+			stacks = append(stacks, [2]uint64{sp, hi})
+		}
+	}
+
+	gs, _, err := GoroutinesInfo(h.t, 0, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, g := range gs {
+		if g.Unreadable != nil {
+			continue
+		}
+		sp := g.SP
+		if g.Thread != nil && !g.SystemStack {
+			if regs, err := g.Thread.Registers(); err == nil {
+				sp = regs.SP()
+			}
+		}
+		addStack(sp, g.stack.lo, g.stack.hi)
+	}
+
+	for _, th := range h.t.ThreadList() {
+		regs, err := th.Registers()
+		if err != nil {
+			continue
+		}
+		if regsv, err := regs.Slice(false); err == nil {
+			for _, reg := range regsv {
+				if reg.Reg.Bytes == nil {
+					regvals = append(regvals, reg.Reg.Uint64Val)
+				}
+			}
+		}
+		if tls := regs.TLS(); tls > dumpPageSize {
+			// thread local storage, contains the pointer to the current goroutine
+			// on some platforms.
+			stacks = append(stacks, [2]uint64{tls - dumpPageSize, tls + dumpPageSize})
+		}
+
+		sp := regs.SP()
+		lo, hi := sp, sp+dumpThreadStackSize
+		if g, _ := GetG(th); g != nil && g.variable != nil {
+			if sp >= g.stack.lo && sp < g.stack.hi {
+				// already added with the goroutine
+				continue
+			}
+			if mvar, _ := g.variable.structMember("m"); mvar != nil {
+				if g0var, _ := mvar.structMember("g0"); g0var != nil {
+					if g0, _ := g0var.parseG(); g0 != nil && sp >= g0.stack.lo && sp < g0.stack.hi {
+						lo, hi = g0.stack.lo, g0.stack.hi
+					}
+				}
+			}
+		}
+		addStack(sp, lo, hi)
+	}
+	return stacks, regvals, nil
+}
+
 type internalError struct {
 	Err   interface{}
 	Stack []internalErrorFrame
@@ -6,10 +6,10 @@ import (
 	"fmt"
 	"go/ast"
 	"go/constant"
-	"go/parser"
 	"go/token"
 	"reflect"
 	"runtime/debug"
+	"slices"
 	"sort"
 	"strings"
 
@@ -51,6 +51,8 @@ type EvalScope struct {
 
 	dictAddr uint64 // dictionary address for instantiated generic functions
 
+	liveConvVars []string // live convenience variables being evaluated
+
 	enclosingRangeScopes []*EvalScope
 	rangeFrames          []Stackframe
 }
@@ -228,14 +230,42 @@ func (scope scopeToEvalLookup) HasBuiltin(name string) bool {
 	return supportedBuiltins[name] != nil
 }
 
+func (scope scopeToEvalLookup) HasFunction(name string) bool {
+	return scope.BinInfo.lookupOneFunc(name) != nil
+}
+
+func (scope scopeToEvalLookup) HasGenericFunction(name string) bool {
+	_, fns := scope.findGenericFunction(name)
+	return len(fns) > 0
+}
+
 func (scope scopeToEvalLookup) PtrSize() int {
 	return scope.BinInfo.Arch.ptrSize
 }
 
+func (scope scopeToEvalLookup) LookupConstant(name string) (constant.Value, bool) {
+	if !strings.Contains(name, ".") {
+		// unqualified names are constants of the current package, unless a
+		// local variable shadows them
+		if scope.Fn == nil {
+			return nil, false
+		}
+		if vars, err := scope.Locals(0, name); err != nil || len(vars) > 0 {
+			return nil, false
+		}
+		name = scope.Fn.PackageName() + "." + name
+	}
+	v, err := scope.findGlobalInternal(name)
+	if err != nil || v == nil || v.Flags&VariableConstant == 0 {
+		return nil, false
+	}
+	return v.Value, true
+}
+
 // ChanGoroutines returns the list of goroutines waiting to receive from or
 // send to the channel.
 func (scope *EvalScope) ChanGoroutines(expr string, start, count int) ([]int64, error) {
-	t, err := parser.ParseExpr(expr)
+	t, err := evalop.ParseExpr(expr)
 	if err != nil {
 		return nil, err
 	}
@@ -571,19 +601,29 @@ func readLocalPtrVar(dwarfTree *godwarf.Tree, vname string, tgt *Target, bi *Bin
 //   - If srcv is nil and dstv is of a nil'able type then dstv is nilled.
 //   - If srcv is the empty string and dstv is a string then dstv is set to the
 //     empty string.
-//   - If dstv is an "interface {}" and srcv is either an interface (possibly
-//     non-empty) or a pointer shaped type (map, channel, pointer or struct
-//     containing a single pointer field) the type conversion to "interface {}"
-//     is performed.
+//   - If dstv is an interface and srcv is either an interface or a pointer
+//     shaped type (map, channel, pointer or struct containing a single
+//     pointer field) the type conversion to the type of dstv is performed.
+//     Other values must be copied into a new allocation first, see
+//     CopyToAlloc.
 //   - If srcv and dstv have the same type and are both addressable then the
 //     contents of srcv are copied byte-by-byte into dstv
 func (scope *EvalScope) setValue(dstv, srcv *Variable, srcExpr string) error {
 	srcv.loadValue(loadSingleValue)
 
 	typerr := srcv.isType(dstv.RealType, dstv.Kind)
-	if _, isTypeConvErr := typerr.(*typeConvErr); isTypeConvErr {
-		// attempt iface -> eface and ptr-shaped -> eface conversions.
-		return convertToEface(srcv, dstv)
+	if typerr != nil && dstv.Kind == reflect.Interface && srcv.Unreadable == nil {
+		// attempt conversions of interfaces and concrete values to interfaces.
+		switch {
+		case scope.needsBox(dstv, srcv):
+			// the value needs to be copied into a new allocation, this is done
+			// by CopyToAlloc when calls are allowed.
+			if scope.callCtx == nil {
+				return ErrFuncCallNotAllowedLitAlloc
+			}
+		case srcv.DwarfType != nil:
+			return convertToInterface(scope.Mem, srcv, dstv)
+		}
 	}
 	if typerr != nil {
 		return typerr
@@ -627,7 +667,7 @@ func (scope *EvalScope) setValue(dstv, srcv *Variable, srcExpr string) error {
 
 	if srcv.Kind == reflect.String {
 		if srcv.Base == 0 && srcv.Len > 0 && srcv.Flags&VariableConstant != 0 {
-			return errFuncCallNotAllowedStrAlloc
+			return ErrFuncCallNotAllowedStrAlloc
 		}
 		return dstv.writeString(uint64(srcv.Len), srcv.Base)
 	}
@@ -1125,6 +1165,22 @@ func (stack *evalStack) executeOp() {
 			}
 		}
 
+	case *evalop.PushGenericFunction:
+		v, err := scope.instantiateGenericFunction(op.Name, op.TypeArgs)
+		if err != nil {
+			stack.err = err
+			return
+		}
+		stack.push(v)
+
+	case *evalop.PushConvVar:
+		v, err := scope.convVar(op.Name)
+		if err != nil {
+			stack.err = err
+			return
+		}
+		stack.push(v)
+
 	case *evalop.PushIdent:
 		found := stack.pushIdent(scope, op.Name)
 		if !found {
@@ -1205,7 +1261,14 @@ func (stack *evalStack) executeOp() {
 		if actualArg.Name == "" {
 			actualArg.Name = astutil.ExprToString(op.ArgExpr)
 		}
-		stack.err = funcCallCopyOneArg(scope, fncall, actualArg, &fncall.formalArgs[op.ArgNum], curthread)
+		formalArg := &fncall.formalArgs[op.ArgNum]
+		if op.ConvertPointer && actualArg != nilVariable {
+			actualArg, stack.err = convertPointerArg(actualArg, formalArg)
+			if stack.err != nil {
+				break
+			}
+		}
+		stack.err = funcCallCopyOneArg(scope, fncall, actualArg, formalArg, curthread)
 
 	case *evalop.CallInjectionComplete:
 		fncall := stack.fncallPeek()
@@ -1237,11 +1300,37 @@ func (stack *evalStack) executeOp() {
 	case *evalop.ConvertAllocToString:
 		scope.convertAllocToString(stack)
 
+	case *evalop.ConvertAllocToSlice:
+		scope.convertAllocToSlice(op, stack)
+
+	case *evalop.ConvertAllocToMap:
+		scope.convertAllocToMap(op, stack)
+
+	case *evalop.PushRuntimeTypeOf:
+		scope.pushRuntimeTypeOf(stack)
+
+	case *evalop.CopyToAlloc:
+		scope.copyToAlloc(op, stack)
+
+	case *evalop.ConvertToMapKey:
+		scope.convertToMapKey(op, stack)
+
+	case *evalop.ConvertToMapElem:
+		scope.convertToMapElem(stack)
+
 	case *evalop.SetValue:
 		lhv := stack.pop()
 		rhv := stack.pop()
 		stack.err = scope.setValue(lhv, rhv, astutil.ExprToString(op.Rhe))
 
+	case *evalop.SetConvVar:
+		v := stack.pop()
+		if scope.target == nil || scope.target.convVars == nil {
+			stack.err = errors.New("convenience variables are not available")
+			return
+		}
+		stack.err = scope.target.convVars.setSnapshot(op.Name, v)
+
 	case *evalop.PushPinAddress:
 		debugPinCount++
 		fncall := stack.fncallPeek()
@@ -1271,13 +1360,7 @@ func (stack *evalStack) executeOp() {
 			stack.err = err
 			break
 		}
-		rttyp, err := scope.BinInfo.findType(scope.BinInfo.runtimeTypeTypename())
-		if err != nil {
-			stack.err = err
-			break
-		}
-		v := newVariable("", typeAddr, rttyp, scope.BinInfo, scope.Mem)
-		stack.push(v.pointerToVariable())
+		stack.pushErr(scope.runtimeTypePointer(typeAddr))
 
 	case *evalop.PushNewFakeVariable:
 		stack.pushNewFakeVariable(scope, op.Type)
@@ -1382,7 +1465,13 @@ func (stack *evalStack) pushIdent(scope *EvalScope, name string) (found bool) {
 }
 
 func (stack *evalStack) pushNewFakeVariable(scope *EvalScope, typ godwarf.Type) {
-	cm, err := CreateCompositeMemory(scope.Mem, scope.BinInfo.Arch, *new(op.DwarfRegisters), []op.Piece{{Kind: op.ImmPiece, Bytes: make([]byte, typ.Size()), Size: int(typ.Size())}}, typ.Size())
+	stack.pushNewFakeVariableWithContents(scope, typ, make([]byte, typ.Size()))
+}
+
+// pushNewFakeVariableWithContents pushes a new debugger allocated variable
+// of type typ, initialized with buf, on the stack.
+func (stack *evalStack) pushNewFakeVariableWithContents(scope *EvalScope, typ godwarf.Type, buf []byte) {
+	cm, err := CreateCompositeMemory(scope.Mem, scope.BinInfo.Arch, *new(op.DwarfRegisters), []op.Piece{{Kind: op.ImmPiece, Bytes: buf, Size: len(buf)}}, int64(len(buf)))
 	if err != nil {
 		stack.err = err
 		return
@@ -1427,6 +1516,18 @@ func (scope *EvalScope) evalJump(op *evalop.Jump, stack *evalStack) {
 			return
 		}
 		return
+	case evalop.JumpIfBoxChecksFail:
+		lhv, rhv := stack.peek(), stack.stack[len(stack.stack)-2]
+		if scope.callCtx == nil || !scope.needsBox(lhv, rhv) {
+			stack.opidx = op.Target - 1
+		}
+		return
+	case evalop.JumpIfMapAssignChecksFail:
+		mapv := stack.stack[len(stack.stack)-2]
+		if mapv.Kind != reflect.Map {
+			stack.opidx = op.Target - 1
+		}
+		return
 	case evalop.JumpAlways:
 		stack.opidx = op.Target - 1
 		return
@@ -1470,7 +1571,7 @@ func (scope *EvalScope) evalTypeCast(op *evalop.TypeCast, stack *evalStack) {
 	if typeCastCompatibleTypes(argv.RealType, typ) {
 		ptyp, isptr := typ.(*godwarf.PtrType)
 		_, isvoid := argv.DwarfType.(*godwarf.VoidType)
-		if (argv.Kind == reflect.Ptr || isvoid) && argv.loaded && len(argv.Children) > 0 && isptr {
+		if (argv.Kind == reflect.Ptr || argv.Kind == reflect.UnsafePointer || isvoid) && argv.loaded && len(argv.Children) > 0 && isptr {
 			cv := argv.Children[0]
 			argv.Children[0] = *newVariable(cv.Name, cv.Addr, ptyp.Type, cv.bi, cv.mem)
 			argv.Children[0].OnlyAddr = true
@@ -1828,6 +1929,11 @@ var supportedBuiltins = map[string]func([]*Variable, []ast.Expr) (*Variable, err
 	"real":    realBuiltin,
 	"min":     minBuiltin,
 	"max":     maxBuiltin,
+
+	"chanpeek":  chanpeekBuiltin,
+	"mapkeys":   mapkeysBuiltin,
+	"mapvalues": mapvaluesBuiltin,
+	"haskey":    haskeyBuiltin,
 }
 
 func capBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
@@ -2043,6 +2149,158 @@ func minmaxBuiltin(name string, op token.Token, args []*Variable, nodeargs []ast
 	return best, nil
 }
 
+// chanpeekBuiltin returns the i-th element of the buffer of a channel,
+// where 0 is the element that will be received next, without removing it.
+func chanpeekBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments to chanpeek: %d", len(args))
+	}
+
+	ch, idxv := args[0], args[1]
+	if ch.Kind != reflect.Chan {
+		return nil, fmt.Errorf("invalid argument %s (type %s) for chanpeek", astutil.ExprToString(nodeargs[0]), ch.TypeString())
+	}
+	idxv.loadValue(loadSingleValue)
+	if idxv.Unreadable != nil {
+		return nil, idxv.Unreadable
+	}
+	if idxv.Value == nil || idxv.Value.Kind() != constant.Int {
+		return nil, fmt.Errorf("invalid index %s (type %s) for chanpeek", astutil.ExprToString(nodeargs[1]), idxv.TypeString())
+	}
+	idx, _ := constant.Int64Val(idxv.Value)
+
+	chanType, ok := ch.RealType.(*godwarf.ChanType)
+	if !ok {
+		return nil, errors.New("bad channel type")
+	}
+	sv := ch.clone()
+	sv.RealType = godwarf.ResolveTypedef(&(chanType.TypedefType))
+	sv = sv.maybeDereference()
+	if sv.Unreadable != nil {
+		return nil, sv.Unreadable
+	}
+	if sv.Addr == 0 {
+		return nil, fmt.Errorf("%s is a nil channel", astutil.ExprToString(nodeargs[0]))
+	}
+
+	structType, ok := sv.RealType.(*godwarf.StructType)
+	if !ok {
+		return nil, errors.New("bad channel type")
+	}
+	var qcount, dataqsiz, recvx, buf uint64
+	for _, f := range structType.Field {
+		var err error
+		fv, _ := sv.toField(f)
+		switch f.Name {
+		case "qcount": // +rtype -fieldof hchan uint
+			qcount, err = fv.asUint()
+		case "dataqsiz": // +rtype -fieldof hchan uint
+			dataqsiz, err = fv.asUint()
+		case "recvx": // +rtype -fieldof hchan uint
+			recvx, err = fv.asUint()
+		case "buf": // +rtype -fieldof hchan unsafe.Pointer
+			buf, err = readUintRaw(fv.mem, fv.Addr, int64(ch.bi.Arch.PtrSize()))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if idx < 0 || uint64(idx) >= qcount {
+		return nil, fmt.Errorf("index %d out of bounds for channel buffer of length %d", idx, qcount)
+	}
+	if dataqsiz == 0 {
+		return nil, errors.New("bad channel buffer size")
+	}
+
+	i := (recvx + uint64(idx)) % dataqsiz
+	return ch.newVariable("", buf+i*uint64(chanType.ElemType.Size()), chanType.ElemType, DereferenceMemory(ch.mem)), nil
+}
+
+// mapkeysBuiltin returns an array containing the keys of a map.
+func mapkeysBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
+	return mapContentsBuiltin("mapkeys", true, args, nodeargs)
+}
+
+// mapvaluesBuiltin returns an array containing the values of a map.
+func mapvaluesBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
+	return mapContentsBuiltin("mapvalues", false, args, nodeargs)
+}
+
+func mapContentsBuiltin(name string, keys bool, args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments to %s: %d", name, len(args))
+	}
+	m := args[0]
+	mt, ok := m.RealType.(*godwarf.MapType)
+	if !ok {
+		return nil, fmt.Errorf("invalid argument %s (type %s) for %s", astutil.ExprToString(nodeargs[0]), m.TypeString(), name)
+	}
+	typ := mt.ElemType
+	if keys {
+		typ = mt.KeyType
+	}
+
+	it := m.mapIterator(0)
+	if it == nil {
+		return nil, m.Unreadable
+	}
+	var elems []*Variable
+	for it.next() {
+		if keys {
+			elems = append(elems, it.key())
+		} else {
+			elems = append(elems, it.value())
+		}
+	}
+	if m.Unreadable != nil {
+		return nil, m.Unreadable
+	}
+	return newFakeArray(m.bi, m.mem, typ, elems)
+}
+
+// newFakeArray returns a debugger allocated array of type typ containing a
+// copy of elems.
+func newFakeArray(bi *BinaryInfo, mem MemoryReadWriter, typ godwarf.Type, elems []*Variable) (*Variable, error) {
+	sz := typ.Size()
+	buf := make([]byte, 0, int64(len(elems))*sz)
+	for _, elem := range elems {
+		elembuf := make([]byte, sz)
+		if sz > 0 {
+			if _, err := elem.mem.ReadMemory(elembuf, elem.Addr); err != nil {
+				return nil, err
+			}
+		}
+		buf = append(buf, elembuf...)
+	}
+	cm, err := CreateCompositeMemory(mem, bi.Arch, *new(op.DwarfRegisters), []op.Piece{{Kind: op.ImmPiece, Bytes: buf, Size: len(buf)}}, int64(len(buf)))
+	if err != nil {
+		return nil, err
+	}
+	v := newVariable("", cm.base, fakeArrayType(uint64(len(elems)), typ), bi, cm)
+	v.Flags = VariableFakeAddress
+	return v, nil
+}
+
+// haskeyBuiltin returns true if the map contains the specified key.
+func haskeyBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments to haskey: %d", len(args))
+	}
+	m, key := args[0], args[1]
+	if m.Kind != reflect.Map {
+		return nil, fmt.Errorf("invalid argument %s (type %s) for haskey", astutil.ExprToString(nodeargs[0]), m.TypeString())
+	}
+	key.loadValue(loadFullValue)
+	if key.Unreadable != nil {
+		return nil, key.Unreadable
+	}
+	r, err := m.mapLookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return newConstant(constant.MakeBool(r != nil), m.bi, m.mem), nil
+}
+
 // Evaluates expressions <subexpr>.<field name> where subexpr is not a package name
 func (scope *EvalScope) evalStructSelector(op *evalop.Select, stack *evalStack) {
 	xv := stack.pop()
@@ -2192,7 +2450,8 @@ func (scope *EvalScope) evalIndex(op *evalop.Index, stack *evalStack) {
 	}
 }
 
-// Evaluates expressions <subexpr>[<subexpr>:<subexpr>]
+// Evaluates expressions <subexpr>[<subexpr>:<subexpr>] and
+// <subexpr>[<subexpr>:<subexpr>:<subexpr>]
 // HACK: slicing a map expression with [0:0] will return the whole map
 func (scope *EvalScope) evalReslice(op *evalop.Reslice, stack *evalStack) {
 	low, err := stack.pop().asInt()
@@ -2208,25 +2467,55 @@ func (scope *EvalScope) evalReslice(op *evalop.Reslice, stack *evalStack) {
 			return
 		}
 	}
+	max := int64(-1)
+	if op.HasMax {
+		max, err = stack.pop().asInt()
+		if err != nil {
+			stack.err = err
+			return
+		}
+		if max < 0 {
+			stack.err = errors.New("index out of bounds")
+			return
+		}
+	}
 	xev := stack.pop()
 	if xev.Unreadable != nil {
 		stack.err = xev.Unreadable
 		return
 	}
+
+	if xev.Kind == reflect.Ptr && xev.Flags&VariableCPtr == 0 {
+		// slicing a pointer to array slices the array it points to
+		if ptyp, isptr := xev.RealType.(*godwarf.PtrType); isptr {
+			if _, isarr := godwarf.ResolveTypedef(ptyp.Type).(*godwarf.ArrayType); isarr {
+				xev = xev.maybeDereference()
+				if xev.Addr == 0 {
+					stack.err = fmt.Errorf("can not slice %q, nil pointer dereference", astutil.ExprToString(op.Node.X))
+					return
+				}
+			}
+		}
+	}
+
 	if !op.HasHigh {
 		high = xev.Len
 	}
 
 	switch xev.Kind {
 	case reflect.Slice, reflect.Array, reflect.String:
-		if xev.Base == 0 {
+		if xev.Base == 0 && (xev.Kind != reflect.Slice || xev.Cap != 0) {
 			stack.err = fmt.Errorf("can not slice %q", astutil.ExprToString(op.Node.X))
 			return
 		}
-		stack.pushErr(xev.reslice(low, high, op.TrustLen))
+		if xev.Kind == reflect.String && op.HasMax {
+			stack.err = fmt.Errorf("invalid operation: 3-index slice of string")
+			return
+		}
+		stack.pushErr(xev.reslice(low, high, max, op.TrustLen))
 		return
 	case reflect.Map:
-		if op.Node.High != nil {
+		if op.Node.High != nil || op.HasMax {
 			stack.err = errors.New("second slice argument must be empty for maps")
 			return
 		}
@@ -2240,7 +2529,7 @@ func (scope *EvalScope) evalReslice(op *evalop.Reslice, stack *evalStack) {
 		return
 	case reflect.Ptr:
 		if xev.Flags&VariableCPtr != 0 {
-			stack.pushErr(xev.reslice(low, high, op.TrustLen))
+			stack.pushErr(xev.reslice(low, high, max, op.TrustLen))
 			return
 		}
 		fallthrough
@@ -2307,32 +2596,6 @@ func (v *Variable) pointerToVariable() *Variable {
 	return rv
 }
 
-func constantUnaryOp(op token.Token, y constant.Value) (r constant.Value, err error) {
-	defer func() {
-		if ierr := recover(); ierr != nil {
-			err = fmt.Errorf("%v", ierr)
-		}
-	}()
-	r = constant.UnaryOp(op, y, 0)
-	return
-}
-
-func constantBinaryOp(op token.Token, x, y constant.Value) (r constant.Value, err error) {
-	defer func() {
-		if ierr := recover(); ierr != nil {
-			err = fmt.Errorf("%v", ierr)
-		}
-	}()
-	switch op {
-	case token.SHL, token.SHR:
-		n, _ := constant.Uint64Val(y)
-		r = constant.Shift(x, op, uint(n))
-	default:
-		r = constant.BinaryOp(x, op, y)
-	}
-	return
-}
-
 func constantCompare(op token.Token, x, y constant.Value) (r bool, err error) {
 	defer func() {
 		if ierr := recover(); ierr != nil {
@@ -2360,7 +2623,7 @@ func (scope *EvalScope) evalUnary(op *evalop.Unary, stack *evalStack) {
 		stack.err = fmt.Errorf("operator %s can not be applied to %q", op.Node.Op.String(), astutil.ExprToString(op.Node.X))
 		return
 	}
-	rc, err := constantUnaryOp(op.Node.Op, xv.Value)
+	rc, err := evalop.ConstantUnaryOp(op.Node.Op, xv.Value)
 	if err != nil {
 		stack.err = err
 		return
@@ -2438,6 +2701,49 @@ func negotiateTypeNil(op token.Token, v *Variable) error {
 	}
 }
 
+// compareInterfaceToConstant compares an interface value with an untyped
+// constant, like the Go compiler does the constant is converted to its
+// default type and the two are equal only if the dynamic type of the
+// interface is that type and the dynamic value is equal to the constant.
+// The second return value is false if xv and yv are not an interface and
+// an untyped constant or op is not == or !=.
+func compareInterfaceToConstant(op token.Token, xv, yv *Variable) (bool, bool, error) {
+	if op != token.EQL && op != token.NEQ {
+		return false, false, nil
+	}
+	iface, c := xv, yv
+	if iface.Kind != reflect.Interface {
+		iface, c = yv, xv
+	}
+	if iface.Kind != reflect.Interface || c == nilVariable || c.DwarfType != nil || c.Value == nil {
+		return false, false, nil
+	}
+	var deftyp string
+	switch c.Value.Kind() {
+	case constant.Bool:
+		deftyp = "bool"
+	case constant.String:
+		deftyp = "string"
+	case constant.Int:
+		deftyp = "int"
+	case constant.Float:
+		deftyp = "float64"
+	case constant.Complex:
+		deftyp = "complex128"
+	default:
+		return false, false, nil
+	}
+	if len(iface.Children) == 0 || iface.Children[0].Kind == reflect.Invalid || iface.Children[0].DwarfType == nil || iface.Children[0].DwarfType.String() != deftyp {
+		return op == token.NEQ, true, nil
+	}
+	concrete := &iface.Children[0]
+	if concrete.Unreadable != nil {
+		return false, true, concrete.Unreadable
+	}
+	r, err := compareOp(op, concrete, c)
+	return r, true, err
+}
+
 func (scope *EvalScope) evalBinary(binop *evalop.Binary, stack *evalStack) {
 	node := binop.Node
 
@@ -2464,6 +2770,15 @@ func (scope *EvalScope) evalBinary(binop *evalop.Binary, stack *evalStack) {
 		return
 	}
 
+	if r, ok, err := compareInterfaceToConstant(node.Op, xv, yv); ok {
+		if err != nil {
+			stack.err = err
+			return
+		}
+		stack.push(newConstant(constant.MakeBool(r), xv.bi, xv.mem))
+		return
+	}
+
 	typ, err := negotiateType(node.Op, xv, yv)
 	if err != nil {
 		stack.err = err
@@ -2521,7 +2836,7 @@ func (scope *EvalScope) evalBinary(binop *evalop.Binary, stack *evalStack) {
 			return
 		}
 
-		rc, err := constantBinaryOp(op, xv.Value, yv.Value)
+		rc, err := evalop.ConstantBinaryOp(op, xv.Value, yv.Value)
 		if err != nil {
 			stack.err = err
 			return
@@ -2827,12 +3142,26 @@ func (v *Variable) sliceAccess(idx int) (*Variable, error) {
 	}
 	mem := v.mem
 	if v.Kind != reflect.Array {
-		mem = DereferenceMemory(mem)
+		mem = derefMemoryAt(mem, v.Base)
 	}
 	return v.newVariable("", v.Base+uint64(int64(idx)*v.stride), v.fieldType, mem), nil
 }
 
 func (v *Variable) mapAccess(idx *Variable) (*Variable, error) {
+	r, err := v.mapLookup(idx)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		// go would return zero for the map value type here, we do not have the ability to create zeroes
+		return nil, errors.New("key not found")
+	}
+	return r, nil
+}
+
+// mapLookup returns the value associated with idx in the map v, or nil if
+// the map does not contain idx.
+func (v *Variable) mapLookup(idx *Variable) (*Variable, error) {
 	it := v.mapIterator(0)
 	if it == nil {
 		return nil, fmt.Errorf("can not access unreadable map: %v", v.Unreadable)
@@ -2870,8 +3199,7 @@ func (v *Variable) mapAccess(idx *Variable) (*Variable, error) {
 	if v.Unreadable != nil {
 		return nil, v.Unreadable
 	}
-	// go would return zero for the map value type here, we do not have the ability to create zeroes
-	return nil, errors.New("key not found")
+	return nil, nil
 }
 
 // LoadResliced returns a new array, slice or map that starts at index start and contains
@@ -2883,7 +3211,7 @@ func (v *Variable) LoadResliced(start int, cfg LoadConfig) (newV *Variable, err
 		if high > v.Len {
 			high = v.Len
 		}
-		newV, err = v.reslice(low, high, false)
+		newV, err = v.reslice(low, high, -1, false)
 		if err != nil {
 			return nil, err
 		}
@@ -2899,20 +3227,31 @@ func (v *Variable) LoadResliced(start int, cfg LoadConfig) (newV *Variable, err
 	return newV, nil
 }
 
-func (v *Variable) reslice(low int64, high int64, trustLen bool) (*Variable, error) {
+// reslice returns v[low:high:max], if max is negative it returns
+// v[low:high].
+// The indices must satisfy 0 <= low <= high <= max <= cap(v), where the
+// capacity of arrays and strings is their length.
+func (v *Variable) reslice(low, high, max int64, trustLen bool) (*Variable, error) {
 	wrong := false
 	cptrNeedsFakeSlice := false
 	if v.Flags&VariableCPtr == 0 {
+		c := v.Len
 		if v.Kind == reflect.Slice {
-			wrong = low < 0 || low > v.Cap || high < 0 || high > v.Cap
-		} else {
-			wrong = low < 0 || low > v.Len || high < 0 || high > v.Len
+			c = v.Cap
+		}
+		if max < 0 {
+			max = c
 		}
+		wrong = low < 0 || high < low || max < high || max > c
 	} else {
 		wrong = low < 0 || high < 0
 		if high == 0 {
 			high = low
 		}
+		if max < 0 {
+			max = high
+		}
+		wrong = wrong || max < high
 		cptrNeedsFakeSlice = v.Kind != reflect.String
 	}
 	if wrong {
@@ -2933,15 +3272,11 @@ func (v *Variable) reslice(low int64, high int64, trustLen bool) (*Variable, err
 
 	mem := v.mem
 	if v.Kind != reflect.Array {
-		mem = DereferenceMemory(mem)
+		mem = derefMemoryAt(mem, v.Base)
 	}
 
 	r := v.newVariable("", 0, typ, mem)
-	if v.Flags&VariableCPtr == 0 {
-		r.Cap = v.Cap - low
-	} else {
-		r.Cap = len
-	}
+	r.Cap = max - low
 	r.Len = len
 	r.Base = base
 	r.stride = v.stride
@@ -2958,6 +3293,10 @@ func (v *Variable) reslice(low int64, high int64, trustLen bool) (*Variable, err
 // findMethod finds method mname in the type of variable v
 func (v *Variable) findMethod(mname string) (*Variable, error) {
 	if _, isiface := v.RealType.(*godwarf.InterfaceType); isiface {
+		r, err := v.itabMethod(mname)
+		if r != nil || err != nil {
+			return r, err
+		}
 		v.loadInterface(0, false, loadFullValue)
 		if v.Unreadable != nil {
 			return nil, v.Unreadable
@@ -2983,6 +3322,12 @@ func (v *Variable) findMethod(mname string) (*Variable, error) {
 		}
 
 		typePath := typ.Common().Name
+		var typeArgs string
+		if lbr := strings.Index(typePath, "["); lbr >= 0 && strings.HasSuffix(typePath, "]") {
+			// instantiated generic type
+			typeArgs = typePath[lbr+1 : len(typePath)-1]
+			typePath = typePath[:lbr]
+		}
 		dot := strings.LastIndex(typePath, ".")
 		if dot < 0 {
 			// probably just a C type
@@ -2992,13 +3337,32 @@ func (v *Variable) findMethod(mname string) (*Variable, error) {
 		pkg := typePath[:dot]
 		receiver := typePath[dot+1:]
 
-		//TODO(aarzilli): support generic functions?
-
-		if fns := v.bi.LookupFunc()[fmt.Sprintf("%s.%s.%s", pkg, receiver, mname)]; len(fns) == 1 {
-			r, err := functionToVariable(fns[0], v.bi, v.mem)
-			if err != nil {
-				return nil, err
+		lookupMethod := func(format string) (*Variable, error) {
+			name := fmt.Sprintf(format, pkg, receiver, mname)
+			if typeArgs == "" {
+				if fns := v.bi.LookupFunc()[name]; len(fns) == 1 {
+					return functionToVariable(fns[0], v.bi, v.mem)
+				}
+				return nil, nil
+			}
+			fns := v.bi.LookupGenericFunc()[name]
+			if len(fns) == 0 {
+				return nil, nil
+			}
+			typeArgNames := splitTypeArgs(typeArgs)
+			typeArgTypes := make([]godwarf.Type, len(typeArgNames))
+			for i := range typeArgNames {
+				typeArgTypes[i], _ = v.bi.findType(typeArgNames[i])
 			}
+			instReceiver := receiver + "[" + typeArgs + "]"
+			return genericInstantiation(v.bi, v.mem, fns, fmt.Sprintf(format, pkg, instReceiver, mname), pkg+dictSymbolSep+instReceiver, typeArgTypes)
+		}
+
+		r, err := lookupMethod("%s.%s.%s")
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
 			if isptr {
 				r.Children = append(r.Children, *(v.maybeDereference()))
 			} else {
@@ -3007,11 +3371,11 @@ func (v *Variable) findMethod(mname string) (*Variable, error) {
 			return r, nil
 		}
 
-		if fns := v.bi.LookupFunc()[fmt.Sprintf("%s.(*%s).%s", pkg, receiver, mname)]; len(fns) == 1 {
-			r, err := functionToVariable(fns[0], v.bi, v.mem)
-			if err != nil {
-				return nil, err
-			}
+		r, err = lookupMethod("%s.(*%s).%s")
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
 			if isptr {
 				r.Children = append(r.Children, *v)
 			} else {
@@ -3055,16 +3419,188 @@ func functionToVariable(fn *Function, bi *BinaryInfo, mem MemoryReadWriter) (*Va
 	return v, nil
 }
 
+// findGenericFunction returns the fully qualified name of the generic
+// function name, as written in an expression, and its instantiations.
+func (scope *EvalScope) findGenericFunction(name string) (string, []*Function) {
+	lookup := scope.BinInfo.LookupGenericFunc()
+	dot := strings.LastIndex(name, ".")
+	if dot < 0 {
+		if scope.Fn == nil {
+			return "", nil
+		}
+		name = scope.Fn.PackageName() + "." + name
+		return name, lookup[name]
+	}
+	for _, pkgPath := range scope.BinInfo.PackageMap[name[:dot]] {
+		if fns := lookup[pkgPath+name[dot:]]; len(fns) > 0 {
+			return pkgPath + name[dot:], fns
+		}
+	}
+	return name, lookup[name]
+}
+
+// instantiateGenericFunction returns a function variable for the
+// instantiation of the generic function name with type arguments typeArgs.
+func (scope *EvalScope) instantiateGenericFunction(name string, typeArgs []godwarf.Type) (*Variable, error) {
+	name, fns := scope.findGenericFunction(name)
+	if len(fns) == 0 {
+		return nil, &errCouldNotFindSymbol{name}
+	}
+	typeArgNames := make([]string, len(typeArgs))
+	for i := range typeArgs {
+		typeArgNames[i] = typeArgs[i].Common().Name
+		if typeArgNames[i] == "" {
+			typeArgNames[i] = typeArgs[i].String()
+		}
+	}
+	dot := strings.LastIndex(name, ".")
+	inst := name[dot+1:] + "[" + strings.Join(typeArgNames, ",") + "]"
+	return genericInstantiation(scope.BinInfo, scope.Mem, fns, name[:dot]+"."+inst, name[:dot]+dictSymbolSep+inst, typeArgs)
+}
+
+// genericInstantiation returns a function variable for the instantiation
+// of a generic function, chosen among fns, that can be called with type
+// arguments typeArgs. The dictionary of the instantiation is the symbol
+// dictName, instName is used in error messages.
+// A nil type argument matches any shape.
+func genericInstantiation(bi *BinaryInfo, mem MemoryReadWriter, fns []*Function, instName, dictName string, typeArgs []godwarf.Type) (*Variable, error) {
+	dictAddr := bi.dictionaries[dictName]
+	if dictAddr == 0 {
+		return nil, fmt.Errorf("instantiation %s was not compiled into the executable", instName)
+	}
+
+	var cands, exact []*Function
+	for _, fn := range fns {
+		ok, isexact := shapesMatch(fn, typeArgs)
+		if !ok {
+			continue
+		}
+		cands = append(cands, fn)
+		if isexact {
+			exact = append(exact, fn)
+		}
+	}
+	if len(cands) > 1 && len(exact) == 1 {
+		cands = exact
+	}
+	switch len(cands) {
+	case 0:
+		return nil, fmt.Errorf("instantiation %s was not compiled into the executable", instName)
+	case 1:
+		// ok
+	default:
+		names := make([]string, len(cands))
+		for i := range cands {
+			names[i] = cands[i].Name
+		}
+		return nil, fmt.Errorf("instantiation %s is ambiguous, could be any of: %s", instName, strings.Join(names, ", "))
+	}
+
+	if cands[0].Entry == 0 {
+		return nil, fmt.Errorf("function %s is inlined", cands[0].Name)
+	}
+	r, err := functionToVariable(cands[0], bi, mem)
+	if err != nil {
+		return nil, err
+	}
+	r.dictAddr = dictAddr
+	return r, nil
+}
+
+// shapesMatch returns true if the shapes of the type parameters of fn are
+// compatible with typeArgs. If exact is true the match does not depend on
+// heuristics.
+func shapesMatch(fn *Function, typeArgs []godwarf.Type) (ok, exact bool) {
+	inst := fn.instRange()
+	if inst[0] == inst[1] {
+		return false, false
+	}
+	shapes := splitTypeArgs(fn.Name[inst[0]+1 : inst[1]])
+	if len(shapes) != len(typeArgs) {
+		return false, false
+	}
+	exact = true
+	for i := range shapes {
+		ok, isexact := shapeMatch(shapes[i], typeArgs[i])
+		if !ok {
+			return false, false
+		}
+		exact = exact && isexact
+	}
+	return true, exact
+}
+
+// shapeMatch returns true if typ has the specified shape.
+// The shape of a type is derived from its underlying type, with all
+// pointer types sharing the same shape (*uint8). Since the names used in
+// shapes can differ from the names used in debug_info, composite types
+// are matched by kind when their names do not match exactly.
+func shapeMatch(shape string, typ godwarf.Type) (ok, exact bool) {
+	if typ == nil {
+		return true, false
+	}
+	if rest, ok := strings.CutPrefix(shape, "go.shape."); ok {
+		shape = rest
+	} else {
+		shape = strings.TrimPrefix(shape, ".shape.")
+	}
+	t := godwarf.ResolveTypedef(typ)
+	if _, isptr := t.(*godwarf.PtrType); isptr {
+		return shape == "*uint8", true
+	}
+	switch k := t.Common().ReflectKind; k {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.String, reflect.UnsafePointer:
+		return shape == k.String(), true
+	case reflect.Invalid:
+		return true, false
+	}
+	if shape == t.String() {
+		return true, true
+	}
+	var prefix string
+	switch t.(type) {
+	case *godwarf.SliceType:
+		prefix = "[]"
+	case *godwarf.ArrayType:
+		prefix = "["
+	case *godwarf.MapType:
+		prefix = "map["
+	case *godwarf.ChanType:
+		prefix = "chan"
+	case *godwarf.FuncType:
+		prefix = "func("
+	case *godwarf.StructType:
+		prefix = "struct {"
+	case *godwarf.InterfaceType:
+		prefix = "interface {"
+	default:
+		return true, false
+	}
+	return strings.HasPrefix(shape, prefix), false
+}
+
+// splitTypeArgs splits a list of type arguments, separated by commas.
+func splitTypeArgs(s string) []string {
+	var r []string
+	depth, start := 0, 0
+	for i, ch := range s {
+		switch ch {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				r = append(r, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(r, strings.TrimSpace(s[start:]))
+}
+
 func fakeArrayType(n uint64, fieldType godwarf.Type) godwarf.Type {
-	stride := alignAddr(fieldType.Common().ByteSize, fieldType.Align())
-	return &godwarf.ArrayType{
-		CommonType: godwarf.CommonType{
-			ReflectKind: reflect.Array,
-			ByteSize:    int64(n) * stride,
-			Name:        fmt.Sprintf("[%d]%s", n, fieldType.String())},
-		Type:          fieldType,
-		StrideBitSize: stride * 8,
-		Count:         int64(n)}
+	return godwarf.FakeArrayType(n, fieldType)
 }
 
 var errMethodEvalUnsupported = errors.New("evaluating methods not supported on this version of Go")
@@ -3100,6 +3636,10 @@ func (fn *Function) fakeType(bi *BinaryInfo, removeReceiver bool) (*godwarf.Func
 	// functional bug, it only affects the string representation of the fake
 	// function type we create. It's not really easy to tell here if we use
 	// the receiver or not. Perhaps we should not perform this manipulation at all?
+	formalArgs = slices.DeleteFunc(formalArgs, func(formalArg funcCallArg) bool {
+		// the dictionary of generic functions is passed automatically
+		return formalArg.name == goDictionaryName
+	})
 	if removeReceiver && len(formalArgs) > 0 {
 		formalArgs = formalArgs[1:]
 	}
@@ -17,8 +17,8 @@ import (
 )
 
 var (
-	ErrFuncCallNotAllowed = errors.New("function calls not allowed without using 'call'")
-	errFuncCallNotAllowedLitAlloc = errors.New("literal can not be allocated because function calls are not allowed without using 'call'")
+	ErrFuncCallNotAllowed         = errors.New("function calls not allowed without using 'call'")
+	ErrFuncCallNotAllowedLitAlloc = errors.New("literal can not be allocated because function calls are not allowed without using 'call'")
 )
 
 const (
@@ -26,22 +26,34 @@ const (
 	BreakpointHitCountVarName          = "bphitcount"
 	BreakpointHitCountVarNameQualified = BreakpointHitCountVarNamePackage + "." + BreakpointHitCountVarName
 	DebugPinnerFunctionName            = "runtime.debugPinnerV1"
+
+	// convVarIdentPrefix is the prefix of the identifiers that replace
+	// convenience variables ($name) in parsed expressions.
+	convVarIdentPrefix = "__dlvconv_"
+
+	mapsDeleteFunctionName = "internal/runtime/maps.(*Map).Delete"
 )
 
 type compileCtx struct {
 	evalLookup
-	ops        []Op
-	allowCalls bool
-	curCall    int
-	flags      Flags
-	pinnerUsed bool
-	hasCalls   bool
+	ops         []Op
+	allowCalls  bool
+	curCall     int
+	flags       Flags
+	pinnerUsed  bool
+	hasCalls    bool
+	hasLitAlloc bool
 }
 
 type evalLookup interface {
 	FindTypeExpr(ast.Expr) (godwarf.Type, error)
 	HasBuiltin(string) bool
+	HasFunction(string) bool
+	HasGenericFunction(string) bool
 	PtrSize() int
+	// LookupConstant returns the value of the named constant of the target
+	// program name, which can be qualified by its package.
+	LookupConstant(name string) (constant.Value, bool)
 }
 
 // Flags describes flags used to control Compile and CompileAST
@@ -72,6 +84,7 @@ func CompileAST(lookup evalLookup, t ast.Expr, flags Flags) ([]Op, error) {
 // Compile compiles the expression expr into a list of instructions.
 // If canSet is true expressions like "x = y" are also accepted.
 func Compile(lookup evalLookup, expr string, flags Flags) ([]Op, error) {
+	expr = rewriteConvVars(expr)
 	t, err := parser.ParseExpr(expr)
 	if err != nil {
 		if flags&CanSet != 0 {
@@ -96,11 +109,11 @@ func isAssignment(err error) (int, bool) {
 // CompileSet compiles the expression setting lhexpr to rhexpr into a list of
 // instructions.
 func CompileSet(lookup evalLookup, lhexpr, rhexpr string, flags Flags) ([]Op, error) {
-	lhe, err := parser.ParseExpr(lhexpr)
+	lhe, err := ParseExpr(lhexpr)
 	if err != nil {
 		return nil, err
 	}
-	rhe, err := parser.ParseExpr(rhexpr)
+	rhe, err := ParseExpr(rhexpr)
 	if err != nil {
 		return nil, err
 	}
@@ -111,17 +124,31 @@ func CompileSet(lookup evalLookup, lhexpr, rhexpr string, flags Flags) ([]Op, er
 		return nil, err
 	}
 
-	err = ctx.maybeMaterialize(rhe)
-	if err != nil {
-		return nil, err
-	}
+	name, isconv := ConvVarName(removeParen(lhe))
 
-	err = ctx.compileAST(lhe, false)
-	if err != nil {
-		return nil, err
+	// String literals assigned to convenience variables are stored by the
+	// debugger, they do not need to be allocated in the target.
+	if !isconv || !isStringLiteralThatNeedsAlloc(rhe) {
+		err = ctx.maybeMaterialize(rhe)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	ctx.pushOp(&SetValue{lhe: lhe, Rhe: rhe})
+	if isconv {
+		ctx.pushOp(&SetConvVar{Name: name})
+	} else {
+		if idx, isindex := removeParen(lhe).(*ast.IndexExpr); isindex {
+			err = ctx.compileIndexAssign(idx)
+		} else {
+			err = ctx.compileAST(lhe, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		ctx.compileSetValue(lhe, rhe, nil)
+	}
 
 	ctx.compileDebugPinnerSetupTeardown()
 
@@ -132,6 +159,197 @@ func CompileSet(lookup evalLookup, lhexpr, rhexpr string, flags Flags) ([]Op, er
 	return ctx.ops, nil
 }
 
+// ParseExpr parses expr like go/parser.ParseExpr but also accepts
+// references to convenience variables ($name), which are replaced by
+// identifiers recognized by ConvVarName.
+func ParseExpr(expr string) (ast.Expr, error) {
+	return parser.ParseExpr(rewriteConvVars(expr))
+}
+
+// ConvVarName returns the name, without the leading '$', of the convenience
+// variable referenced by e, if e is a convenience variable.
+func ConvVarName(e ast.Expr) (string, bool) {
+	ident, ok := e.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	return strings.CutPrefix(ident.Name, convVarIdentPrefix)
+}
+
+// rewriteConvVars replaces every convenience variable in expr, a '$'
+// immediately followed by an identifier, with an identifier that go/parser
+// can parse. Occurrences of '$' inside string and character literals are
+// left untouched.
+func rewriteConvVars(expr string) string {
+	if !strings.Contains(expr, "$") {
+		return expr
+	}
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(expr))
+	var s scanner.Scanner
+	s.Init(file, []byte(expr), func(token.Position, string) {}, 0)
+	var buf strings.Builder
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.ILLEGAL || lit != "$" {
+			continue
+		}
+		off := file.Offset(pos)
+		npos, ntok, _ := s.Scan()
+		if ntok == token.IDENT && file.Offset(npos) == off+1 {
+			buf.WriteString(expr[last:off])
+			buf.WriteString(convVarIdentPrefix)
+			last = off + 1
+		}
+		if ntok == token.EOF {
+			break
+		}
+	}
+	buf.WriteString(expr[last:])
+	return buf.String()
+}
+
+// compileIndexAssign compiles node, the left hand side of an assignment.
+// If node.X is a map the element is inserted into the map, by calling
+// runtime.mapassign, otherwise this is the same as compiling node.
+func (ctx *compileCtx) compileIndexAssign(node *ast.IndexExpr) error {
+	err := ctx.compileAST(node.X, false)
+	if err != nil {
+		return err
+	}
+	err = ctx.compileAST(node.Index, false)
+	if err != nil {
+		return err
+	}
+	err = ctx.maybeMaterialize(node.Index)
+	if err != nil {
+		return err
+	}
+
+	jmp := &Jump{When: JumpIfMapAssignChecksFail}
+	ctx.pushOp(jmp)                    // stack: [ key, map ]
+	ctx.pushOp(&Roll{1})               // stack after: [ map, key ]
+	ctx.pushOp(&Dup{})                 // stack after: [ map, map, key ]
+	ctx.pushOp(&Roll{2})               // stack after: [ key, map, map ]
+	ctx.compileAllocMapKey(node, true) // stack after: [ ptrToKey, map, map ]
+	ctx.pushOp(&Roll{1})               // stack after: [ map, ptrToKey, map ]
+	ctx.compileSpecialCall("runtime.mapassign", []ast.Expr{
+		&ast.Ident{Name: "map-type"},
+		node.X,
+		node.Index,
+	}, []Op{
+		&PushRuntimeTypeOf{},
+		nil,
+		nil,
+	}, specialCallConvertPointerArgs) // stack after: [ ptrToElem, map ]
+	ctx.pushOp(&ConvertToMapElem{})
+	jmpEnd := &Jump{When: JumpAlways}
+	ctx.pushOp(jmpEnd)
+
+	jmp.Target = len(ctx.ops)
+	ctx.pushOp(&Index{node})
+	jmpEnd.Target = len(ctx.ops)
+	return nil
+}
+
+// compileAllocMapKey converts the variable at the top of the stack to the
+// key type of the map below it and replaces it with a pointer to a copy of
+// it allocated in the target.
+// If complainAboutLitAlloc is set and calls are not allowed the allocation
+// fails with ErrFuncCallNotAllowedLitAlloc.
+func (ctx *compileCtx) compileAllocMapKey(node *ast.IndexExpr, complainAboutLitAlloc bool) {
+	flags := specialCallFlags(0)
+	if complainAboutLitAlloc {
+		flags = specialCallComplainAboutLitAlloc
+	}
+	ctx.pushOp(&ConvertToMapKey{Node: node.Index})
+	ctx.compileSpecialCall("runtime.newobject", []ast.Expr{
+		&ast.Ident{Name: "map-key-type"},
+	}, []Op{
+		&PushRuntimeTypeOf{},
+	}, flags)
+	ctx.pushOp(&CopyToAlloc{})
+}
+
+// compileSetValue compiles the assignment of the value below the top of
+// the stack to the topmost stack variable. If the type of the topmost
+// stack variable, lhtyp, is an interface type or is not known at compile
+// time, values that can not be stored directly in an interface are first
+// copied into a new allocation.
+func (ctx *compileCtx) compileSetValue(lhe, rhe ast.Expr, lhtyp godwarf.Type) {
+	if _, isiface := godwarf.ResolveTypedef(lhtyp).(*godwarf.InterfaceType); lhtyp == nil || isiface {
+		jmp := &Jump{When: JumpIfBoxChecksFail}
+		ctx.pushOp(jmp)      // stack: [ lhv, rhv ]
+		ctx.pushOp(&Roll{1}) // stack after: [ rhv, lhv ]
+		ctx.compileSpecialCall("runtime.newobject", []ast.Expr{
+			&ast.Ident{Name: "boxed-type"},
+		}, []Op{
+			&PushRuntimeTypeOf{},
+		}, 0)
+		ctx.pushOp(&CopyToAlloc{Box: true}) // stack after: [ box, lhv ]
+		ctx.pushOp(&Roll{1})                // stack after: [ lhv, box ]
+		jmp.Target = len(ctx.ops)
+	}
+	ctx.pushOp(&SetValue{lhe: lhe, Rhe: rhe})
+}
+
+// compileDelete compiles a call to the delete builtin, which is
+// implemented by calling runtime.mapdelete.
+func (ctx *compileCtx) compileDelete(node *ast.CallExpr) error {
+	if len(node.Args) != 2 {
+		return fmt.Errorf("wrong number of arguments to delete: %d", len(node.Args))
+	}
+	if !ctx.allowCalls {
+		return ErrFuncCallNotAllowed
+	}
+	idx := &ast.IndexExpr{X: node.Args[0], Index: node.Args[1]}
+	err := ctx.compileAST(idx.X, false)
+	if err != nil {
+		return err
+	}
+	err = ctx.compileAST(idx.Index, false)
+	if err != nil {
+		return err
+	}
+	err = ctx.maybeMaterialize(idx.Index)
+	if err != nil {
+		return err
+	}
+
+	ctx.pushOp(&Roll{1})               // stack after: [ map, key ]
+	ctx.pushOp(&Dup{})                 // stack after: [ map, map, key ]
+	ctx.pushOp(&Roll{2})               // stack after: [ key, map, map ]
+	ctx.compileAllocMapKey(idx, false) // stack after: [ ptrToKey, map, map ]
+	ctx.pushOp(&Roll{1})               // stack after: [ map, ptrToKey, map ]
+	ctx.pushOp(&PushRuntimeTypeOf{})   // stack after: [ mapType, map, ptrToKey, map ]
+
+	typeIdent := &ast.Ident{Name: "map-type"}
+	switch {
+	case ctx.HasFunction("runtime.mapdelete"):
+		ctx.compileSpecialCall("runtime.mapdelete", []ast.Expr{
+			typeIdent, idx.X, idx.Index,
+		}, []Op{nil, nil, nil}, specialCallConvertPointerArgs)
+	case ctx.HasFunction(mapsDeleteFunctionName):
+		// Swiss maps, runtime.mapdelete is only linked in if the program
+		// deletes from a map with a key type that does not have a specialized
+		// delete function.
+		ctx.pushOp(&Roll{1}) // stack after: [ map, mapType, ptrToKey, map ]
+		ctx.compileSpecialCall(mapsDeleteFunctionName, []ast.Expr{
+			idx.X, typeIdent, idx.Index,
+		}, []Op{nil, nil, nil}, specialCallConvertPointerArgs)
+	default:
+		return errors.New("can not delete map elements, no map delete function found in the target program")
+	}
+	// stack: [ result, map ]
+	ctx.pushOp(&Roll{1})
+	ctx.pushOp(&Pop{})
+	return nil
+}
+
 func (ctx *compileCtx) compileAllocLiteralString() {
 	jmp := &Jump{When: JumpIfAllocStringChecksFail}
 	ctx.pushOp(jmp)
@@ -155,7 +373,10 @@ type specialCallFlags uint8
 const (
 	specialCallDoPinning specialCallFlags = 1 << iota
 	specialCallIsStringAlloc
+	specialCallIsLitAlloc
 	specialCallComplainAboutStringAlloc
+	specialCallComplainAboutLitAlloc
+	specialCallConvertPointerArgs
 )
 
 func (ctx *compileCtx) compileSpecialCall(fnname string, argAst []ast.Expr, args []Op, flags specialCallFlags) {
@@ -168,21 +389,27 @@ func (ctx *compileCtx) compileSpecialCall(fnname string, argAst []ast.Expr, args
 		FnName: fnname,
 		ArgAst: argAst,
 
-		ComplainAboutStringAlloc: flags&specialCallComplainAboutStringAlloc != 0})
+		ComplainAboutStringAlloc: flags&specialCallComplainAboutStringAlloc != 0,
+		ComplainAboutLitAlloc:    flags&specialCallComplainAboutLitAlloc != 0})
 	ctx.pushOp(&CallInjectionSetTarget{id: id})
 
 	for i := range args {
 		if args[i] != nil {
 			ctx.pushOp(args[i])
 		}
-		ctx.pushOp(&CallInjectionCopyArg{id: id, ArgNum: i})
+		ctx.pushOp(&CallInjectionCopyArg{id: id, ArgNum: i, ConvertPointer: flags&specialCallConvertPointerArgs != 0})
 	}
 
 	doPinning = doPinning && (ctx.flags&HasDebugPinner != 0)
 
 	if doPinning {
 		ctx.pinnerUsed = true
-		if flags&specialCallIsStringAlloc == 0 {
+		switch {
+		case flags&specialCallIsStringAlloc != 0:
+			// nothing to do
+		case flags&specialCallIsLitAlloc != 0:
+			ctx.hasLitAlloc = true
+		default:
 			ctx.hasCalls = true
 		}
 	}
@@ -204,7 +431,11 @@ func (ctx *compileCtx) compileDebugPinnerSetupTeardown() {
 	ctx.ops = []Op{}
 	flags := specialCallFlags(0)
 	if !ctx.hasCalls {
-		flags = specialCallComplainAboutStringAlloc
+		if ctx.hasLitAlloc {
+			flags = specialCallComplainAboutLitAlloc
+		} else {
+			flags = specialCallComplainAboutStringAlloc
+		}
 	}
 	ctx.compileSpecialCall(DebugPinnerFunctionName, []ast.Expr{}, []Op{}, flags)
 	ctx.pushOp(&SetDebugPinner{})
@@ -267,7 +498,9 @@ func (ctx *compileCtx) depthCheck(endDepth int) error {
 			return fmt.Errorf("internal debugger error: depth check error at instruction %d: expected at least %d have %d\n%s", i, npop, depth[i], Listing(depth, ctx.ops))
 		}
 		d := depth[i] - npop + npush
-		checkAndSet(i+1, d)
+		if jmp, isjmp := op.(*Jump); !isjmp || jmp.When != JumpAlways {
+			checkAndSet(i+1, d)
+		}
 		switch op := op.(type) {
 		case *Jump:
 			checkAndSet(op.Target, d)
@@ -305,6 +538,9 @@ func (ctx *compileCtx) compileAST(t ast.Expr, toplevel bool) error {
 		switch x := node.X.(type) {
 		case *ast.Ident:
 			switch {
+			case strings.HasPrefix(x.Name, convVarIdentPrefix):
+				return ctx.compileUnary(node.X, &Select{node.Sel.Name})
+
 			case x.Name == "runtime" && node.Sel.Name == "curg":
 				ctx.pushOp(&PushCurg{})
 
@@ -360,12 +596,18 @@ func (ctx *compileCtx) compileAST(t ast.Expr, toplevel bool) error {
 		return ctx.compileTypeAssert(node)
 
 	case *ast.IndexExpr:
+		if ctx.isGenericFunction(node.X) {
+			return ctx.compileGenericFunction(node.X, []ast.Expr{node.Index})
+		}
 		return ctx.compileBinary(node.X, node.Index, nil, &Index{node})
 
-	case *ast.SliceExpr:
-		if node.Slice3 {
-			return errors.New("3-index slice expressions not supported")
+	case *ast.IndexListExpr:
+		if ctx.isGenericFunction(node.X) {
+			return ctx.compileGenericFunction(node.X, node.Indices)
 		}
+		return fmt.Errorf("expression %T not implemented", t)
+
+	case *ast.SliceExpr:
 		return ctx.compileReslice(node)
 
 	case *ast.StarExpr:
@@ -373,10 +615,20 @@ func (ctx *compileCtx) compileAST(t ast.Expr, toplevel bool) error {
 		return ctx.compileUnary(node.X, &PointerDeref{node})
 
 	case *ast.UnaryExpr:
-		// The unary operators we support are +, - and & (note that unary * is parsed as ast.StarExpr)
+		// The unary operators we support are +, -, & and <- (note that unary * is parsed as ast.StarExpr)
 		switch node.Op {
 		case token.AND:
 			return ctx.compileUnary(node.X, &AddrOf{node})
+		case token.ARROW:
+			// Receiving from a channel is compiled as a call to chanpeek, it
+			// does not remove the element from the channel buffer.
+			err := ctx.compileAST(node.X, false)
+			if err != nil {
+				return err
+			}
+			ctx.pushOp(&PushConst{constant.MakeInt64(0)})
+			ctx.pushOp(&BuiltinCall{"chanpeek", []ast.Expr{node.X, &ast.BasicLit{Kind: token.INT, Value: "0"}}})
+			return nil
 		default:
 			return ctx.compileUnary(node.X, &Unary{node})
 		}
@@ -407,74 +659,336 @@ func (ctx *compileCtx) compileAST(t ast.Expr, toplevel bool) error {
 		ctx.pushOp(&PushConst{constant.MakeFromLiteral(node.Value, node.Kind, 0)})
 
 	case *ast.CompositeLit:
-		notimplerr := fmt.Errorf("expression %T not implemented", t)
 		if ctx.flags&HasDebugPinner == 0 {
-			return notimplerr
+			return fmt.Errorf("expression %T not implemented", t)
 		}
-		dtyp, err := ctx.FindTypeExpr(node.Type)
+		dtyp, err := ctx.findCompositeLitType(node)
 		if err != nil {
 			return err
 		}
-		typ := godwarf.ResolveTypedef(dtyp)
-		switch typ := typ.(type) {
-		case *godwarf.StructType:
-			if !ctx.allowCalls {
-				return errFuncCallNotAllowedLitAlloc
-			}
+		return ctx.compileCompositeLit(node, dtyp)
 
-			ctx.pushOp(&PushNewFakeVariable{Type: dtyp})
+	default:
+		return fmt.Errorf("expression %T not implemented", t)
+	}
+	return nil
+}
 
-			for i, elt := range node.Elts {
-				ctx.pushOp(&Dup{})
+// findCompositeLitType returns the type of the composite literal node.
+// Array types with an implicit length ([...]T) and slice types that do not
+// appear in the debug info of the target are synthesized.
+func (ctx *compileCtx) findCompositeLitType(node *ast.CompositeLit) (godwarf.Type, error) {
+	atyp, isarr := node.Type.(*ast.ArrayType)
+	if !isarr {
+		return ctx.FindTypeExpr(node.Type)
+	}
+	if _, isellipsis := atyp.Len.(*ast.Ellipsis); isellipsis {
+		elemType, err := ctx.FindTypeExpr(atyp.Elt)
+		if err != nil {
+			return nil, err
+		}
+		_, n, err := ctx.compositeLitIndexes(node)
+		if err != nil {
+			return nil, err
+		}
+		return godwarf.FakeArrayType(uint64(n), elemType), nil
+	}
+	dtyp, err := ctx.FindTypeExpr(node.Type)
+	if err != nil && atyp.Len == nil {
+		elemType, err2 := ctx.FindTypeExpr(atyp.Elt)
+		if err2 != nil {
+			return nil, err
+		}
+		return godwarf.FakeSliceType(elemType), nil
+	}
+	return dtyp, err
+}
 
-				var rhe ast.Expr
-				switch elt := elt.(type) {
-				case *ast.KeyValueExpr:
-					ctx.pushOp(&Select{Name: elt.Key.(*ast.Ident).Name})
-					rhe = elt.Value
-					err := ctx.compileAST(elt.Value, false)
-					if err != nil {
-						return err
-					}
-					err = ctx.maybeMaterialize(elt.Value)
-					if err != nil {
-						return err
-					}
-				default:
-					ctx.pushOp(&Select{Name: typ.Field[i].Name})
-					rhe = elt
-					err := ctx.compileAST(elt, false)
-					if err != nil {
-						return err
-					}
-					err = ctx.maybeMaterialize(elt)
-					if err != nil {
-						return err
+// compileCompositeLit compiles the composite literal node of type dtyp.
+// Struct and array literals are created in the debugger's memory, slice
+// and map literals are allocated in the target program.
+func (ctx *compileCtx) compileCompositeLit(node *ast.CompositeLit, dtyp godwarf.Type) error {
+	switch typ := godwarf.ResolveTypedef(dtyp).(type) {
+	case *godwarf.StructType:
+		if !ctx.allowCalls {
+			return ErrFuncCallNotAllowedLitAlloc
+		}
+
+		ctx.pushOp(&PushNewFakeVariable{Type: dtyp})
+
+		for i, elt := range node.Elts {
+			ctx.pushOp(&Dup{})
+
+			var rhe ast.Expr
+			var fieldType godwarf.Type
+			switch elt := elt.(type) {
+			case *ast.KeyValueExpr:
+				name := elt.Key.(*ast.Ident).Name
+				ctx.pushOp(&Select{Name: name})
+				for _, field := range typ.Field {
+					if field.Name == name {
+						fieldType = field.Type
+						break
 					}
 				}
-				ctx.pushOp(&Roll{1})
-				ctx.pushOp(&SetValue{Rhe: rhe})
+				rhe = elt.Value
+			default:
+				if i >= len(typ.Field) {
+					return fmt.Errorf("too many values in %s literal", dtyp.String())
+				}
+				ctx.pushOp(&Select{Name: typ.Field[i].Name})
+				fieldType = typ.Field[i].Type
+				rhe = elt
 			}
+			err := ctx.compileAST(rhe, false)
+			if err != nil {
+				return err
+			}
+			err = ctx.maybeMaterialize(rhe)
+			if err != nil {
+				return err
+			}
+			ctx.pushOp(&Roll{1})
+			ctx.compileSetValue(nil, rhe, fieldType)
+		}
 
-		case *godwarf.SliceType:
-			return notimplerr
+	case *godwarf.ArrayType:
+		idxs, n, err := ctx.compositeLitIndexes(node)
+		if err != nil {
+			return err
+		}
+		if n > typ.Count {
+			return fmt.Errorf("index %d out of bounds for %s literal", n-1, dtyp.String())
+		}
+		ctx.pushOp(&PushNewFakeVariable{Type: dtyp})
+		return ctx.compileIndexedElems(node, idxs, typ.Type)
+
+	case *godwarf.SliceType:
+		if !ctx.allowCalls {
+			return ErrFuncCallNotAllowedLitAlloc
+		}
+		idxs, n, err := ctx.compositeLitIndexes(node)
+		if err != nil {
+			return err
+		}
+		// Build the backing array in the debugger's memory and then copy it
+		// into a new allocation.
+		arrtyp := godwarf.FakeArrayType(uint64(n), typ.ElemType)
+		ctx.pushOp(&PushNewFakeVariable{Type: arrtyp})
+		err = ctx.compileIndexedElems(node, idxs, typ.ElemType)
+		if err != nil {
+			return err
+		}
+		ctx.compileAllocLiteral(arrtyp)
+		ctx.pushOp(&ConvertAllocToSlice{Type: dtyp})
 
-		case *godwarf.MapType:
-			return notimplerr
+	case *godwarf.MapType:
+		if !ctx.allowCalls {
+			return ErrFuncCallNotAllowedLitAlloc
+		}
+		typeIdent := &ast.Ident{Name: dtyp.String()}
+		ctx.compileSpecialCall("runtime.makemap", []ast.Expr{
+			typeIdent,
+			&ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(len(node.Elts))},
+			&ast.Ident{Name: "nil"},
+		}, []Op{
+			&PushRuntimeType{dtyp},
+			&PushConst{constant.MakeInt64(int64(len(node.Elts)))},
+			&PushNil{},
+		}, specialCallDoPinning|specialCallIsLitAlloc|specialCallConvertPointerArgs)
+		for _, elt := range node.Elts {
+			kv, iskv := elt.(*ast.KeyValueExpr)
+			if !iskv {
+				return fmt.Errorf("missing key in %s literal", dtyp.String())
+			}
+			ctx.pushOp(&Dup{}) // stack after: [ mapPtr, mapPtr ]
+			err := ctx.compileCompositeLitElem(kv.Key, typ.KeyType)
+			if err != nil {
+				return err
+			}
+			ctx.compileAllocLiteral(typ.KeyType) // stack after: [ ptrToKey, mapPtr, mapPtr ]
+			ctx.pushOp(&Roll{1})                 // stack after: [ mapPtr, ptrToKey, mapPtr ]
+			ctx.compileSpecialCall("runtime.mapassign", []ast.Expr{
+				typeIdent,
+				&ast.Ident{Name: "map-literal"},
+				kv.Key,
+			}, []Op{
+				&PushRuntimeType{dtyp},
+				nil,
+				nil,
+			}, specialCallIsLitAlloc|specialCallConvertPointerArgs) // stack after: [ ptrToElem, mapPtr ]
+			ctx.pushOp(&TypeCast{
+				DwarfType: godwarf.FakePointerType(typ.ElemType, int64(ctx.PtrSize())),
+				Node: &ast.CallExpr{
+					Fun:  &ast.Ident{Name: typ.ElemType.String()},
+					Args: []ast.Expr{&ast.Ident{Name: "map-element"}}}})
+			ctx.pushOp(&PointerDeref{&ast.StarExpr{X: &ast.Ident{Name: "map-element"}}}) // stack after: [ elem, mapPtr ]
+			err = ctx.compileCompositeLitElem(kv.Value, typ.ElemType)
+			if err != nil {
+				return err
+			}
+			ctx.pushOp(&Roll{1})
+			ctx.compileSetValue(nil, kv.Value, typ.ElemType) // stack after: [ mapPtr ]
+		}
+		ctx.pushOp(&ConvertAllocToMap{Type: dtyp})
 
-		case *godwarf.ArrayType:
-			return notimplerr
+	default:
+		return fmt.Errorf("expression %T not implemented", node)
+	}
+	return nil
+}
 
-		default:
-			return notimplerr
+// compositeLitIndexes returns the index of each element of the array or
+// slice literal node and the length of the literal.
+func (ctx *compileCtx) compositeLitIndexes(node *ast.CompositeLit) ([]int64, int64, error) {
+	idxs := make([]int64, len(node.Elts))
+	seen := make(map[int64]bool)
+	var idx, n int64
+	for i, elt := range node.Elts {
+		if kv, iskv := elt.(*ast.KeyValueExpr); iskv {
+			key := astutil.ExprToString(kv.Key)
+			v, err := ctx.evalConstant(kv.Key)
+			if err != nil {
+				return nil, 0, fmt.Errorf("index %s must be a constant expression: %v", key, err)
+			}
+			v = constant.ToInt(v)
+			if v.Kind() != constant.Int {
+				return nil, 0, fmt.Errorf("index %s must be an integer constant", key)
+			}
+			var ok bool
+			idx, ok = constant.Int64Val(v)
+			if !ok || idx < 0 {
+				return nil, 0, fmt.Errorf("index %s out of range", key)
+			}
 		}
+		if seen[idx] {
+			return nil, 0, fmt.Errorf("duplicate index %d in literal", idx)
+		}
+		seen[idx] = true
+		idxs[i] = idx
+		idx++
+		if idx > n {
+			n = idx
+		}
+	}
+	return idxs, n, nil
+}
 
+// evalConstant evaluates expr as a constant expression, made of literals,
+// named constants of the target program and operators applied to them.
+func (ctx *compileCtx) evalConstant(expr ast.Expr) (constant.Value, error) {
+	switch node := expr.(type) {
+	case *ast.BasicLit:
+		v := constant.MakeFromLiteral(node.Value, node.Kind, 0)
+		if v.Kind() == constant.Unknown {
+			return nil, fmt.Errorf("invalid literal %s", node.Value)
+		}
+		return v, nil
+	case *ast.ParenExpr:
+		return ctx.evalConstant(node.X)
+	case *ast.UnaryExpr:
+		x, err := ctx.evalConstant(node.X)
+		if err != nil {
+			return nil, err
+		}
+		return ConstantUnaryOp(node.Op, x)
+	case *ast.BinaryExpr:
+		x, err := ctx.evalConstant(node.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := ctx.evalConstant(node.Y)
+		if err != nil {
+			return nil, err
+		}
+		return ConstantBinaryOp(node.Op, x, y)
+	case *ast.Ident:
+		if v, ok := ctx.LookupConstant(node.Name); ok {
+			return v, nil
+		}
+	case *ast.SelectorExpr:
+		if pkg, ok := node.X.(*ast.Ident); ok {
+			if v, ok := ctx.LookupConstant(pkg.Name + "." + node.Sel.Name); ok {
+				return v, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s is not constant", astutil.ExprToString(expr))
+}
+
+// ConstantUnaryOp applies op to the constant y, errors are returned instead
+// of panicking.
+func ConstantUnaryOp(op token.Token, y constant.Value) (r constant.Value, err error) {
+	defer func() {
+		if ierr := recover(); ierr != nil {
+			err = fmt.Errorf("%v", ierr)
+		}
+	}()
+	r = constant.UnaryOp(op, y, 0)
+	return
+}
+
+// ConstantBinaryOp applies op to the constants x and y, errors are
+// returned instead of panicking.
+func ConstantBinaryOp(op token.Token, x, y constant.Value) (r constant.Value, err error) {
+	defer func() {
+		if ierr := recover(); ierr != nil {
+			err = fmt.Errorf("%v", ierr)
+		}
+	}()
+	switch op {
+	case token.SHL, token.SHR:
+		n, _ := constant.Uint64Val(y)
+		r = constant.Shift(x, op, uint(n))
 	default:
-		return fmt.Errorf("expression %T not implemented", t)
+		r = constant.BinaryOp(x, op, y)
+	}
+	return
+}
+
+// compileIndexedElems compiles the elements of an array or slice literal
+// and assigns them to the array at the top of the stack.
+func (ctx *compileCtx) compileIndexedElems(node *ast.CompositeLit, idxs []int64, elemType godwarf.Type) error {
+	for i, elt := range node.Elts {
+		if kv, iskv := elt.(*ast.KeyValueExpr); iskv {
+			elt = kv.Value
+		}
+		ctx.pushOp(&Dup{})
+		ctx.pushOp(&PushConst{constant.MakeInt64(idxs[i])})
+		ctx.pushOp(&Index{Node: &ast.IndexExpr{X: node, Index: &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(idxs[i], 10)}}})
+		err := ctx.compileCompositeLitElem(elt, elemType)
+		if err != nil {
+			return err
+		}
+		ctx.pushOp(&Roll{1})
+		ctx.compileSetValue(nil, elt, elemType)
 	}
 	return nil
 }
 
+// compileCompositeLitElem compiles elt, an element of an array, slice or
+// map literal of type elemType. If elt is a composite literal with an
+// elided type elemType is used as its type.
+func (ctx *compileCtx) compileCompositeLitElem(elt ast.Expr, elemType godwarf.Type) error {
+	if lit, islit := elt.(*ast.CompositeLit); islit && lit.Type == nil {
+		if ptyp, isptr := godwarf.ResolveTypedef(elemType).(*godwarf.PtrType); isptr {
+			// {...} is a shorthand for &T{...} when the element type is *T
+			err := ctx.compileCompositeLit(lit, ptyp.Type)
+			if err != nil {
+				return err
+			}
+			ctx.compileAllocLiteral(ptyp.Type)
+			return nil
+		}
+		return ctx.compileCompositeLit(lit, elemType)
+	}
+	err := ctx.compileAST(elt, false)
+	if err != nil {
+		return err
+	}
+	return ctx.maybeMaterialize(elt)
+}
+
 func (ctx *compileCtx) compileTypeCastOrFuncCall(node *ast.CallExpr, toplevel bool) error {
 	if len(node.Args) != 1 {
 		// Things that have more or less than one argument are always function calls.
@@ -524,18 +1038,26 @@ func (ctx *compileCtx) compileTypeCastOrFuncCall(node *ast.CallExpr, toplevel bo
 		return ctx.compileFunctionCall(node, toplevel)
 	case *ast.IndexExpr:
 		// Ambiguous, could be a parametric type
+		if ctx.isGenericFunction(n.X) {
+			return ctx.compileFunctionCall(node, toplevel)
+		}
 		switch n.X.(type) {
 		case *ast.Ident, *ast.SelectorExpr:
 			// Do the type-cast first since evaluating node.Fun could be expensive.
+			oldOps := ctx.ops
 			err := ctx.compileTypeCast(node, nil)
 			if err == nil || err != reader.ErrTypeNotFound {
 				return err
 			}
+			ctx.ops = oldOps
 			return ctx.compileFunctionCall(node, toplevel)
 		default:
 			return ctx.compileFunctionCall(node, toplevel)
 		}
 	case *ast.IndexListExpr:
+		if ctx.isGenericFunction(n.X) {
+			return ctx.compileFunctionCall(node, toplevel)
+		}
 		return ctx.compileTypeCast(node, nil)
 	default:
 		// All other expressions must be function calls
@@ -543,6 +1065,37 @@ func (ctx *compileCtx) compileTypeCastOrFuncCall(node *ast.CallExpr, toplevel bo
 	}
 }
 
+// isGenericFunction returns true if fnnode is the name of a generic
+// function.
+func (ctx *compileCtx) isGenericFunction(fnnode ast.Expr) bool {
+	switch fnnode := fnnode.(type) {
+	case *ast.Ident:
+	case *ast.SelectorExpr:
+		if _, isident := fnnode.X.(*ast.Ident); !isident {
+			return false
+		}
+	default:
+		return false
+	}
+	return ctx.HasGenericFunction(astutil.ExprToString(fnnode))
+}
+
+// compileGenericFunction compiles the instantiation of generic function
+// fnnode with type arguments indices.
+func (ctx *compileCtx) compileGenericFunction(fnnode ast.Expr, indices []ast.Expr) error {
+	name := astutil.ExprToString(fnnode)
+	typeArgs := make([]godwarf.Type, len(indices))
+	for i := range indices {
+		typ, err := ctx.FindTypeExpr(indices[i])
+		if err != nil {
+			return fmt.Errorf("could not instantiate %s: %v", name, err)
+		}
+		typeArgs[i] = typ
+	}
+	ctx.pushOp(&PushGenericFunction{Name: name, TypeArgs: typeArgs})
+	return nil
+}
+
 func (ctx *compileCtx) compileTypeCast(node *ast.CallExpr, ambiguousErr error) error {
 	err := ctx.compileAST(node.Args[0], false)
 	if err != nil {
@@ -586,6 +1139,10 @@ func (ctx *compileCtx) compileBuiltinCall(builtin string, args []ast.Expr) error
 }
 
 func (ctx *compileCtx) compileIdent(node *ast.Ident) error {
+	if name, isconv := ConvVarName(node); isconv {
+		ctx.pushOp(&PushConvVar{Name: name})
+		return nil
+	}
 	ctx.pushOp(&PushIdent{node.Name})
 	return nil
 }
@@ -642,6 +1199,15 @@ func (ctx *compileCtx) compileReslice(node *ast.SliceExpr) error {
 	}
 
 	trustLen := true
+	hasMax := false
+	if node.Slice3 {
+		hasMax = true
+		err = ctx.compileAST(node.Max, false)
+		if err != nil {
+			return err
+		}
+	}
+
 	hasHigh := false
 	if node.High != nil {
 		hasHigh = true
@@ -666,12 +1232,15 @@ func (ctx *compileCtx) compileReslice(node *ast.SliceExpr) error {
 		ctx.pushOp(&PushConst{constant.MakeInt64(0)})
 	}
 
-	ctx.pushOp(&Reslice{Node: node, HasHigh: hasHigh, TrustLen: trustLen})
+	ctx.pushOp(&Reslice{Node: node, HasHigh: hasHigh, HasMax: hasMax, TrustLen: trustLen})
 	return nil
 }
 
 func (ctx *compileCtx) compileFunctionCall(node *ast.CallExpr, toplevel bool) error {
 	if fnnode, ok := node.Fun.(*ast.Ident); ok {
+		if fnnode.Name == "delete" {
+			return ctx.compileDelete(node)
+		}
 		if ctx.HasBuiltin(fnnode.Name) {
 			return ctx.compileBuiltinCall(fnnode.Name, node.Args)
 		}
@@ -822,49 +1391,71 @@ func (ctx *compileCtx) maybeMaterialize(expr ast.Expr) error {
 		return nil
 	}
 
-	dtyp, err := ctx.FindTypeExpr(lit.Type)
+	dtyp, err := ctx.findCompositeLitType(lit)
 	if err != nil {
 		return err
 	}
 
-	switch godwarf.ResolveTypedef(dtyp).(type) {
-	case *godwarf.StructType, *godwarf.ArrayType:
-		if _, isaddrof := ctx.ops[len(ctx.ops)-1].(*AddrOf); isaddrof {
-			ctx.ops = ctx.ops[:len(ctx.ops)-1]
-		} else {
-			ctx.pushOp(&PointerDeref{&ast.StarExpr{X: &ast.Ident{Name: "unallocated-literal"}}})
-		}
+	if _, isfunc := godwarf.ResolveTypedef(dtyp).(*godwarf.FuncType); isfunc {
+		return fmt.Errorf("allocating a literal of type %s not implemented", dtyp.String())
+	}
 
-		ctx.compileSpecialCall("runtime.mallocgc", []ast.Expr{
-			&ast.BasicLit{Kind: token.INT, Value: "1"},
-			lit.Type,
-			&ast.Ident{Name: "true"},
-		}, []Op{
-			&PushConst{Value: constant.MakeInt64(1)},
-			&PushRuntimeType{dtyp},
-			&PushConst{Value: constant.MakeBool(true)},
-		}, specialCallDoPinning)
-		ctx.pushOp(&TypeCast{
-			DwarfType: godwarf.FakePointerType(dtyp, int64(ctx.PtrSize())),
-			Node: &ast.CallExpr{
-				Fun:  lit.Type,
-				Args: []ast.Expr{&ast.Ident{Name: "new allocation"}}}})
-
-		xderef := &ast.StarExpr{X: &ast.Ident{Name: "new-allocation"}}
-		xset := &ast.Ident{Name: "literal-allocation"}
-
-		ctx.pushOp(&Dup{})                // stack after: [ ptrToRealLiteral, ptrToRealLiteral, fakeLiteral ]
-		ctx.pushOp(&PointerDeref{xderef}) // stack after: [ realLiteral, ptrToRealLiteral, fakeLiteral ]
-		ctx.pushOp(&Roll{2})              // stack after: [ fakeLiteral, realLiteral, ptrToRealLiteral ]
-		ctx.pushOp(&Roll{1})              // stack after: [ realLiteral, fakeLiteral, ptrToRealLiteral ]
-		ctx.pushOp(&SetValue{Rhe: xset})  // stack after: [ ptrToRealLiteral ]
-		return nil
+	if _, isaddrof := ctx.ops[len(ctx.ops)-1].(*AddrOf); isaddrof {
+		ctx.ops = ctx.ops[:len(ctx.ops)-1]
+	} else {
+		ctx.pushOp(&PointerDeref{&ast.StarExpr{X: &ast.Ident{Name: "unallocated-literal"}}})
+	}
 
-	default:
-		// either *godwarf.MapType, *godwarf.SliceType or *godwarf.FuncType
-		return fmt.Errorf("allocating a literal of type %s not implemented", dtyp.String())
+	ctx.compileAllocLiteral(dtyp)
+	return nil
+}
+
+// compileAllocLiteral allocates a variable of type dtyp in the target
+// program and copies into it the literal at the top of the stack, which is
+// replaced by a pointer to the new allocation.
+func (ctx *compileCtx) compileAllocLiteral(dtyp godwarf.Type) {
+	// Arrays are allocated using the type of their elements, like
+	// runtime.makeslice does, since synthesized array types do not have a
+	// runtime type.
+	rtyp := dtyp
+	for {
+		atyp, isarr := godwarf.ResolveTypedef(rtyp).(*godwarf.ArrayType)
+		if !isarr {
+			break
+		}
+		rtyp = atyp.Type
 	}
 
+	ctx.compileSpecialCall("runtime.mallocgc", []ast.Expr{
+		&ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(dtyp.Size(), 10)},
+		&ast.Ident{Name: rtyp.String()},
+		&ast.Ident{Name: "true"},
+	}, []Op{
+		&PushConst{Value: constant.MakeInt64(dtyp.Size())},
+		&PushRuntimeType{rtyp},
+		&PushConst{Value: constant.MakeBool(true)},
+	}, specialCallDoPinning|specialCallIsLitAlloc)
+	ctx.pushOp(&TypeCast{
+		DwarfType: godwarf.FakePointerType(dtyp, int64(ctx.PtrSize())),
+		Node: &ast.CallExpr{
+			Fun:  &ast.Ident{Name: dtyp.String()},
+			Args: []ast.Expr{&ast.Ident{Name: "new allocation"}}}})
+
+	if dtyp.Size() == 0 {
+		// nothing to copy
+		ctx.pushOp(&Roll{1})
+		ctx.pushOp(&Pop{})
+		return
+	}
+
+	xderef := &ast.StarExpr{X: &ast.Ident{Name: "new-allocation"}}
+	xset := &ast.Ident{Name: "literal-allocation"}
+
+	ctx.pushOp(&Dup{})                // stack after: [ ptrToRealLiteral, ptrToRealLiteral, fakeLiteral ]
+	ctx.pushOp(&PointerDeref{xderef}) // stack after: [ realLiteral, ptrToRealLiteral, fakeLiteral ]
+	ctx.pushOp(&Roll{2})              // stack after: [ fakeLiteral, realLiteral, ptrToRealLiteral ]
+	ctx.pushOp(&Roll{1})              // stack after: [ realLiteral, fakeLiteral, ptrToRealLiteral ]
+	ctx.pushOp(&SetValue{Rhe: xset})  // stack after: [ ptrToRealLiteral ]
 }
 
 func Listing(depth []int, ops []Op) string {
@@ -59,6 +59,14 @@ type PushIdent struct {
 
 func (*PushIdent) depthCheck() (npop, npush int) { return 0, 1 }
 
+// PushConvVar pushes the value of the convenience variable $Name on the
+// stack.
+type PushConvVar struct {
+	Name string
+}
+
+func (*PushConvVar) depthCheck() (npop, npush int) { return 0, 1 }
+
 // PushPackageVarOrSelect pushes the value of Name.Sel on the stack, which
 // could either be a global variable (with the package name specified), or a
 // field of a local variable.
@@ -69,6 +77,15 @@ type PushPackageVarOrSelect struct {
 
 func (*PushPackageVarOrSelect) depthCheck() (npop, npush int) { return 0, 1 }
 
+// PushGenericFunction pushes the instantiation of the generic function
+// Name with type arguments TypeArgs on the stack.
+type PushGenericFunction struct {
+	Name     string
+	TypeArgs []godwarf.Type
+}
+
+func (*PushGenericFunction) depthCheck() (npop, npush int) { return 0, 1 }
+
 // PushNil pushes an untyped nil on the stack.
 type PushNil struct {
 }
@@ -130,19 +147,25 @@ func (*TypeCast) depthCheck() (npop, npush int) { return 1, 1 }
 // If HasHigh is set it pops three variables, low, high and v, and pushes
 // v[low:high].
 // Otherwise it pops two variables, low and v, and pushes v[low:].
+// If HasMax is set an additional variable, max, is popped after high and
+// v[low:high:max] is pushed.
 // If TrustLen is set when the variable resulting from the reslice is loaded it will be fully loaded.
 type Reslice struct {
 	HasHigh  bool
+	HasMax   bool
 	TrustLen bool
 	Node     *ast.SliceExpr
 }
 
 func (op *Reslice) depthCheck() (npop, npush int) {
+	npop = 2
 	if op.HasHigh {
-		return 3, 1
-	} else {
-		return 2, 1
+		npop++
+	}
+	if op.HasMax {
+		npop++
 	}
+	return npop, 1
 }
 
 // Index pops two variables, idx and v, and pushes v[idx].
@@ -171,6 +194,8 @@ func (jmpif *Jump) depthCheck() (npop, npush int) {
 		} else {
 			return 1, 1
 		}
+	case JumpIfBoxChecksFail, JumpIfMapAssignChecksFail:
+		return 2, 2
 	}
 	return 0, 0
 }
@@ -184,6 +209,8 @@ const (
 	JumpIfAllocStringChecksFail
 	JumpAlways
 	JumpIfPinningDone
+	JumpIfBoxChecksFail       // looks at the two topmost stack variables, jumps unless the second one must be allocated to be assigned to the first one, an interface
+	JumpIfMapAssignChecksFail // looks at the two topmost stack variables, jumps unless the second one is a map and calls are allowed
 )
 
 // Binary pops two variables from the stack, applies the specified binary
@@ -255,6 +282,8 @@ type CallInjectionCopyArg struct {
 	id      int
 	ArgNum  int
 	ArgExpr ast.Expr
+
+	ConvertPointer bool // if the argument is a pointer convert it to the type of the formal argument
 }
 
 func (*CallInjectionCopyArg) depthCheck() (npop, npush int) { return 1, 0 }
@@ -293,6 +322,7 @@ type CallInjectionStartSpecial struct {
 	ArgAst []ast.Expr
 
 	ComplainAboutStringAlloc bool // if this call injection can not be made complain specifically about string allocation
+	ComplainAboutLitAlloc    bool // if this call injection can not be made complain specifically about literal allocation
 }
 
 func (*CallInjectionStartSpecial) depthCheck() (npop, npush int) { return 0, 1 }
@@ -306,6 +336,56 @@ type ConvertAllocToString struct {
 
 func (*ConvertAllocToString) depthCheck() (npop, npush int) { return 2, 1 }
 
+// ConvertAllocToSlice pops a pointer to an array from the stack and pushes
+// a slice of type Type that uses the array as its backing storage.
+type ConvertAllocToSlice struct {
+	Type godwarf.Type
+}
+
+func (*ConvertAllocToSlice) depthCheck() (npop, npush int) { return 1, 1 }
+
+// ConvertAllocToMap pops the return value of runtime.makemap from the
+// stack and pushes a map of type Type using it.
+type ConvertAllocToMap struct {
+	Type godwarf.Type
+}
+
+func (*ConvertAllocToMap) depthCheck() (npop, npush int) { return 1, 1 }
+
+// PushRuntimeTypeOf pushes the *runtime._type of the type of the topmost
+// stack variable, untyped constants have their default type.
+type PushRuntimeTypeOf struct {
+}
+
+func (*PushRuntimeTypeOf) depthCheck() (npop, npush int) { return 1, 2 }
+
+// CopyToAlloc pops two variables from the stack, the return value of
+// runtime.newobject and a value v, copies v into the new object and pushes
+// a pointer to it.
+// If Box is set it pushes an empty interface containing the copy of v
+// instead.
+type CopyToAlloc struct {
+	Box bool
+}
+
+func (*CopyToAlloc) depthCheck() (npop, npush int) { return 2, 1 }
+
+// ConvertToMapKey pops a variable from the stack and pushes it back
+// converted to the key type of the map below it.
+type ConvertToMapKey struct {
+	Node ast.Expr
+}
+
+func (*ConvertToMapKey) depthCheck() (npop, npush int) { return 2, 2 }
+
+// ConvertToMapElem pops two variables from the stack, the return value of
+// runtime.mapassign and a map, and pushes the map element returned by
+// runtime.mapassign.
+type ConvertToMapElem struct {
+}
+
+func (*ConvertToMapElem) depthCheck() (npop, npush int) { return 2, 1 }
+
 // SetValue pops to variables from the stack, lhv and rhv, and sets lhv to
 // rhv.
 type SetValue struct {
@@ -314,6 +394,14 @@ type SetValue struct {
 
 func (*SetValue) depthCheck() (npop, npush int) { return 2, 0 }
 
+// SetConvVar pops one variable from the stack and stores a snapshot of its
+// value in the convenience variable $Name.
+type SetConvVar struct {
+	Name string
+}
+
+func (*SetConvVar) depthCheck() (npop, npush int) { return 1, 0 }
+
 // SetDebugPinner pops one variable from the stack and uses it as the saved debug pinner.
 type SetDebugPinner struct {
 }
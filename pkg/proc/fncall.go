@@ -65,7 +65,15 @@ var (
 	errTooManyArguments           = errors.New("too many arguments")
 	errNotEnoughArguments         = errors.New("not enough arguments")
 	errNotAGoFunction             = errors.New("not a Go function")
-	errFuncCallNotAllowedStrAlloc = errors.New("literal string can not be allocated because function calls are not allowed without using 'call'")
+)
+
+var (
+	// ErrFuncCallNotAllowedStrAlloc is returned when a string literal needs
+	// to be allocated in the target but function calls are not allowed.
+	ErrFuncCallNotAllowedStrAlloc = errors.New("literal string can not be allocated because function calls are not allowed without using 'call'")
+	// ErrFuncCallNotAllowedLitAlloc is returned when a composite literal
+	// needs to be allocated in the target but function calls are not allowed.
+	ErrFuncCallNotAllowedLitAlloc = evalop.ErrFuncCallNotAllowedLitAlloc
 )
 
 type functionCallState struct {
@@ -81,6 +89,11 @@ type functionCallState struct {
 	receiver *Variable
 	// closureAddr is the address of the closure being called
 	closureAddr uint64
+	// dictAddr is the address of the dictionary passed to fn, if fn is an
+	// instantiation of a generic function
+	dictAddr uint64
+	// dictArg is the formal argument of fn receiving the dictionary
+	dictArg *funcCallArg
 	// formalArgs are the formal arguments of fn
 	formalArgs []funcCallArg
 	// argFrameSize contains the size of the arguments
@@ -456,6 +469,12 @@ func fncallLog(fmtstr string, args ...interface{}) {
 
 // writePointer writes val as an architecture pointer at addr in mem.
 func writePointer(bi *BinaryInfo, mem MemoryReadWriter, addr, val uint64) error {
+	_, err := mem.WriteMemory(addr, pointerBytes(bi, val))
+	return err
+}
+
+// pointerBytes returns the encoding of val as an architecture pointer.
+func pointerBytes(bi *BinaryInfo, val uint64) []byte {
 	ptrbuf := make([]byte, bi.Arch.PtrSize())
 
 	// TODO: use target architecture endianness instead of LittleEndian
@@ -467,8 +486,7 @@ func writePointer(bi *BinaryInfo, mem MemoryReadWriter, addr, val uint64) error
 	default:
 		panic(fmt.Errorf("unsupported pointer size %d", len(ptrbuf)))
 	}
-	_, err := mem.WriteMemory(addr, ptrbuf)
-	return err
+	return ptrbuf
 }
 
 // callOP simulates a call instruction on the given thread:
@@ -508,7 +526,7 @@ func funcCallEvalFuncExpr(scope *EvalScope, stack *evalStack, fncall *functionCa
 	if fnvar.Kind != reflect.Func {
 		return fmt.Errorf("expression %q is not a function", astutil.ExprToString(fncall.expr.Fun))
 	}
-	fnvar.loadValue(LoadConfig{false, 0, 0, 0, 0, 0})
+	fnvar.loadValue(LoadConfig{false, 0, 0, 0, 0, 0, nil})
 	if fnvar.Unreadable != nil {
 		return fnvar.Unreadable
 	}
@@ -523,6 +541,7 @@ func funcCallEvalFuncExpr(scope *EvalScope, stack *evalStack, fncall *functionCa
 		return errNotAGoFunction
 	}
 	fncall.closureAddr = fnvar.closureAddr
+	fncall.dictAddr = fnvar.dictAddr
 
 	var err error
 	fncall.argFrameSize, fncall.formalArgs, err = funcCallArgs(fncall.fn, bi, false)
@@ -530,6 +549,18 @@ func funcCallEvalFuncExpr(scope *EvalScope, stack *evalStack, fncall *functionCa
 		return err
 	}
 
+	// The dictionary of instantiated generic functions is passed
+	// automatically, it isn't one of the arguments of the call expression.
+	fncall.dictArg = nil
+	for i := range fncall.formalArgs {
+		if fncall.dictAddr != 0 && fncall.formalArgs[i].name == goDictionaryName {
+			dictArg := fncall.formalArgs[i]
+			fncall.dictArg = &dictArg
+			fncall.formalArgs = append(fncall.formalArgs[:i], fncall.formalArgs[i+1:]...)
+			break
+		}
+	}
+
 	argnum := len(fncall.expr.Args)
 
 	// If the function variable has a child then that child is the method
@@ -578,21 +609,10 @@ func funcCallCopyOneArg(scope *EvalScope, fncall *functionCallState, actualArg *
 	//TODO(aarzilli): automatic wrapping in interfaces for cases not handled
 	// by convertToEface.
 
-	formalScope, err := GoroutineScope(scope.target, thread)
+	formalArgVar, err := funcCallFormalArgVar(scope, fncall, formalArg, thread)
 	if err != nil {
 		return err
 	}
-
-	var formalArgVar *Variable
-	if formalArg.dwarfEntry != nil {
-		var err error
-		formalArgVar, err = extractVarInfoFromEntry(scope.target, formalScope.BinInfo, formalScope.image(), formalScope.Regs, formalScope.Mem, formalArg.dwarfEntry, 0)
-		if err != nil {
-			return err
-		}
-	} else {
-		formalArgVar = newVariable(formalArg.name, uint64(formalArg.off+formalScope.Regs.CFA), formalArg.typ, scope.BinInfo, scope.Mem)
-	}
 	if err := scope.setValue(formalArgVar, actualArg, actualArg.Name); err != nil {
 		return err
 	}
@@ -600,6 +620,20 @@ func funcCallCopyOneArg(scope *EvalScope, fncall *functionCallState, actualArg *
 	return nil
 }
 
+// funcCallFormalArgVar returns a variable for formalArg, the formal
+// argument of the function being called.
+func funcCallFormalArgVar(scope *EvalScope, fncall *functionCallState, formalArg *funcCallArg, thread Thread) (*Variable, error) {
+	formalScope, err := GoroutineScope(scope.target, thread)
+	if err != nil {
+		return nil, err
+	}
+
+	if formalArg.dwarfEntry != nil {
+		return extractVarInfoFromEntry(scope.target, formalScope.BinInfo, formalScope.image(), formalScope.Regs, formalScope.Mem, formalArg.dwarfEntry, fncall.dictAddr)
+	}
+	return newVariable(formalArg.name, uint64(formalArg.off+formalScope.Regs.CFA), formalArg.typ, scope.BinInfo, scope.Mem), nil
+}
+
 func funcCallArgs(fn *Function, bi *BinaryInfo, includeRet bool) (argFrameSize int64, formalArgs []funcCallArg, err error) {
 	dwarfTree, err := fn.cu.image.getDwarfTree(fn.offset)
 	if err != nil {
@@ -892,6 +926,7 @@ func funcCallStep(callScope *EvalScope, stack *evalStack, thread Thread) bool {
 
 		// pretend we are still inside the function we called
 		fakeFunctionEntryScope(retScope, fncall.fn, int64(regs.SP()), regs.SP()-uint64(bi.Arch.PtrSize()))
+		retScope.dictAddr = fncall.dictAddr
 		var flags localsFlags
 		flags |= localsNoDeclLineCheck // if the function we are calling is an autogenerated stub then declaration lines have no meaning
 		if !bi.regabi {
@@ -996,9 +1031,12 @@ func (scope *EvalScope) evalCallInjectionSetTarget(op *evalop.CallInjectionSetTa
 	}
 
 	if fncall.closureAddr != 0 {
-		// When calling a function pointer we must set the DX register to the
-		// address of the function pointer itself.
-		setClosureReg(thread, fncall.closureAddr)
+		// When calling a function pointer we must set the closure context
+		// register (DX on amd64) to the address of the function pointer itself.
+		if err := setClosureReg(thread, fncall.closureAddr); err != nil {
+			stack.err = fmt.Errorf("could not set closure context register: %v", err)
+			return
+		}
 	}
 
 	undo := new(undoInjection)
@@ -1010,6 +1048,17 @@ func (scope *EvalScope) evalCallInjectionSetTarget(op *evalop.CallInjectionSetTa
 
 	fncall.undoInjection = undo
 
+	if fncall.dictArg != nil {
+		dictVar, err := funcCallFormalArgVar(scope, fncall, fncall.dictArg, thread)
+		if err == nil {
+			err = dictVar.writeUint(fncall.dictAddr, int64(scope.BinInfo.Arch.PtrSize()))
+		}
+		if err != nil {
+			stack.err = fmt.Errorf("could not set dictionary argument: %v", err)
+			return
+		}
+	}
+
 	if fncall.receiver != nil {
 		err := funcCallCopyOneArg(scope, fncall, fncall.receiver, &fncall.formalArgs[0], thread)
 		if err != nil {
@@ -1063,14 +1112,20 @@ func fakeFunctionEntryScope(scope *EvalScope, fn *Function, cfa int64, sp uint64
 
 func (scope *EvalScope) callInjectionStartSpecial(stack *evalStack, op *evalop.CallInjectionStartSpecial, curthread Thread) bool {
 	if op.ComplainAboutStringAlloc && scope.callCtx == nil {
-		stack.err = errFuncCallNotAllowedStrAlloc
+		stack.err = ErrFuncCallNotAllowedStrAlloc
+		return false
+	}
+	if op.ComplainAboutLitAlloc && scope.callCtx == nil {
+		stack.err = ErrFuncCallNotAllowedLitAlloc
 		return false
 	}
 	fnv, err := scope.findGlobalInternal(op.FnName)
 	if fnv == nil {
 		if err == nil {
 			if op.ComplainAboutStringAlloc {
-				err = errFuncCallNotAllowedStrAlloc
+				err = ErrFuncCallNotAllowedStrAlloc
+			} else if op.ComplainAboutLitAlloc {
+				err = ErrFuncCallNotAllowedLitAlloc
 			} else {
 				err = fmt.Errorf("function %s not found", op.FnName)
 			}
@@ -1116,6 +1171,235 @@ func (scope *EvalScope) convertAllocToString(stack *evalStack) {
 	stack.push(v)
 }
 
+func (scope *EvalScope) convertAllocToSlice(op *evalop.ConvertAllocToSlice, stack *evalStack) {
+	ptrv := stack.pop()
+	ptrv.loadValue(loadSingleValue)
+	if ptrv.Unreadable != nil {
+		stack.err = ptrv.Unreadable
+		return
+	}
+	if ptrv.Kind != reflect.Ptr || len(ptrv.Children) != 1 || ptrv.Children[0].Kind != reflect.Array {
+		stack.err = errors.New("internal error, could not interpret backing array of slice literal")
+		return
+	}
+	arrv := &ptrv.Children[0]
+
+	v := newVariable("", 0, op.Type, scope.BinInfo, DereferenceMemory(scope.Mem))
+	v.Base = arrv.Addr
+	v.Len = arrv.Len
+	v.Cap = arrv.Len
+	v.fieldType = arrv.fieldType
+	v.stride = arrv.stride
+	stack.push(v)
+}
+
+func (scope *EvalScope) convertAllocToMap(op *evalop.ConvertAllocToMap, stack *evalStack) {
+	makemapv := stack.pop()
+	makemapv.loadValue(loadSingleValue)
+	if makemapv.Unreadable != nil {
+		stack.err = makemapv.Unreadable
+		return
+	}
+	if makemapv.Kind != reflect.Ptr || len(makemapv.Children) != 1 {
+		stack.err = errors.New("internal error, could not interpret return value of makemap call")
+		return
+	}
+
+	// Maps are pointers to the runtime representation of the map, store the
+	// pointer returned by makemap in a debugger allocated variable.
+	stack.pushNewFakeVariableWithContents(scope, op.Type, pointerBytes(scope.BinInfo, makemapv.Children[0].Addr))
+}
+
+// runtimeTypePointer returns a *runtime._type variable pointing to typeAddr.
+func (scope *EvalScope) runtimeTypePointer(typeAddr uint64) (*Variable, error) {
+	rttyp, err := scope.BinInfo.findType(scope.BinInfo.runtimeTypeTypename())
+	if err != nil {
+		return nil, err
+	}
+	return newVariable("", typeAddr, rttyp, scope.BinInfo, scope.Mem).pointerToVariable(), nil
+}
+
+// defaultType returns the type of v or, if v is an untyped constant, its
+// default type.
+func (scope *EvalScope) defaultType(v *Variable) (godwarf.Type, error) {
+	if v.DwarfType != nil {
+		return v.DwarfType, nil
+	}
+	if v.Value == nil {
+		return nil, fmt.Errorf("value %s has no type", v.Name)
+	}
+	var typename string
+	switch v.Value.Kind() {
+	case constant.Bool:
+		typename = "bool"
+	case constant.String:
+		typename = "string"
+	case constant.Int:
+		typename = "int"
+	case constant.Float:
+		typename = "float64"
+	case constant.Complex:
+		typename = "complex128"
+	default:
+		return nil, fmt.Errorf("value %s has no type", v.Value)
+	}
+	return scope.BinInfo.findType(typename)
+}
+
+// needsBox returns true if srcv is a value that can only be assigned to
+// dstv, an interface, after being copied into a new allocation, i.e. it
+// is an untyped constant or a value that is not pointer shaped.
+func (scope *EvalScope) needsBox(dstv, srcv *Variable) bool {
+	if dstv.Kind != reflect.Interface || srcv == nilVariable || srcv.Unreadable != nil {
+		return false
+	}
+	if srcv.DwarfType == nil {
+		return srcv.Value != nil
+	}
+	if srcv.Kind == reflect.Interface {
+		return false
+	}
+	_, typeKind, found, err := runtimeTypeOf(scope.BinInfo, scope.Mem, srcv.DwarfType)
+	return err == nil && found && typeKind&kindDirectIface == 0
+}
+
+func (scope *EvalScope) pushRuntimeTypeOf(stack *evalStack) {
+	typ, err := scope.defaultType(stack.peek())
+	if err != nil {
+		stack.err = err
+		return
+	}
+	typeAddr, _, found, err := runtimeTypeOf(scope.BinInfo, scope.Mem, typ)
+	if err != nil {
+		stack.err = err
+		return
+	}
+	if !found {
+		stack.err = fmt.Errorf("could not find runtime type for %s", typ.String())
+		return
+	}
+	stack.pushErr(scope.runtimeTypePointer(typeAddr))
+}
+
+func (scope *EvalScope) copyToAlloc(op *evalop.CopyToAlloc, stack *evalStack) {
+	newobjv := stack.pop()
+	v := stack.pop()
+
+	newobjv.loadValue(loadSingleValue)
+	if newobjv.Unreadable != nil {
+		stack.err = newobjv.Unreadable
+		return
+	}
+	if (newobjv.Kind != reflect.Ptr && newobjv.Kind != reflect.UnsafePointer) || len(newobjv.Children) != 1 {
+		stack.err = errors.New("internal error, could not interpret return value of newobject call")
+		return
+	}
+
+	typ, err := scope.defaultType(v)
+	if err != nil {
+		stack.err = err
+		return
+	}
+	objv := newVariable("", newobjv.Children[0].Addr, typ, scope.BinInfo, DereferenceMemory(scope.Mem))
+	if err := scope.setValue(objv, v, v.Name); err != nil {
+		stack.err = err
+		return
+	}
+
+	if !op.Box {
+		stack.push(objv.pointerToVariable())
+		return
+	}
+
+	// Push an empty interface containing the new object, it will be
+	// converted to the destination interface type by setValue.
+	efacetyp, err := scope.BinInfo.findType("interface {}")
+	if err != nil {
+		stack.err = err
+		return
+	}
+	typeAddr, _, _, err := runtimeTypeOf(scope.BinInfo, scope.Mem, typ)
+	if err != nil {
+		stack.err = err
+		return
+	}
+	stack.pushNewFakeVariable(scope, efacetyp)
+	if stack.err != nil {
+		return
+	}
+	stack.err = stack.peek().writeInterface(typeAddr, objv.Addr)
+}
+
+func (scope *EvalScope) convertToMapKey(op *evalop.ConvertToMapKey, stack *evalStack) {
+	keyv := stack.pop()
+	mapv := stack.peek()
+	maptyp, ismap := mapv.RealType.(*godwarf.MapType)
+	if !ismap {
+		stack.err = fmt.Errorf("internal debugger error: expected map, got %s", mapv.TypeString())
+		return
+	}
+	if keyv.DwarfType != nil && sameType(keyv.DwarfType, maptyp.KeyType) {
+		stack.push(keyv)
+		return
+	}
+	stack.pushNewFakeVariable(scope, maptyp.KeyType)
+	if stack.err != nil {
+		return
+	}
+	fakev := stack.pop()
+	stack.err = scope.setValue(fakev, keyv, astutil.ExprToString(op.Node))
+	if stack.err != nil {
+		return
+	}
+	// The header of strings and slices is read when the variable is created,
+	// create it again to see the value we just wrote.
+	v := newVariable("", fakev.Addr, fakev.DwarfType, scope.BinInfo, fakev.mem)
+	v.Flags = VariableFakeAddress
+	stack.push(v)
+}
+
+func (scope *EvalScope) convertToMapElem(stack *evalStack) {
+	mapassignv := stack.pop()
+	mapv := stack.pop()
+	mapassignv.loadValue(loadSingleValue)
+	if mapassignv.Unreadable != nil {
+		stack.err = mapassignv.Unreadable
+		return
+	}
+	maptyp, ismap := mapv.RealType.(*godwarf.MapType)
+	if (mapassignv.Kind != reflect.Ptr && mapassignv.Kind != reflect.UnsafePointer) || len(mapassignv.Children) != 1 || !ismap {
+		stack.err = errors.New("internal error, could not interpret return value of mapassign call")
+		return
+	}
+	stack.push(newVariable("", mapassignv.Children[0].Addr, maptyp.ElemType, scope.BinInfo, DereferenceMemory(scope.Mem)))
+}
+
+// convertPointerArg converts actualArg, a pointer or a map, to the type of
+// formalArg, if it is a pointer type.
+func convertPointerArg(actualArg *Variable, formalArg *funcCallArg) (*Variable, error) {
+	ptyp, isptr := godwarf.ResolveTypedef(formalArg.typ).(*godwarf.PtrType)
+	if !isptr {
+		return actualArg, nil
+	}
+	switch actualArg.Kind {
+	case reflect.Ptr:
+		// use actualArg as is
+	case reflect.Map:
+		// Maps are pointers to the runtime representation of the map.
+		addr, err := readUintRaw(actualArg.mem, actualArg.Addr, ptyp.ByteSize)
+		if err != nil {
+			return nil, err
+		}
+		actualArg = newVariable("", addr, ptyp.Type, actualArg.bi, DereferenceMemory(actualArg.mem)).pointerToVariable()
+	default:
+		return actualArg, nil
+	}
+	convertedArg := *actualArg
+	convertedArg.DwarfType = formalArg.typ
+	convertedArg.RealType = ptyp
+	return &convertedArg, nil
+}
+
 func isCallInjectionStop(t *Target, thread Thread, loc *Location) bool {
 	if loc.Fn == nil {
 		return false
@@ -1266,10 +1550,15 @@ func debugCallProtocolReg(archName string, version int) (uint64, bool) {
 // runtimeWhitelist is a list of functions in the runtime that we can call
 // (through call injection) even if they are optimized.
 var runtimeWhitelist = map[string]bool{
-	"runtime.mallocgc":             true,
-	evalop.DebugPinnerFunctionName: true,
-	"runtime.(*Pinner).Unpin":      true,
-	"runtime.(*Pinner).Pin":        true,
+	"runtime.mallocgc":                    true,
+	"runtime.newobject":                   true,
+	"runtime.makemap":                     true,
+	"runtime.mapassign":                   true,
+	"runtime.mapdelete":                   true,
+	"internal/runtime/maps.(*Map).Delete": true,
+	evalop.DebugPinnerFunctionName:        true,
+	"runtime.(*Pinner).Unpin":             true,
+	"runtime.(*Pinner).Pin":               true,
 }
 
 // runtimeOptimizedWorkaround modifies the input DIE so that arguments and
@@ -1296,16 +1585,36 @@ func runtimeOptimizedWorkaround(bi *BinaryInfo, image *Image, in *godwarf.Tree)
 			}
 			isret, _ := child.Entry.Val(dwarf.AttrVarParam).(bool)
 
-			var reg int
-			if isret {
-				reg = bi.Arch.argumentRegs[curRet]
-				curRet++
-			} else {
-				reg = bi.Arch.argumentRegs[curArg]
-				curArg++
+			// Strings, interfaces and slices are passed in more than one register.
+			nregs := 1
+			if off, ok := child.Entry.Val(dwarf.AttrType).(dwarf.Offset); ok {
+				if typ, err := image.Type(off); err == nil {
+					switch godwarf.ResolveTypedef(typ).(type) {
+					case *godwarf.StringType, *godwarf.InterfaceType:
+						nregs = 2
+					case *godwarf.SliceType:
+						nregs = 3
+					}
+				}
+			}
+
+			var loc []byte
+			for i := 0; i < nregs; i++ {
+				var reg int
+				if isret {
+					reg = bi.Arch.argumentRegs[curRet]
+					curRet++
+				} else {
+					reg = bi.Arch.argumentRegs[curArg]
+					curArg++
+				}
+				loc = append(loc, byte(op.DW_OP_reg0)+byte(reg))
+				if nregs > 1 {
+					loc = append(loc, byte(op.DW_OP_piece), byte(bi.Arch.PtrSize()))
+				}
 			}
 
-			newlocfield := dwarf.Field{Attr: dwarf.AttrLocation, Val: []byte{byte(op.DW_OP_reg0) + byte(reg)}, Class: dwarf.ClassBlock}
+			newlocfield := dwarf.Field{Attr: dwarf.AttrLocation, Val: loc, Class: dwarf.ClassBlock}
 
 			locfield := childEntry.AttrField(dwarf.AttrLocation)
 			if locfield != nil {
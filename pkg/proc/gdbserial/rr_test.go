@@ -294,3 +294,26 @@ func TestIssue1376(t *testing.T) {
 		assertNoError(grp.Continue(), t, "Continue (backward)")
 	})
 }
+
+func TestRestartFrame(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestRecording("restartframe", t, func(grp *proc.TargetGroup, fixture protest.Fixture) {
+		p := grp.Selected
+		setFileBreakpoint(p, t, fixture, 8)
+		assertNoError(grp.Continue(), t, "Continue")
+
+		// Rewinding undoes the assignment to a.
+		assertNoError(grp.RestartFrame(0, false), t, "RestartFrame")
+		loc, err := proc.ThreadLocation(p.CurrentThread())
+		assertNoError(err, t, "ThreadLocation")
+		if loc.Fn == nil || loc.Fn.Name != "main.add" || loc.PC != loc.Fn.Entry {
+			t.Fatalf("stopped at %#x %v, expected entry point of main.add", loc.PC, loc.Fn)
+		}
+		assertNoError(grp.Continue(), t, "Continue")
+		loc, err = proc.ThreadLocation(p.CurrentThread())
+		assertNoError(err, t, "ThreadLocation")
+		if loc.Line != 8 {
+			t.Fatalf("stopped at %d (expected 8)", loc.Line)
+		}
+	})
+}
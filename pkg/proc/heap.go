@@ -0,0 +1,898 @@
+package proc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/goversion"
+)
+
+// +rtype -var mheap_ mheap
+// +rtype -field mheap.allspans []*mspan
+// +rtype -field mspan.startAddr uintptr
+// +rtype -field mspan.npages uintptr
+// +rtype -field mspan.nelems uint16|uintptr
+// +rtype -field mspan.freeindex uint16|uintptr
+// +rtype -field mspan.allocBits *gcBits
+// +rtype -field mspan.elemsize uintptr
+// +rtype -field mspan.spanclass spanClass
+// +rtype -field mspan.state mSpanStateBox
+// +rtype -field mspan.specials *special
+// +rtype -field special.next *special
+// +rtype -field special.offset uintptr
+// +rtype -field special.kind uint8
+// +rtype -field specialfinalizer.fn *funcval
+// +rtype -field specialfinalizer.ot *ptrtype
+
+const (
+	spanInUse    = 1       // +rtype mSpanInUse
+	heapPageSize = 1 << 13 // +rtype pageSize
+
+	// Go 1.24 and earlier
+	kindSpecialFinalizerGo124 = 1
+	// Go 1.25 and later
+	kindSpecialFinalizer = 2 // +rtype go1.25 _KindSpecialFinalizer
+
+	// maxSpecialsPerSpan is the maximum number of specials read from the
+	// list of each span.
+	maxSpecialsPerSpan = 1 << 16
+
+	// maxMapStructElems is the maximum number of tables, groups or buckets
+	// pointed to by the internal structs of a map.
+	maxMapStructElems = 1 << 32
+
+	// heapScanStackDepth is the maximum number of frames of each goroutine
+	// that are scanned for pointers into the heap.
+	heapScanStackDepth = 1024
+)
+
+// HeapObject is an object allocated in the heap of the target.
+type HeapObject struct {
+	// Addr is the address of the object, for objects that start with a
+	// malloc header this is the address of the first byte after the header.
+	Addr uint64
+	// Size is the size of the memory block allocated for the object.
+	Size int64
+	// Type is the type of the object, nil if it could not be determined.
+	// Objects containing N values of type T, for example the backing arrays
+	// of slices, have type [N]T.
+	Type godwarf.Type
+}
+
+// HeapObjects returns the objects allocated in the heap of the target,
+// sorted by address.
+//
+// The list of objects is read from the spans of the heap and from their
+// allocation bitmaps. The type of objects that start with a malloc header,
+// or are the only object in their span, is read from the runtime type
+// information, the type of the other objects is determined by following
+// typed pointers starting from global variables, from the local variables
+// of all goroutines and from the objects whose type is already known.
+// Objects that can not be reached this way have a nil Type.
+//
+// If the scan is interrupted using SetCancel ErrCanceled is returned.
+func HeapObjects(t *Target) ([]HeapObject, error) {
+	h, err := newHeapScanner(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.scanRoots(); err != nil {
+		return nil, err
+	}
+	if err := h.propagateTypes(); err != nil {
+		return nil, err
+	}
+	r := make([]HeapObject, len(h.objs))
+	for i, obj := range h.objs {
+		r[i] = HeapObject{Addr: obj.base + obj.hdr, Size: int64(obj.size), Type: obj.typ}
+	}
+	return r, nil
+}
+
+// heapScanner reads the list of objects allocated in the heap of the
+// target and tries to determine their types.
+type heapScanner struct {
+	t       *Target
+	bi      *BinaryInfo
+	mem     MemoryReadWriter
+	mds     []ModuleData
+	ptrSize int64
+
+	objs       []heapObject // sorted by address
+	queue      []int        // indexes of objects whose type changed and need to be scanned
+	roots      []heapRoot
+	finalizers []heapFinalizer
+	funcTabs   []funcTab
+	spans      [][2]uint64 // address ranges of all spans, sorted by address
+	metadata   [][2]uint64 // address ranges of the span structs and their allocation bitmaps
+
+	rtyp         godwarf.Type // type of runtime type descriptors
+	byteType     godwarf.Type
+	runtimeTypes map[uint64]heapRuntimeType
+	hasPtrs      map[godwarf.Type]bool
+	mapStructs   map[*godwarf.StructType]*mapStruct
+}
+
+// heapObject is a memory block allocated in the heap.
+type heapObject struct {
+	base uint64 // address of the memory block
+	size uint64 // size of the memory block
+	hdr  uint64 // size of the malloc header at the start of the memory block
+	typ  godwarf.Type
+
+	noscan bool // the object does not contain pointers
+}
+
+// heapRoot is a value, stored outside of the heap, that can contain
+// pointers to heap objects.
+type heapRoot struct {
+	name string
+	addr uint64       // address of the value, zero if the value is not stored in memory
+	typ  godwarf.Type // type of the value, if nil the value is scanned conservatively
+	buf  []byte
+
+	// finalizer is true if the root is the pointer to an object with a
+	// finalizer, the pointer does not keep the object alive.
+	finalizer bool
+}
+
+// heapFinalizer is a finalizer set on a heap object.
+type heapFinalizer struct {
+	obj uint64 // address of the object
+	fn  uint64 // address of the funcval of the finalizer
+	ot  uint64 // runtime type of the pointer to the object
+}
+
+// heapRuntimeType is the result of the conversion of a runtime type to a
+// DWARF type.
+type heapRuntimeType struct {
+	typ    godwarf.Type
+	direct bool // values of the type are stored directly in interfaces
+}
+
+func newHeapScanner(t *Target) (*heapScanner, error) {
+	bi := t.BinInfo()
+	mem := t.Memory()
+	mds, err := bi.getModuleData(mem)
+	if err != nil {
+		return nil, err
+	}
+	rtyp, err := bi.findType(bi.runtimeTypeTypename())
+	if err != nil {
+		return nil, err
+	}
+	h := &heapScanner{
+		t:            t,
+		bi:           bi,
+		mem:          mem,
+		mds:          mds,
+		ptrSize:      int64(bi.Arch.PtrSize()),
+		rtyp:         rtyp,
+		runtimeTypes: make(map[uint64]heapRuntimeType),
+		hasPtrs:      make(map[godwarf.Type]bool),
+		mapStructs:   make(map[*godwarf.StructType]*mapStruct),
+	}
+	h.byteType, _ = bi.findType("uint8")
+	if err := h.readSpans(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// readSpans reads the list of allocated objects from the spans in
+// runtime.mheap_.allspans.
+func (h *heapScanner) readSpans() error {
+	scope := globalScope(h.t, h.bi, h.bi.Images[0], h.mem)
+	mheap, err := scope.findGlobal("runtime", "mheap_")
+	if err != nil {
+		return err
+	}
+	allspans, err := mheap.structMember("allspans")
+	if err != nil {
+		return err
+	}
+	allspans.loadValue(LoadConfig{})
+	if allspans.Unreadable != nil {
+		return allspans.Unreadable
+	}
+
+	mspanTyp, err := h.bi.findType("runtime.mspan")
+	if err != nil {
+		return err
+	}
+	fields, ok := structFieldsByName(mspanTyp)
+	if !ok {
+		return fmt.Errorf("unsupported type for runtime.mspan: %s", mspanTyp)
+	}
+	for _, name := range []string{"startAddr", "npages", "nelems", "freeindex", "allocBits", "elemsize", "spanclass", "state"} {
+		if fields[name] == nil {
+			return fmt.Errorf("unsupported type for runtime.mspan: no %s field", name)
+		}
+	}
+	freeIndexField := "freeindex"
+	if fields["freeIndexForScan"] != nil {
+		freeIndexField = "freeIndexForScan"
+	}
+	// Since Go 1.22 objects that don't fit in a span with a heap bitmap start
+	// with a malloc header pointing to their type, large objects have their
+	// type stored in the span.
+	hasMallocHeaders := fields["largeType"] != nil
+	minSizeForMallocHeader := uint64(h.ptrSize * h.ptrSize * 8)
+
+	spanptrs := make([]byte, allspans.Len*h.ptrSize)
+	if _, err := h.mem.ReadMemory(spanptrs, allspans.Base); err != nil {
+		return err
+	}
+	h.metadata = append(h.metadata, [2]uint64{allspans.Base, allspans.Base + uint64(len(spanptrs))})
+	buf := make([]byte, mspanTyp.Size())
+	for i := int64(0); i < allspans.Len; i++ {
+		if h.t.canceled() {
+			return ErrCanceled
+		}
+		spanAddr := readUintBytes(spanptrs[i*h.ptrSize:], h.ptrSize)
+		if spanAddr == 0 {
+			continue
+		}
+		if _, err := h.mem.ReadMemory(buf, spanAddr); err != nil {
+			continue
+		}
+		field := func(name string) uint64 {
+			f := fields[name]
+			if f == nil {
+				return 0
+			}
+			return readUintBytes(buf[f.ByteOffset:], f.Type.Size())
+		}
+		h.metadata = append(h.metadata, [2]uint64{spanAddr, spanAddr + uint64(len(buf))})
+		base := field("startAddr")
+		if npages := field("npages"); npages > 0 {
+			h.spans = append(h.spans, [2]uint64{base, base + npages*heapPageSize})
+		}
+		if field("state") != spanInUse {
+			continue
+		}
+		nelems, elemsize := field("nelems"), field("elemsize")
+		if elemsize == 0 || nelems*elemsize > field("npages")*heapPageSize {
+			continue
+		}
+		freeindex := field(freeIndexField)
+		allocBits := make([]byte, (nelems+7)/8)
+		if _, err := h.mem.ReadMemory(allocBits, field("allocBits")); err != nil {
+			continue
+		}
+		h.metadata = append(h.metadata, [2]uint64{field("allocBits"), field("allocBits") + uint64(len(allocBits))})
+		spanclass := field("spanclass")
+		sizeclass, noscan := spanclass>>1, spanclass&1 != 0
+
+		var hdr uint64
+		if hasMallocHeaders && !noscan && elemsize > minSizeForMallocHeader && sizeclass != 0 {
+			// the malloc header is a pointer to the type of the object
+			hdr = uint64(h.ptrSize)
+		}
+		first := len(h.objs)
+		for j := uint64(0); j < nelems; j++ {
+			if j >= freeindex && allocBits[j/8]&(1<<(j%8)) == 0 {
+				continue
+			}
+			h.objs = append(h.objs, heapObject{base: base + j*elemsize, size: elemsize, hdr: hdr, noscan: noscan})
+		}
+		if specials := field("specials"); specials != 0 {
+			h.readFinalizers(base, specials)
+		}
+
+		switch {
+		case hasMallocHeaders && !noscan && sizeclass == 0:
+			if len(h.objs) > first {
+				h.setRuntimeType(first, field("largeType"))
+			}
+		case hdr != 0:
+			for j := first; j < len(h.objs); j++ {
+				typeAddr, err := readUintRaw(h.mem, h.objs[j].base, h.ptrSize)
+				if err == nil {
+					h.setRuntimeType(j, typeAddr)
+				}
+			}
+		}
+	}
+
+	// the runtime does not keep allspans sorted
+	sort.Slice(h.objs, func(i, j int) bool { return h.objs[i].base < h.objs[j].base })
+	sort.Slice(h.spans, func(i, j int) bool { return h.spans[i][0] < h.spans[j][0] })
+	h.queue = h.queue[:0]
+	for i := range h.objs {
+		if h.objs[i].typ != nil {
+			h.queue = append(h.queue, i)
+		}
+	}
+	return nil
+}
+
+// readFinalizers reads the finalizers from the list of specials of the
+// span starting at base.
+func (h *heapScanner) readFinalizers(base, specials uint64) {
+	specialTyp, err := h.bi.findType("runtime.special")
+	if err != nil {
+		return
+	}
+	finalizerTyp, err := h.bi.findType("runtime.specialfinalizer")
+	if err != nil {
+		return
+	}
+	specialFields, ok := structFieldsByName(specialTyp)
+	if !ok || specialFields["next"] == nil || specialFields["offset"] == nil || specialFields["kind"] == nil {
+		return
+	}
+	finalizerFields, ok := structFieldsByName(finalizerTyp)
+	if !ok || finalizerFields["fn"] == nil || finalizerFields["ot"] == nil {
+		return
+	}
+	kindFinalizer := uint64(kindSpecialFinalizer)
+	if !goversion.ProducerAfterOrEqual(h.bi.Producer(), 1, 25) {
+		kindFinalizer = kindSpecialFinalizerGo124
+	}
+
+	buf := make([]byte, max(specialTyp.Size(), finalizerTyp.Size()))
+	field := func(fields map[string]*godwarf.StructField, name string) uint64 {
+		f := fields[name]
+		return readUintBytes(buf[f.ByteOffset:], f.Type.Size())
+	}
+	for p, n := specials, 0; p != 0 && n < maxSpecialsPerSpan; n++ {
+		if _, err := h.mem.ReadMemory(buf[:specialTyp.Size()], p); err != nil {
+			return
+		}
+		next, off := field(specialFields, "next"), field(specialFields, "offset")
+		if field(specialFields, "kind") == kindFinalizer {
+			if _, err := h.mem.ReadMemory(buf[:finalizerTyp.Size()], p); err != nil {
+				return
+			}
+			h.finalizers = append(h.finalizers, heapFinalizer{obj: base + off, fn: field(finalizerFields, "fn"), ot: field(finalizerFields, "ot")})
+		}
+		p = next
+	}
+}
+
+// structFieldsByName returns the fields of the struct type typ indexed by
+// name.
+func structFieldsByName(typ godwarf.Type) (map[string]*godwarf.StructField, bool) {
+	styp, ok := godwarf.ResolveTypedef(typ).(*godwarf.StructType)
+	if !ok {
+		return nil, false
+	}
+	fields := make(map[string]*godwarf.StructField, len(styp.Field))
+	for _, f := range styp.Field {
+		fields[f.Name] = f
+	}
+	return fields, true
+}
+
+// setRuntimeType sets the type of object i to the runtime type at
+// typeAddr, read from its malloc header.
+func (h *heapScanner) setRuntimeType(i int, typeAddr uint64) {
+	rt := h.runtimeType(typeAddr)
+	if rt.typ == nil || rt.typ.Size() <= 0 {
+		return
+	}
+	obj := &h.objs[i]
+	typ := rt.typ
+	// the malloc header of an array of values contains the type of its
+	// elements
+	if n := (obj.size - obj.hdr) / uint64(typ.Size()); n > 1 {
+		typ = fakeArrayType(n, typ)
+	}
+	obj.typ = typ
+	h.queue = append(h.queue, i)
+}
+
+// runtimeType returns the DWARF type corresponding to the runtime type at
+// typeAddr.
+func (h *heapScanner) runtimeType(typeAddr uint64) heapRuntimeType {
+	if typeAddr == 0 {
+		return heapRuntimeType{}
+	}
+	if rt, ok := h.runtimeTypes[typeAddr]; ok {
+		return rt
+	}
+	var rt heapRuntimeType
+	rt.typ, rt.direct, _ = RuntimeTypeToDIE(newVariable("", typeAddr, h.rtyp, h.bi, h.mem), 0, h.mds)
+	if rt.typ != nil {
+		rt.typ = wrapStructType(h.bi, rt.typ)
+	}
+	h.runtimeTypes[typeAddr] = rt
+	return rt
+}
+
+// findObject returns the index of the object containing addr, or -1.
+func (h *heapScanner) findObject(addr uint64) int {
+	i := sort.Search(len(h.objs), func(i int) bool { return h.objs[i].base > addr }) - 1
+	if i < 0 || addr >= h.objs[i].base+h.objs[i].size {
+		return -1
+	}
+	return i
+}
+
+// inSpans returns true if addr is inside a span, allocated or not.
+func (h *heapScanner) inSpans(addr uint64) bool {
+	i := sort.Search(len(h.spans), func(i int) bool { return h.spans[i][0] > addr }) - 1
+	return i >= 0 && addr < h.spans[i][1]
+}
+
+// scanRoots follows the pointers contained in global variables, in the
+// live local variables of all goroutines and in finalizers.
+//
+// The liveness of local variables is determined using the stack maps of
+// the garbage collector, when they are available: variables stored in stack
+// slots that do not contain live pointers at the call site where a frame is
+// suspended are not roots. The topmost frame of goroutines running on a
+// thread can be stopped anywhere and all its variables are considered live.
+func (h *heapScanner) scanRoots() error {
+	for _, pkgvar := range h.bi.packageVars {
+		if h.t.canceled() {
+			return ErrCanceled
+		}
+		reader := pkgvar.cu.image.dwarfReader
+		reader.Seek(pkgvar.offset)
+		entry, err := reader.Next()
+		if err != nil {
+			return err
+		}
+		scope := globalScope(h.t, h.bi, pkgvar.cu.image, h.mem)
+		v, err := extractVarInfoFromEntry(h.t, h.bi, pkgvar.cu.image, scope.Regs, h.mem, godwarf.EntryToTree(entry), 0)
+		if err != nil {
+			continue
+		}
+		h.addRoot(v.Name, v)
+	}
+
+	h.funcTabs, _ = loadFuncTabs(h.bi, h.mem)
+	gs, _, err := GoroutinesInfo(h.t, 0, 0)
+	if err != nil {
+		return err
+	}
+	for _, g := range gs {
+		if h.t.canceled() {
+			return ErrCanceled
+		}
+		if g.Unreadable != nil {
+			continue
+		}
+		frames, err := GoroutineStacktrace(h.t, g, heapScanStackDepth, 0)
+		if err != nil {
+			continue
+		}
+		threadID := 0
+		if g.Thread != nil {
+			threadID = g.Thread.ThreadID()
+		}
+		top := g.Thread != nil
+		for i := range frames {
+			if i > 0 && !frames[i-1].Inlined {
+				// frames interrupted by a signal are not stopped at a call
+				// instruction.
+				top = frames[i-1].Current.Fn != nil && (frames[i-1].Current.Fn.Name == "runtime.asyncPreempt" || frames[i-1].Current.Fn.Name == "runtime.sigpanic")
+			}
+			if frames[i].Current.Fn == nil {
+				continue
+			}
+			var liveness *stackLiveness
+			if !top && !frames[i].SystemStack {
+				liveness, _ = frameLiveness(h.bi, h.mem, h.funcTabs, &frames[i])
+			}
+			scope := FrameToScope(h.t, h.mem, g, threadID, frames[i:]...)
+			vars, err := scope.Locals(0, "")
+			if err != nil {
+				continue
+			}
+			for _, v := range vars {
+				if liveness != nil && v.Flags&VariableEscaped == 0 && v.DwarfType != nil && v.Addr >= g.stack.lo && v.Addr < g.stack.hi && liveness.dead(v.Addr, uint64(v.DwarfType.Size())) {
+					continue
+				}
+				h.addRoot(fmt.Sprintf("%s in %s (goroutine %d frame %d)", v.Name, frames[i].Current.Fn.Name, g.ID, i), v)
+			}
+		}
+	}
+
+	for _, fin := range h.finalizers {
+		if h.t.canceled() {
+			return ErrCanceled
+		}
+		// the finalizer keeps alive the objects reachable from the object
+		// it is set on, and its closure, but not the object itself.
+		ptrbuf := make([]byte, h.ptrSize)
+		putUintBytes(ptrbuf, fin.obj)
+		var ptyp godwarf.Type = pointerTo(&godwarf.VoidType{}, h.bi.Arch)
+		if rt := h.runtimeType(fin.ot); rt.typ != nil {
+			if _, isptr := rt.typ.(*godwarf.PtrType); isptr {
+				ptyp = rt.typ
+			}
+		}
+		h.addRootValue(heapRoot{name: "finalizer", typ: ptyp, buf: ptrbuf, finalizer: true})
+		fnbuf := make([]byte, h.ptrSize)
+		putUintBytes(fnbuf, fin.fn)
+		h.addRootValue(heapRoot{name: "finalizer function", typ: &godwarf.FuncType{CommonType: godwarf.CommonType{ByteSize: h.ptrSize, Name: "func()"}}, buf: fnbuf})
+	}
+	return nil
+}
+
+// addRoot adds variable v to the list of roots.
+func (h *heapScanner) addRoot(name string, v *Variable) {
+	if v == nil || v.Unreadable != nil || v.DwarfType == nil {
+		return
+	}
+	if v.Flags&VariableEscaped != 0 {
+		// v is a local variable that was moved to the heap, the root is the
+		// pointer to it.
+		buf := make([]byte, h.ptrSize)
+		putUintBytes(buf, v.Addr)
+		h.addRootValue(heapRoot{name: name, typ: pointerTo(v.DwarfType, h.bi.Arch), buf: buf})
+		return
+	}
+	if !h.hasPointers(v.DwarfType) {
+		return
+	}
+	buf := make([]byte, v.DwarfType.Size())
+	if _, err := v.mem.ReadMemory(buf, v.Addr); err != nil {
+		return
+	}
+	addr := v.Addr
+	if v.Flags&VariableFakeAddress != 0 {
+		addr = 0
+	}
+	h.addRootValue(heapRoot{name: name, addr: addr, typ: v.DwarfType, buf: buf})
+}
+
+// addRootValue adds root to the list of roots and follows the pointers it
+// contains.
+func (h *heapScanner) addRootValue(root heapRoot) {
+	h.roots = append(h.roots, root)
+	h.rootPointers(&root, func(_ int64, p uint64, typ godwarf.Type) {
+		h.found(p, typ)
+	})
+}
+
+// rootPointers calls fn for every pointer contained in root.
+func (h *heapScanner) rootPointers(root *heapRoot, fn func(off int64, p uint64, typ godwarf.Type)) {
+	if root.typ == nil {
+		h.scanWords(root.buf, fn)
+		return
+	}
+	h.scanPointers(root.buf, 0, root.typ, fn)
+}
+
+// objectPointers calls fn for every pointer contained in object i, objects
+// of unknown type that can contain pointers are scanned conservatively.
+func (h *heapScanner) objectPointers(i int, fn func(off int64, p uint64, typ godwarf.Type)) {
+	obj := h.objs[i]
+	if obj.noscan || (obj.typ != nil && !h.hasPointers(obj.typ)) {
+		return
+	}
+	size := obj.size - obj.hdr
+	if obj.typ != nil {
+		size = uint64(obj.typ.Size())
+	}
+	buf := make([]byte, size)
+	if _, err := h.mem.ReadMemory(buf, obj.base+obj.hdr); err != nil {
+		return
+	}
+	if obj.typ == nil {
+		h.scanWords(buf, fn)
+		return
+	}
+	h.scanPointers(buf, 0, obj.typ, fn)
+}
+
+// scanWords calls fn for every word of buf that could be a pointer into
+// the heap.
+func (h *heapScanner) scanWords(buf []byte, fn func(off int64, p uint64, typ godwarf.Type)) {
+	for off := int64(0); off+h.ptrSize <= int64(len(buf)); off += h.ptrSize {
+		if p := h.readPtr(buf, off); p != 0 && h.findObject(p) >= 0 {
+			fn(off, p, nil)
+		}
+	}
+}
+
+// found records that a pointer to addr of type *typ exists. If addr is the
+// address of a heap object and typ is larger than its current type the
+// type of the object is changed to typ.
+func (h *heapScanner) found(addr uint64, typ godwarf.Type) {
+	if addr == 0 || typ == nil {
+		return
+	}
+	i := h.findObject(addr)
+	if i < 0 {
+		return
+	}
+	obj := &h.objs[i]
+	sz := typ.Size()
+	if addr != obj.base+obj.hdr || sz <= 0 || uint64(sz) > obj.size-obj.hdr {
+		return
+	}
+	if obj.typ != nil && obj.typ.Size() >= sz {
+		return
+	}
+	obj.typ = typ
+	h.queue = append(h.queue, i)
+}
+
+// propagateTypes follows the pointers contained in the objects whose type
+// is known until no more types can be determined.
+func (h *heapScanner) propagateTypes() error {
+	for n := 0; len(h.queue) > 0; n++ {
+		if n%4096 == 0 && h.t.canceled() {
+			return ErrCanceled
+		}
+		i := h.queue[len(h.queue)-1]
+		h.queue = h.queue[:len(h.queue)-1]
+		obj := h.objs[i]
+		if !h.hasPointers(obj.typ) {
+			continue
+		}
+		buf := make([]byte, obj.typ.Size())
+		if _, err := h.mem.ReadMemory(buf, obj.base+obj.hdr); err != nil {
+			continue
+		}
+		h.scanPointers(buf, 0, obj.typ, func(_ int64, p uint64, typ godwarf.Type) {
+			h.found(p, typ)
+		})
+	}
+	return nil
+}
+
+// scanPointers calls fn for every pointer contained in the value of type
+// typ stored in buf at offset off, with the type of the value it points
+// to, if it is known.
+func (h *heapScanner) scanPointers(buf []byte, off int64, typ godwarf.Type, fn func(off int64, p uint64, typ godwarf.Type)) {
+	if off < 0 || off+typ.Size() > int64(len(buf)) {
+		return
+	}
+	switch t := typ.(type) {
+	case *godwarf.PtrType:
+		var pointee godwarf.Type = t.Type
+		if _, isvoid := pointee.(*godwarf.VoidType); isvoid {
+			// unsafe.Pointer
+			pointee = nil
+		}
+		fn(off, h.readPtr(buf, off), pointee)
+	case *godwarf.FuncType:
+		// the type of closures is not known
+		fn(off, h.readPtr(buf, off), nil)
+	case *godwarf.MapType:
+		h.scanPointers(buf, off, t.TypedefType.Type, fn)
+	case *godwarf.ChanType:
+		h.scanPointers(buf, off, t.TypedefType.Type, fn)
+	case *godwarf.InterfaceType:
+		h.scanInterface(buf, off, t, fn)
+	case *godwarf.StringType:
+		p, n := h.readPtr(buf, off), h.readPtr(buf, off+h.ptrSize)
+		if n == 0 || h.byteType == nil {
+			fn(off, p, nil)
+			return
+		}
+		fn(off, p, fakeArrayType(n, h.byteType))
+	case *godwarf.SliceType:
+		p, c := h.readPtr(buf, off), h.readPtr(buf, off+2*h.ptrSize)
+		if c == 0 || t.ElemType.Size() <= 0 {
+			fn(off, p, nil)
+			return
+		}
+		fn(off, p, fakeArrayType(c, t.ElemType))
+	case *godwarf.StructType:
+		ms := h.mapStruct(t)
+		for _, f := range t.Field {
+			if ms != nil && f == ms.ptr {
+				fn(off+f.ByteOffset, h.readPtr(buf, off+f.ByteOffset), ms.pointee(buf, off))
+				continue
+			}
+			if h.hasPointers(f.Type) {
+				h.scanPointers(buf, off+f.ByteOffset, f.Type, fn)
+			}
+		}
+	case *godwarf.ArrayType:
+		if t.Count <= 0 || !h.hasPointers(t.Type) {
+			return
+		}
+		stride := t.Size() / t.Count
+		for i := int64(0); i < t.Count; i++ {
+			h.scanPointers(buf, off+i*stride, t.Type, fn)
+		}
+	case *godwarf.ParametricType:
+		h.scanPointers(buf, off, t.TypedefType.Type, fn)
+	case *godwarf.TypedefType:
+		h.scanPointers(buf, off, t.Type, fn)
+	case *godwarf.QualType:
+		h.scanPointers(buf, off, t.Type, fn)
+	}
+}
+
+// mapStruct describes a pointer field of one of the structs used to
+// implement maps, whose DWARF type does not describe the value it points
+// to.
+type mapStruct struct {
+	kind  mapStructKind
+	ptr   *godwarf.StructField
+	n     *godwarf.StructField // field containing the number of elements pointed to by ptr
+	elem  godwarf.Type
+	group godwarf.Type // type of the groups of swiss maps
+}
+
+type mapStructKind uint8
+
+const (
+	mapStructSwissDirectory mapStructKind = iota // map<K,V>.dirPtr
+	mapStructSwissGroups                         // groupReference<K,V>.data
+	mapStructBuckets                             // hash<K,V>.buckets
+)
+
+// mapStruct returns the description of the pointer field of t, if t is
+// one of the structs used to implement maps, or nil:
+//   - the directory of swiss maps, map<K,V>.dirPtr, is recorded as a
+//     **table but points to a group for small maps (if dirLen is 0) and to
+//     a [dirLen]*table otherwise
+//   - the groups of swiss tables, groupReference<K,V>.data, are recorded as
+//     *group but point to [lengthMask+1]group
+//   - the buckets of classic maps, hash<K,V>.buckets, are recorded as
+//     *bucket but point to [1<<B]bucket
+//
+// See also mapIteratorSwiss.loadTypes.
+func (h *heapScanner) mapStruct(t *godwarf.StructType) *mapStruct {
+	if ms, ok := h.mapStructs[t]; ok {
+		return ms
+	}
+	var ms *mapStruct
+	pointee := func(f *godwarf.StructField) godwarf.Type {
+		if f == nil {
+			return nil
+		}
+		if ptyp, ok := f.Type.(*godwarf.PtrType); ok {
+			return ptyp.Type
+		}
+		return nil
+	}
+	fields, _ := structFieldsByName(t)
+	switch {
+	case strings.HasPrefix(t.StructName, "map<"):
+		ms = &mapStruct{kind: mapStructSwissDirectory, ptr: fields["dirPtr"], n: fields["dirLen"]}
+		ms.elem = pointee(ms.ptr)
+		if tableptr, ok := ms.elem.(*godwarf.PtrType); ok {
+			if table, ok := godwarf.ResolveTypedef(tableptr.Type).(*godwarf.StructType); ok {
+				tableFields, _ := structFieldsByName(table)
+				if groups := tableFields["groups"]; groups != nil {
+					if groupsStruct, ok := godwarf.ResolveTypedef(groups.Type).(*godwarf.StructType); ok {
+						groupsFields, _ := structFieldsByName(groupsStruct)
+						ms.group = pointee(groupsFields["data"])
+					}
+				}
+			}
+		}
+		if ms.group == nil {
+			ms = nil
+		}
+	case strings.HasPrefix(t.StructName, "groupReference<"):
+		ms = &mapStruct{kind: mapStructSwissGroups, ptr: fields["data"], n: fields["lengthMask"]}
+		ms.elem = pointee(ms.ptr)
+	case strings.HasPrefix(t.StructName, "hash<"):
+		ms = &mapStruct{kind: mapStructBuckets, ptr: fields["buckets"], n: fields["B"]}
+		ms.elem = pointee(ms.ptr)
+	}
+	if ms != nil && (ms.ptr == nil || ms.n == nil || ms.elem == nil || ms.elem.Size() <= 0) {
+		ms = nil
+	}
+	h.mapStructs[t] = ms
+	return ms
+}
+
+// pointee returns the type of the value pointed to by the field ms.ptr of
+// the struct stored in buf at offset off.
+func (ms *mapStruct) pointee(buf []byte, off int64) godwarf.Type {
+	n := readUintBytes(buf[off+ms.n.ByteOffset:], ms.n.Type.Size())
+	switch ms.kind {
+	case mapStructSwissDirectory:
+		if n == 0 {
+			return ms.group
+		}
+	case mapStructSwissGroups:
+		n++
+	case mapStructBuckets:
+		if n >= 32 {
+			return nil
+		}
+		n = 1 << n
+	}
+	if n > maxMapStructElems {
+		return nil
+	}
+	return fakeArrayType(n, ms.elem)
+}
+
+// scanInterface calls fn for the data pointer of the interface stored in
+// buf at offset off, the type of the value it points to is read from the
+// type word of the interface.
+func (h *heapScanner) scanInterface(buf []byte, off int64, t *godwarf.InterfaceType, fn func(off int64, p uint64, typ godwarf.Type)) {
+	typeAddr, data := h.readPtr(buf, off), h.readPtr(buf, off+h.ptrSize)
+	if typeAddr == 0 {
+		return
+	}
+	if istruct, ok := godwarf.ResolveTypedef(t.TypedefType.Type).(*godwarf.StructType); ok && len(istruct.Field) > 0 && istruct.Field[0].Name == "tab" {
+		// non-empty interface, the type word points to an itab and the type
+		// is its second field
+		var err error
+		typeAddr, err = readUintRaw(h.mem, typeAddr+uint64(h.ptrSize), h.ptrSize)
+		if err != nil {
+			return
+		}
+	}
+	rt := h.runtimeType(typeAddr)
+	switch {
+	case rt.typ == nil:
+		fn(off+h.ptrSize, data, nil)
+	case rt.direct:
+		// the data word is the value
+		h.scanPointers(buf, off+h.ptrSize, rt.typ, fn)
+	default:
+		fn(off+h.ptrSize, data, rt.typ)
+	}
+}
+
+// hasPointers returns true if values of type typ can contain pointers.
+func (h *heapScanner) hasPointers(typ godwarf.Type) bool {
+	if t, isarray := typ.(*godwarf.ArrayType); isarray {
+		// array types are often created on the fly, do not cache them
+		return t.Count > 0 && h.hasPointers(t.Type)
+	}
+	if r, ok := h.hasPtrs[typ]; ok {
+		return r
+	}
+	// recursive types can only be defined through pointers, assume there are
+	// none while the type is being examined
+	h.hasPtrs[typ] = false
+	var r bool
+	switch t := typ.(type) {
+	case *godwarf.PtrType, *godwarf.FuncType, *godwarf.MapType, *godwarf.ChanType, *godwarf.InterfaceType, *godwarf.StringType, *godwarf.SliceType:
+		r = true
+	case *godwarf.StructType:
+		for _, f := range t.Field {
+			if h.hasPointers(f.Type) {
+				r = true
+				break
+			}
+		}
+	case *godwarf.ParametricType:
+		r = h.hasPointers(t.TypedefType.Type)
+	case *godwarf.TypedefType:
+		r = h.hasPointers(t.Type)
+	case *godwarf.QualType:
+		r = h.hasPointers(t.Type)
+	}
+	h.hasPtrs[typ] = r
+	return r
+}
+
+func (h *heapScanner) readPtr(buf []byte, off int64) uint64 {
+	return readUintBytes(buf[off:], h.ptrSize)
+}
+
+// putUintBytes encodes v as a little endian unsigned integer of size
+// len(buf).
+func putUintBytes(buf []byte, v uint64) {
+	for i := range buf {
+		buf[i] = byte(v >> (8 * i))
+	}
+}
+
+// readUintBytes decodes the little endian unsigned integer of the given
+// size at the start of buf.
+func readUintBytes(buf []byte, size int64) uint64 {
+	switch size {
+	case 1:
+		return uint64(buf[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(buf))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(buf))
+	case 8:
+		return binary.LittleEndian.Uint64(buf)
+	}
+	return 0
+}
@@ -0,0 +1,328 @@
+package proc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/frame"
+	"github.com/go-delve/delve/pkg/dwarf/leb128"
+	"github.com/go-delve/delve/pkg/goversion"
+)
+
+// This file implements moving the program counter of a goroutine to a
+// different line of the function it is executing, without running any of
+// the code in between.
+//
+// This is only safe in a limited number of circumstances, the destination
+// must:
+//   - belong to the function of the topmost frame of the goroutine (and not
+//     to one of its closures or to the function containing the closure)
+//   - have the same canonical frame address rule as the current PC, so
+//     that the stack pointer does not need to be adjusted
+//   - not be separated from the current PC by a defer statement, skipping
+//     or repeating those would corrupt the list of deferred calls of the
+//     goroutine or the bitmask of open-coded defers of the frame.
+//
+// Nothing is done about local variables, which could be uninitialized at
+// the destination.
+
+// deferprocFunctions are the runtime functions called by defer statements
+// that are not open-coded.
+var deferprocFunctions = []string{"runtime.deferproc", "runtime.deferprocStack", "runtime.deferprocat"}
+
+// JumpDestination returns the address that SetPCToLine would set the
+// program counter of g to, or an error if the program counter of g can not
+// be moved to file:line.
+// If g is nil the current thread is used.
+func (t *Target) JumpDestination(g *G, file string, line int) (uint64, error) {
+	thread, err := t.jumpThread(g)
+	if err != nil {
+		return 0, err
+	}
+	return t.jumpDestination(thread, file, line)
+}
+
+// SetPCToLine moves the program counter of g to the first statement of
+// file:line, which must be in the same function g is currently executing.
+// If g is nil the current thread is used.
+func (t *Target) SetPCToLine(g *G, file string, line int) error {
+	thread, err := t.jumpThread(g)
+	if err != nil {
+		return err
+	}
+	pc, err := t.jumpDestination(thread, file, line)
+	if err != nil {
+		return err
+	}
+	if err := setPC(thread, pc); err != nil {
+		return err
+	}
+	t.ClearCaches()
+	thread.Breakpoint().Clear()
+	return thread.SetCurrentBreakpoint(false)
+}
+
+func (t *Target) jumpThread(g *G) (Thread, error) {
+	if ok, err := t.Valid(); !ok {
+		return nil, err
+	}
+	if recorded, _ := t.recman.Recorded(); recorded {
+		return nil, errors.New("can not change the program counter of a recording")
+	}
+	if t.Breakpoints().HasSteppingBreakpoints() {
+		return nil, errors.New("can not change the program counter while next, step or stepout is in progress")
+	}
+	if g == nil {
+		return t.CurrentThread(), nil
+	}
+	if g.Thread == nil {
+		return nil, fmt.Errorf("goroutine %d is not running on a thread", g.ID)
+	}
+	return g.Thread, nil
+}
+
+func (t *Target) jumpDestination(thread Thread, file string, line int) (uint64, error) {
+	bi := t.BinInfo()
+	regs, err := thread.Registers()
+	if err != nil {
+		return 0, err
+	}
+	pc := regs.PC()
+	fn := bi.PCToFunc(pc)
+	if fn == nil {
+		return 0, fmt.Errorf("could not find function at %#x", pc)
+	}
+
+	pcs, err := FindFileLocation(t, file, line)
+	if err != nil {
+		return 0, err
+	}
+	var dest uint64
+	var other *Function
+	for _, destpc := range pcs {
+		if fn.Entry <= destpc && destpc < fn.End {
+			if dest == 0 || destpc < dest {
+				dest = destpc
+			}
+		} else if other == nil {
+			other = bi.PCToFunc(destpc)
+		}
+	}
+	if dest == 0 {
+		switch {
+		case other != nil && isClosureOf(other, fn):
+			return 0, fmt.Errorf("can not jump into closure %s", other.Name)
+		case other != nil && isClosureOf(fn, other):
+			return 0, fmt.Errorf("can not jump out of closure %s", fn.Name)
+		default:
+			return 0, fmt.Errorf("%s:%d is not in the current function %s", file, line, fn.Name)
+		}
+	}
+
+	cfa, err := cfaRule(bi, pc)
+	if err != nil {
+		return 0, err
+	}
+	destcfa, err := cfaRule(bi, dest)
+	if err != nil {
+		return 0, err
+	}
+	if cfa.Rule != destcfa.Rule || cfa.Reg != destcfa.Reg || cfa.Offset != destcfa.Offset || len(cfa.Expression) > 0 || len(destcfa.Expression) > 0 {
+		return 0, fmt.Errorf("can not jump to %s:%d, the stack pointer would need to be adjusted", file, line)
+	}
+
+	start, end := pc, dest
+	if start > end {
+		start, end = end, start
+	}
+	text, err := disassemble(t.Memory(), nil, t.Breakpoints(), bi, start, end, false)
+	if err != nil {
+		return 0, err
+	}
+	for _, instr := range text {
+		if instr.IsCall() && instr.DestLoc != nil && instr.DestLoc.Fn != nil {
+			for _, name := range deferprocFunctions {
+				if instr.DestLoc.Fn.Name == name {
+					return 0, fmt.Errorf("can not jump to %s:%d across the defer statement at %s:%d", file, line, instr.Loc.File, instr.Loc.Line)
+				}
+			}
+		}
+	}
+
+	defers, err := t.openCodedDefers(fn, cfa, regs.SP())
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range defers {
+		if d.pc == 0 {
+			if dest < pc {
+				return 0, fmt.Errorf("can not jump to %s:%d, the position of the deferred call to %s is not known", file, line, d.fn.Name)
+			}
+			continue
+		}
+		// Executed defer statements can not be repeated, defer statements
+		// that did not execute yet can not be skipped.
+		if (d.executed && dest <= d.pc && d.pc < pc) || (!d.executed && pc <= d.pc && d.pc < dest) {
+			dfile, dline, _ := bi.PCToLine(d.pc)
+			return 0, fmt.Errorf("can not jump to %s:%d across the defer statement at %s:%d", file, line, dfile, dline)
+		}
+	}
+
+	return dest, nil
+}
+
+// openCodedDefer is an open-coded defer statement of a function.
+type openCodedDefer struct {
+	executed bool      // the defer statement was executed by the current frame
+	fn       *Function // the deferred function, or its wrapper
+	pc       uint64    // address of the defer statement, zero if not known
+}
+
+// openCodedDefers returns the open-coded defer statements of the frame of
+// fn at the top of the stack, given the rule used to compute its
+// canonical frame address and the current value of the stack pointer.
+// The executed defer statements are read from the deferBits variable of
+// the frame, which records which deferred calls must be made when fn
+// returns, and from the slots of their closures. The defer statements that
+// did not execute yet are found looking for the wrappers created by the
+// compiler for them.
+func (t *Target) openCodedDefers(fn *Function, cfa frame.DWRule, sp uint64) ([]openCodedDefer, error) {
+	bi := t.BinInfo()
+	mem := t.Memory()
+	fts, err := loadFuncTabs(bi, mem)
+	if err != nil {
+		return nil, err
+	}
+	f, err := findFuncInfo(mem, fts, fn.Entry)
+	if f == nil || err != nil {
+		return nil, err
+	}
+	info := f.funcdata(funcdataOpenCodedDeferInfo)
+	if info == 0 {
+		return nil, nil
+	}
+	if cfa.Rule != frame.RuleCFA || cfa.Reg != bi.Arch.SPRegNum {
+		return nil, fmt.Errorf("can not change the program counter of %s, it uses open-coded defers", fn.Name)
+	}
+
+	// varp is computed from the canonical frame address like in
+	// frameLiveness.
+	ptrSize := uint64(bi.Arch.PtrSize())
+	varp := uint64(int64(sp) + cfa.Offset)
+	if !bi.Arch.usesLR {
+		// return address
+		varp -= ptrSize
+	}
+	// saved frame pointer
+	varp -= ptrSize
+
+	// See runtime.(*_panic).nextFrame and, before Go 1.22,
+	// runtime.runOpenDeferFrame for the format of the open-coded defer info.
+	const maxOpenDefers = 8 // deferBits is a uint8
+	var buf [(2 + maxOpenDefers) * binary.MaxVarintLen64]byte
+	if _, err := mem.ReadMemory(buf[:], info); err != nil {
+		return nil, err
+	}
+	rd := bytes.NewReader(buf[:])
+	deferBitsOffset, _ := leb128.DecodeUnsigned(rd)
+	var slots [maxOpenDefers]uint64
+	if goversion.ProducerAfterOrEqual(bi.Producer(), 1, 22) {
+		slotsOffset, _ := leb128.DecodeUnsigned(rd)
+		for i := range slots {
+			slots[i] = varp - slotsOffset + uint64(i)*ptrSize
+		}
+	} else {
+		nDefers, _ := leb128.DecodeUnsigned(rd)
+		if nDefers > maxOpenDefers {
+			return nil, fmt.Errorf("malformed open-coded defer info for %s", fn.Name)
+		}
+		for i := int(nDefers) - 1; i >= 0; i-- {
+			off, _ := leb128.DecodeUnsigned(rd)
+			slots[i] = varp - off
+		}
+	}
+
+	deferBits, err := readUintRaw(mem, varp-deferBitsOffset, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	var defers []openCodedDefer
+	executed := make(map[*Function]bool)
+	for i := range slots {
+		if deferBits&(1<<i) == 0 {
+			continue
+		}
+		if slots[i] == 0 {
+			return nil, fmt.Errorf("malformed open-coded defer info for %s", fn.Name)
+		}
+		closure, err := readUintRaw(mem, slots[i], int64(ptrSize))
+		if err != nil {
+			return nil, err
+		}
+		fnaddr, err := readUintRaw(mem, closure, int64(ptrSize))
+		if err != nil {
+			return nil, err
+		}
+		deferred := bi.PCToFunc(fnaddr)
+		if deferred == nil {
+			return nil, fmt.Errorf("could not find the function deferred by %s at %#x", fn.Name, fnaddr)
+		}
+		executed[deferred] = true
+		defers = append(defers, openCodedDefer{executed: true, fn: deferred, pc: t.deferStatementPC(fn, deferred)})
+	}
+
+	for i := range bi.Functions {
+		wrapper := &bi.Functions[i]
+		if !executed[wrapper] && strings.HasPrefix(wrapper.Name, fn.Name+".deferwrap") {
+			if pc := t.deferStatementPC(fn, wrapper); pc != 0 {
+				defers = append(defers, openCodedDefer{fn: wrapper, pc: pc})
+			}
+		}
+	}
+	return defers, nil
+}
+
+// deferStatementPC returns the address, inside fn, of the defer statement
+// that deferred a call to closure, or zero if closure is not a function
+// literal or defer wrapper defined in fn, whose position is that of the
+// defer statement.
+func (t *Target) deferStatementPC(fn, closure *Function) uint64 {
+	if !isClosureOf(closure, fn) && !strings.HasPrefix(closure.Name, fn.Name+".deferwrap") {
+		return 0
+	}
+	file, line, _ := t.BinInfo().PCToLine(closure.Entry)
+	pcs, err := FindFileLocation(t, file, line)
+	if err != nil {
+		return 0
+	}
+	var r uint64
+	for _, pc := range pcs {
+		if fn.Entry <= pc && pc < fn.End && (r == 0 || pc < r) {
+			r = pc
+		}
+	}
+	return r
+}
+
+// isClosureOf returns true if fn is a function literal, or the body of a
+// range-over-func loop, defined inside parent.
+func isClosureOf(fn, parent *Function) bool {
+	return strings.HasPrefix(fn.Name, parent.Name+".func") || strings.HasPrefix(fn.Name, parent.Name+"-range")
+}
+
+// cfaRule returns the rule used to compute the canonical frame address at pc.
+func cfaRule(bi *BinaryInfo, pc uint64) (frame.DWRule, error) {
+	fde, err := bi.frameEntries.FDEForPC(pc)
+	if err != nil {
+		return frame.DWRule{}, err
+	}
+	fctxt, err := fde.EstablishFrame(pc)
+	if err != nil {
+		return frame.DWRule{}, err
+	}
+	return bi.Arch.fixFrameUnwindContext(fctxt, pc, bi).CFA, nil
+}
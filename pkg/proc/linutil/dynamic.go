@@ -2,10 +2,13 @@ package linutil
 
 import (
 	"bytes"
+	"debug/elf"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/go-delve/delve/pkg/proc"
 )
@@ -20,6 +23,8 @@ var ErrTooManyLibraries = errors.New("number of loaded libraries exceeds maximum
 const (
 	_DT_NULL  = 0  // DT_NULL as defined by SysV ABI specification
 	_DT_DEBUG = 21 // DT_DEBUG as defined by SysV ABI specification
+
+	_NT_GNU_BUILD_ID = 3 // type of the note containing the build ID
 )
 
 // readUintRaw reads an integer of ptrSize bytes, with the specified byte order, from reader.
@@ -181,7 +186,13 @@ func ElfUpdateSharedObjects(p proc.Process) error {
 		if !first || lm.addr != 0 {
 			// First entry is the executable, we don't need to add it, and doing so
 			// can cause duplicate entries due to base address mismatches.
-			bi.AddImage(lm.name, lm.addr)
+			var buildID string
+			if _, err := os.Stat(lm.name); err != nil {
+				// the library is not on this system, its build ID is used to
+				// download it from debuginfod
+				buildID = readBuildID(p, lm.addr)
+			}
+			bi.AddImageWithBuildID(lm.name, lm.addr, buildID)
 		}
 		libs = append(libs, lm.name)
 		first = false
@@ -190,3 +201,97 @@ func ElfUpdateSharedObjects(p proc.Process) error {
 
 	return nil
 }
+
+const maxNoteSize = 0x10000 // maximum size of a PT_NOTE segment read from memory
+
+// readBuildID reads the GNU build ID of the shared library loaded at
+// addr from the ELF header and program headers mapped in memory. It
+// returns an empty string if the build ID can not be found.
+func readBuildID(p proc.Process, addr uint64) string {
+	mem := p.Memory()
+	var ident [elf.EI_NIDENT]byte
+	if _, err := mem.ReadMemory(ident[:], addr); err != nil {
+		return ""
+	}
+	if string(ident[:4]) != elf.ELFMAG || elf.Data(ident[elf.EI_DATA]) != elf.ELFDATA2LSB {
+		return ""
+	}
+
+	var phoff, phentsize, phnum uint64
+	switch elf.Class(ident[elf.EI_CLASS]) {
+	case elf.ELFCLASS64:
+		var hdr elf.Header64
+		if err := readStruct(mem, addr, &hdr); err != nil {
+			return ""
+		}
+		phoff, phentsize, phnum = hdr.Phoff, uint64(hdr.Phentsize), uint64(hdr.Phnum)
+	case elf.ELFCLASS32:
+		var hdr elf.Header32
+		if err := readStruct(mem, addr, &hdr); err != nil {
+			return ""
+		}
+		phoff, phentsize, phnum = uint64(hdr.Phoff), uint64(hdr.Phentsize), uint64(hdr.Phnum)
+	default:
+		return ""
+	}
+
+	for i := uint64(0); i < phnum; i++ {
+		var typ elf.ProgType
+		var vaddr, filesz uint64
+		phaddr := addr + phoff + i*phentsize
+		if elf.Class(ident[elf.EI_CLASS]) == elf.ELFCLASS64 {
+			var ph elf.Prog64
+			if err := readStruct(mem, phaddr, &ph); err != nil {
+				return ""
+			}
+			typ, vaddr, filesz = elf.ProgType(ph.Type), ph.Vaddr, ph.Filesz
+		} else {
+			var ph elf.Prog32
+			if err := readStruct(mem, phaddr, &ph); err != nil {
+				return ""
+			}
+			typ, vaddr, filesz = elf.ProgType(ph.Type), uint64(ph.Vaddr), uint64(ph.Filesz)
+		}
+		if typ != elf.PT_NOTE || filesz > maxNoteSize {
+			continue
+		}
+		notes := make([]byte, filesz)
+		if _, err := mem.ReadMemory(notes, addr+vaddr); err != nil {
+			continue
+		}
+		if buildID := findBuildIDNote(notes); buildID != "" {
+			return buildID
+		}
+	}
+	return ""
+}
+
+// findBuildIDNote returns the NT_GNU_BUILD_ID note contained in notes,
+// the contents of a PT_NOTE segment.
+func findBuildIDNote(notes []byte) string {
+	align4 := func(n uint64) uint64 { return (n + 3) &^ 3 }
+	for len(notes) >= 12 {
+		namesz := uint64(binary.LittleEndian.Uint32(notes[0:]))
+		descsz := uint64(binary.LittleEndian.Uint32(notes[4:]))
+		typ := binary.LittleEndian.Uint32(notes[8:])
+		notes = notes[12:]
+		if align4(namesz)+align4(descsz) > uint64(len(notes)) {
+			return ""
+		}
+		name := notes[:namesz]
+		desc := notes[align4(namesz):][:descsz]
+		notes = notes[align4(namesz)+align4(descsz):]
+		if typ == _NT_GNU_BUILD_ID && string(name) == "GNU\x00" {
+			return hex.EncodeToString(desc)
+		}
+	}
+	return ""
+}
+
+func readStruct(mem proc.MemoryReadWriter, addr uint64, data interface{}) error {
+	buf := make([]byte, binary.Size(data))
+	if _, err := mem.ReadMemory(buf, addr); err != nil {
+		return err
+	}
+	return binary.Read(bytes.NewReader(buf), binary.LittleEndian, data)
+}
@@ -245,3 +245,15 @@ func DereferenceMemory(mem MemoryReadWriter) MemoryReadWriter {
 	}
 	return mem
 }
+
+// derefMemoryAt is like DereferenceMemory but, if mem is a composite memory
+// that contains addr, it returns mem itself. It is used to read the
+// contents of slices that point into memory that does not belong to the
+// inferior process, such as the result of slicing an array stored in
+// registers or allocated by the debugger.
+func derefMemoryAt(mem MemoryReadWriter, addr uint64) MemoryReadWriter {
+	if cm, ok := mem.(*compositeMemory); ok && addr >= cm.base && addr < cm.base+uint64(len(cm.data)) {
+		return mem
+	}
+	return DereferenceMemory(mem)
+}
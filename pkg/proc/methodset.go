@@ -0,0 +1,301 @@
+package proc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go/constant"
+	"reflect"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+)
+
+const (
+	tflagUncommon = 1 << 0 // +rtype tflagUncommon|internal/abi.TFlagUncommon
+
+	kindMask = (1 << 5) - 1
+)
+
+// runtimeKindTypes maps the kind of a runtime type to the names of the
+// structs that can describe it, the uncommon type of a runtime type
+// immediately follows this struct.
+// See internal/abi.(*Type).Uncommon.
+var runtimeKindTypes = map[reflect.Kind][]string{
+	reflect.Array:     {"internal/abi.ArrayType"},
+	reflect.Chan:      {"internal/abi.ChanType"},
+	reflect.Func:      {"internal/abi.FuncType"},
+	reflect.Interface: {"internal/abi.InterfaceType"},
+	reflect.Map:       {"internal/abi.MapType", "internal/abi.SwissMapType", "internal/abi.OldMapType"},
+	reflect.Pointer:   {"internal/abi.PtrType"},
+	reflect.Slice:     {"internal/abi.SliceType"},
+	reflect.Struct:    {"internal/abi.StructType"},
+}
+
+// Method is a method of a type, as described by the runtime type
+// information of the target.
+type Method struct {
+	Name string
+	// Type is the type of the method, without the receiver, nil if it could
+	// not be determined.
+	Type godwarf.Type
+	// Fn is the function implementing the method, nil if it was removed by
+	// the linker.
+	Fn *Function
+}
+
+// MethodSet returns the method set of the type of v. If v is an interface
+// the method set of its concrete type is returned.
+// The method set is read from the runtime type information of the target,
+// only methods that were not removed by the linker have a non-nil Fn.
+func (scope *EvalScope) MethodSet(v *Variable) ([]Method, error) {
+	if v.RealType == nil {
+		return nil, fmt.Errorf("%s has no type", v.Name)
+	}
+	bi := scope.BinInfo
+	mem := DereferenceMemory(scope.Mem)
+	var typeAddr uint64
+	if _, isiface := v.RealType.(*godwarf.InterfaceType); isiface {
+		_type, _, isnil := v.readInterface()
+		if v.Unreadable != nil {
+			return nil, v.Unreadable
+		}
+		if isnil || _type == nil {
+			return nil, fmt.Errorf("%s is nil", v.Name)
+		}
+		_type = _type.maybeDereference()
+		if _type.Unreadable != nil {
+			return nil, _type.Unreadable
+		}
+		typeAddr = _type.Addr
+	} else {
+		var found bool
+		var err error
+		typeAddr, _, found, err = runtimeTypeOf(bi, mem, v.DwarfType)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("could not find runtime type for %s", v.DwarfType)
+		}
+	}
+	mds, err := bi.getModuleData(mem)
+	if err != nil {
+		return nil, err
+	}
+	return runtimeTypeMethods(bi, mem, mds, typeAddr)
+}
+
+// runtimeTypeMethods returns the methods listed in the uncommon type of
+// the runtime type at typeAddr.
+func runtimeTypeMethods(bi *BinaryInfo, mem MemoryReadWriter, mds []ModuleData, typeAddr uint64) ([]Method, error) {
+	md := findModuleDataForType(mds, typeAddr)
+	if md == nil {
+		return nil, fmt.Errorf("could not find module data for type at %#x", typeAddr)
+	}
+	rtyp, err := bi.findType(bi.runtimeTypeTypename())
+	if err != nil {
+		return nil, err
+	}
+	_type := newVariable("", typeAddr, rtyp, bi, mem)
+	tflagv := _type.loadFieldNamed("TFlag")
+	if tflagv == nil || tflagv.Value == nil {
+		return nil, errors.New("unreadable runtime type (no TFlag field)")
+	}
+	if tflag, _ := constant.Int64Val(tflagv.Value); tflag&tflagUncommon == 0 {
+		// types without a name and without methods have no uncommon type
+		return nil, nil
+	}
+	kindv := _type.loadFieldNamed("Kind_")
+	if kindv == nil || kindv.Value == nil {
+		return nil, errors.New("unreadable runtime type (no Kind_ field)")
+	}
+	kind, _ := constant.Int64Val(kindv.Value)
+
+	// the uncommon type follows the struct describing the kind of the type
+	kindTyp := rtyp
+	if names := runtimeKindTypes[reflect.Kind(kind&kindMask)]; len(names) > 0 {
+		kindTyp = nil
+		for _, name := range names {
+			if kindTyp, err = bi.findType(name); err == nil {
+				break
+			}
+		}
+		if kindTyp == nil {
+			return nil, fmt.Errorf("could not find runtime type for kind %s", reflect.Kind(kind&kindMask))
+		}
+	}
+	uncommonTyp, err := bi.findType("internal/abi.UncommonType")
+	if err != nil {
+		return nil, err
+	}
+	methodTyp, err := bi.findType("internal/abi.Method")
+	if err != nil {
+		return nil, err
+	}
+	uncommon := newVariable("", typeAddr+uint64(kindTyp.Size()), uncommonTyp, bi, mem)
+	mcountv := uncommon.loadFieldNamed("Mcount")
+	moffv := uncommon.loadFieldNamed("Moff")
+	if mcountv == nil || moffv == nil {
+		return nil, errors.New("unreadable uncommon type")
+	}
+	mcount, _ := constant.Int64Val(mcountv.Value)
+	moff, _ := constant.Int64Val(moffv.Value)
+
+	methods := make([]Method, 0, mcount)
+	for i := int64(0); i < mcount; i++ {
+		mv := newVariable("", uncommon.Addr+uint64(moff+i*methodTyp.Size()), methodTyp, bi, mem)
+		mv.loadValue(loadFullValue)
+		if mv.Unreadable != nil {
+			return nil, mv.Unreadable
+		}
+		nameOff, _ := constant.Int64Val(mv.fieldVariable("Name").Value)
+		mtypOff, _ := constant.Int64Val(mv.fieldVariable("Mtyp").Value)
+		ifnOff, _ := constant.Int64Val(mv.fieldVariable("Ifn").Value)
+		name, err := readRuntimeName(mem, md.types+uint64(nameOff))
+		if err != nil {
+			return nil, err
+		}
+		m := Method{Name: name}
+		if ifnOff != -1 {
+			m.Fn = bi.PCToFunc(md.text + uint64(ifnOff))
+		}
+		if mtypOff != -1 {
+			m.Type, _, _ = RuntimeTypeToDIE(newVariable("", md.types+uint64(mtypOff), rtyp, bi, mem), 0, mds)
+		}
+		if m.Type == nil && m.Fn != nil {
+			if typ, err := m.Fn.fakeType(bi, true); err == nil {
+				m.Type = typ
+			}
+		}
+		methods = append(methods, m)
+	}
+	return methods, nil
+}
+
+// readRuntimeName reads the internal/abi.Name at addr.
+// A name is encoded as a byte of flags followed by the length of the name,
+// as a varint, and by its bytes.
+func readRuntimeName(mem MemoryReadWriter, addr uint64) (string, error) {
+	var buf [1 + binary.MaxVarintLen16]byte
+	if _, err := mem.ReadMemory(buf[:], addr); err != nil {
+		return "", err
+	}
+	n, sz := binary.Uvarint(buf[1:])
+	if sz <= 0 {
+		return "", fmt.Errorf("invalid runtime name at %#x", addr)
+	}
+	name := make([]byte, n)
+	if _, err := mem.ReadMemory(name, addr+1+uint64(sz)); err != nil {
+		return "", err
+	}
+	return string(name), nil
+}
+
+// itabMethod returns the function that implements method mname for the
+// non-empty interface v, read from the method table of its itab, with the
+// concrete value of v appended as its receiver.
+// If the method can not be found in the itab nil is returned.
+func (v *Variable) itabMethod(mname string) (*Variable, error) {
+	ityp, isstruct := godwarf.ResolveTypedef(&v.RealType.(*godwarf.InterfaceType).TypedefType).(*godwarf.StructType)
+	if !isstruct {
+		return nil, nil
+	}
+	var tab *Variable
+	for _, f := range ityp.Field {
+		if f.Name == "tab" {
+			tab, _ = v.toField(f) // +rtype *itab|*internal/abi.ITab
+		}
+	}
+	if tab == nil {
+		// empty interfaces do not have an itab
+		return nil, nil
+	}
+	tab = tab.maybeDereference()
+	if tab.Unreadable != nil || tab.Addr == 0 {
+		return nil, nil
+	}
+	inter, err := tab.structMember("Inter")
+	if err != nil {
+		inter, err = tab.structMember("inter")
+		if err != nil {
+			return nil, nil
+		}
+	}
+	fun, err := tab.structMember("Fun")
+	if err != nil {
+		fun, err = tab.structMember("fun")
+		if err != nil {
+			return nil, nil
+		}
+	}
+	inter = inter.maybeDereference()
+	imethods, err := inter.structMember("Methods")
+	if err != nil {
+		return nil, nil
+	}
+
+	mem := DereferenceMemory(v.mem)
+	mds, err := v.bi.getModuleData(mem)
+	if err != nil {
+		return nil, err
+	}
+	md := findModuleDataForType(mds, inter.Addr)
+	if md == nil {
+		return nil, nil
+	}
+	imethods.loadValue(LoadConfig{MaxArrayValues: int(imethods.Len), MaxStructFields: -1, MaxVariableRecurse: 1})
+	if imethods.Unreadable != nil {
+		return nil, imethods.Unreadable
+	}
+	idx := -1
+	for i := range imethods.Children {
+		nameOff, _ := constant.Int64Val(imethods.Children[i].fieldVariable("Name").Value)
+		name, err := readRuntimeName(mem, md.types+uint64(nameOff))
+		if err != nil {
+			return nil, err
+		}
+		if name == mname {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, nil
+	}
+
+	ptrSize := int64(v.bi.Arch.PtrSize())
+	pc, err := readUintRaw(mem, fun.Addr+uint64(int64(idx)*ptrSize), ptrSize)
+	if err != nil {
+		return nil, err
+	}
+	fn := v.bi.PCToFunc(pc)
+	if fn == nil {
+		return nil, fmt.Errorf("could not find function for method %s at %#x", mname, pc)
+	}
+	if fn.Name == "runtime.unreachableMethod" {
+		// the linker removed the method because it is never called through
+		// an interface, let the caller find it by name.
+		return nil, nil
+	}
+	r, err := functionToVariable(fn, v.bi, v.mem)
+	if err != nil {
+		// the function could be an autogenerated wrapper without enough debug
+		// information to call it, let the caller find the method by name.
+		return nil, nil
+	}
+
+	// The functions in the itab of a type that is not stored directly in
+	// the interface are wrappers with a pointer receiver, the data word of
+	// the interface is the pointer.
+	v.loadInterface(0, false, loadFullValue)
+	if v.Unreadable != nil {
+		return nil, v.Unreadable
+	}
+	recv := &v.Children[0]
+	if recv.Kind != reflect.Ptr && strings.Contains(fn.Name, ".(*") {
+		recv = recv.pointerToVariable()
+	}
+	r.Children = append(r.Children, *recv)
+	return r, nil
+}
@@ -1,12 +1,15 @@
 package proc
 
 import (
+	"go/constant"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 	"unsafe"
 
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
 	protest "github.com/go-delve/delve/pkg/proc/test"
 )
 
@@ -190,3 +193,33 @@ func TestGenericFunctionParser(t *testing.T) {
 		}
 	}
 }
+
+func TestConvenienceVariablesRestore(t *testing.T) {
+	// Snapshots of plain values keep their type across a restart, snapshots
+	// referencing the memory of the old target are dropped.
+	typ := &godwarf.UintType{BasicType: godwarf.BasicType{CommonType: godwarf.CommonType{ByteSize: 1, Name: "uint8", ReflectKind: reflect.Uint8}}}
+	old := NewConvenienceVariables()
+	old.vars["b"] = &convVar{v: &Variable{Name: "$b", Kind: reflect.Uint8, DwarfType: typ, RealType: typ, Value: constant.MakeUint64(200), loaded: true}}
+	old.vars["p"] = &convVar{v: &Variable{Name: "$p", Kind: reflect.Ptr, Addr: 0x1000, loaded: true}}
+	assertNoError(old.SetLive("l", "a + 1"), t, "SetLive")
+
+	cv := NewConvenienceVariables()
+	cv.restore(old)
+
+	b, ok := cv.get("b")
+	if !ok {
+		t.Fatal("$b not restored")
+	}
+	if b.v.Kind != reflect.Uint8 || b.v.DwarfType != typ || b.v.RealType != typ {
+		t.Errorf("$b lost its type: %v %v", b.v.Kind, b.v.DwarfType)
+	}
+	if n, _ := constant.Uint64Val(b.v.Value); n != 200 || b.v.Name != "$b" {
+		t.Errorf("wrong $b: %s = %v", b.v.Name, b.v.Value)
+	}
+	if _, ok := cv.get("p"); ok {
+		t.Error("$p restored")
+	}
+	if l, ok := cv.get("l"); !ok || l.expr != "a + 1" {
+		t.Error("$l not restored")
+	}
+}
@@ -39,7 +39,7 @@ import (
 	"github.com/go-delve/delve/service/api"
 )
 
-var normalLoadConfig = proc.LoadConfig{true, 1, 64, 64, -1, 0}
+var normalLoadConfig = proc.LoadConfig{true, 1, 64, 64, -1, 0, nil}
 var testBackend, buildMode string
 
 func init() {
@@ -1778,6 +1778,32 @@ func TestPanicBreakpoint(t *testing.T) {
 	})
 }
 
+func TestPanicCondBreakpoint(t *testing.T) {
+	// Conditions of breakpoints on runtime.gopanic can compare the panic
+	// value, an interface, with a constant directly. The panic value is stored in registers
+	// at the entry point of runtime.gopanic, this also checks that the
+	// dynamic type of an interface stored in registers can be loaded.
+	protest.AllowRecording(t)
+	withTestProcess("panicfilters", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		bp := setFunctionBreakpoint(p, t, "runtime.gopanic")
+		cond, err := parser.ParseExpr(`e == "third"`)
+		assertNoError(err, t, "ParseExpr")
+		bp.UserBreaklet().Cond = cond
+
+		assertNoError(grp.Continue(), t, "Continue()")
+		e := evalVariable(p, t, "e")
+		if len(e.Children) != 1 || e.Children[0].Kind != reflect.String || constant.StringVal(e.Children[0].Value) != "third" {
+			t.Fatalf("stopped on the wrong panic: %v", e)
+		}
+
+		// The same comparison works outside of breakpoint conditions.
+		v := evalVariable(p, t, `e == "third"`)
+		if v.Kind != reflect.Bool || !constant.BoolVal(v.Value) {
+			t.Fatalf("e == \"third\" is %v", v)
+		}
+	})
+}
+
 func TestCmdLineArgs(t *testing.T) {
 	expectSuccess := func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
 		err := grp.Continue()
@@ -2427,7 +2453,7 @@ func BenchmarkTrace(b *testing.B) {
 			assertNoError(grp.Continue(), b, "Continue()")
 			s, err := proc.GoroutineScope(p, p.CurrentThread())
 			assertNoError(err, b, "Scope()")
-			_, err = s.FunctionArguments(proc.LoadConfig{false, 0, 64, 0, 3, 0})
+			_, err = s.FunctionArguments(proc.LoadConfig{false, 0, 64, 0, 3, 0, nil})
 			assertNoError(err, b, "FunctionArguments()")
 		}
 		b.StopTimer()
@@ -4533,17 +4559,18 @@ func TestDump(t *testing.T) {
 		return fmt.Sprintf("currentPC=%#x callPC=%#x frameOff=%#x\n", frame.Current.PC, frame.Call.PC, frame.FrameOffset())
 	}
 
-	makeDump := func(p *proc.Target, corePath, exePath string, flags proc.DumpFlags) *proc.Target {
+	makeDump := func(p *proc.Target, corePath, exePath string, opts proc.DumpOptions) *proc.Target {
 		fh, err := os.Create(corePath)
 		assertNoError(err, t, "Create()")
 		var state proc.DumpState
-		p.Dump(fh, flags, &state)
+		p.Dump(fh, opts, &state)
 		assertNoError(state.Err, t, "Dump()")
 		if state.ThreadsDone != state.ThreadsTotal || state.MemDone != state.MemTotal || !state.AllDone || state.Dumping || state.Canceled {
 			t.Fatalf("bad DumpState %#v", &state)
 		}
 		c, err := core.OpenCore(corePath, exePath, nil)
 		assertNoError(err, t, "OpenCore()")
+		t.Cleanup(func() { c.Detach(false) })
 		return c.Selected
 	}
 
@@ -4630,7 +4657,7 @@ func TestDump(t *testing.T) {
 
 		t.Logf("testing normal dump")
 
-		c := makeDump(p, corePath, fixture.Path, 0)
+		c := makeDump(p, corePath, fixture.Path, proc.DumpOptions{})
 		defer os.Remove(corePath)
 		testDump(p, c)
 
@@ -4638,10 +4665,21 @@ func TestDump(t *testing.T) {
 			// No reason to do this test on other goos/goarch because they use the
 			// platform-independent format anyway.
 			t.Logf("testing platform-independent dump")
-			c2 := makeDump(p, corePathPlatIndep, fixture.Path, proc.DumpPlatformIndependent)
+			c2 := makeDump(p, corePathPlatIndep, fixture.Path, proc.DumpOptions{Flags: proc.DumpPlatformIndependent})
 			defer os.Remove(corePathPlatIndep)
 			testDump(p, c2)
 		}
+
+		for _, opts := range []proc.DumpOptions{
+			{Flags: proc.DumpExcludeFileBacked, Compression: proc.DumpGzip},
+			{Flags: proc.DumpReachableOnly, Compression: proc.DumpZstd},
+		} {
+			t.Logf("testing dump with options %#v", opts)
+			corePathOpts := filepath.Join(fixture.BuildDir, "coredump-opts")
+			c3 := makeDump(p, corePathOpts, fixture.Path, opts)
+			defer os.Remove(corePathOpts)
+			testDump(p, c3)
+		}
 	})
 }
 
@@ -5745,3 +5783,366 @@ func TestChainedBreakpoint(t *testing.T) {
 		}
 	})
 }
+
+func TestStatementPositions(t *testing.T) {
+	withTestProcess("breakpointlocations", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		positions := p.BinInfo().StatementPositions(fixture.Source, 10, 12)
+		var got []string
+		for _, pos := range positions {
+			if len(pos.PCs) == 0 {
+				t.Errorf("no PCs for %s at line %d", pos.Fn.Name, pos.Line)
+			}
+			got = append(got, fmt.Sprintf("%d:%d %s", pos.Line, pos.Column, pos.Fn.Name))
+		}
+		sort.Strings(got)
+		want := []string{"10:0 main.main", "10:0 main.main.func1", "10:0 main.main.func2", "11:0 main.main"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestSetPCToLine(t *testing.T) {
+	withTestProcess("jump", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertJumpError := func(line int, want string) {
+			t.Helper()
+			err := p.SetPCToLine(nil, fixture.Source, line)
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("jump to line %d: got %v, want error containing %q", line, err, want)
+			}
+		}
+
+		// Skip the assignments on lines 7 and 8.
+		bp := setFileBreakpoint(p, t, fixture.Source, 7)
+		assertNoError(grp.Continue(), t, "Continue")
+		assertLineNumber(p, t, 7, "Continue")
+		assertJumpError(21, "is not in the current function main.skip")
+		assertNoError(p.SetPCToLine(nil, fixture.Source, 9), t, "SetPCToLine")
+		assertLineNumber(p, t, 9, "SetPCToLine")
+		if x, _ := constant.Int64Val(evalVariable(p, t, "x").Value); x != 1 {
+			t.Errorf("x = %d, want 1", x)
+		}
+		assertNoError(grp.Next(), t, "Next")
+		assertLineNumberIn(p, t, []int{10, 20}, "Next")
+		assertNoError(p.ClearBreakpoint(bp.Addr), t, "ClearBreakpoint")
+
+		// Closure boundaries.
+		setFileBreakpoint(p, t, fixture.Source, 22)
+		assertNoError(grp.Continue(), t, "Continue")
+		assertLineNumber(p, t, 22, "Continue")
+		assertJumpError(24, "can not jump out of closure main.main.func1")
+		assertNoError(grp.StepOut(), t, "StepOut")
+		assertJumpError(22, "can not jump into closure main.main.func1")
+
+		// Defer statements.
+		setFileBreakpoint(p, t, fixture.Source, 16)
+		assertNoError(grp.Continue(), t, "Continue")
+		assertLineNumber(p, t, 16, "Continue")
+		assertJumpError(13, "across the defer statement")
+	})
+
+	// Open-coded defers, which are only used in optimized builds.
+	withTestProcessArgs("jump", t, ".", []string{}, protest.EnableOptimization, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertDeferError := func(line int) {
+			t.Helper()
+			err := p.SetPCToLine(nil, fixture.Source, line)
+			if err == nil || !strings.Contains(err.Error(), "across the defer statement at "+fixture.Source+":31") {
+				t.Errorf("jump to line %d: got %v, want error about the defer statement at line 31", line, err)
+			}
+		}
+
+		// The defer statement has not executed yet and can not be skipped.
+		setFileBreakpoint(p, t, fixture.Source, 30)
+		assertNoError(grp.Continue(), t, "Continue")
+		assertLineNumber(p, t, 30, "Continue")
+		assertDeferError(32)
+
+		// The defer statement has executed and can not be repeated.
+		setFileBreakpoint(p, t, fixture.Source, 32)
+		assertNoError(grp.Continue(), t, "Continue")
+		assertLineNumber(p, t, 32, "Continue")
+		assertDeferError(30)
+	})
+}
+
+func TestStepInTarget(t *testing.T) {
+	withTestProcess("stepintargets", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		targetNames := func() []string {
+			t.Helper()
+			targets, err := p.StepInTargets(p.SelectedGoroutine())
+			assertNoError(err, t, "StepInTargets")
+			var names []string
+			for _, target := range targets {
+				names = append(names, target.Fn.Name)
+			}
+			return names
+		}
+		stepInTarget := func(name string) {
+			t.Helper()
+			targets, err := p.StepInTargets(p.SelectedGoroutine())
+			assertNoError(err, t, "StepInTargets")
+			for _, target := range targets {
+				if target.Fn.Name == name {
+					assertNoError(grp.StepInTarget(target.PC), t, "StepInTarget")
+					return
+				}
+			}
+			t.Fatalf("no target calling %s", name)
+		}
+
+		setFileBreakpoint(p, t, fixture.Source, 19)
+		assertNoError(grp.Continue(), t, "Continue")
+		assertLineNumber(p, t, 19, "Continue")
+		if names := targetNames(); !reflect.DeepEqual(names, []string{"main.g", "main.h", "main.f"}) {
+			t.Fatalf("got %v, want [main.g main.h main.f]", names)
+		}
+
+		stepInTarget("main.h")
+		assertFunctionName(p, t, "main.h", "StepInTarget")
+
+		// Calls already executed are not targets anymore.
+		assertNoError(grp.StepOut(), t, "StepOut")
+		assertLineNumber(p, t, 19, "StepOut")
+		if names := targetNames(); !reflect.DeepEqual(names, []string{"main.f"}) {
+			t.Fatalf("got %v, want [main.f]", names)
+		}
+		stepInTarget("main.f")
+		assertFunctionName(p, t, "main.f", "StepInTarget")
+
+		err := grp.StepInTarget(0)
+		if err == nil {
+			t.Errorf("StepInTarget(0) did not return an error")
+		}
+	})
+}
+
+func TestRestartFrame(t *testing.T) {
+	if testBackend == "rr" {
+		t.Skip("restarting frames of recordings is tested in gdbserial")
+	}
+	withTestProcess("restartframe", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertIntVar := func(name string, want int64) {
+			t.Helper()
+			if v, _ := constant.Int64Val(evalVariable(p, t, name).Value); v != want {
+				t.Errorf("%s = %d, want %d", name, v, want)
+			}
+		}
+
+		// No statement of add has been executed yet.
+		bp := setFunctionBreakpoint(p, t, "main.add")
+		assertNoError(grp.Continue(), t, "Continue")
+		assertNoError(grp.RestartFrame(0, false), t, "RestartFrame")
+		assertFunctionName(p, t, "main.add", "RestartFrame")
+		assertIntVar("a", 1)
+		assertIntVar("b", 2)
+		assertNoError(grp.Continue(), t, "Continue")
+		assertFunctionName(p, t, "main.add", "Continue")
+		assertNoError(p.ClearBreakpoint(bp.Addr), t, "ClearBreakpoint")
+
+		// The arguments keep their current value.
+		setFileBreakpoint(p, t, fixture.Source, 8)
+		assertNoError(grp.Continue(), t, "Continue")
+		assertLineNumber(p, t, 8, "Continue")
+		if err := grp.RestartFrame(0, false); !errors.Is(err, proc.ErrRestartFrameSideEffects) {
+			t.Fatalf("RestartFrame: got %v, want %v", err, proc.ErrRestartFrameSideEffects)
+		}
+		assertNoError(grp.RestartFrame(0, true), t, "RestartFrame")
+		assertIntVar("a", 10)
+		assertNoError(grp.Continue(), t, "Continue")
+		assertLineNumber(p, t, 8, "Continue")
+		assertIntVar("c", 12)
+
+		// Restart the caller.
+		assertNoError(grp.RestartFrame(1, true), t, "RestartFrame")
+		assertFunctionName(p, t, "main.outer", "RestartFrame")
+		assertIntVar("x", 1)
+		assertNoError(grp.Continue(), t, "Continue")
+		assertLineNumber(p, t, 8, "Continue")
+		assertIntVar("c", 3)
+	})
+}
+
+func TestCompletions(t *testing.T) {
+	withTestProcess("completions", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Continue(), t, "Continue()")
+		scope, err := proc.GoroutineScope(p, p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope()")
+
+		names := func(operand, prefix string) []string {
+			t.Helper()
+			completions, err := scope.Completions(operand, prefix)
+			assertNoError(err, t, fmt.Sprintf("Completions(%q, %q)", operand, prefix))
+			r := []string{}
+			for _, c := range completions {
+				r = append(r, c.Name)
+			}
+			return r
+		}
+
+		for _, tc := range []struct {
+			operand, prefix string
+			tgt             []string
+		}{
+			{"", "item", []string{"item", "itemCount", "itemTotal"}},
+			{"", "runt", []string{"runtime"}},
+			{"item", "", []string{"Base", "Count", "ID", "Identify", "Label", "Name"}},
+			{"item", "I", []string{"ID", "Identify"}},
+			{"boxed", "", []string{"Base", "Count", "ID", "Identify", "Label", "Name"}},
+			{"item.Base", "", []string{"ID", "Identify"}},
+			{"main", "It", []string{"Item"}},
+			{"fmt", "Sprintf", []string{"Sprintf"}},
+		} {
+			if got := names(tc.operand, tc.prefix); !reflect.DeepEqual(got, tc.tgt) {
+				t.Errorf("Completions(%q, %q): got %v, want %v", tc.operand, tc.prefix, got, tc.tgt)
+			}
+		}
+
+		_, err = scope.Completions("nonexistent", "")
+		if err == nil {
+			t.Errorf("Completions(\"nonexistent\", \"\"): expected error")
+		}
+	})
+}
+
+func TestCancelOperations(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testvariables2", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Continue(), t, "Continue()")
+
+		canceled := make(chan struct{})
+		close(canceled)
+
+		p.ClearCaches()
+		p.SetCancel(canceled)
+		gs, next, err := proc.GoroutinesInfo(p, 0, 0)
+		if !errors.Is(err, proc.ErrCanceled) || len(gs) != 0 || next != 0 {
+			t.Errorf("GoroutinesInfo: got %d goroutines, next %d, error %v", len(gs), next, err)
+		}
+		_, err = proc.ThreadStacktrace(p, p.CurrentThread(), 10)
+		if !errors.Is(err, proc.ErrCanceled) {
+			t.Errorf("ThreadStacktrace: got error %v", err)
+		}
+		p.SetCancel(nil)
+
+		gs, _, err = proc.GoroutinesInfo(p, 0, 0)
+		assertNoError(err, t, "GoroutinesInfo()")
+		if len(gs) == 0 {
+			t.Errorf("GoroutinesInfo: no goroutines")
+		}
+
+		scope, err := proc.GoroutineScope(p, p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope()")
+		cfg := pnormalLoadConfig
+		cfg.Cancel = canceled
+		v, err := scope.EvalExpression("m1", cfg)
+		assertNoError(err, t, "EvalExpression(m1)")
+		if v.Flags&proc.VariableLoadCanceled == 0 || !v.OnlyAddr || len(v.Children) != 0 {
+			t.Errorf("m1: got flags %#x, OnlyAddr %v, %d children", v.Flags, v.OnlyAddr, len(v.Children))
+		}
+		v = evalVariable(p, t, "m1")
+		if v.Flags&proc.VariableLoadCanceled != 0 || len(v.Children) == 0 {
+			t.Errorf("m1: got flags %#x, %d children", v.Flags, len(v.Children))
+		}
+	})
+}
+
+func TestObjectReferences(t *testing.T) {
+	if (runtime.GOOS == "darwin" && testBackend == "native") || (runtime.GOOS == "windows" && runtime.GOARCH != "amd64") {
+		t.Skip("dump not supported")
+	}
+	skipOn(t, "not implemented", "ppc64le")
+	protest.AllowRecording(t)
+
+	refs := func(p *proc.Target, expr string) []string {
+		t.Helper()
+		// frame 0 of goroutine 1 is main.stop, the variables of main.main are
+		// only live if they are used after the call.
+		scope, err := proc.ConvertEvalScope(p, 1, 1, 0)
+		assertNoError(err, t, "ConvertEvalScope()")
+		v, err := scope.EvalExpression(expr, proc.LoadConfig{})
+		assertNoError(err, t, "EvalExpression("+expr+")")
+		addr, err := proc.ReferencedObjectAddress(v)
+		assertNoError(err, t, "ReferencedObjectAddress("+expr+")")
+		obj, refs, err := proc.ObjectReferences(p, addr)
+		assertNoError(err, t, "ObjectReferences("+expr+")")
+		if obj.Size <= 0 {
+			t.Errorf("%s: wrong object size %d", expr, obj.Size)
+		}
+		r := make([]string, 0, len(refs))
+		for _, ref := range refs {
+			if ref.Reachable {
+				r = append(r, strings.Join(ref.Path, " -> "))
+			}
+		}
+		t.Logf("%s: %q", expr, r)
+		return r
+	}
+
+	check := func(p *proc.Target) {
+		t.Helper()
+		r := refs(p, "conn")
+		for _, tgt := range []string{
+			"main.cache -> map bucket -> *main.Session.conn",
+			"finalizer -> *main.resource.conn",
+			"conn in main.main (goroutine 1 frame 1)",
+		} {
+			if !slices.Contains(r, tgt) {
+				t.Errorf("reference %q not found", tgt)
+			}
+		}
+		if len(r) != 3 {
+			t.Errorf("expected 3 references to conn, got %d", len(r))
+		}
+		// dead is not used after the call to main.stop
+		if r := refs(p, "dead"); len(r) != 0 {
+			t.Errorf("expected no references to dead, got %q", r)
+		}
+	}
+
+	withTestProcess("heaprefs", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Continue(), t, "Continue()")
+		check(p)
+
+		corePath := filepath.Join(t.TempDir(), "core")
+		fh, err := os.Create(corePath)
+		assertNoError(err, t, "Create()")
+		var state proc.DumpState
+		p.Dump(fh, proc.DumpOptions{}, &state)
+		assertNoError(state.Err, t, "Dump()")
+		c, err := core.OpenCore(corePath, fixture.Path, nil)
+		assertNoError(err, t, "OpenCore()")
+		check(c.Selected)
+	})
+}
+
+func TestHeapObjects(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("heapobjs", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Continue(), t, "Continue()")
+		objs, err := proc.HeapObjects(p)
+		assertNoError(err, t, "HeapObjects()")
+
+		count := map[string]int{}
+		for i, obj := range objs {
+			if i > 0 && obj.Addr <= objs[i-1].Addr {
+				t.Fatalf("objects not sorted: %#x after %#x", obj.Addr, objs[i-1].Addr)
+			}
+			if obj.Type != nil {
+				count[obj.Type.String()]++
+			}
+		}
+		t.Logf("%d objects, %d types", len(objs), len(count))
+		for _, tc := range []struct {
+			typ string
+			min int
+		}{
+			{"main.Session", 110}, // reachable from a global and from a local variable
+			{"main.Conn", 100},    // has a malloc header
+			{"main.Big", 1},       // large object
+		} {
+			if count[tc.typ] < tc.min {
+				t.Errorf("expected at least %d objects of type %s, found %d", tc.min, tc.typ, count[tc.typ])
+			}
+		}
+	})
+}
@@ -0,0 +1,280 @@
+package proc
+
+import (
+	"fmt"
+	"go/constant"
+	"reflect"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+)
+
+// ObjectReference is a pointer to a heap object.
+type ObjectReference struct {
+	// Addr is the address of the pointer, zero if the pointer is not stored
+	// in memory (for example if it is a local variable stored in a register).
+	Addr uint64
+	// Path is the retention path of the pointer: the first element describes
+	// the root of the path (a global variable, a local variable of a
+	// goroutine or a finalizer), the following elements describe the heap
+	// objects that have to be traversed to reach the pointer, the last
+	// element describes the value containing the pointer.
+	// Each element is the name of a variable, or the type of a heap object,
+	// followed by the path of the field containing the pointer to the next
+	// element.
+	Path []string
+	// Reachable is true if the first element of Path is a root. Pointers
+	// stored in objects that can only be reached by passing through the
+	// referenced object, or that can not be reached at all, do not keep it
+	// alive.
+	Reachable bool
+}
+
+// heapEdge is a pointer from a root or a heap object to a heap object.
+type heapEdge struct {
+	from int   // index of the object containing the pointer, or -1-i for root i
+	off  int64 // offset of the pointer in the object or root
+}
+
+// ObjectReferences returns the heap object containing addr and the list of
+// pointers to it.
+//
+// The roots of the garbage collector (global variables, live local
+// variables of goroutines and finalizers, see heapScanner.scanRoots) and
+// the heap objects reachable from them are scanned for pointers to the
+// object, following the shortest path from a root to each object. The
+// objects that can not be reached this way are scanned afterwards.
+// Pointers contained in heap objects whose type is not known are found by
+// scanning their memory conservatively.
+//
+// References from roots are returned first, followed by references from
+// other reachable objects, in order of the length of their retention path,
+// and by references from objects that are not reachable.
+//
+// If the scan is interrupted using SetCancel ErrCanceled is returned.
+func ObjectReferences(t *Target, addr uint64) (HeapObject, []ObjectReference, error) {
+	h, err := newHeapScanner(t)
+	if err != nil {
+		return HeapObject{}, nil, err
+	}
+	target := h.findObject(addr)
+	if target < 0 {
+		return HeapObject{}, nil, fmt.Errorf("%#x is not the address of a heap object", addr)
+	}
+	if err := h.scanRoots(); err != nil {
+		return HeapObject{}, nil, err
+	}
+	if err := h.propagateTypes(); err != nil {
+		return HeapObject{}, nil, err
+	}
+	obj := h.objs[target]
+	refs, err := h.references(target)
+	if err != nil {
+		return HeapObject{}, nil, err
+	}
+	return HeapObject{Addr: obj.base + obj.hdr, Size: int64(obj.size), Type: obj.typ}, refs, nil
+}
+
+// ReferencedObjectAddress returns the address of the object referenced by
+// v: the value of v if it is a pointer, a map, a channel or a function, the
+// value of v interpreted as an address if it is an integer and the address
+// of v otherwise.
+func ReferencedObjectAddress(v *Variable) (uint64, error) {
+	if v.Unreadable != nil {
+		return 0, v.Unreadable
+	}
+	switch v.Kind {
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Map, reflect.Chan, reflect.Func:
+		return v.pointerShapedValue()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Value != nil {
+			if addr, exact := constant.Uint64Val(v.Value); exact {
+				return addr, nil
+			}
+		}
+	}
+	if v.Addr == 0 || v.Flags&VariableFakeAddress != 0 {
+		return 0, fmt.Errorf("%s does not have an address", v.Name)
+	}
+	return v.Addr, nil
+}
+
+// references returns the pointers to object target.
+func (h *heapScanner) references(target int) ([]ObjectReference, error) {
+	tobj := h.objs[target]
+	intarget := func(p uint64) bool {
+		return p >= tobj.base && p < tobj.base+tobj.size
+	}
+
+	// breadth first search from the roots, recording the edge used to reach
+	// each object.
+	parent := make([]heapEdge, len(h.objs))
+	visited := make([]bool, len(h.objs))
+	var queue []int
+	var edges []heapEdge
+	visit := func(from int, off int64, p uint64) {
+		if intarget(p) && from != target && (from >= 0 || !h.roots[-1-from].finalizer) {
+			edges = append(edges, heapEdge{from, off})
+		}
+		j := h.findObject(p)
+		if j < 0 || visited[j] {
+			return
+		}
+		visited[j] = true
+		parent[j] = heapEdge{from, off}
+		queue = append(queue, j)
+	}
+	for i := range h.roots {
+		h.rootPointers(&h.roots[i], func(off int64, p uint64, _ godwarf.Type) {
+			visit(-1-i, off, p)
+		})
+	}
+	for n := 0; n < len(queue); n++ {
+		if n%4096 == 0 && h.t.canceled() {
+			return nil, ErrCanceled
+		}
+		i := queue[n]
+		if i == target {
+			// objects reachable only through the target do not retain it
+			continue
+		}
+		h.objectPointers(i, func(off int64, p uint64, _ godwarf.Type) {
+			visit(i, off, p)
+		})
+	}
+
+	for i := range h.objs {
+		if i%4096 == 0 && h.t.canceled() {
+			return nil, ErrCanceled
+		}
+		if visited[i] || i == target {
+			continue
+		}
+		h.objectPointers(i, func(off int64, p uint64, _ godwarf.Type) {
+			if intarget(p) {
+				edges = append(edges, heapEdge{i, off})
+			}
+		})
+	}
+
+	refs := make([]ObjectReference, 0, len(edges))
+	for _, e := range edges {
+		refs = append(refs, h.referencePath(parent, visited, e))
+	}
+	return refs, nil
+}
+
+// referencePath returns the retention path of the pointer described by e.
+func (h *heapScanner) referencePath(parent []heapEdge, visited []bool, e heapEdge) ObjectReference {
+	var ref ObjectReference
+	if e.from < 0 {
+		if root := &h.roots[-1-e.from]; root.addr != 0 {
+			ref.Addr = root.addr + uint64(e.off)
+		}
+	} else {
+		obj := &h.objs[e.from]
+		ref.Addr = obj.base + obj.hdr + uint64(e.off)
+	}
+	for {
+		if e.from < 0 {
+			root := &h.roots[-1-e.from]
+			ref.Path = append(ref.Path, root.name+h.fieldPath(root.typ, e.off))
+			ref.Reachable = true
+			break
+		}
+		elem := h.objectPathElem(&h.objs[e.from], e.off)
+		if n := len(ref.Path); n == 0 || elem != mapBucketPathElem || ref.Path[n-1] != elem {
+			ref.Path = append(ref.Path, elem)
+		}
+		if !visited[e.from] {
+			break
+		}
+		e = parent[e.from]
+	}
+	for i, j := 0, len(ref.Path)-1; i < j; i, j = i+1, j-1 {
+		ref.Path[i], ref.Path[j] = ref.Path[j], ref.Path[i]
+	}
+	return ref
+}
+
+// mapBucketPathElem is the description used in retention paths for the
+// objects used internally by the implementation of maps.
+const mapBucketPathElem = "map bucket"
+
+// objectPathElem returns the description of the pointer at offset off of
+// obj, used in retention paths.
+func (h *heapScanner) objectPathElem(obj *heapObject, off int64) string {
+	switch {
+	case obj.typ == nil:
+		return "<unknown>" + h.fieldPath(nil, off)
+	case isMapInternalType(obj.typ):
+		return mapBucketPathElem
+	}
+	if atyp, isarray := obj.typ.(*godwarf.ArrayType); isarray {
+		if isMapInternalType(atyp.Type) {
+			return mapBucketPathElem
+		}
+		return "[...]" + atyp.Type.String() + h.fieldPath(obj.typ, off)
+	}
+	return "*" + obj.typ.String() + h.fieldPath(obj.typ, off)
+}
+
+// isMapInternalType returns true if typ is one of the types used by the
+// runtime to implement maps.
+func isMapInternalType(typ godwarf.Type) bool {
+	name := typ.Common().Name
+	for _, prefix := range []string{"hash<", "bucket<", "map<", "table<", "*table<", "group<", "noalg.map."} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldPath returns the path of the field at offset off of a value of type
+// typ, for example ".a.b[2].c".
+// If typ is nil the offset is returned instead.
+func (h *heapScanner) fieldPath(typ godwarf.Type, off int64) string {
+	if typ == nil {
+		if off == 0 {
+			return ""
+		}
+		return fmt.Sprintf("+%d", off)
+	}
+	var b strings.Builder
+	for {
+		switch t := typ.(type) {
+		case *godwarf.StructType:
+			var field *godwarf.StructField
+			for _, f := range t.Field {
+				if off >= f.ByteOffset && off < f.ByteOffset+f.Type.Size() {
+					field = f
+					break
+				}
+			}
+			if field == nil {
+				return b.String()
+			}
+			b.WriteString("." + field.Name)
+			off -= field.ByteOffset
+			typ = field.Type
+		case *godwarf.ArrayType:
+			if t.Count <= 0 || t.Type.Size() <= 0 {
+				return b.String()
+			}
+			stride := t.Size() / t.Count
+			i := off / stride
+			fmt.Fprintf(&b, "[%d]", i)
+			off -= i * stride
+			typ = t.Type
+		case *godwarf.ParametricType:
+			typ = t.TypedefType.Type
+		case *godwarf.TypedefType:
+			typ = t.Type
+		case *godwarf.QualType:
+			typ = t.Type
+		default:
+			return b.String()
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/astutil"
+	"github.com/go-delve/delve/pkg/dwarf/op"
+)
+
+// ErrRestartFrameSideEffects is returned by RestartFrame when the frame can
+// be restarted on a live target but some of its code has already been
+// executed, and would be executed a second time.
+var ErrRestartFrameSideEffects = errors.New("some of the code of the frame has already been executed")
+
+// RestartFrame moves the selected goroutine back to the entry point of the
+// function of its frame-th stack frame, discarding the frames above it.
+//
+// On recordings this is done by rewinding execution to the call instruction
+// that created the frame and then stepping into it, so that the state of
+// the program is exactly the one it had when the function was called.
+//
+// On live targets the stack pointer and program counter of the goroutine
+// are reset to the values they had at the entry point of the function and
+// the current values of the arguments are copied back to the locations
+// where the function expects to find them. Any other change made by the
+// code of the frame, or by the frames above it, is not undone, therefore
+// unless force is true this is only done if none of the statements of the
+// function have been executed yet, otherwise ErrRestartFrameSideEffects is
+// returned.
+func (grp *TargetGroup) RestartFrame(frame int, force bool) error {
+	if _, err := grp.Valid(); err != nil {
+		return err
+	}
+	if grp.HasSteppingBreakpoints() {
+		return errors.New("can not restart a frame while next, step or stepout is in progress")
+	}
+	if frame < 0 {
+		return fmt.Errorf("invalid frame %d", frame)
+	}
+	dbp := grp.Selected
+	selg := dbp.SelectedGoroutine()
+	thread := dbp.CurrentThread()
+	if selg != nil && selg.Thread != nil {
+		thread = selg.Thread
+	}
+
+	var frames []Stackframe
+	var err error
+	if selg == nil {
+		frames, err = ThreadStacktrace(dbp, thread, frame+1)
+	} else {
+		frames, err = GoroutineStacktrace(dbp, selg, frame+1, StacktraceReadDefers)
+	}
+	if err != nil {
+		return err
+	}
+	if frame+1 >= len(frames) {
+		return fmt.Errorf("frame %d does not have a caller", frame)
+	}
+	fr, callerfr := &frames[frame], &frames[frame+1]
+	if fr.Current.Fn == nil {
+		return &ErrNoSourceForPC{fr.Current.PC}
+	}
+	if callerfr.Current.Fn == nil {
+		return &ErrNoSourceForPC{callerfr.Current.PC}
+	}
+	if fr.Inlined {
+		return fmt.Errorf("can not restart inlined call to %s", fr.Call.Fn.Name)
+	}
+
+	if recorded, _ := grp.recman.Recorded(); recorded {
+		return grp.restartFrameReverse(thread, fr, callerfr)
+	}
+
+	if selg != nil && selg.Thread == nil {
+		return fmt.Errorf("goroutine %d is not running on a thread", selg.ID)
+	}
+	return restartFrameLive(dbp, selg, thread, frames[:frame+2], force)
+}
+
+// restartFrameReverse implements RestartFrame for recordings, the caller
+// frame is rewound to the call instruction that created fr which is then
+// executed.
+func (grp *TargetGroup) restartFrameReverse(thread Thread, fr, callerfr *Stackframe) error {
+	dbp := grp.Selected
+	callpc, err := findCallInstrForRet(dbp, dbp.Memory(), fr.Ret, callerfr.Current.Fn)
+	if err != nil {
+		return err
+	}
+
+	if err := grp.ChangeDirection(Backward); err != nil {
+		return err
+	}
+	sameGCond := sameGoroutineCondition(dbp.BinInfo(), dbp.SelectedGoroutine(), thread.ThreadID())
+	cond := astutil.And(sameGCond, frameoffCondition(callerfr))
+	if _, err := allowDuplicateBreakpoint(dbp.SetBreakpoint(0, callpc, NextBreakpoint, cond)); err != nil {
+		_ = dbp.ClearSteppingBreakpoints()
+		_ = grp.ChangeDirection(Forward)
+		return err
+	}
+	if err := grp.Continue(); err != nil {
+		return err
+	}
+
+	// Execution could have stopped before getting to the call instruction,
+	// for example because of a breakpoint, in that case the restart is
+	// abandoned.
+	_ = dbp.ClearSteppingBreakpoints()
+	if err := grp.ChangeDirection(Forward); err != nil {
+		return err
+	}
+	regs, err := dbp.CurrentThread().Registers()
+	if err != nil {
+		return err
+	}
+	if regs.PC() != callpc || dbp.StopReason != StopNextFinished {
+		return nil
+	}
+	return grp.StepInstruction(false)
+}
+
+// restartFrameLive implements RestartFrame for live targets. The frame to
+// restart is the second to last element of frames, the last one is its
+// caller.
+func restartFrameLive(dbp *Target, g *G, thread Thread, frames []Stackframe, force bool) error {
+	bi := dbp.BinInfo()
+	fr := &frames[len(frames)-2]
+	fn := fr.Current.Fn
+
+	for i := range frames[:len(frames)-1] {
+		if frames[i].SystemStack {
+			return errors.New("can not restart a frame while the goroutine is executing on the system stack")
+		}
+		if len(frames[i].Defers) > 0 {
+			return errors.New("can not restart a frame, deferred calls have been registered with the runtime")
+		}
+		if i < len(frames)-2 && frames[i].Call.Fn != nil && frames[i].Call.Fn.privateRuntime() {
+			return fmt.Errorf("can not restart a frame while the goroutine is executing %s", frames[i].Call.Fn.Name)
+		}
+	}
+	if extra := fn.extra(bi); extra != nil && (len(extra.closureStructType.Field) > 0 || extra.rangeParent != nil) {
+		return fmt.Errorf("can not restart closure %s, the closure context would be lost", fn.Name)
+	}
+	if bi.regabi && fn.Optimized() {
+		return fmt.Errorf("can not restart optimized function %s", fn.Name)
+	}
+
+	if !force {
+		firstPCAfterPrologue, err := FirstPCAfterPrologue(dbp, fn, false)
+		if err != nil {
+			return err
+		}
+		if len(frames) > 2 || fr.Current.PC > firstPCAfterPrologue {
+			return ErrRestartFrameSideEffects
+		}
+	}
+
+	// Read the current value of the arguments before the frame is reset,
+	// their location could change.
+	_, formalArgs, err := funcCallArgs(fn, bi, false)
+	if err != nil {
+		return err
+	}
+	scope := FrameToScope(dbp, dbp.Memory(), g, thread.ThreadID(), frames[len(frames)-2:]...)
+	argValues := make([][]byte, len(formalArgs))
+	for i, formalArg := range formalArgs {
+		if formalArg.dwarfEntry == nil {
+			// Arguments passed on the stack are already where the function
+			// expects them.
+			continue
+		}
+		v, err := extractVarInfoFromEntry(dbp, bi, scope.image(), scope.Regs, scope.Mem, formalArg.dwarfEntry, 0)
+		if err != nil {
+			return err
+		}
+		if v.Unreadable != nil {
+			return fmt.Errorf("can not restart frame, argument %s is unreadable: %v", formalArg.name, v.Unreadable)
+		}
+		argValues[i] = make([]byte, v.RealType.Size())
+		if _, err := v.mem.ReadMemory(argValues[i], v.Addr); err != nil {
+			return fmt.Errorf("can not restart frame, argument %s is unreadable: %v", formalArg.name, err)
+		}
+	}
+
+	// Reset the registers to the values they had when the CALL instruction
+	// was executed.
+	sp := uint64(fr.Regs.CFA)
+	if !bi.Arch.usesLR {
+		sp -= uint64(bi.Arch.PtrSize())
+	}
+	if err := setSP(thread, sp); err != nil {
+		return err
+	}
+	if err := thread.SetReg(bi.Arch.BPRegNum, op.DwarfRegisterFromUint64(frames[len(frames)-1].Regs.BP())); err != nil {
+		return err
+	}
+	if bi.Arch.usesLR {
+		if err := setLR(thread, fr.Ret); err != nil {
+			return err
+		}
+	}
+	if err := setPC(thread, fn.Entry); err != nil {
+		return err
+	}
+	dbp.ClearCaches()
+
+	if len(formalArgs) > 0 {
+		formalScope, err := GoroutineScope(dbp, thread)
+		if err != nil {
+			return err
+		}
+		for i, formalArg := range formalArgs {
+			if argValues[i] == nil {
+				continue
+			}
+			v, err := extractVarInfoFromEntry(dbp, bi, formalScope.image(), formalScope.Regs, formalScope.Mem, formalArg.dwarfEntry, 0)
+			if err != nil {
+				return err
+			}
+			if v.Unreadable != nil {
+				return fmt.Errorf("could not restore argument %s: %v", formalArg.name, v.Unreadable)
+			}
+			if _, err := v.mem.WriteMemory(v.Addr, argValues[i]); err != nil {
+				return fmt.Errorf("could not restore argument %s: %v", formalArg.name, err)
+			}
+		}
+		dbp.ClearCaches()
+	}
+
+	thread.Breakpoint().Clear()
+	return thread.SetCurrentBreakpoint(false)
+}
@@ -38,6 +38,7 @@ func RISCV64Arch(goos string) *Arch {
 		usesLR:                           true,
 		PCRegNum:                         regnum.RISCV64_PC,
 		SPRegNum:                         regnum.RISCV64_SP,
+		ContextRegNum:                    regnum.RISCV64_S10,
 		asmRegisters:                     riscv64AsmRegisters,
 		RegisterNameToDwarf:              nameToDwarfFunc(regnum.RISCV64NameToDwarf),
 		RegnumToString:                   regnum.RISCV64ToName,
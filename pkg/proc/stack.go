@@ -228,6 +228,10 @@ func (it *stackIterator) Next() bool {
 	if it.err != nil || it.atend {
 		return false
 	}
+	if it.target != nil && it.target.canceled() {
+		it.err = ErrCanceled
+		return false
+	}
 
 	if logflags.Stack() {
 		logger := logflags.StackLogger()
@@ -748,7 +752,7 @@ const maxRangeFuncDefers = 10
 
 func (d *Defer) load(canrecur bool) {
 	v := d.variable // +rtype _defer
-	v.loadValue(LoadConfig{false, 1, 0, 0, -1, 0})
+	v.loadValue(LoadConfig{false, 1, 0, 0, -1, 0, nil})
 	if v.Unreadable != nil {
 		d.Unreadable = v.Unreadable
 		return
@@ -0,0 +1,451 @@
+package proc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+)
+
+// +rtype -field moduledata.pctab []byte
+// +rtype -field moduledata.pclntable []byte
+// +rtype -field moduledata.ftab []functab
+// +rtype -field moduledata.gofunc uintptr
+// +rtype -field moduledata.etext uintptr
+// +rtype -field functab.entryoff uint32
+// +rtype -field functab.funcoff uint32
+// +rtype -field _func.entryOff uint32
+// +rtype -field _func.npcdata uint32
+// +rtype -field _func.nfuncdata uint8
+// +rtype -field stackmap.n int32
+// +rtype -field stackmap.nbit int32
+// +rtype -field stackObjectRecord.off int32
+// +rtype -field stackObjectRecord.size int32
+
+const (
+	pcdataStackMapIndex        = 1 // +rtype internal/abi.PCDATA_StackMapIndex
+	funcdataArgsPointerMaps    = 0 // +rtype internal/abi.FUNCDATA_ArgsPointerMaps
+	funcdataLocalsPointerMaps  = 1 // +rtype internal/abi.FUNCDATA_LocalsPointerMaps
+	funcdataStackObjects       = 2 // +rtype internal/abi.FUNCDATA_StackObjects
+	funcdataOpenCodedDeferInfo = 4 // +rtype internal/abi.FUNCDATA_OpenCodedDeferInfo
+
+	stackObjectRecordSize = 16 // size of runtime.stackObjectRecord
+	pctabChunkSize        = 256
+)
+
+// funcTab is the function table of a module, used to read the stack maps
+// generated by the compiler for each function.
+type funcTab struct {
+	text, etext uint64
+	gofunc      uint64
+	pctab       uint64
+	pctabLen    uint64
+	pclntable   uint64
+	ftab        []byte // contents of moduledata.ftab
+	layout      *funcLayout
+}
+
+// funcLayout describes the layout of runtime._func, the header of each
+// entry of the function table.
+type funcLayout struct {
+	entryOff, npcdata, nfuncdata int64 // offsets of the fields
+	size                         int64 // size of the header, the pcdata and funcdata offsets follow it
+}
+
+// loadFuncLayout reads the layout of runtime._func from the debug info.
+func loadFuncLayout(bi *BinaryInfo) (*funcLayout, error) {
+	typ, err := bi.findType("runtime._func")
+	if err != nil {
+		return nil, fmt.Errorf("could not find runtime._func: %v", err)
+	}
+	styp, ok := godwarf.ResolveTypedef(typ).(*godwarf.StructType)
+	if !ok {
+		return nil, fmt.Errorf("wrong type for runtime._func: %s", typ)
+	}
+	l := &funcLayout{entryOff: -1, npcdata: -1, nfuncdata: -1}
+	fields := []struct {
+		name string
+		dst  *int64
+		size int64
+	}{
+		{"entryOff", &l.entryOff, 4},
+		{"npcdata", &l.npcdata, 4},
+		{"nfuncdata", &l.nfuncdata, 1},
+	}
+	for _, f := range fields {
+		for _, field := range styp.Field {
+			if field.Name == f.name && field.Type.Size() == f.size {
+				*f.dst = field.ByteOffset
+			}
+		}
+		if *f.dst < 0 {
+			return nil, fmt.Errorf("unsupported layout of runtime._func: field %s not found", f.name)
+		}
+	}
+	// nfuncdata is the last field of runtime._func, the pcdata and funcdata
+	// offsets start right after it, see runtime.funcdata.
+	l.size = l.nfuncdata + 1
+	return l, nil
+}
+
+// loadFuncTabs reads the function tables of all modules.
+func loadFuncTabs(bi *BinaryInfo, mem MemoryReadWriter) ([]funcTab, error) {
+	layout, err := loadFuncLayout(bi)
+	if err != nil {
+		return nil, err
+	}
+	scope := globalScope(nil, bi, bi.Images[0], mem)
+	md, err := scope.findGlobal("runtime", "firstmoduledata")
+	if err != nil {
+		return nil, err
+	}
+	var r []funcTab
+	for md.Addr != 0 {
+		ft := funcTab{layout: layout}
+		fields := []struct {
+			name string
+			dst  *uint64
+		}{
+			{"text", &ft.text},
+			{"etext", &ft.etext},
+			{"gofunc", &ft.gofunc},
+		}
+		for _, f := range fields {
+			v, err := md.structMember(f.name)
+			if err != nil {
+				return nil, err
+			}
+			if *f.dst, err = v.asUint(); err != nil {
+				return nil, err
+			}
+		}
+		sliceFields := []struct {
+			name     string
+			base, ln *uint64
+		}{
+			{"pctab", &ft.pctab, &ft.pctabLen},
+			{"pclntable", &ft.pclntable, nil},
+		}
+		for _, s := range sliceFields {
+			v, err := md.structMember(s.name)
+			if err != nil {
+				return nil, err
+			}
+			v.loadValue(LoadConfig{})
+			if v.Unreadable != nil {
+				return nil, v.Unreadable
+			}
+			*s.base = v.Base
+			if s.ln != nil {
+				*s.ln = uint64(v.Len)
+			}
+		}
+		ftab, err := md.structMember("ftab")
+		if err != nil {
+			return nil, err
+		}
+		ftab.loadValue(LoadConfig{})
+		if ftab.Unreadable != nil {
+			return nil, ftab.Unreadable
+		}
+		ft.ftab = make([]byte, ftab.Len*8)
+		if _, err := mem.ReadMemory(ft.ftab, ftab.Base); err != nil {
+			return nil, err
+		}
+		r = append(r, ft)
+
+		next, err := md.structMember("next")
+		if err != nil {
+			return nil, err
+		}
+		md = next.maybeDereference()
+		if md.Unreadable != nil {
+			return nil, md.Unreadable
+		}
+	}
+	return r, nil
+}
+
+// stackLiveness describes which words of a stack frame contain live
+// pointers at the call site where the frame is suspended, as recorded in
+// the stack maps used by the garbage collector.
+type stackLiveness struct {
+	ptrSize      uint64
+	locals, args stackBitmap
+	objs         [][2]uint64 // address ranges of the stack objects of the frame
+}
+
+// stackBitmap is a bitmap with one bit for each word of a region of a stack
+// frame, the bit is set if the word contains a live pointer.
+type stackBitmap struct {
+	addr uint64 // address of the first word
+	n    uint64 // number of words
+	bits []byte
+}
+
+// dead returns true if the memory range [addr, addr+size) is entirely
+// described by the stack maps and contains no live pointers.
+// Stack objects, that is variables whose address is taken, are never
+// considered dead because their liveness is determined by the garbage
+// collector by following pointers.
+func (l *stackLiveness) dead(addr, size uint64) bool {
+	if size == 0 {
+		return false
+	}
+	for _, obj := range l.objs {
+		if addr < obj[1] && obj[0] < addr+size {
+			return false
+		}
+	}
+	for a := addr &^ (l.ptrSize - 1); a < addr+size; a += l.ptrSize {
+		live, covered := l.locals.word(a, l.ptrSize)
+		if !covered {
+			live, covered = l.args.word(a, l.ptrSize)
+		}
+		if !covered || live {
+			return false
+		}
+	}
+	return true
+}
+
+// word returns whether the word at addr contains a live pointer and
+// whether it is described by the bitmap.
+func (bm *stackBitmap) word(addr, ptrSize uint64) (live, covered bool) {
+	if bm.n == 0 || addr < bm.addr || addr >= bm.addr+bm.n*ptrSize {
+		return false, false
+	}
+	i := (addr - bm.addr) / ptrSize
+	return bm.bits[i/8]&(1<<(i%8)) != 0, true
+}
+
+// frameLiveness returns the liveness information for the stack frame
+// frame, which must be suspended at a call instruction.
+// Liveness information is only supported on amd64 and arm64, for other
+// architectures, and for functions without stack maps, nil is returned.
+func frameLiveness(bi *BinaryInfo, mem MemoryReadWriter, fts []funcTab, frame *Stackframe) (*stackLiveness, error) {
+	var pcQuantum, minFrameSize, minLocalsSize uint64
+	ptrSize := uint64(bi.Arch.PtrSize())
+	switch bi.Arch.Name {
+	case "amd64":
+		pcQuantum, minFrameSize, minLocalsSize = 1, 0, 0
+	case "arm64":
+		pcQuantum, minFrameSize, minLocalsSize = 4, ptrSize, 16
+	default:
+		return nil, nil
+	}
+
+	pc := frame.Current.PC
+	f, err := findFuncInfo(mem, fts, pc)
+	if f == nil || err != nil {
+		return nil, err
+	}
+	entry := f.entry
+
+	// See runtime.(*stkframe).getStackMap
+	stackid := int64(-1)
+	if targetpc := pc; targetpc != entry {
+		targetpc--
+		if pcdataStackMapIndex < f.npcdata {
+			tab := binary.LittleEndian.Uint32(f.tabs[4*pcdataStackMapIndex:])
+			var err error
+			stackid, err = f.ft.pcvalue(mem, uint64(tab), entry, targetpc, pcQuantum)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if stackid < 0 {
+		// function prologue
+		stackid = 0
+	}
+
+	sp, fp := frame.Regs.SP(), uint64(frame.Regs.CFA)
+	varp := fp
+	if !bi.Arch.usesLR {
+		// return address
+		varp -= ptrSize
+	}
+	if varp > sp {
+		// saved frame pointer
+		varp -= ptrSize
+	}
+	argp := fp + minFrameSize
+
+	l := &stackLiveness{ptrSize: ptrSize}
+	readStackMap := func(addr uint64, bm *stackBitmap) (bool, error) {
+		if addr == 0 {
+			return false, nil
+		}
+		var buf [8]byte
+		if _, err := mem.ReadMemory(buf[:], addr); err != nil {
+			return false, err
+		}
+		n, nbit := int64(int32(binary.LittleEndian.Uint32(buf[:]))), int64(int32(binary.LittleEndian.Uint32(buf[4:])))
+		if n <= 0 || nbit < 0 || stackid >= n {
+			return false, nil
+		}
+		bm.n = uint64(nbit)
+		bm.bits = make([]byte, (nbit+7)/8)
+		if _, err := mem.ReadMemory(bm.bits, addr+8+uint64(stackid)*uint64(len(bm.bits))); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if varp-sp > minLocalsSize {
+		ok, err := readStackMap(f.funcdata(funcdataLocalsPointerMaps), &l.locals)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, nil
+		}
+		l.locals.addr = varp - l.locals.n*ptrSize
+	}
+	if _, err := readStackMap(f.funcdata(funcdataArgsPointerMaps), &l.args); err != nil {
+		return nil, err
+	}
+	l.args.addr = argp
+
+	if objs := f.funcdata(funcdataStackObjects); objs != 0 {
+		n, err := readUintRaw(mem, objs, int64(ptrSize))
+		if err != nil {
+			return nil, err
+		}
+		if n > 1<<16 {
+			return nil, errors.New("too many stack objects")
+		}
+		buf := make([]byte, n*stackObjectRecordSize)
+		if _, err := mem.ReadMemory(buf, objs+ptrSize); err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < n; i++ {
+			rec := buf[i*stackObjectRecordSize:]
+			off, size := int64(int32(binary.LittleEndian.Uint32(rec))), uint64(binary.LittleEndian.Uint32(rec[4:]))
+			base := argp
+			if off < 0 {
+				base = varp
+			}
+			addr := uint64(int64(base) + off)
+			l.objs = append(l.objs, [2]uint64{addr, addr + size})
+		}
+	}
+	return l, nil
+}
+
+// funcInfo is the entry of the function table for a function.
+type funcInfo struct {
+	ft      *funcTab
+	entry   uint64
+	npcdata uint64
+	tabs    []byte // pcdata and funcdata offsets
+}
+
+// findFuncInfo returns the entry of the function table for the function
+// containing pc, or nil if pc is not in any function.
+func findFuncInfo(mem MemoryReadWriter, fts []funcTab, pc uint64) (*funcInfo, error) {
+	var ft *funcTab
+	for i := range fts {
+		if pc >= fts[i].text && pc < fts[i].etext {
+			ft = &fts[i]
+			break
+		}
+	}
+	if ft == nil || len(ft.ftab) < 16 {
+		return nil, nil
+	}
+
+	// find the physical function containing pc, the last entry of ftab
+	// marks the end of the text section.
+	nftab := len(ft.ftab)/8 - 1
+	off := uint32(pc - ft.text)
+	i := sort.Search(nftab, func(i int) bool {
+		return binary.LittleEndian.Uint32(ft.ftab[i*8:]) > off
+	}) - 1
+	if i < 0 {
+		return nil, nil
+	}
+	funcAddr := ft.pclntable + uint64(binary.LittleEndian.Uint32(ft.ftab[i*8+4:]))
+	l := ft.layout
+	hdr := make([]byte, l.size)
+	if _, err := mem.ReadMemory(hdr, funcAddr); err != nil {
+		return nil, err
+	}
+	f := &funcInfo{
+		ft:      ft,
+		entry:   ft.text + uint64(binary.LittleEndian.Uint32(hdr[l.entryOff:])),
+		npcdata: uint64(binary.LittleEndian.Uint32(hdr[l.npcdata:])),
+	}
+	nfuncdata := uint64(hdr[l.nfuncdata])
+	f.tabs = make([]byte, 4*(f.npcdata+nfuncdata))
+	if _, err := mem.ReadMemory(f.tabs, funcAddr+uint64(l.size)); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// funcdata returns the address of the i-th funcdata of the function, or 0.
+func (f *funcInfo) funcdata(i uint64) uint64 {
+	if 4*(f.npcdata+i) >= uint64(len(f.tabs)) {
+		return 0
+	}
+	off := binary.LittleEndian.Uint32(f.tabs[4*(f.npcdata+i):])
+	if off == ^uint32(0) {
+		return 0
+	}
+	return f.ft.gofunc + uint64(off)
+}
+
+// pcvalue returns the value of the pc-value table at offset off of pctab
+// for targetpc, in a function starting at entry, or -1.
+// See runtime.pcvalue.
+func (ft *funcTab) pcvalue(mem MemoryReadWriter, off, entry, targetpc, pcQuantum uint64) (int64, error) {
+	if off == 0 || off >= ft.pctabLen {
+		return -1, nil
+	}
+	var buf []byte
+	readvarint := func() (uint64, error) {
+		if len(buf) < binary.MaxVarintLen64 && off < ft.pctabLen {
+			n := min(uint64(pctabChunkSize), ft.pctabLen-off)
+			chunk := make([]byte, n)
+			if _, err := mem.ReadMemory(chunk, ft.pctab+off); err != nil {
+				return 0, err
+			}
+			off += n
+			buf = append(buf, chunk...)
+		}
+		v, sz := binary.Uvarint(buf)
+		if sz <= 0 {
+			return 0, errors.New("malformed pc-value table")
+		}
+		buf = buf[sz:]
+		return v, nil
+	}
+
+	val, pc := int64(-1), entry
+	for first := true; ; first = false {
+		uvdelta, err := readvarint()
+		if err != nil {
+			return -1, err
+		}
+		if uvdelta == 0 && !first {
+			return -1, nil
+		}
+		if uvdelta&1 != 0 {
+			uvdelta = ^(uvdelta >> 1)
+		} else {
+			uvdelta >>= 1
+		}
+		pcdelta, err := readvarint()
+		if err != nil {
+			return -1, err
+		}
+		pc += pcdelta * pcQuantum
+		val += int64(int32(uvdelta))
+		if targetpc < pc {
+			return val, nil
+		}
+	}
+}
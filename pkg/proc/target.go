@@ -25,6 +25,10 @@ var (
 
 	// ErrProcessDetached indicates that we detached from the target process.
 	ErrProcessDetached = errors.New("detached from the process")
+
+	// ErrCanceled is returned by operations interrupted by closing the
+	// channel passed to SetCancel.
+	ErrCanceled = errors.New("canceled")
 )
 
 type LaunchFlags uint8
@@ -79,6 +83,14 @@ type Target struct {
 	fakeMemoryRegistryMap map[string]*compositeMemory
 
 	partOfGroup bool
+
+	// convVars contains the convenience variables of the group this target
+	// belongs to.
+	convVars *ConvenienceVariables
+
+	// cancel is closed to interrupt the goroutine listing or stack unwinding
+	// operation in progress, see SetCancel.
+	cancel <-chan struct{}
 }
 
 type KeepSteppingBreakpoints uint8
@@ -255,6 +267,29 @@ func (t *Target) SupportsFunctionCalls() bool {
 	return t.Process.BinInfo().Arch.Name == "amd64" || (t.Process.BinInfo().Arch.Name == "arm64" && t.Process.BinInfo().GOOS != "windows") || t.Process.BinInfo().Arch.Name == "ppc64le"
 }
 
+// SetCancel sets the channel that, when closed, interrupts the goroutine
+// listing and stack unwinding operations executed on t. The interrupted
+// operations return the results collected until then together with
+// ErrCanceled.
+// Variable loading is interrupted using the Cancel field of LoadConfig.
+func (t *Target) SetCancel(cancel <-chan struct{}) {
+	t.cancel = cancel
+}
+
+func (t *Target) canceled() bool {
+	return isClosed(t.cancel)
+}
+
+// isClosed returns true if ch is closed, a nil channel is never closed.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
 // ClearCaches clears internal caches that should not survive a restart.
 // This should be called anytime the target process executes instructions.
 func (t *Target) ClearCaches() {
@@ -364,33 +399,51 @@ func setAsyncPreemptOff(p *Target, v int64) {
 
 // createUnrecoveredPanicBreakpoint creates the unrecoverable-panic breakpoint.
 func (t *Target) createUnrecoveredPanicBreakpoint() {
-	panicpcs, err := FindFunctionLocation(t.Process, "runtime.startpanic", 0)
-	if _, isFnNotFound := err.(*ErrFunctionNotFound); isFnNotFound {
-		panicpcs, err = FindFunctionLocation(t.Process, "runtime.fatalpanic", 0)
-	}
-	if err == nil {
+	panicpcs := unrecoveredPanicPCs(t)
+	if len(panicpcs) > 0 {
 		bp, err := t.SetBreakpoint(unrecoveredPanicID, panicpcs[0], UserBreakpoint, nil)
 		if err == nil {
 			bp.Logical.Name = UnrecoveredPanic
 			bp.Logical.Variables = []string{"runtime.curg._panic.arg"}
+			bp.Logical.Set.Expr = unrecoveredPanicPCs
 		}
 	}
 }
 
+// unrecoveredPanicPCs returns the address of the unrecoverable-panic
+// breakpoint in t.
+func unrecoveredPanicPCs(t *Target) []uint64 {
+	panicpcs, err := FindFunctionLocation(t.Process, "runtime.startpanic", 0)
+	if _, isFnNotFound := err.(*ErrFunctionNotFound); isFnNotFound {
+		panicpcs, err = FindFunctionLocation(t.Process, "runtime.fatalpanic", 0)
+	}
+	if err != nil || len(panicpcs) == 0 {
+		return nil
+	}
+	return panicpcs[:1]
+}
+
 // createFatalThrowBreakpoint creates the a breakpoint as runtime.fatalthrow.
 func (t *Target) createFatalThrowBreakpoint() {
-	setFatalThrow := func(pcs []uint64, err error) {
+	for _, pc := range fatalThrowPCs(t) {
+		bp, err := t.SetBreakpoint(fatalThrowID, pc, UserBreakpoint, nil)
 		if err == nil {
-			bp, err := t.SetBreakpoint(fatalThrowID, pcs[0], UserBreakpoint, nil)
-			if err == nil {
-				bp.Logical.Name = FatalThrow
-			}
+			bp.Logical.Name = FatalThrow
+			bp.Logical.Set.Expr = fatalThrowPCs
+		}
+	}
+}
+
+// fatalThrowPCs returns the addresses of the fatal-throw breakpoint in t.
+func fatalThrowPCs(t *Target) []uint64 {
+	var r []uint64
+	for _, fnname := range []string{"runtime.throw", "runtime.fatal", "runtime.winthrow", "runtime.fatalsignal"} {
+		pcs, err := FindFunctionLocation(t.Process, fnname, 0)
+		if err == nil {
+			r = append(r, pcs[0])
 		}
 	}
-	setFatalThrow(FindFunctionLocation(t.Process, "runtime.throw", 0))
-	setFatalThrow(FindFunctionLocation(t.Process, "runtime.fatal", 0))
-	setFatalThrow(FindFunctionLocation(t.Process, "runtime.winthrow", 0))
-	setFatalThrow(FindFunctionLocation(t.Process, "runtime.fatalsignal", 0))
+	return r
 }
 
 // createPluginOpenBreakpoint creates a breakpoint at the return instruction
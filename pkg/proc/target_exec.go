@@ -494,6 +494,141 @@ func (grp *TargetGroup) Step() (err error) {
 	return grp.Continue()
 }
 
+// StepInTarget is a function call on the current line of a goroutine that
+// can be stepped into.
+type StepInTarget struct {
+	PC uint64    // address of the call instruction
+	Fn *Function // called function, nil if it is only known when the call is executed
+}
+
+// StepInTargets returns the function calls on the current line of g that
+// have not been executed yet, in the order they appear in the code. Calls
+// that Step would not enter, for example calls to unexported runtime
+// functions, are not returned.
+// If g is nil the current thread is used.
+func (t *Target) StepInTargets(g *G) ([]StepInTarget, error) {
+	if _, err := t.Valid(); err != nil {
+		return nil, err
+	}
+	thread := t.CurrentThread()
+	if g != nil && g.Thread != nil {
+		thread = g.Thread
+	}
+	topframe, _, err := topframe(t, g, thread)
+	if err != nil {
+		return nil, err
+	}
+	if topframe.Current.Fn == nil {
+		return nil, &ErrNoSourceForPC{topframe.Current.PC}
+	}
+	var regs Registers
+	if g == nil || g.Thread != nil {
+		regs, err = thread.Registers()
+		if err != nil {
+			return nil, err
+		}
+	}
+	bi := t.BinInfo()
+	curfn := topframe.Current.Fn
+	text, err := disassemble(t.Memory(), regs, t.Breakpoints(), bi, curfn.Entry, curfn.End, false)
+	if err != nil {
+		return nil, err
+	}
+	stepIntoUnexportedRuntime := strings.HasPrefix(curfn.Name, "runtime.")
+	var targets []StepInTarget
+	for _, instr := range text {
+		if instr.Loc.PC < topframe.Current.PC || instr.Loc.File != topframe.Current.File || instr.Loc.Line != topframe.Current.Line || !instr.IsCall() {
+			continue
+		}
+		var fn *Function
+		if instr.DestLoc != nil {
+			fn = instr.DestLoc.Fn
+			if (!stepIntoUnexportedRuntime && fn != nil && fn.privateRuntime()) || bi.Arch.inhibitStepInto(bi, instr.DestLoc.PC) {
+				continue
+			}
+			if fn2, _ := skipAutogeneratedWrappersIn(t, fn, instr.DestLoc.PC, false); fn2 != nil {
+				fn = fn2
+			}
+		}
+		targets = append(targets, StepInTarget{PC: instr.Loc.PC, Fn: fn})
+	}
+	return targets, nil
+}
+
+// StepInTarget resumes the processes in the group, continuing the selected
+// target until the next source line, like Step, but only enters the
+// function called by the call instruction at pc, which must be one of the
+// StepInTargets of the selected goroutine. The other calls on the current
+// line are stepped over.
+func (grp *TargetGroup) StepInTarget(pc uint64) (err error) {
+	if _, err := grp.Valid(); err != nil {
+		return err
+	}
+	if grp.HasSteppingBreakpoints() {
+		return errors.New("next while nexting")
+	}
+	if grp.GetDirection() == Backward {
+		return errors.New("can not step into a specific call backwards")
+	}
+
+	dbp := grp.Selected
+	targets, err := dbp.StepInTargets(dbp.SelectedGoroutine())
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, target := range targets {
+		if target.PC == pc {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("no call to step into at %#x on the current line", pc)
+	}
+
+	if err = next(dbp, false, false); err != nil {
+		_ = dbp.ClearSteppingBreakpoints()
+		return err
+	}
+	if err = setStepInTargetBreakpoint(dbp, pc); err != nil {
+		_ = dbp.ClearSteppingBreakpoints()
+		return err
+	}
+
+	return grp.Continue()
+}
+
+// setStepInTargetBreakpoint sets the breakpoints needed to step into the
+// function called by the call instruction at pc.
+// If the call instruction is the current instruction its destination is
+// known and a breakpoint is set there directly, otherwise a StepBreakpoint
+// is set on the call instruction and stepIntoCallback will take care of
+// the destination, so that other calls to the same function are not
+// entered.
+func setStepInTargetBreakpoint(dbp *Target, pc uint64) error {
+	curthread := dbp.CurrentThread()
+	sameGCond := sameGoroutineCondition(dbp.BinInfo(), dbp.SelectedGoroutine(), curthread.ThreadID())
+	regs, err := curthread.Registers()
+	if err != nil {
+		return err
+	}
+	if regs.PC() == pc {
+		text, err := disassembleCurrentInstruction(dbp, curthread, 0)
+		if err != nil {
+			return err
+		}
+		return setStepIntoBreakpoint(dbp, dbp.BinInfo().PCToFunc(pc), text, sameGCond)
+	}
+	bp, err := allowDuplicateBreakpoint(dbp.SetBreakpoint(0, pc, StepBreakpoint, sameGCond))
+	if err != nil {
+		return err
+	}
+	breaklet := bp.Breaklets[len(bp.Breaklets)-1]
+	breaklet.callback = stepIntoCallback
+	return nil
+}
+
 // sameGoroutineCondition returns an expression that evaluates to true when
 // the current goroutine is g.
 func sameGoroutineCondition(bi *BinaryInfo, g *G, threadID int) ast.Expr {
@@ -3,13 +3,13 @@ package proc
 import (
 	"bytes"
 	"fmt"
-	"go/parser"
 	"go/token"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/go-delve/delve/pkg/logflags"
+	"github.com/go-delve/delve/pkg/proc/evalop"
 )
 
 // TargetGroup represents a group of target processes being debugged that
@@ -39,6 +39,10 @@ type TargetGroup struct {
 
 	LogicalBreakpoints map[int]*LogicalBreakpoint
 
+	// ConvenienceVariables contains the convenience variables ($name) that
+	// can be used in expressions evaluated on any target of the group.
+	ConvenienceVariables *ConvenienceVariables
+
 	cctx    *ContinueOnceContext
 	cfg     NewTargetGroupConfig
 	CanDump bool
@@ -57,17 +61,19 @@ type AddTargetFunc func(ProcessInternal, int, Thread, string, StopReason, string
 // NewGroup creates a TargetGroup containing the specified Target.
 func NewGroup(procgrp ProcessGroup, cfg NewTargetGroupConfig) (*TargetGroup, AddTargetFunc) {
 	grp := &TargetGroup{
-		procgrp:            procgrp,
-		cctx:               &ContinueOnceContext{},
-		LogicalBreakpoints: make(map[int]*LogicalBreakpoint),
-		StopReason:         cfg.StopReason,
-		cfg:                cfg,
-		CanDump:            cfg.CanDump,
+		procgrp:              procgrp,
+		cctx:                 &ContinueOnceContext{},
+		LogicalBreakpoints:   make(map[int]*LogicalBreakpoint),
+		ConvenienceVariables: NewConvenienceVariables(),
+		StopReason:           cfg.StopReason,
+		cfg:                  cfg,
+		CanDump:              cfg.CanDump,
 	}
 	return grp, grp.addTarget
 }
 
-// Restart copies breakpoints and follow exec status from oldgrp into grp.
+// Restart copies breakpoints, follow exec status and convenience variables
+// from oldgrp into grp.
 // Breakpoints that can not be set will be discarded, if discard is not nil
 // it will be called for each discarded breakpoint.
 func Restart(grp, oldgrp *TargetGroup, discard func(*LogicalBreakpoint, error)) {
@@ -94,6 +100,7 @@ func Restart(grp, oldgrp *TargetGroup, discard func(*LogicalBreakpoint, error))
 			delete(grp.LogicalBreakpoints, bp.LogicalID)
 		}
 	}
+	grp.ConvenienceVariables.restore(oldgrp.ConvenienceVariables)
 	if oldgrp.followExecEnabled {
 		rgx := ""
 		if oldgrp.followExecRegex != nil {
@@ -133,6 +140,7 @@ func (grp *TargetGroup) addTarget(p ProcessInternal, pid int, currentThread Thre
 		grp.Selected = t
 	}
 	t.Breakpoints().Logical = grp.LogicalBreakpoints
+	t.convVars = grp.ConvenienceVariables
 	for _, lbp := range grp.LogicalBreakpoints {
 		if lbp.LogicalID < 0 {
 			continue
@@ -400,7 +408,7 @@ func (grp *TargetGroup) ChangeBreakpointCondition(lbp *LogicalBreakpoint, cond,
 	lbp.cond = nil
 	if cond != "" {
 		var err error
-		lbp.cond, err = parser.ParseExpr(cond)
+		lbp.cond, err = evalop.ParseExpr(cond)
 		if err != nil {
 			return err
 		}
@@ -168,6 +168,17 @@ func dwarfToRuntimeType(bi *BinaryInfo, mem MemoryReadWriter, typ godwarf.Type)
 		return 0, 0, false, err
 	}
 	off, ok := e.Val(godwarf.AttrGoRuntimeType).(uint64)
+	if !ok && e.Tag == dwarf.TagTypedef {
+		// Named types can be described by a typedef of a type with the same
+		// name, the runtime type is attached to the latter.
+		if tgt, isoff := e.Val(dwarf.AttrType).(dwarf.Offset); isoff {
+			rdr.Seek(tgt)
+			e2, err := rdr.Next()
+			if err == nil && e2 != nil && e2.Val(dwarf.AttrName) == e.Val(dwarf.AttrName) {
+				off, ok = e2.Val(godwarf.AttrGoRuntimeType).(uint64)
+			}
+		}
+	}
 	if !ok {
 		return 0, 0, false, nil
 	}
@@ -204,5 +215,104 @@ func dwarfToRuntimeType(bi *BinaryInfo, mem MemoryReadWriter, typ godwarf.Type)
 		return 0, 0, false, fmt.Errorf("unreadable interface type: %v", kindv.Unreadable)
 	}
 	typeKind, _ = constant.Uint64Val(kindv.Value)
+	// Go 1.26 and later have the direct interface flag in the TFlag field,
+	// report it in the kind like earlier versions did.
+	if tflagv := _type.loadFieldNamed("TFlag"); tflagv != nil && tflagv.Unreadable == nil && tflagv.Value != nil {
+		if tflag, _ := constant.Int64Val(tflagv.Value); tflag&tflagDirectIface != 0 {
+			typeKind |= kindDirectIface
+		}
+	}
 	return typeAddr, typeKind, true, nil
 }
+
+// runtimeTypeOf is like dwarfToRuntimeType but types synthesized by the
+// debugger are looked up by name.
+func runtimeTypeOf(bi *BinaryInfo, mem MemoryReadWriter, typ godwarf.Type) (typeAddr uint64, typeKind uint64, found bool, err error) {
+	if typ.Common().Offset == 0 {
+		typ2, err := bi.findType(typ.String())
+		if err != nil {
+			return 0, 0, false, nil
+		}
+		typ = typ2
+	}
+	return dwarfToRuntimeType(bi, mem, typ)
+}
+
+// findItab returns the address of the itab for interface type interAddr
+// and concrete type typeAddr. The itab is searched in runtime.itabTable,
+// which contains every itab the program used or created.
+func findItab(bi *BinaryInfo, mem MemoryReadWriter, interAddr, typeAddr uint64) (uint64, bool, error) {
+	// +rtype -var itabTable *itabTableType
+	// +rtype -field itabTableType.size uintptr
+	// +rtype -field itabTableType.entries anytype
+
+	scope := globalScope(nil, bi, bi.Images[0], mem)
+	tabv, err := scope.findGlobal("runtime", "itabTable")
+	if err != nil {
+		return 0, false, err
+	}
+	tabv = tabv.maybeDereference()
+	sizev, err := tabv.structMember("size")
+	if err != nil {
+		return 0, false, err
+	}
+	size, err := sizev.asUint()
+	if err != nil {
+		return 0, false, err
+	}
+	entriesv, err := tabv.structMember("entries")
+	if err != nil {
+		return 0, false, err
+	}
+	entriesTyp, isarr := entriesv.RealType.(*godwarf.ArrayType)
+	if !isarr {
+		return 0, false, errors.New("unexpected type for runtime.itabTable.entries")
+	}
+	itabTyp, isptr := godwarf.ResolveTypedef(entriesTyp.Type).(*godwarf.PtrType)
+	if !isptr {
+		return 0, false, errors.New("unexpected type for runtime.itabTable.entries")
+	}
+	itabStruct, isstruct := godwarf.ResolveTypedef(itabTyp.Type).(*godwarf.StructType)
+	if !isstruct {
+		return 0, false, errors.New("unexpected type for runtime.itabTable.entries")
+	}
+
+	interOff, typeOff := int64(-1), int64(-1)
+	for _, f := range itabStruct.Field {
+		switch f.Name {
+		case "Inter", "inter":
+			interOff = f.ByteOffset
+		case "Type", "_type":
+			typeOff = f.ByteOffset
+		}
+	}
+	if interOff < 0 || typeOff < 0 {
+		return 0, false, errors.New("unexpected type for runtime.itab")
+	}
+
+	ptrSize := int64(bi.Arch.PtrSize())
+	for i := uint64(0); i < size; i++ {
+		itabAddr, err := readUintRaw(mem, entriesv.Addr+i*uint64(ptrSize), ptrSize)
+		if err != nil {
+			return 0, false, err
+		}
+		if itabAddr == 0 {
+			continue
+		}
+		inter, err := readUintRaw(mem, itabAddr+uint64(interOff), ptrSize)
+		if err != nil {
+			return 0, false, err
+		}
+		if inter != interAddr {
+			continue
+		}
+		typ, err := readUintRaw(mem, itabAddr+uint64(typeOff), ptrSize)
+		if err != nil {
+			return 0, false, err
+		}
+		if typ == typeAddr {
+			return itabAddr, true, nil
+		}
+	}
+	return 0, false, nil
+}
@@ -89,6 +89,12 @@ const (
 	VariableCPtr
 	// VariableCPURegister means this variable is a CPU register.
 	VariableCPURegister
+	// VariableLoadCanceled means that loading of this variable, or of some
+	// of its children, was interrupted using the Cancel field of LoadConfig.
+	VariableLoadCanceled
+	// VariableFormatted means that Formatted and FormattedChildren contain
+	// the output of a pretty printer for this variable.
+	VariableFormatted
 	// variableTrustLen means that when this variable is loaded its length
 	// should be trusted and used instead of MaxArrayValues
 	variableTrustLen
@@ -129,6 +135,9 @@ type Variable struct {
 
 	// closureAddr is the closure address for function variables (0 for non-closures)
 	closureAddr uint64
+	// dictAddr is the address of the dictionary that must be passed to
+	// instantiations of generic functions (0 for non-generic functions)
+	dictAddr uint64
 
 	// number of elements to skip when loading a map
 	mapSkip int
@@ -143,6 +152,12 @@ type Variable struct {
 
 	LocationExpr *locationExpr // location expression
 	DeclLine     int64         // line number of this variable's declaration
+
+	// Formatted is the representation of this variable produced by a pretty
+	// printer, FormattedChildren, if not nil, are the synthetic children it
+	// produced. Only valid if the VariableFormatted flag is set.
+	Formatted         string
+	FormattedChildren []Variable
 }
 
 // LoadConfig controls how variables are loaded from the targets memory.
@@ -186,11 +201,20 @@ type LoadConfig struct {
 	// sparse map is in scope, but evaluating a single variable will still work
 	// correctly, even if the variable in question is a very sparse map.
 	MaxMapBuckets int
+
+	// Cancel, if not nil, interrupts loading when it is closed. Variables
+	// that could not be loaded, and the variables containing them, are
+	// marked with the VariableLoadCanceled flag.
+	Cancel <-chan struct{}
+}
+
+func (cfg *LoadConfig) canceled() bool {
+	return isClosed(cfg.Cancel)
 }
 
-var loadSingleValue = LoadConfig{false, 0, 64, 0, 0, 0}
-var loadFullValue = LoadConfig{true, 1, 64, 64, -1, 0}
-var loadFullValueLongerStrings = LoadConfig{true, 1, 1024 * 1024, 64, -1, 0}
+var loadSingleValue = LoadConfig{false, 0, 64, 0, 0, 0, nil}
+var loadFullValue = LoadConfig{true, 1, 64, 64, -1, 0, nil}
+var loadFullValueLongerStrings = LoadConfig{true, 1, 1024 * 1024, 64, -1, 0, nil}
 
 // G status, from: src/runtime/runtime2.go
 const (
@@ -313,6 +337,8 @@ func GetG(thread Thread) (*G, error) {
 // GoroutinesInfo also returns the next index to be used as 'start' argument
 // while scanning for all available goroutines, or -1 if there was an error
 // or if the index already reached the last possible value.
+// If the scan is interrupted using SetCancel the goroutines found until then
+// are returned along with ErrCanceled.
 func GoroutinesInfo(dbp *Target, start, count int) ([]*G, int, error) {
 	if _, err := dbp.Valid(); err != nil {
 		return nil, -1, err
@@ -346,6 +372,9 @@ func GoroutinesInfo(dbp *Target, start, count int) ([]*G, int, error) {
 		if count != 0 && len(allg) >= count {
 			return allg, int(i), nil
 		}
+		if dbp.canceled() {
+			return allg, int(i), ErrCanceled
+		}
 		gvar, err := newGVariable(dbp.CurrentThread(), allgptr+(i*uint64(dbp.BinInfo().Arch.PtrSize())), true)
 		if err != nil {
 			allg = append(allg, &G{Unreadable: err})
@@ -647,30 +676,7 @@ func (v *Variable) newVariable(name string, addr uint64, dwarfType godwarf.Type,
 }
 
 func newVariable(name string, addr uint64, dwarfType godwarf.Type, bi *BinaryInfo, mem MemoryReadWriter) *Variable {
-	if styp, isstruct := dwarfType.(*godwarf.StructType); isstruct && !strings.Contains(styp.Name, "<") && !strings.Contains(styp.Name, "{") {
-		// For named structs the compiler will emit a DW_TAG_structure_type entry
-		// and a DW_TAG_typedef entry.
-		//
-		// Normally variables refer to the typedef entry but sometimes global
-		// variables will refer to the struct entry incorrectly.
-		// Also the runtime type offset resolution (runtimeTypeToDIE) will return
-		// the struct entry directly.
-		//
-		// In both cases we prefer to have a typedef type for consistency's sake.
-		//
-		// So we wrap all struct types into a fake typedef type except for:
-		// a. types not defined by go
-		// b. anonymous struct types (they contain the '{' character)
-		// c. Go internal struct types used to describe maps (they contain the '<'
-		// character).
-		cu := bi.Images[dwarfType.Common().Index].findCompileUnitForOffset(dwarfType.Common().Offset)
-		if cu != nil && cu.isgo {
-			dwarfType = &godwarf.TypedefType{
-				CommonType: *(dwarfType.Common()),
-				Type:       dwarfType,
-			}
-		}
-	}
+	dwarfType = wrapStructType(bi, dwarfType)
 
 	v := &Variable{
 		Name:      name,
@@ -774,6 +780,35 @@ func newVariable(name string, addr uint64, dwarfType godwarf.Type, bi *BinaryInf
 	return v
 }
 
+// wrapStructType wraps named Go struct types into a fake typedef type.
+func wrapStructType(bi *BinaryInfo, dwarfType godwarf.Type) godwarf.Type {
+	if styp, isstruct := dwarfType.(*godwarf.StructType); isstruct && !strings.Contains(styp.Name, "<") && !strings.Contains(styp.Name, "{") {
+		// For named structs the compiler will emit a DW_TAG_structure_type entry
+		// and a DW_TAG_typedef entry.
+		//
+		// Normally variables refer to the typedef entry but sometimes global
+		// variables will refer to the struct entry incorrectly.
+		// Also the runtime type offset resolution (runtimeTypeToDIE) will return
+		// the struct entry directly.
+		//
+		// In both cases we prefer to have a typedef type for consistency's sake.
+		//
+		// So we wrap all struct types into a fake typedef type except for:
+		// a. types not defined by go
+		// b. anonymous struct types (they contain the '{' character)
+		// c. Go internal struct types used to describe maps (they contain the '<'
+		// character).
+		cu := bi.Images[dwarfType.Common().Index].findCompileUnitForOffset(dwarfType.Common().Offset)
+		if cu != nil && cu.isgo {
+			dwarfType = &godwarf.TypedefType{
+				CommonType: *(dwarfType.Common()),
+				Type:       dwarfType,
+			}
+		}
+	}
+	return dwarfType
+}
+
 var constantMaxInt64 = constant.MakeInt64(1<<63 - 1)
 
 func newConstant(val constant.Value, bi *BinaryInfo, mem MemoryReadWriter) *Variable {
@@ -1341,12 +1376,22 @@ func loadValues(vars []*Variable, cfg LoadConfig) {
 // Extracts the value of the variable at the given address.
 func (v *Variable) loadValue(cfg LoadConfig) {
 	v.loadValueInternal(0, cfg)
+	if cfg.canceled() {
+		v.Flags |= VariableLoadCanceled
+	}
 }
 
 func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 	if v.Unreadable != nil || v.loaded || (v.Addr == 0 && v.Base == 0) {
 		return
 	}
+	if cfg.canceled() {
+		// Leave the variable unloaded, the same thing that happens to
+		// variables that exceed MaxVariableRecurse.
+		v.OnlyAddr = true
+		v.Flags |= VariableLoadCanceled
+		return
+	}
 
 	v.loaded = true
 	switch v.Kind {
@@ -1416,7 +1461,7 @@ func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 			}
 
 		default:
-			val, v.Unreadable = readStringValue(DereferenceMemory(v.mem), v.Base, v.Len, cfg)
+			val, v.Unreadable = readStringValue(derefMemoryAt(v.mem, v.Base), v.Base, v.Len, cfg)
 		}
 		v.Value = constant.MakeString(val)
 
@@ -1495,33 +1540,74 @@ func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 	}
 }
 
-// convertToEface converts srcv into an "interface {}" and writes it to
-// dstv.
-// Dstv must be a variable of type "interface {}" and srcv must either be an
-// interface or a pointer shaped variable (map, channel, pointer or struct
-// containing a single pointer)
-func convertToEface(srcv, dstv *Variable) error {
-	if dstv.RealType.String() != "interface {}" {
-		return &typeConvErr{srcv.DwarfType, dstv.RealType}
-	}
+// convertToInterface converts srcv into the interface type of dstv and
+// writes it to dstv. Runtime type information is read from mem.
+// Srcv must either be an interface or a pointer shaped variable (map,
+// channel, pointer or struct containing a single pointer), other values
+// must be copied into a new allocation first, see CopyToAlloc.
+func convertToInterface(mem MemoryReadWriter, srcv, dstv *Variable) error {
+	var typeAddr, data uint64
+	srcTypeName := srcv.TypeString()
 	if _, isiface := srcv.RealType.(*godwarf.InterfaceType); isiface {
-		// iface -> eface conversion
-		_type, data, _ := srcv.readInterface()
+		// iface -> eface and iface -> iface conversion
+		_type, srcdata, isnil := srcv.readInterface()
 		if srcv.Unreadable != nil {
 			return srcv.Unreadable
 		}
+		if isnil {
+			return dstv.writeZero()
+		}
+		var err error
+		data, err = srcdata.pointerShapedValue()
+		if err != nil {
+			return err
+		}
 		_type = _type.maybeDereference()
-		dstv.writeEmptyInterface(_type.Addr, data)
-		return nil
+		typeAddr = _type.Addr
+		if mds, err := LoadModuleData(srcv.bi, mem); err == nil {
+			if typ, _, err := RuntimeTypeToDIE(_type, data, mds); err == nil {
+				srcTypeName = typ.String()
+				if typ.Common().Name != "" {
+					srcTypeName = typ.Common().Name
+				}
+			}
+		}
+	} else {
+		var typeKind uint64
+		var found bool
+		var err error
+		typeAddr, typeKind, found, err = runtimeTypeOf(srcv.bi, mem, srcv.DwarfType)
+		if err != nil {
+			return fmt.Errorf("can not convert value of type %s to %s: %v", srcTypeName, dstv.DwarfType.String(), err)
+		}
+		if !found || typeKind&kindDirectIface == 0 {
+			return &typeConvErr{srcv.DwarfType, dstv.RealType}
+		}
+		data, err = srcv.pointerShapedValue()
+		if err != nil {
+			return err
+		}
 	}
-	typeAddr, typeKind, runtimeTypeFound, err := dwarfToRuntimeType(srcv.bi, srcv.mem, srcv.RealType)
+
+	if dstv.RealType.String() == "interface {}" {
+		return dstv.writeInterface(typeAddr, data)
+	}
+
+	interAddr, _, found, err := runtimeTypeOf(dstv.bi, mem, dstv.DwarfType)
+	if err == nil && !found {
+		err = errors.New("runtime type not found")
+	}
+	if err != nil {
+		return fmt.Errorf("can not convert value of type %s to %s: %v", srcTypeName, dstv.DwarfType.String(), err)
+	}
+	itab, found, err := findItab(dstv.bi, mem, interAddr, typeAddr)
 	if err != nil {
-		return fmt.Errorf("can not convert value of type %s to %s: %v", srcv.DwarfType.String(), dstv.DwarfType.String(), err)
+		return fmt.Errorf("can not convert value of type %s to %s: %v", srcTypeName, dstv.DwarfType.String(), err)
 	}
-	if !runtimeTypeFound || typeKind&kindDirectIface == 0 {
-		return &typeConvErr{srcv.DwarfType, dstv.RealType}
+	if !found {
+		return fmt.Errorf("can not convert value of type %s to %s: itab not found, the conversion is not used by the program", srcTypeName, dstv.DwarfType.String())
 	}
-	return dstv.writeEmptyInterface(typeAddr, srcv)
+	return dstv.writeInterface(itab, data)
 }
 
 func readStringInfo(mem MemoryReadWriter, arch *Arch, addr uint64, typ *godwarf.StringType) (uint64, int64, error) {
@@ -1759,10 +1845,14 @@ func (v *Variable) loadArrayValues(recurseLevel int, cfg LoadConfig) {
 
 	mem := v.mem
 	if v.Kind != reflect.Array {
-		mem = DereferenceMemory(mem)
+		mem = derefMemoryAt(mem, v.Base)
 	}
 
 	for i := int64(0); i < count; i++ {
+		if cfg.canceled() {
+			v.Flags |= VariableLoadCanceled
+			break
+		}
 		fieldvar := v.newVariable("", uint64(int64(v.Base)+(i*v.stride)), v.fieldType, mem)
 		fieldvar.loadValueInternal(recurseLevel+1, cfg)
 
@@ -1923,17 +2013,40 @@ func (v *Variable) writeZero() error {
 	return err
 }
 
-// writeEmptyInterface writes the empty interface of type typeAddr and data as the data field.
-func (v *Variable) writeEmptyInterface(typeAddr uint64, data *Variable) error {
-	dstType, dstData, _ := v.readInterface()
-	if v.Unreadable != nil {
-		return v.Unreadable
+// writeInterface writes an interface with type word typeWord, the type
+// for empty interfaces and the itab for other interfaces, and data word
+// data.
+func (v *Variable) writeInterface(typeWord, data uint64) error {
+	ityp := godwarf.ResolveTypedef(&v.RealType.(*godwarf.InterfaceType).TypedefType).(*godwarf.StructType)
+	for _, f := range ityp.Field {
+		var word uint64
+		switch f.Name {
+		case "tab", "_type":
+			word = typeWord
+		case "data":
+			word = data
+		default:
+			continue
+		}
+		fv, err := v.toField(f)
+		if err != nil {
+			return err
+		}
+		if err := fv.writeUint(word, fv.RealType.Size()); err != nil {
+			return err
+		}
 	}
-	dstType.writeUint(typeAddr, dstType.RealType.Size())
-	dstData.writeCopy(data)
 	return nil
 }
 
+// pointerShapedValue returns the value of v, a pointer shaped variable.
+func (v *Variable) pointerShapedValue() (uint64, error) {
+	if (v.Kind == reflect.Ptr || v.Kind == reflect.UnsafePointer) && len(v.Children) == 1 && (v.loaded || v.Addr == 0 || v.Flags&VariableFakeAddress != 0) {
+		return v.Children[0].Addr, nil
+	}
+	return readUintRaw(v.mem, v.Addr, int64(v.bi.Arch.PtrSize()))
+}
+
 func (v *Variable) writeSlice(len, cap int64, base uint64) error {
 	for _, f := range v.RealType.(*godwarf.SliceType).Field {
 		switch f.Name {
@@ -2039,6 +2152,10 @@ func (v *Variable) loadMap(recurseLevel int, cfg LoadConfig) {
 	count := 0
 	errcount := 0
 	for it.next() {
+		if cfg.canceled() {
+			v.Flags |= VariableLoadCanceled
+			break
+		}
 		key := it.key()
 		val := it.value()
 		key.loadValueInternal(recurseLevel+1, cfg)
@@ -2129,7 +2246,9 @@ func (v *Variable) loadInterface(recurseLevel int, loadData bool, cfg LoadConfig
 		return
 	}
 
-	mds, err := LoadModuleData(_type.bi, _type.mem)
+	// The interface could be stored in registers, the module data is
+	// always read from the memory of the target.
+	mds, err := LoadModuleData(_type.bi, DereferenceMemory(_type.mem))
 	if err != nil {
 		v.Unreadable = fmt.Errorf("error loading module data: %v", err)
 		return
@@ -104,7 +104,7 @@ func doFuzzEvalExpressionSetup(f *testing.F) {
 		fh, err := os.Create(fuzzCoredump)
 		assertNoError(err, f, "Creating coredump")
 		var state proc.DumpState
-		p.Dump(fh, 0, &state)
+		p.Dump(fh, proc.DumpOptions{}, &state)
 		assertNoError(state.Err, f, "Dump()")
 		out, err := exec.Command("cp", exePath, fuzzExecutable).CombinedOutput()
 		f.Log(string(out))
@@ -334,6 +334,46 @@ func TestSetVariable(t *testing.T) {
 	})
 }
 
+func TestConvenienceVariables(t *testing.T) {
+	withTestProcess("testvariables", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Continue(), t, "Continue()")
+
+		_, err := evalVariableWithCfg(p, "$x", pnormalLoadConfig)
+		if err == nil || err.Error() != "convenience variable $x is not set" {
+			t.Fatalf("unexpected error evaluating unset convenience variable: %v", err)
+		}
+
+		assertNoError(setVariable(p, "$x", "a2"), t, "SetVariable($x)")
+		assertNoError(setVariable(p, "$s", "a6"), t, "SetVariable($s)")
+		assertNoError(grp.ConvenienceVariables.SetLive("l", "a2"), t, "SetLive($l)")
+		assertNoError(grp.ConvenienceVariables.SetLive("loop", "$loop + 1"), t, "SetLive($loop)")
+
+		// changing the target must not change the snapshots
+		assertNoError(setVariable(p, "a2", "10"), t, "SetVariable(a2)")
+		assertNoError(setVariable(p, "a6.Baz", "20"), t, "SetVariable(a6.Baz)")
+
+		for _, tc := range []varTest{
+			{"$x", true, "6", "", "int", nil},
+			{"$l", true, "10", "", "int", nil},
+			{"$x + $l", false, "16", "", "int", nil},
+			{"$s", true, "main.FooBar {Baz: 8, Bur: \"word\"}", "", "main.FooBar", nil},
+			{"$s.Baz", true, "8", "", "int", nil},
+			{"a6.Baz - $s.Baz", false, "12", "", "int", nil},
+			{"$loop", true, "", "", "", errors.New("evaluating $loop: convenience variable $loop refers to itself")},
+		} {
+			variable, err := evalVariableWithCfg(p, tc.name, pnormalLoadConfig)
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Fatalf("%s: expected error %q, got %v", tc.name, tc.err, err)
+				}
+				continue
+			}
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", tc.name))
+			assertVariable(t, variable, tc)
+		}
+	})
+}
+
 func TestVariableEvaluationShort(t *testing.T) {
 	testcases := []varTest{
 		{"a1", true, "\"foofoofoofoofoofoo\"", "", "string", nil},
@@ -642,6 +682,21 @@ func getEvalExpressionTestCases() []varTest {
 		{"str1[11:]", false, "\"\"", "\"\"", "string", nil},
 		{"longbyteslice[:70]", false, "[]uint8 len: 70, cap: 144, [118,101,114,121,32,108,111,110,103,32,115,116,114,105,110,103,32,48,49,50,51,52,53,54,55,56,57,97,48,49,50,51,52,53,54,55,56,57,98,48,49,50,51,52,53,54,55,56,57,99,48,49,50,51,52,53,54,55,56,57,100,48,49,50,51,52,53,54,55,56]", "[]uint8 len: 70, cap: 144, [118,101,114,121,32,108,111,110,103,32,115,116,114,105,110,103,32,48,49,50,51,52,53,54,55,56,57,97,48,49,50,51,52,53,54,55,56,57,98,48,49,50,51,52,53,54,55,56,57,99,48,49,50,51,52,53,54,55,56,57,100,48,49,50,51,52,53,54,55,56]", "[]uint8", nil},
 		{"longbyteslice[:3][:5]", false, "[]uint8 len: 5, cap: 144, [118,101,114,121,32]", "[]uint8 len: 5, cap: 144, [118,101,114,121,32]", "[]uint8", nil},
+		{"s1[1:3:4]", false, "[]string len: 2, cap: 3, [\"two\",\"three\"]", "[]string len: 2, cap: 3, [\"two\",\"three\"]", "[]string", nil},
+		{"a1[:2:2]", false, "[]string len: 2, cap: 2, [\"one\",\"two\"]", "[]string len: 2, cap: 2, [\"one\",\"two\"]", "[]string", nil},
+		{"s1[1:3:4][:3]", false, "[]string len: 3, cap: 3, [\"two\",\"three\",\"four\"]", "[]string len: 3, cap: 3, [\"two\",\"three\",\"four\"]", "[]string", nil},
+		{"s1[1:3:4][:4]", false, "", "", "", errors.New("index out of bounds")},
+		{"s1[1:3:6]", false, "", "", "", errors.New("index out of bounds")},
+		{"s1[1:3:2]", false, "", "", "", errors.New("index out of bounds")},
+		{"s3[2:4]", false, "[]int len: 2, cap: 4, [0,0]", "[]int len: 2, cap: 4, [0,0]", "[]int", nil},
+		{"len(s1[1:3:4]) > 0", false, "true", "true", "", nil},
+		{"cap(s1[1:3:4])", false, "3", "3", "", nil},
+		{"str1[1:2:3]", false, "", "", "", errors.New("invalid operation: 3-index slice of string")},
+		{"parr[1:3]", false, "[]int len: 2, cap: 3, [1,2]", "[]int len: 2, cap: 3, [1,2]", "[]int", nil},
+		{"parr[:2:3]", false, "[]int len: 2, cap: 3, [0,1]", "[]int len: 2, cap: 3, [0,1]", "[]int", nil},
+		{"parr[3:5]", false, "", "", "", errors.New("index out of bounds")},
+		{"[4]int{4, 5, 6, 7}[1:3]", false, "[]int len: 2, cap: 3, [5,6]", "[]int len: 2, cap: 3, [5,6]", "[]int", nil},
+		{"[4]int{4, 5, 6, 7}[1:3:3][1]", false, "6", "6", "int", nil},
 
 		// NaN and Inf floats
 		{"pinf", false, "+Inf", "+Inf", "float64", nil},
@@ -774,6 +829,37 @@ func getEvalExpressionTestCases() []varTest {
 		{"min(s1[0], s1[1], s1[2])", false, `"one"`, `"one"`, "string", nil},
 		{`max(s1[0], "two", s1[2])`, false, `"two"`, `"two"`, "", nil},
 		{`min(s1[0], "two", s1[2])`, false, `"one"`, `"one"`, "string", nil},
+		{"chanpeek(ch1, 0)", false, "1", "1", "int", nil},
+		{"chanpeek(ch1, 3)", false, "2", "2", "int", nil},
+		{"<-ch1", false, "1", "1", "int", nil},
+		{"chanpeek(ch1, 4)", false, "", "", "", errors.New("index 4 out of bounds for channel buffer of length 4")},
+		{"chanpeek(chnil, 0)", false, "", "", "", errors.New("chnil is a nil channel")},
+		{"chanpeek(int3chan, 1).a", false, "2", "2", "int", nil},
+		{"chanpeek(m1, 0)", false, "", "", "", errors.New("invalid argument m1 (type map[string]main.astruct) for chanpeek")},
+		{"mapkeys(m2)", false, "[1]int [1]", "[1]int [...]", "[1]int", nil},
+		{"mapvalues(m2)[0].A", false, "10", "10", "int", nil},
+		{"len(mapkeys(m1))", false, "66", "66", "", nil},
+		{"len(mapvalues(mnil))", false, "0", "0", "", nil},
+		{`haskey(m1, "Malone")`, false, "true", "true", "", nil},
+		{`haskey(m1, "Kohl")`, false, "false", "false", "", nil},
+		{`haskey(mnil, "Malone")`, false, "false", "false", "", nil},
+		{`haskey(m1, "Malone") && len(mapkeys(m2)) == 1`, false, "true", "true", "", nil},
+		{"haskey(ch1, 1)", false, "", "", "", errors.New("invalid argument ch1 (type chan int) for haskey")},
+
+		// composite literals
+		{"[3]int{1, 2}", false, "[3]int [1,2,0]", "[3]int [...]", "[3]int", nil},
+		{"[...]int{2: 5, 1}[2]", false, "5", "5", "int", nil},
+		{"len([...]int{4: 1})", false, "5", "5", "", nil},
+		{"len([...]int{2*(1+1) + 1: 1})", false, "6", "6", "", nil},
+		{"len([...]int{main.UintConst: 1})", false, "43", "43", "", nil},
+		{"len([...]int{UintConst - 40: 1})", false, "3", "3", "", nil},
+		{"[...]int{i1: 1}", false, "", "", "", errors.New("index i1 must be a constant expression: i1 is not constant")},
+		{`[...]int{"a": 1}`, false, "", "", "", errors.New(`index "a" must be an integer constant`)},
+		{"[...]int{-1: 1}", false, "", "", "", errors.New("index -1 out of range")},
+		{"[2][2]int{{1, 2}, {3}}[1]", false, "[2]int [3,0]", "[2]int [...]", "[2]int", nil},
+		{"[]int{1, 2}", false, "", "", "", errors.New("literal can not be allocated because function calls are not allowed without using 'call'")},
+		{"[2]int{1, 2, 3}", false, "", "", "", errors.New("index 2 out of bounds for [2]int literal")},
+		{"[]int{1, 0: 2}", false, "", "", "", errors.New("duplicate index 0 in literal")},
 
 		// nil
 		{"nil", false, "nil", "nil", "", nil},
@@ -846,6 +932,14 @@ func getEvalExpressionTestCases() []varTest {
 		{"ifacearr", false, "[]error len: 2, cap: 2, [*main.astruct {A: 0, B: 0},nil]", "[]error len: 2, cap: 2, [...]", "[]error", nil},
 		{"efacearr", false, `[]interface {} len: 3, cap: 3, [*main.astruct {A: 0, B: 0},"test",nil]`, "[]interface {} len: 3, cap: 3, [...]", "[]interface {}", nil},
 
+		// comparison of interfaces with untyped constants
+		{`iface2 == "test"`, false, "true", "true", "", nil},
+		{`"test" != iface2`, false, "false", "false", "", nil},
+		{`iface2 == "other"`, false, "false", "false", "", nil},
+		{`iface1 == "test"`, false, "false", "false", "", nil},
+		{`iface2 == 1`, false, "false", "false", "", nil},
+		{`ifacenil != 1`, false, "true", "true", "", nil},
+
 		{"zsslice", false, `[]struct {} len: 3, cap: 3, [{},{},{}]`, `[]struct {} len: 3, cap: 3, [...]`, "[]struct {}", nil},
 		{"zsvmap", false, `map[string]struct {} ["testkey": {}, ]`, `map[string]struct {} [...]`, "map[string]struct {}", nil},
 		{"tm", false, "main.truncatedMap {v: []map[string]main.astruct len: 1, cap: 1, [[...]]}", "main.truncatedMap {v: []map[string]main.astruct len: 1, cap: 1, [...]}", "main.truncatedMap", nil},
@@ -1310,6 +1404,7 @@ func TestCallFunction(t *testing.T) {
 		{`vable_pa.VRcvr(6)`, []string{`:string:"6 + 6 = 12"`}, nil, 0}, // indirect call of method on interface / containing value with value method
 		{`pable_pa.PRcvr(7)`, []string{`:string:"7 - 6 = 1"`}, nil, 0},  // indirect call of method on interface / containing pointer with value method
 		{`vable_a.VRcvr(5)`, []string{`:string:"5 + 3 = 8"`}, nil, 0},   // indirect call of method on interface / containing pointer with pointer method
+		{`methoder.Method()`, []string{`:int:4`}, nil, 0},               // indirect call of method on interface / promoted from an embedded field
 
 		{`pa.nonexistent()`, nil, errors.New("pa has no member nonexistent"), 0},
 		{`a.nonexistent()`, nil, errors.New("a has no member nonexistent"), 0},
@@ -1325,6 +1420,9 @@ func TestCallFunction(t *testing.T) {
 
 		{"fn2nil()", nil, errors.New("nil pointer dereference"), 0},
 
+		{`fnslice[0](15)`, []string{`:string:"15 + 6 = 21"`}, nil, 0},        // indirect call of func value / element of a slice
+		{`fnmap["clos"](16)`, []string{`:string:"3 + 6 + 16 = 25"`}, nil, 0}, // indirect call of func value / element of a map
+
 		{"ga.PRcvr(2)", []string{`:string:"2 - 0 = 2"`}, nil, 0},
 
 		{"x.CallMe()", nil, nil, 0},
@@ -1410,6 +1508,32 @@ func TestCallFunction(t *testing.T) {
 		{`mul2ptr(&main.a2struct{Y: 3})`, []string{":int:6"}, nil, 1},
 		{`mul2ptr(&main.a2struct{1})`, []string{":int:2"}, nil, 1},
 		{`m[main.intpair{3, 1}]`, []string{`:string:"three,one"`}, nil, 0},
+
+		// slice, array and map literals
+		{`stringsJoin([]string{"a", "b"}, ",")`, []string{`:string:"a,b"`}, nil, 4},
+		{`stringsJoin([]string{2: "c", 0: "a", "b"}, "")`, []string{`:string:"abc"`}, nil, 4},
+		{`stringsJoin([]string{}, ",")`, []string{`:string:""`}, nil, 2},
+		{`mapsum(map[string]int{"a": 1, "b": 2, "c": 3})`, []string{":int:6"}, nil, 7},
+		{`mul2(([2]main.a2struct{{Y: 1}, {Y: 2}})[1])`, []string{":int:4"}, nil, 0},
+		{`mul2(([...]main.a2struct{3: {Y: 5}})[3])`, []string{":int:10"}, nil, 0},
+		{`mul2ptr(([]*main.a2struct{{Y: 6}})[0])`, []string{":int:12"}, nil, 2},
+		{`intslice = []int{4, 5}; intslice`, []string{`intslice:[]int:[]int len: 2, cap: 2, [4,5]`}, nil, 1},
+		{`stringslice = [][]string{{"x", "y"}, {"z"}}[0]; stringslice`, []string{`stringslice:[]string:[]string len: 2, cap: 2, ["x","y"]`}, nil, 6},
+
+		// assignments that need allocations or runtime calls
+		{`str = "new value"; str`, []string{`str:string:"new value"`}, nil, 1},
+		{`iface = 5; iface`, []string{`iface:interface {}:interface {}(int) 5`}, nil, 0},
+		{`iface = "boxed"; iface`, []string{`iface:interface {}:interface {}(string) "boxed"`}, nil, 1},
+		{`iface = a; iface`, []string{`iface:interface {}:interface {}(main.astruct) {X: 3}`}, nil, 0},
+		{`iface = x; iface`, []string{`iface:interface {}:interface {}(main.X) 2`}, nil, 0},
+		{`iface = main.strpair{"lit", 3}; iface`, []string{`iface:interface {}:interface {}(main.strpair) {S: "lit", N: 3}`}, nil, 1},
+		{`vable_pa = a; vable_pa`, []string{`vable_pa:main.VRcvrable:main.VRcvrable(main.astruct) {X: 3}`}, nil, 0},
+		{`vable_a = pa; vable_a`, []string{`vable_a:main.VRcvrable:main.VRcvrable(*main.astruct) *{X: 6}`}, nil, 0},
+		{`pable_pa = a`, nil, errors.New("can not convert value of type main.astruct to main.PRcvrable: itab not found, the conversion is not used by the program"), 0},
+		{`sp = main.strpair{S: "new", N: 2}; sp`, []string{`sp:main.strpair:main.strpair {S: "new", N: 2}`}, nil, 1},
+		{`smap["one"] = 10; smap`, []string{`smap:map[string]int:map[string]int ["one": 10, "two": 2, ]`}, nil, 1},
+		{`smap[comma] = 3; smap[comma]`, []string{`:int:3`}, nil, 0},
+		{`delete(smap, "two"); smap`, []string{`smap:map[string]int:map[string]int ["one": 10, ",": 3, ]`}, nil, 1},
 	}
 
 	withTestProcessArgs("fncall", t, ".", nil, protest.AllNonOptimized, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
@@ -1461,6 +1585,46 @@ func TestCallFunction(t *testing.T) {
 	})
 }
 
+func TestMethodSet(t *testing.T) {
+	testcases := []struct {
+		expr    string
+		methods []string
+	}{
+		{"a", []string{"VRcvr func(int) string"}},
+		{"pa", []string{"PRcvr func(int) string", "VRcvr func(int) string"}},
+		{"vable_a", []string{"VRcvr func(int) string"}},
+		{"pable_pa", []string{"PRcvr func(int) string", "VRcvr func(int) string"}},
+		{"d", []string{"Method func() int"}},
+		{"methoder", []string{"Method func() int"}},
+		{"x", []string{"CallMe func()"}},
+		{"iface", nil},
+	}
+	withTestProcessArgs("fncall", t, ".", nil, protest.AllNonOptimized, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Continue(), t, "Continue()")
+		scope, err := proc.GoroutineScope(p, p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope")
+		for _, tc := range testcases {
+			v, err := scope.EvalExpression(tc.expr, pnormalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalExpression(%s)", tc.expr))
+			methods, err := scope.MethodSet(v)
+			assertNoError(err, t, fmt.Sprintf("MethodSet(%s)", tc.expr))
+			var out []string
+			for _, m := range methods {
+				out = append(out, fmt.Sprintf("%s %s", m.Name, m.Type.Common().Name))
+			}
+			if !slices.Equal(out, tc.methods) {
+				t.Errorf("%s: expected %q got %q", tc.expr, tc.methods, out)
+			}
+		}
+		// methods called through an interface are kept by the linker
+		methods, err := scope.MethodSet(evalVariable(p, t, "methoder"))
+		assertNoError(err, t, "MethodSet(methoder)")
+		if methods[0].Fn == nil || methods[0].Fn.Name != "main.(*Derived).Method" {
+			t.Errorf("wrong function for methoder.Method: %v", methods[0].Fn)
+		}
+	})
+}
+
 func testCallFunctionSetBreakpoint(t *testing.T, p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
 	buf, err := os.ReadFile(fixture.Source)
 	assertNoError(err, t, "ReadFile")
@@ -1870,6 +2034,33 @@ func TestCapturedVariable(t *testing.T) {
 	})
 }
 
+func TestClosureCapturedVariables(t *testing.T) {
+	// Checks that the variables captured by a closure are loaded as children
+	// of the func value.
+	if !goversion.VersionAfterOrEqual(runtime.Version(), 1, 23) {
+		t.Skip("N/A")
+	}
+	protest.AllowRecording(t)
+	withTestProcess("fncall", t, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		checkClosure := func(tgt string) {
+			t.Helper()
+			v := evalVariable(p, t, "fn2clos")
+			if out := api.ConvertVar(v).MultilineString("", ""); out != tgt {
+				t.Errorf("fn2clos: got %q, expected %q", out, tgt)
+			}
+		}
+
+		assertNoError(grp.Continue(), t, "Continue()")
+		checkClosure("main.makeclos.func1 {\n\ti int = 0\n\tpa *main.astruct = *{X: 6}\n}")
+
+		// i is captured by reference, the change made by the call to fn2clos
+		// must be visible.
+		setFileBreakpoint(p, t, fixture.Source, 307)
+		assertNoError(grp.Continue(), t, "Continue()")
+		checkClosure("main.makeclos.func1 {\n\ti int = 1\n\tpa *main.astruct = *{X: 6}\n}")
+	})
+}
+
 func TestSetupRangeFramesCrash(t *testing.T) {
 	// See issue #3806
 	if !goversion.VersionAfterOrEqual(runtime.Version(), 1, 23) {
@@ -1967,3 +2158,34 @@ func TestCallFunctionRegisterArg(t *testing.T) {
 		assertNoError(proc.EvalExpressionWithCalls(grp, p.SelectedGoroutine(), "value.Type()", pnormalLoadConfig, true), t, "EvalExpressionWithCalls")
 	})
 }
+
+func TestCallFunctionGeneric(t *testing.T) {
+	protest.MustSupportFunctionCalls(t, testBackend)
+	if !goversion.VersionAfterOrEqual(runtime.Version(), 1, 21) {
+		t.Skip("not supported")
+	}
+	protest.AllowRecording(t)
+
+	var testcases = []testCaseCallFunction{
+		{"Add[int](1, 2)", []string{":int:3"}, nil, 0},
+		{"main.Add[float64](1.5, 2)", []string{":float64:3.5"}, nil, 0},
+		{"Add[main.MyInt](3, 4)", []string{":main.MyInt:7"}, nil, 0},
+		{`Pair[string, int]("a", 1)`, []string{`:string:"a=1"`}, nil, 1},
+		{"First[*main.astruct](s)", []string{":*main.astruct:*main.astruct {X: 1, Y: 2}"}, nil, 0},
+		{"First[string](strs)", []string{`:string:"a"`}, nil, 0},
+		{"bi.Get()", []string{":int:4"}, nil, 0},
+		{"bs.Get()", []string{`:string:"hello"`}, nil, 0},
+		{"bp.Set(5); bp.val", []string{"bp.val:int:5"}, nil, 0},
+
+		{"Add[float32](1, 2)", nil, errors.New("instantiation main.Add[float32] was not compiled into the executable"), 0},
+		{`bs.Set("x")`, nil, errors.New("instantiation main.(*Box[string]).Set was not compiled into the executable"), 0},
+		{"Add[MyInt](3, 4)", nil, errors.New("could not instantiate Add: no type entry found, use 'types' for a list of valid types"), 0},
+	}
+
+	withTestProcessArgs("fncall_generic", t, ".", nil, protest.AllNonOptimized, func(p *proc.Target, grp *proc.TargetGroup, fixture protest.Fixture) {
+		assertNoError(grp.Continue(), t, "Continue()")
+		for _, tc := range testcases {
+			testCallFunction(t, grp, p, tc)
+		}
+	})
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/cosiner/argv"
 	"github.com/go-delve/delve/pkg/config"
 	"github.com/go-delve/delve/pkg/locspec"
+	"github.com/go-delve/delve/pkg/proc"
 	"github.com/go-delve/delve/pkg/proc/debuginfod"
 	"github.com/go-delve/delve/service"
 	"github.com/go-delve/delve/service/api"
@@ -202,7 +203,12 @@ For example:
 	continue main.main
 	continue encoding/json.Marshal
 `},
-		{aliases: []string{"step", "s"}, group: runCmds, cmdFn: c.step, allowedPrefixes: revPrefix, helpMsg: "Single step through program."},
+		{aliases: []string{"step", "s"}, group: runCmds, cmdFn: c.step, allowedPrefixes: revPrefix, helpMsg: `Single step through program.
+
+	step [-target [n]]
+
+With -target steps into the n-th function call of the current line that has not been executed yet, stepping over the other calls. Without n the list of calls is printed.
+`},
 		{aliases: []string{"step-instruction", "si", "stepi"}, group: runCmds, allowedPrefixes: revPrefix, cmdFn: c.stepInstruction, helpMsg: "Single step a single cpu instruction."},
 		{aliases: []string{"next-instruction", "ni", "nexti"}, group: runCmds, allowedPrefixes: revPrefix, cmdFn: c.nextInstruction, helpMsg: "Single step a single cpu instruction, skipping function calls."},
 		{aliases: []string{"next", "n"}, group: runCmds, cmdFn: c.next, allowedPrefixes: revPrefix, helpMsg: `Step over to next source line.
@@ -216,6 +222,12 @@ Optional [count] argument allows you to skip multiple lines.
 	
 	call [-unsafe] <function call expression>
 	
+Generic functions are called by specifying their type arguments, for
+example 'call pkg.F[int](x)', the dictionary argument is passed
+automatically. Methods of instantiated generic types can be called
+normally. In both cases the instantiation must have been compiled into
+the executable.
+
 Current limitations:
 - only pointers to stack-allocated objects can be passed as argument.
 - only some automatic type conversions are supported.
@@ -228,6 +240,13 @@ Current limitations:
 - calling a function will resume execution of all goroutines.
 - only supported on linux's native backend.
 `},
+		{aliases: []string{"jump"}, group: runCmds, cmdFn: c.jump, helpMsg: `Moves the program counter to a different line of the current function.
+
+	jump <locspec>
+
+The code between the current line and the destination is not executed. The destination must belong to the function of the topmost frame of the current goroutine, it can not be inside a closure (or outside of the current closure) and the jump can not cross a defer statement. Local variables are not updated and may be uninitialized at the destination.
+
+See Documentation/cli/locspec.md for the syntax of locspec.`},
 		{aliases: []string{"threads"}, group: goroutineCmds, cmdFn: threads, helpMsg: "Print out info for every traced thread."},
 		{aliases: []string{"thread", "tr"}, group: goroutineCmds, cmdFn: thread, helpMsg: `Switch to the specified thread.
 
@@ -352,12 +371,18 @@ See Documentation/cli/expr.md for a description of supported expressions.
 The optional format argument is a format specifier, like the ones used by the fmt package. For example "print %x v" will print v as an hexadecimal number.`},
 		{aliases: []string{"whatis"}, group: dataCmds, cmdFn: whatisCommand, helpMsg: `Prints type of an expression.
 
-	whatis <expression>`},
+	whatis [-methods] <expression>
+
+With -methods the method set of the type is also printed, if the expression is an interface the methods of its concrete type are printed. The method set is read from the runtime type information of the program, methods that are never called are usually removed by the linker and can not be called by the debugger.`},
 		{aliases: []string{"set"}, group: dataCmds, cmdFn: setVar, helpMsg: `Changes the value of a variable.
 
 	[goroutine <n>] [frame <m>] set <variable> = <value>
+	[goroutine <n>] [frame <m>] set $<name> = <value>
+	set -live $<name> = <expression>
 
-See Documentation/cli/expr.md for a description of supported expressions. Only numerical variables and pointers can be changed.`},
+See Documentation/cli/expr.md for a description of supported expressions. Numbers, pointers, strings, slices, structs, interfaces and map elements can be changed. Assignments that need memory allocated in the target program (string and composite literals, non pointer-shaped values assigned to interfaces, map element insertions) use call injection and only work on frame 0 of a goroutine stopped at a safe point.
+
+The second form sets the convenience variable $<name> to a snapshot of <value>, taken when the command is executed. The third form sets $<name> to <expression>, which is evaluated every time $<name> is used. Convenience variables can be used in any expression, including breakpoint conditions, and are kept when the target is restarted, unless they contain a snapshot of something other than a boolean, a number or a string.`},
 		{aliases: []string{"sources"}, cmdFn: sources, helpMsg: `Print list of source files.
 
 	sources [<regex>]
@@ -606,9 +631,43 @@ If display is called without arguments it will print the value of all expression
 
 		{aliases: []string{"dump"}, cmdFn: dump, helpMsg: `Creates a core dump from the current process state
 
-	dump <output file>
+	dump [-exclude-file-backed] [-max-mapping-size <size>] [-reachable] [-compress gzip|zstd|none] <output file>
+
+The core dump is always written in ELF, even on systems (windows, macOS) where this is not customary. For environments other than linux/amd64 threads and registers are dumped in a format that only Delve can read back.
+
+Options:
+
+	-exclude-file-backed	do not dump read-only memory mappings backed by a file, their contents will not be available when the core dump is opened (except for the executable)
+	-max-mapping-size <size>	do not dump anonymous memory mappings larger than size bytes, the size can be followed by K, M or G
+	-reachable	only dump the stacks of goroutines and threads, global variables, the heap objects reachable from them and the memory they point to outside of the heap
+	-compress <format>	compress the core dump with gzip or zstd, by default output files ending in .gz or .zst are compressed with gzip and zstd respectively
+
+Compressed core dumps can be opened with 'dlv core'.`},
+
+		{aliases: []string{"heap"}, group: dataCmds, cmdFn: heapCommand, helpMsg: `Lists the objects allocated in the heap.
+
+	heap [-top <n>]
+	heap -type <type> [-max <n>]
+
+The first form prints the number of objects of each type allocated in the heap and the memory they use, sorted by memory used. If -top is specified only the first n types are printed. Arrays with the same element type T are counted together as [...]T.
+
+The second form prints the address and size of the objects of the specified type, and of the arrays of elements of the specified type. At most n objects are printed (default 100), 0 means all objects.
+
+The type of an object is read from the runtime type information, when the runtime records it, or inferred by following typed pointers from global variables, from the local variables of all goroutines and from objects of known type. Objects whose type can not be determined are counted as <unknown>.`},
+
+		{aliases: []string{"refs"}, group: dataCmds, cmdFn: refsCommand, helpMsg: `Lists the pointers to a heap object.
+
+	refs <expression>
 
-The core dump is always written in ELF, even on systems (windows, macOS) where this is not customary. For environments other than linux/amd64 threads and registers are dumped in a format that only Delve can read back.`},
+The object is the one the expression points to, if it evaluates to a pointer, a map or a channel, the object at the address it evaluates to, if it is an integer, or the object containing its value otherwise.
+
+Global variables, the live local variables of all goroutines, finalizers and all heap objects are scanned for pointers into the object. For each pointer its address and its retention path are printed, for example:
+
+	main.cache -> map bucket -> *main.Session.conn
+
+the path starts with a root and lists the heap objects, and their fields, that have to be traversed to reach the pointer. The liveness of local variables is determined using the stack maps of the garbage collector, on architectures where they are supported. Pointers stored in objects that can not be reached without passing through the object itself, and therefore do not keep it alive, are listed separately.
+
+The command also works on core files, including the ones written by the dump command.`},
 
 		{aliases: []string{"transcript"}, cmdFn: transcript, helpMsg: `Appends command output to a file.
 
@@ -1511,6 +1570,27 @@ func (c *Commands) step(t *Term, ctx callContext, args string) error {
 	if ctx.Prefix == revPrefix {
 		stepfn = t.client.ReverseStep
 	}
+	if argv := config.Split2PartsBySpace(args); argv[0] == "-target" {
+		if ctx.Prefix == revPrefix {
+			return errors.New("can not step into a specific call backwards")
+		}
+		targets, err := t.client.StepInTargets(-1)
+		if err != nil {
+			return err
+		}
+		if len(argv) < 2 {
+			printStepInTargets(t, targets)
+			return nil
+		}
+		n, err := strconv.Atoi(argv[1])
+		if err != nil || n <= 0 || n > len(targets) {
+			return fmt.Errorf("invalid target %q, there are %d calls on the current line", argv[1], len(targets))
+		}
+		pc := targets[n-1].PC
+		stepfn = func() (*api.DebuggerState, error) {
+			return t.client.StepInTarget(pc)
+		}
+	}
 	state, err := exitedToError(stepfn())
 	if err != nil {
 		printcontextNoState(t)
@@ -1520,6 +1600,20 @@ func (c *Commands) step(t *Term, ctx callContext, args string) error {
 	return continueUntilCompleteNext(t, state, "step", true)
 }
 
+func printStepInTargets(t *Term, targets []api.StepInTarget) {
+	if len(targets) == 0 {
+		fmt.Fprintln(t.stdout, "No function calls on the current line.")
+		return
+	}
+	for i, target := range targets {
+		if target.Function != nil {
+			fmt.Fprintf(t.stdout, "%d\t%s\n", i+1, target.Function.Name())
+		} else {
+			fmt.Fprintf(t.stdout, "%d\tdynamic call at %#x\n", i+1, target.PC)
+		}
+	}
+}
+
 var errNotOnFrameZero = errors.New("not on topmost frame")
 
 // stepInstruction implements the step-instruction (stepi) command.
@@ -1648,6 +1742,35 @@ func (c *Commands) call(t *Term, ctx callContext, args string) error {
 	return continueUntilCompleteNext(t, state, "call", true)
 }
 
+func (c *Commands) jump(t *Term, ctx callContext, args string) error {
+	if err := scopePrefixSwitch(t, ctx); err != nil {
+		return err
+	}
+	if c.frame != 0 {
+		return errNotOnFrameZero
+	}
+	if len(args) == 0 {
+		return errors.New("not enough arguments")
+	}
+	locs, _, err := t.client.FindLocation(ctx.Scope, args, false, t.substitutePathRules())
+	if err != nil {
+		return err
+	}
+	if len(locs) > 1 {
+		return locspec.AmbiguousLocationError{Location: args, CandidatesLocation: locs}
+	}
+
+	defer t.onStop()
+
+	state, err := t.client.Jump(ctx.Scope.GoroutineID, locs[0].File, locs[0].Line)
+	if err != nil {
+		return err
+	}
+	printcontext(t, state)
+	printPos(t, state.CurrentThread, printPosShowArrow)
+	return nil
+}
+
 func clear(t *Term, ctx callContext, args string) error {
 	if len(args) == 0 {
 		return errors.New("not enough arguments")
@@ -2243,6 +2366,11 @@ func (c *Commands) printVar(t *Term, ctx callContext, args string) error {
 }
 
 func whatisCommand(t *Term, ctx callContext, args string) error {
+	methods := false
+	if rest, ok := strings.CutPrefix(args, "-methods"); ok && (rest == "" || rest[0] == ' ') {
+		methods = true
+		args = strings.TrimSpace(rest)
+	}
 	if len(args) == 0 {
 		return errors.New("not enough arguments")
 	}
@@ -2266,10 +2394,49 @@ func whatisCommand(t *Term, ctx callContext, args string) error {
 	if t.conf.ShowLocationExpr && val.LocationExpr != "" {
 		fmt.Fprintf(t.stdout, "location: %s\n", val.LocationExpr)
 	}
+	if methods {
+		ms, err := t.client.ListMethods(ctx.Scope, args)
+		if err != nil {
+			return err
+		}
+		if len(ms) == 0 {
+			fmt.Fprintln(t.stdout, "No methods")
+			return nil
+		}
+		fmt.Fprintln(t.stdout, "Methods:")
+		for _, m := range ms {
+			typ := strings.TrimPrefix(m.Type, "func")
+			if m.Fn == nil {
+				fmt.Fprintf(t.stdout, "\t%s%s (removed by the linker)\n", m.Name, typ)
+			} else {
+				fmt.Fprintf(t.stdout, "\t%s%s\n", m.Name, typ)
+			}
+		}
+	}
 	return nil
 }
 
 func setVar(t *Term, ctx callContext, args string) error {
+	live := false
+	if rest, ok := strings.CutPrefix(args, "-live "); ok {
+		live = true
+		args = strings.TrimSpace(rest)
+	}
+	if strings.HasPrefix(args, "$") {
+		name, value, ok := strings.Cut(args, "=")
+		if !ok {
+			return errors.New("syntax error '=' not found")
+		}
+		name = strings.TrimSpace(name)
+		if live {
+			return t.client.SetLiveVariable(name[1:], strings.TrimSpace(value))
+		}
+		return t.client.SetVariable(ctx.Scope, name, value)
+	}
+	if live {
+		return errors.New("-live can only be used with convenience variables")
+	}
+
 	// HACK: in go '=' is not an operator, we detect the error and try to recover from it by splitting the input string
 	_, err := parser.ParseExpr(args)
 	if err == nil {
@@ -2283,7 +2450,27 @@ func setVar(t *Term, ctx callContext, args string) error {
 
 	lexpr := args[:el[0].Pos.Offset]
 	rexpr := args[el[0].Pos.Offset+1:]
-	return t.client.SetVariable(ctx.Scope, lexpr, rexpr)
+	err = t.client.SetVariable(ctx.Scope, lexpr, rexpr)
+	if err == nil || !needsCallInjection(err) || ctx.Scope.Frame != 0 || ctx.Scope.DeferredCall != 0 {
+		return err
+	}
+
+	// The new value must be allocated in the target program, or the
+	// assignment needs runtime functions, use call injection.
+	state, err := exitedToError(t.client.Call(ctx.Scope.GoroutineID, lexpr+"="+rexpr, false))
+	if err != nil {
+		printcontextNoState(t)
+		return err
+	}
+	printcontext(t, state)
+	return continueUntilCompleteNext(t, state, "set", false)
+}
+
+// needsCallInjection returns true if err was returned because evaluating
+// an expression requires function call injection.
+func needsCallInjection(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, proc.ErrFuncCallNotAllowedLitAlloc.Error()) || strings.Contains(msg, proc.ErrFuncCallNotAllowedStrAlloc.Error())
 }
 
 func (t *Term) printFilteredVariables(varType string, vars []api.Variable, filter string, cfg api.LoadConfig) error {
@@ -3377,10 +3564,56 @@ func display(t *Term, ctx callContext, args string) error {
 }
 
 func dump(t *Term, ctx callContext, args string) error {
+	var opts api.DumpOptions
+	compressionSet := false
+	nextArg := func() string {
+		v := config.Split2PartsBySpace(args)
+		args = ""
+		if len(v) == 2 {
+			args = v[1]
+		}
+		return v[0]
+	}
+	for strings.HasPrefix(args, "-") {
+		opt := nextArg()
+		switch opt {
+		case "-exclude-file-backed":
+			opts.ExcludeFileBacked = true
+		case "-reachable":
+			opts.ReachableOnly = true
+		case "-max-mapping-size", "-compress":
+			if args == "" {
+				return fmt.Errorf("expected argument after %s", opt)
+			}
+			arg := nextArg()
+			if opt == "-compress" {
+				compressionSet = true
+				if arg != "none" {
+					opts.Compression = arg
+				}
+				break
+			}
+			size, err := parseByteSize(arg)
+			if err != nil {
+				return fmt.Errorf("invalid size %q: %v", arg, err)
+			}
+			opts.MaxMappingSize = size
+		default:
+			return fmt.Errorf("unknown option %s", opt)
+		}
+	}
 	if args == "" {
 		return errors.New("not enough arguments")
 	}
-	dumpState, err := t.client.CoreDumpStart(args)
+	if !compressionSet {
+		switch filepath.Ext(args) {
+		case ".gz":
+			opts.Compression = "gzip"
+		case ".zst":
+			opts.Compression = "zstd"
+		}
+	}
+	dumpState, err := t.client.CoreDumpStartWithOptions(args, opts)
 	if err != nil {
 		return err
 	}
@@ -3406,6 +3639,132 @@ func dump(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+// parseByteSize parses a number of bytes optionally followed by one of the
+// suffixes K, M or G.
+func parseByteSize(s string) (uint64, error) {
+	mult := uint64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k', 'K':
+			mult = 1 << 10
+		case 'm', 'M':
+			mult = 1 << 20
+		case 'g', 'G':
+			mult = 1 << 30
+		}
+		if mult != 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * mult, nil
+}
+
+func heapCommand(t *Term, ctx callContext, argstr string) error {
+	var typ string
+	top, max := 0, 100
+	args := strings.Fields(argstr)
+	for i := 0; i < len(args); i++ {
+		opt := args[i]
+		if i+1 >= len(args) {
+			return fmt.Errorf("expected argument after %s", opt)
+		}
+		i++
+		var err error
+		switch opt {
+		case "-type":
+			typ = args[i]
+		case "-top":
+			top, err = strconv.Atoi(args[i])
+		case "-max":
+			max, err = strconv.Atoi(args[i])
+		default:
+			return fmt.Errorf("unknown option %q", opt)
+		}
+		if err != nil {
+			return fmt.Errorf("wrong argument for %s: %v", opt, err)
+		}
+	}
+
+	types, objs, count, bytes, err := t.client.ListHeapObjects(typ, top, max)
+	if err != nil {
+		return err
+	}
+	typeName := func(name string) string {
+		if name == "" {
+			return "<unknown>"
+		}
+		return name
+	}
+	w := new(tabwriter.Writer)
+	w.Init(t.stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	if typ != "" {
+		fmt.Fprintln(w, "Address\tSize\t Type")
+		for _, obj := range objs {
+			fmt.Fprintf(w, "%#x\t%d\t %s\n", obj.Addr, obj.Size, typeName(obj.Type))
+		}
+	} else {
+		fmt.Fprintln(w, "Count\tBytes\t Type")
+		for _, ht := range types {
+			fmt.Fprintf(w, "%d\t%d\t %s\n", ht.Count, ht.Bytes, typeName(ht.Type))
+		}
+	}
+	w.Flush()
+	if typ != "" && int64(len(objs)) < count {
+		fmt.Fprintf(t.stdout, "(%d objects not shown)\n", count-int64(len(objs)))
+	}
+	fmt.Fprintf(t.stdout, "Total: %d objects, %d bytes\n", count, bytes)
+	return nil
+}
+
+func refsCommand(t *Term, ctx callContext, args string) error {
+	if args == "" {
+		return errors.New("not enough arguments")
+	}
+	obj, refs, err := t.client.ListObjectReferences(ctx.Scope, args)
+	if err != nil {
+		return err
+	}
+	typ := obj.Type
+	if typ == "" {
+		typ = "<unknown>"
+	}
+	fmt.Fprintf(t.stdout, "%s at %#x (%d bytes)\n", typ, obj.Addr, obj.Size)
+	printRefs := func(reachable bool) int {
+		n := 0
+		w := new(tabwriter.Writer)
+		w.Init(t.stdout, 0, 8, 2, ' ', 0)
+		for _, ref := range refs {
+			if ref.Reachable != reachable {
+				continue
+			}
+			addr := "-"
+			if ref.Addr != 0 {
+				addr = fmt.Sprintf("%#x", ref.Addr)
+			}
+			fmt.Fprintf(w, "\t%s\t%s\n", addr, strings.Join(ref.Path, " -> "))
+			n++
+		}
+		w.Flush()
+		return n
+	}
+	fmt.Fprintln(t.stdout, "Referenced by:")
+	if printRefs(true) == 0 {
+		fmt.Fprintln(t.stdout, "  (no references)")
+	}
+	for _, ref := range refs {
+		if !ref.Reachable {
+			fmt.Fprintln(t.stdout, "Referenced by unreachable objects:")
+			printRefs(false)
+			break
+		}
+	}
+	return nil
+}
+
 func transcript(t *Term, ctx callContext, args string) error {
 	argv := strings.SplitN(args, " ", -1)
 	truncate := false
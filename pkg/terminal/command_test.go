@@ -1012,6 +1012,26 @@ func TestIssue1598(t *testing.T) {
 	})
 }
 
+func TestSetCallInjection(t *testing.T) {
+	// Assignments that need memory allocated in the target program use call
+	// injection.
+	test.MustSupportFunctionCalls(t, testBackend)
+	withTestTerminalBuildFlags("fncall", t, test.AllNonOptimized, func(term *FakeTerminal) {
+		term.MustExec("continue")
+		term.MustExec(`set str = "new value"`)
+		if out := term.MustExec("print str"); !strings.Contains(out, `"new value"`) {
+			t.Errorf("wrong value of str after set: %q", out)
+		}
+		term.MustExec(`set smap["three"] = 3`)
+		if out := term.MustExec(`print smap["three"]`); strings.TrimSpace(out) != "3" {
+			t.Errorf("wrong value of smap[\"three\"] after set: %q", out)
+		}
+		if _, err := term.Exec(`frame 1 set str = "other value"`); err == nil {
+			t.Errorf("set with call injection on frame 1 did not fail")
+		}
+	})
+}
+
 func TestExamineMemoryCmd(t *testing.T) {
 	withTestTerminal("examinememory", t, func(term *FakeTerminal) {
 		term.MustExec("break examinememory.go:19")
@@ -1601,3 +1621,113 @@ func TestBreakPointFailWithCond(t *testing.T) {
 		}
 	})
 }
+
+func TestJump(t *testing.T) {
+	withTestTerminal("jump", t, func(term *FakeTerminal) {
+		term.MustExec("break jump.go:7")
+		term.MustExec("continue")
+		out := term.MustExec("jump 9")
+		if !strings.Contains(out, "main.skip() ") || !strings.Contains(out, "=>   9:\t\treturn x") {
+			t.Errorf("wrong output for jump: %s", out)
+		}
+		if out := term.MustExec("print x"); strings.TrimSpace(out) != "1" {
+			t.Errorf("x = %s, want 1", out)
+		}
+		if _, err := term.Exec("jump jump.go:22"); err == nil || !strings.Contains(err.Error(), "is not in the current function main.skip") {
+			t.Errorf("wrong error for jump outside of the current function: %v", err)
+		}
+		term.AssertExecError("jump", "not enough arguments")
+	})
+}
+
+func TestStepTarget(t *testing.T) {
+	withTestTerminal("stepintargets", t, func(term *FakeTerminal) {
+		term.MustExec("break stepintargets.go:19")
+		term.MustExec("continue")
+		if out := term.MustExec("step -target"); out != "1\tmain.g\n2\tmain.h\n3\tmain.f\n" {
+			t.Errorf("wrong output for step -target: %q", out)
+		}
+		term.AssertExecError("step -target 4", `invalid target "4", there are 3 calls on the current line`)
+		out := term.MustExec("step -target 2")
+		if !strings.Contains(out, "main.h() ") {
+			t.Errorf("wrong output for step -target 2: %s", out)
+		}
+	})
+}
+
+func TestHeapCommand(t *testing.T) {
+	withTestTerminal("heapobjs", t, func(term *FakeTerminal) {
+		term.MustExec("continue")
+		out := term.MustExec("heap -top 5")
+		if !strings.Contains(out, " main.Big\n") || !strings.Contains(out, " main.Conn\n") {
+			t.Errorf("wrong output for heap -top 5: %s", out)
+		}
+		if n := strings.Count(out, "\n"); n != 7 {
+			t.Errorf("expected 7 lines in the output of heap -top 5, got %d: %s", n, out)
+		}
+		out = term.MustExec("heap -type main.Session -max 2")
+		if n := strings.Count(out, " main.Session\n"); n != 2 {
+			t.Errorf("expected 2 objects of type main.Session, got %d: %s", n, out)
+		}
+		if !strings.Contains(out, "objects not shown") {
+			t.Errorf("wrong output for heap -type main.Session -max 2: %s", out)
+		}
+		term.AssertExecError("heap -top", "expected argument after -top")
+	})
+}
+
+func TestRefsCommand(t *testing.T) {
+	withTestTerminal("heaprefs", t, func(term *FakeTerminal) {
+		term.MustExec("continue")
+		out := term.MustExec("frame 1 refs conn")
+		t.Logf("%s", out)
+		if !strings.HasPrefix(out, "main.Conn at ") {
+			t.Errorf("wrong object description: %s", out)
+		}
+		for _, tgt := range []string{"main.cache -> map bucket -> *main.Session.conn\n", "finalizer -> *main.resource.conn\n", "conn in main.main (goroutine 1 frame 1)\n"} {
+			if !strings.Contains(out, tgt) {
+				t.Errorf("reference %q not found", tgt)
+			}
+		}
+		out = term.MustExec("frame 1 refs dead")
+		if !strings.Contains(out, "(no references)") {
+			t.Errorf("wrong output for refs dead: %s", out)
+		}
+		term.AssertExecError("refs", "not enough arguments")
+	})
+}
+
+func TestDumpCommand(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("not supported")
+	}
+	withTestTerminal("heaprefs", t, func(term *FakeTerminal) {
+		term.MustExec("continue")
+		dir := t.TempDir()
+		for _, tc := range []struct {
+			cmd, path string
+			magic     []byte
+		}{
+			{"dump -reachable -max-mapping-size 1G %s", "core.zst", []byte{0x28, 0xb5, 0x2f, 0xfd}},
+			{"dump -exclude-file-backed -compress gzip %s", "core", []byte{0x1f, 0x8b}},
+			{"dump -compress none %s", "core.gz", []byte{0x7f, 'E', 'L', 'F'}},
+		} {
+			path := filepath.Join(dir, tc.path)
+			out := term.MustExec(fmt.Sprintf(tc.cmd, path))
+			if strings.Contains(out, "error") || strings.Contains(out, "incomplete") {
+				t.Errorf("%s: %s", tc.cmd, out)
+			}
+			buf, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(buf, tc.magic) {
+				t.Errorf("%s: wrong magic number %x", tc.cmd, buf[:min(len(buf), 4)])
+			}
+		}
+		term.AssertExecError("dump -compress", "expected argument after -compress")
+		term.AssertExecError("dump -max-mapping-size 1X core", `invalid size "1X": strconv.ParseUint: parsing "1X": invalid syntax`)
+		term.AssertExecError("dump -bogus core", "unknown option -bogus")
+		term.AssertExecError("dump -compress lz core", `unknown compression format "lz"`)
+	})
+}
@@ -200,6 +200,9 @@ func (t *Term) sigintGuard(ch <-chan os.Signal, multiClient bool) {
 	for range ch {
 		t.longCommandCancel()
 		t.starlarkEnv.Cancel()
+		// Interrupt any variable loading, stacktrace, goroutine listing or
+		// disassembly that is in progress, the partial result is returned.
+		_ = t.client.CancelOperation()
 		state, err := t.client.GetStateNonBlocking()
 		if err == nil && state.Recording {
 			fmt.Fprintf(t.stdout, "received SIGINT, stopping recording (will not forward signal)\n")
@@ -232,6 +232,17 @@ func ConvertVar(v *proc.Variable) *Variable {
 		}
 	}
 
+	if v.Flags&proc.VariableFormatted != 0 {
+		r.Value = v.Formatted
+		if v.FormattedChildren != nil {
+			r.Children = make([]Variable, len(v.FormattedChildren))
+			for i := range v.FormattedChildren {
+				r.Children[i] = *ConvertVar(&v.FormattedChildren[i])
+			}
+			r.Len = int64(len(r.Children))
+		}
+	}
+
 	return &r
 }
 
@@ -287,6 +298,42 @@ func ConvertFunction(fn *proc.Function) *Function {
 	}
 }
 
+// ConvertMethods converts a slice of proc.Method to a slice of api.Method.
+func ConvertMethods(methods []proc.Method) []Method {
+	r := make([]Method, len(methods))
+	for i, m := range methods {
+		r[i] = Method{Name: m.Name, Fn: ConvertFunction(m.Fn)}
+		if m.Type != nil {
+			r[i].Type = PrettyTypeName(m.Type)
+		}
+	}
+	return r
+}
+
+// ConvertHeapObject converts from proc.HeapObject to api.HeapObject.
+func ConvertHeapObject(obj *proc.HeapObject) HeapObject {
+	return HeapObject{Addr: obj.Addr, Size: obj.Size, Type: PrettyTypeName(obj.Type)}
+}
+
+// ConvertObjectReferences converts from []proc.ObjectReference to
+// []api.ObjectReference.
+func ConvertObjectReferences(refs []proc.ObjectReference) []ObjectReference {
+	r := make([]ObjectReference, len(refs))
+	for i, ref := range refs {
+		r[i] = ObjectReference{Addr: ref.Addr, Path: ref.Path, Reachable: ref.Reachable}
+	}
+	return r
+}
+
+// HeapTypeName returns the name of the type used to group heap objects of
+// type typ, arrays of elements of type T are grouped as [...]T.
+func HeapTypeName(typ godwarf.Type) string {
+	if atyp, isarray := typ.(*godwarf.ArrayType); isarray {
+		return "[...]" + PrettyTypeName(atyp.Type)
+	}
+	return PrettyTypeName(typ)
+}
+
 // ConvertGoroutine converts from proc.G to api.Goroutine.
 func ConvertGoroutine(tgt *proc.Target, g *proc.G) *Goroutine {
 	th := g.Thread
@@ -436,6 +483,28 @@ func ConvertImage(image *proc.Image) Image {
 	return Image{Path: image.Path, Address: image.StaticBase, LoadError: lerr}
 }
 
+// DumpOptionsToProc converts an api.DumpOptions to proc.DumpOptions.
+func DumpOptionsToProc(opts *DumpOptions) (proc.DumpOptions, error) {
+	r := proc.DumpOptions{MaxMappingSize: opts.MaxMappingSize}
+	if opts.ExcludeFileBacked {
+		r.Flags |= proc.DumpExcludeFileBacked
+	}
+	if opts.ReachableOnly {
+		r.Flags |= proc.DumpReachableOnly
+	}
+	switch opts.Compression {
+	case "":
+		r.Compression = proc.DumpUncompressed
+	case "gzip":
+		r.Compression = proc.DumpGzip
+	case "zstd":
+		r.Compression = proc.DumpZstd
+	default:
+		return r, fmt.Errorf("unknown compression format %q", opts.Compression)
+	}
+	return r, nil
+}
+
 // ConvertDumpState converts proc.DumpState to api.DumpState.
 func ConvertDumpState(dumpState *proc.DumpState) *DumpState {
 	dumpState.Mutex.Lock()
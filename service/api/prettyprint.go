@@ -82,6 +82,11 @@ func (v *Variable) writeTo(buf io.Writer, flags prettyFlags, indent, fmtstr stri
 		return
 	}
 
+	if v.OnlyAddr && v.Flags&VariableLoadCanceled != 0 {
+		fmt.Fprint(buf, "(loading canceled)")
+		return
+	}
+
 	if !flags.top() && v.Addr == 0 && v.Value == "" {
 		if flags.includeType() && v.Type != "void" {
 			fmt.Fprintf(buf, "%s nil", v.typeStr(flags))
@@ -91,6 +96,11 @@ func (v *Variable) writeTo(buf io.Writer, flags prettyFlags, indent, fmtstr stri
 		return
 	}
 
+	if v.Flags&VariableFormatted != 0 {
+		v.writeFormattedTo(buf, flags, indent, fmtstr)
+		return
+	}
+
 	switch v.Kind {
 	case reflect.Slice:
 		v.writeSliceTo(buf, flags, indent, fmtstr)
@@ -191,6 +201,20 @@ func (v *Variable) writeTo(buf io.Writer, flags prettyFlags, indent, fmtstr stri
 	}
 }
 
+// writeFormattedTo writes the output of a pretty printer followed, if the
+// pretty printer produced any, by the synthetic children of v.
+func (v *Variable) writeFormattedTo(buf io.Writer, flags prettyFlags, indent, fmtstr string) {
+	if flags.includeType() {
+		fmt.Fprintf(buf, "%s(%s)", v.typeStr(flags), v.Value)
+	} else {
+		fmt.Fprint(buf, v.Value)
+	}
+	if len(v.Children) > 0 {
+		fmt.Fprint(buf, " ")
+		v.writeStructTo(buf, flags.set(prettyIncludeType, false), indent, fmtstr)
+	}
+}
+
 func (v *Variable) writePointerTo(buf io.Writer, flags prettyFlags) {
 	if strings.Contains(v.Type, "/") {
 		fmt.Fprintf(buf, "(%q)(%#x)", v.typeStr(flags), v.Children[0].Addr)
@@ -44,6 +44,9 @@ type DebuggerState struct {
 	// WatchOutOfScope contains the list of watchpoints that went out of scope
 	// during the last continue.
 	WatchOutOfScope []*Breakpoint
+	// WatchExpressions contains the values of the watch expressions at the
+	// last stop.
+	WatchExpressions []*WatchExpression `json:"watchExpressions,omitempty"`
 	// Exited indicates whether the debugged process has exited.
 	Exited     bool `json:"exited"`
 	ExitStatus int  `json:"exitStatus"`
@@ -53,6 +56,31 @@ type DebuggerState struct {
 	Err error `json:"-"`
 }
 
+// WatchExpression is an expression that the debugger evaluates every time
+// the target stops.
+type WatchExpression struct {
+	// ID is a unique identifier for the watch expression.
+	ID int `json:"id"`
+	// Expr is the expression being watched.
+	Expr string `json:"expr"`
+	// Scope is the scope the expression is evaluated in. If
+	// Scope.GoroutineID is -1 the expression is evaluated on the goroutine
+	// that is selected when the target stops.
+	Scope EvalScope `json:"scope"`
+	// Cfg is the configuration used to load the value of the expression.
+	Cfg LoadConfig `json:"cfg"`
+
+	// Value is the value of the expression at the last stop, nil if it
+	// could not be evaluated.
+	Value *Variable `json:"value,omitempty"`
+	// Err is the error encountered evaluating the expression at the last
+	// stop.
+	Err string `json:"err,omitempty"`
+	// Changed is true if the value of the expression, or its error, is
+	// different from the one at the previous stop.
+	Changed bool `json:"changed"`
+}
+
 type TracepointResult struct {
 	// Addr is the address of this tracepoint.
 	Addr uint64 `json:"addr"`
@@ -266,6 +294,49 @@ func (fn *Function) Name() string {
 	return fn.Name_
 }
 
+// Method is a method of a type.
+type Method struct {
+	Name string `json:"name"`
+	// Type is the signature of the method, without the receiver.
+	Type string `json:"type"`
+	// Fn is the function implementing the method, nil if it was removed
+	// by the linker.
+	Fn *Function `json:"fn,omitempty"`
+}
+
+// HeapObject is an object allocated in the heap of the target.
+type HeapObject struct {
+	Addr uint64 `json:"addr"`
+	// Size is the size of the memory block allocated for the object.
+	Size int64 `json:"size"`
+	// Type is the type of the object, empty if it could not be determined.
+	Type string `json:"type"`
+}
+
+// HeapType is the number of heap objects of a type and the memory they
+// use. Arrays of the same element type are counted together.
+type HeapType struct {
+	// Type is the name of the type, arrays are named [...]T and objects
+	// whose type could not be determined are counted with an empty type.
+	Type  string `json:"type"`
+	Count int64  `json:"count"`
+	Bytes int64  `json:"bytes"`
+}
+
+// ObjectReference is a pointer to a heap object.
+type ObjectReference struct {
+	// Addr is the address of the pointer, zero if it is not stored in memory.
+	Addr uint64 `json:"addr"`
+	// Path is the retention path of the pointer, starting from a global
+	// variable, a local variable of a goroutine or a finalizer, through the
+	// heap objects that have to be traversed to reach the pointer.
+	Path []string `json:"path"`
+	// Reachable is false if the pointer is stored in an object that can
+	// not be reached from a root without passing through the referenced
+	// object.
+	Reachable bool `json:"reachable"`
+}
+
 // VariableFlags is the type of the Flags field of Variable.
 type VariableFlags uint16
 
@@ -303,6 +374,15 @@ const (
 
 	// VariableCPURegister means this variable is a CPU register.
 	VariableCPURegister
+
+	// VariableLoadCanceled means that loading of this variable, or of some
+	// of its children, was interrupted by a request to cancel the operation.
+	VariableLoadCanceled
+
+	// VariableFormatted means that the value of this variable was produced
+	// by a pretty printer, Value contains its output and Children contains
+	// the synthetic children it produced, as named fields.
+	VariableFormatted
 )
 
 // Variable describes a variable.
@@ -425,6 +505,27 @@ type DebuggerCommand struct {
 	// violate the rules about stack objects you can disable this safety check
 	// by setting UnsafeCall to true.
 	UnsafeCall bool `json:"unsafeCall,omitempty"`
+
+	// TargetPC is the address of one of the call instructions returned by
+	// StepInTargets, when it is set the Step command will only step into the
+	// function called by that instruction.
+	TargetPC uint64 `json:"targetPC,omitempty"`
+
+	// Frame is the stack frame restarted by the RestartFrame command.
+	Frame int `json:"frame,omitempty"`
+	// Force makes the RestartFrame command restart the frame even if some of
+	// its code has already been executed and would be executed again.
+	Force bool `json:"force,omitempty"`
+}
+
+// StepInTarget is a function call on the current line that the Step
+// command can step into.
+type StepInTarget struct {
+	// PC is the address of the call instruction.
+	PC uint64 `json:"pc"`
+	// Function is the called function, nil if it is only known when the
+	// call is executed.
+	Function *Function `json:"function,omitempty"`
 }
 
 // BreakpointInfo contains information about the current breakpoint
@@ -482,6 +583,9 @@ const (
 	Halt = "halt"
 	// Call resumes process execution injecting a function call.
 	Call = "call"
+	// RestartFrame moves the selected goroutine back to the entry point of
+	// the function of one of its stack frames.
+	RestartFrame = "restartFrame"
 )
 
 // AssemblyFlavour describes the output
@@ -621,6 +725,21 @@ type PackageBuildInfo struct {
 	Files         []string
 }
 
+// DumpOptions describes the contents and the format of a core dump.
+type DumpOptions struct {
+	// ExcludeFileBacked excludes read-only mappings backed by a file.
+	ExcludeFileBacked bool
+	// MaxMappingSize, if not zero, excludes anonymous mappings larger than
+	// MaxMappingSize bytes.
+	MaxMappingSize uint64
+	// ReachableOnly only includes the stacks of goroutines and threads,
+	// global variables and the memory reachable from them.
+	ReachableOnly bool
+	// Compression is the compression format of the core dump: "gzip",
+	// "zstd" or the empty string for no compression.
+	Compression string
+}
+
 // DumpState describes the state of a core dump in progress
 type DumpState struct {
 	Dumping bool
@@ -43,6 +43,10 @@ type Client interface {
 	ReverseNext() (*api.DebuggerState, error)
 	// Step continues to the next source line, entering function calls.
 	Step() (*api.DebuggerState, error)
+	// StepInTarget continues to the next source line, entering only the function called by the call instruction at pc.
+	StepInTarget(pc uint64) (*api.DebuggerState, error)
+	// StepInTargets returns the function calls on the current line that can be stepped into.
+	StepInTargets(goroutineID int64) ([]api.StepInTarget, error)
 	// ReverseStep continues backward to the previous line of source code, entering function calls.
 	ReverseStep() (*api.DebuggerState, error)
 	// StepOut continues to the return address of the current function.
@@ -56,6 +60,9 @@ type Client interface {
 	StepInstruction(skipCalls bool) (*api.DebuggerState, error)
 	// ReverseStepInstruction will reverse step a single cpu instruction.
 	ReverseStepInstruction(skipCalls bool) (*api.DebuggerState, error)
+	// Jump moves the program counter of the specified goroutine to file:line
+	// without executing the code in between.
+	Jump(goroutineID int64, file string, line int) (*api.DebuggerState, error)
 	// SwitchThread switches the current thread context.
 	SwitchThread(threadID int) (*api.DebuggerState, error)
 	// SwitchGoroutine switches the current goroutine (and the current thread as well)
@@ -98,9 +105,36 @@ type Client interface {
 	ListPackageVariables(filter string, cfg api.LoadConfig) ([]api.Variable, error)
 	// EvalVariable returns a variable in the context of the current thread.
 	EvalVariable(scope api.EvalScope, symbol string, cfg api.LoadConfig) (*api.Variable, error)
+	// ListMethods lists the methods of the type of the value of expr, if
+	// the value is an interface the methods of its concrete type are listed.
+	ListMethods(scope api.EvalScope, expr string) ([]api.Method, error)
+
+	// ListHeapObjects lists the objects allocated in the heap of the
+	// target, grouped by type, and returns the number of objects listed and
+	// the memory they use. If typ is not empty only the objects of type
+	// typ, and the arrays of elements of type typ, are listed and up to max
+	// of them are returned. At most top types are returned.
+	ListHeapObjects(typ string, top, max int) ([]api.HeapType, []api.HeapObject, int64, int64, error)
+
+	// ListObjectReferences returns the heap object referenced by expr and
+	// the list of pointers to it, with their retention paths.
+	ListObjectReferences(scope api.EvalScope, expr string) (*api.HeapObject, []api.ObjectReference, error)
+
+	// AddWatchExpression adds a watch expression, evaluated every time the
+	// target stops. If scope.GoroutineID is -1 the expression is evaluated
+	// on the goroutine selected when the target stops.
+	AddWatchExpression(expr string, scope api.EvalScope, cfg *api.LoadConfig) (*api.WatchExpression, error)
+	// RemoveWatchExpression removes the watch expression with the specified ID.
+	RemoveWatchExpression(id int) error
+	// ListWatchExpressions lists the watch expressions with their values
+	// at the last stop.
+	ListWatchExpressions() ([]*api.WatchExpression, error)
 
 	// SetVariable sets the value of a variable
 	SetVariable(scope api.EvalScope, symbol, value string) error
+	// SetLiveVariable sets the convenience variable $name to the live
+	// expression expr.
+	SetLiveVariable(name, expr string) error
 
 	// ListSources lists all source files in the process matching filter.
 	ListSources(filter string) ([]string, error)
@@ -182,11 +216,19 @@ type Client interface {
 
 	// CoreDumpStart starts creating a core dump to the specified file
 	CoreDumpStart(dest string) (api.DumpState, error)
+	// CoreDumpStartWithOptions starts creating a core dump to the specified
+	// file, opts controls which memory is included and the compression.
+	CoreDumpStartWithOptions(dest string, opts api.DumpOptions) (api.DumpState, error)
 	// CoreDumpWait waits for the core dump to finish, or for the specified amount of milliseconds
 	CoreDumpWait(msec int) api.DumpState
 	// CoreDumpCancel cancels a core dump in progress
 	CoreDumpCancel() error
 
+	// CancelOperation interrupts the variable loading, goroutine listing,
+	// stack unwinding or disassembly in progress, even if it was requested
+	// by a different client.
+	CancelOperation() error
+
 	// ListTargets returns the list of connected targets
 	ListTargets() ([]api.Target, error)
 	// FollowExec enables or disables the follow exec mode. In follow exec mode
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/go-delve/delve/pkg/config"
 	"github.com/google/go-dap"
@@ -88,6 +89,46 @@ func debugCommands(s *Session) []command {
 
 var errNoCmd = errors.New("command not available")
 
+// dlvCompletions returns the completions for the last word of cmdstr, a
+// dlv command line without the 'dlv' prefix.
+func (s *Session) dlvCompletions(cmdstr string) []string {
+	words := strings.Fields(cmdstr)
+	if len(words) == 0 || strings.TrimRightFunc(cmdstr, unicode.IsSpace) != cmdstr {
+		words = append(words, "")
+	}
+	var candidates []string
+	commandNames := func() {
+		for _, cmd := range debugCommands(s) {
+			candidates = append(candidates, cmd.aliases...)
+		}
+	}
+	switch {
+	case len(words) == 1:
+		commandNames()
+	case len(words) == 2 && (words[0] == "help" || words[0] == "h"):
+		commandNames()
+	case words[0] == "config" && (len(words) == 2 || len(words) == 3 && words[1] == "-list"):
+		if len(words) == 2 {
+			candidates = append(candidates, "-list")
+		}
+		it := config.IterateConfiguration(&s.args, "cfgName")
+		for it.Next() {
+			if name, _ := it.Field(); name != "" {
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	last := words[len(words)-1]
+	r := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, last) {
+			r = append(r, candidate)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
 func (s *Session) helpMessage(_, _ int, args string) (string, error) {
 	var buf bytes.Buffer
 	if args != "" {
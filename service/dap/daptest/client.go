@@ -106,21 +106,44 @@ func (c *Client) ExpectInitializeResponseAndCapabilities(t *testing.T) *dap.Init
 	initResp := c.ExpectInitializeResponse(t)
 	wantCapabilities := dap.Capabilities{
 		// the values set by dap.(*Server).onInitializeRequest.
-		SupportsConfigurationDoneRequest: true,
-		SupportsConditionalBreakpoints:   true,
-		SupportsDelayedStackTraceLoading: true,
-		SupportsExceptionInfoRequest:     true,
-		SupportsSetVariable:              true,
-		SupportsFunctionBreakpoints:      true,
-		SupportsInstructionBreakpoints:   true,
-		SupportsEvaluateForHovers:        true,
-		SupportsClipboardContext:         true,
-		SupportsSteppingGranularity:      true,
-		SupportsLogPoints:                true,
-		SupportsDisassembleRequest:       true,
+		SupportsConfigurationDoneRequest:   true,
+		SupportsConditionalBreakpoints:     true,
+		SupportsDelayedStackTraceLoading:   true,
+		SupportsExceptionInfoRequest:       true,
+		SupportsSetVariable:                true,
+		SupportsFunctionBreakpoints:        true,
+		SupportsInstructionBreakpoints:     true,
+		SupportsEvaluateForHovers:          true,
+		SupportsClipboardContext:           true,
+		SupportsSteppingGranularity:        true,
+		SupportsLogPoints:                  true,
+		SupportsDisassembleRequest:         true,
+		SupportsDataBreakpoints:            true,
+		SupportsReadMemoryRequest:          true,
+		SupportsWriteMemoryRequest:         true,
+		SupportsLoadedSourcesRequest:       true,
+		SupportsGotoTargetsRequest:         true,
+		SupportsStepInTargetsRequest:       true,
+		SupportsRestartFrame:               true,
+		SupportsCompletionsRequest:         true,
+		CompletionTriggerCharacters:        []string{"."},
+		SupportsCancelRequest:              true,
+		SupportsBreakpointLocationsRequest: true,
+		SupportsExceptionFilterOptions:     true,
 	}
-	if !reflect.DeepEqual(initResp.Body, wantCapabilities) {
-		t.Errorf("capabilities in initializeResponse: got %+v, want %v", pretty(initResp.Body), pretty(wantCapabilities))
+	got := initResp.Body
+	// Only check the identifiers of the exception breakpoint filters.
+	gotFilters := []string{}
+	for _, filter := range got.ExceptionBreakpointFilters {
+		gotFilters = append(gotFilters, filter.Filter)
+	}
+	got.ExceptionBreakpointFilters = nil
+	if !reflect.DeepEqual(got, wantCapabilities) {
+		t.Errorf("capabilities in initializeResponse: got %+v, want %v", pretty(got), pretty(wantCapabilities))
+	}
+	wantFilters := []string{"unrecoveredPanic", "fatalError", "allPanics", "panicCondition"}
+	if !reflect.DeepEqual(gotFilters, wantFilters) {
+		t.Errorf("exception breakpoint filters in initializeResponse: got %v, want %v", gotFilters, wantFilters)
 	}
 	return initResp
 }
@@ -331,12 +354,35 @@ func (c *Client) SetBreakpointsRequestWithArgs(file string, lines []int, conditi
 	c.send(request)
 }
 
+// SetSourceBreakpointsRequest sends a 'setBreakpoints' request with the
+// specified source breakpoints, which can have columns.
+func (c *Client) SetSourceBreakpointsRequest(file string, breakpoints []dap.SourceBreakpoint) {
+	request := &dap.SetBreakpointsRequest{Request: *c.newRequest("setBreakpoints")}
+	request.Arguments = dap.SetBreakpointsArguments{
+		Source: dap.Source{
+			Name: filepath.Base(file),
+			Path: file,
+		},
+		Breakpoints: breakpoints,
+	}
+	c.send(request)
+}
+
 // SetExceptionBreakpointsRequest sends a 'setExceptionBreakpoints' request.
 func (c *Client) SetExceptionBreakpointsRequest() {
 	request := &dap.SetBreakpointsRequest{Request: *c.newRequest("setExceptionBreakpoints")}
 	c.send(request)
 }
 
+// SetExceptionBreakpointsRequestWithArgs sends a 'setExceptionBreakpoints'
+// request enabling the given filters.
+func (c *Client) SetExceptionBreakpointsRequestWithArgs(filters []string, filterOptions []dap.ExceptionFilterOptions) {
+	request := &dap.SetExceptionBreakpointsRequest{Request: *c.newRequest("setExceptionBreakpoints")}
+	request.Arguments.Filters = filters
+	request.Arguments.FilterOptions = filterOptions
+	c.send(request)
+}
+
 // ConfigurationDoneRequest sends a 'configurationDone' request.
 func (c *Client) ConfigurationDoneRequest() {
 	request := &dap.ConfigurationDoneRequest{Request: *c.newRequest("configurationDone")}
@@ -372,6 +418,14 @@ func (c *Client) StepInRequest(thread int) {
 	c.send(request)
 }
 
+// StepInTargetRequest sends a 'stepIn' request for the specified target.
+func (c *Client) StepInTargetRequest(thread, targetID int) {
+	request := &dap.StepInRequest{Request: *c.newRequest("stepIn")}
+	request.Arguments.ThreadId = thread
+	request.Arguments.TargetId = targetID
+	c.send(request)
+}
+
 // StepInInstructionRequest sends a 'stepIn' request with granularity 'instruction'.
 func (c *Client) StepInInstructionRequest(thread int) {
 	request := &dap.StepInRequest{Request: *c.newRequest("stepIn")}
@@ -499,13 +553,18 @@ func (c *Client) SetVariableRequest(variablesRef int, name, value string) {
 }
 
 // RestartFrameRequest sends a 'restartFrame' request.
-func (c *Client) RestartFrameRequest() {
-	c.send(&dap.RestartFrameRequest{Request: *c.newRequest("restartFrame")})
+func (c *Client) RestartFrameRequest(frameID int) {
+	request := &dap.RestartFrameRequest{Request: *c.newRequest("restartFrame")}
+	request.Arguments.FrameId = frameID
+	c.send(request)
 }
 
 // GotoRequest sends a 'goto' request.
-func (c *Client) GotoRequest() {
-	c.send(&dap.GotoRequest{Request: *c.newRequest("goto")})
+func (c *Client) GotoRequest(threadID, targetID int) {
+	request := &dap.GotoRequest{Request: *c.newRequest("goto")}
+	request.Arguments.ThreadId = threadID
+	request.Arguments.TargetId = targetID
+	c.send(request)
 }
 
 // SetExpressionRequest sends a 'setExpression' request.
@@ -533,18 +592,30 @@ func (c *Client) EvaluateRequest(expr string, fid int, context string) {
 }
 
 // StepInTargetsRequest sends a 'stepInTargets' request.
-func (c *Client) StepInTargetsRequest() {
-	c.send(&dap.StepInTargetsRequest{Request: *c.newRequest("stepInTargets")})
+func (c *Client) StepInTargetsRequest(frameID int) {
+	request := &dap.StepInTargetsRequest{Request: *c.newRequest("stepInTargets")}
+	request.Arguments.FrameId = frameID
+	c.send(request)
 }
 
 // GotoTargetsRequest sends a 'gotoTargets' request.
-func (c *Client) GotoTargetsRequest() {
-	c.send(&dap.GotoTargetsRequest{Request: *c.newRequest("gotoTargets")})
+func (c *Client) GotoTargetsRequest(file string, line int) {
+	request := &dap.GotoTargetsRequest{Request: *c.newRequest("gotoTargets")}
+	request.Arguments.Source = dap.Source{
+		Name: filepath.Base(file),
+		Path: file,
+	}
+	request.Arguments.Line = line
+	c.send(request)
 }
 
 // CompletionsRequest sends a 'completions' request.
-func (c *Client) CompletionsRequest() {
-	c.send(&dap.CompletionsRequest{Request: *c.newRequest("completions")})
+func (c *Client) CompletionsRequest(text string, column, frameID int) {
+	request := &dap.CompletionsRequest{Request: *c.newRequest("completions")}
+	request.Arguments.Text = text
+	request.Arguments.Column = column
+	request.Arguments.FrameId = frameID
+	c.send(request)
 }
 
 // ExceptionInfoRequest sends a 'exceptionInfo' request.
@@ -560,18 +631,50 @@ func (c *Client) LoadedSourcesRequest() {
 }
 
 // DataBreakpointInfoRequest sends a 'dataBreakpointInfo' request.
-func (c *Client) DataBreakpointInfoRequest() {
-	c.send(&dap.DataBreakpointInfoRequest{Request: *c.newRequest("dataBreakpointInfo")})
+func (c *Client) DataBreakpointInfoRequest(variablesReference int, name string, frameID int) {
+	c.send(&dap.DataBreakpointInfoRequest{
+		Request: *c.newRequest("dataBreakpointInfo"),
+		Arguments: dap.DataBreakpointInfoArguments{
+			VariablesReference: variablesReference,
+			Name:               name,
+			FrameId:            frameID,
+		},
+	})
 }
 
 // SetDataBreakpointsRequest sends a 'setDataBreakpoints' request.
-func (c *Client) SetDataBreakpointsRequest() {
-	c.send(&dap.SetDataBreakpointsRequest{Request: *c.newRequest("setDataBreakpoints")})
+func (c *Client) SetDataBreakpointsRequest(breakpoints []dap.DataBreakpoint) {
+	c.send(&dap.SetDataBreakpointsRequest{
+		Request: *c.newRequest("setDataBreakpoints"),
+		Arguments: dap.SetDataBreakpointsArguments{
+			Breakpoints: breakpoints,
+		},
+	})
 }
 
 // ReadMemoryRequest sends a 'readMemory' request.
-func (c *Client) ReadMemoryRequest() {
-	c.send(&dap.ReadMemoryRequest{Request: *c.newRequest("readMemory")})
+func (c *Client) ReadMemoryRequest(memoryReference string, offset, count int) {
+	c.send(&dap.ReadMemoryRequest{
+		Request: *c.newRequest("readMemory"),
+		Arguments: dap.ReadMemoryArguments{
+			MemoryReference: memoryReference,
+			Offset:          offset,
+			Count:           count,
+		},
+	})
+}
+
+// WriteMemoryRequest sends a 'writeMemory' request.
+func (c *Client) WriteMemoryRequest(memoryReference string, offset int, allowPartial bool, data string) {
+	c.send(&dap.WriteMemoryRequest{
+		Request: *c.newRequest("writeMemory"),
+		Arguments: dap.WriteMemoryArguments{
+			MemoryReference: memoryReference,
+			Offset:          offset,
+			AllowPartial:    allowPartial,
+			Data:            data,
+		},
+	})
 }
 
 // DisassembleRequest sends a 'disassemble' request.
@@ -588,14 +691,31 @@ func (c *Client) DisassembleRequest(memoryReference string, instructionOffset, i
 	})
 }
 
-// CancelRequest sends a 'cancel' request.
-func (c *Client) CancelRequest() {
-	c.send(&dap.CancelRequest{Request: *c.newRequest("cancel")})
+// CancelRequest sends a 'cancel' request for the request with sequence
+// number requestID.
+func (c *Client) CancelRequest(requestID int) {
+	request := &dap.CancelRequest{Request: *c.newRequest("cancel")}
+	request.Arguments = &dap.CancelArguments{RequestId: requestID}
+	c.send(request)
+}
+
+// Seq returns the sequence number that will be used by the next request.
+func (c *Client) Seq() int {
+	return c.seq
 }
 
 // BreakpointLocationsRequest sends a 'breakpointLocations' request.
-func (c *Client) BreakpointLocationsRequest() {
-	c.send(&dap.BreakpointLocationsRequest{Request: *c.newRequest("breakpointLocations")})
+func (c *Client) BreakpointLocationsRequest(file string, line, endLine int) {
+	request := &dap.BreakpointLocationsRequest{Request: *c.newRequest("breakpointLocations")}
+	request.Arguments = &dap.BreakpointLocationsArguments{
+		Source: dap.Source{
+			Name: filepath.Base(file),
+			Path: file,
+		},
+		Line:    line,
+		EndLine: endLine,
+	}
+	c.send(request)
 }
 
 // ModulesRequest sends a 'modules' request.
@@ -11,23 +11,33 @@ const (
 	// Where applicable and for consistency only,
 	// values below are inspired the original vscode-go debug adaptor.
 
-	FailedToLaunch             = 3000
-	FailedToAttach             = 3001
-	FailedToInitialize         = 3002
-	UnableToSetBreakpoints     = 2002
-	UnableToDisplayThreads     = 2003
-	UnableToProduceStackTrace  = 2004
-	UnableToListLocals         = 2005
-	UnableToListArgs           = 2006
-	UnableToListGlobals        = 2007
-	UnableToLookupVariable     = 2008
-	UnableToEvaluateExpression = 2009
-	UnableToHalt               = 2010
-	UnableToGetExceptionInfo   = 2011
-	UnableToSetVariable        = 2012
-	UnableToDisassemble        = 2013
-	UnableToListRegisters      = 2014
-	UnableToRunDlvCommand      = 2015
+	FailedToLaunch                 = 3000
+	FailedToAttach                 = 3001
+	FailedToInitialize             = 3002
+	UnableToSetBreakpoints         = 2002
+	UnableToDisplayThreads         = 2003
+	UnableToProduceStackTrace      = 2004
+	UnableToListLocals             = 2005
+	UnableToListArgs               = 2006
+	UnableToListGlobals            = 2007
+	UnableToLookupVariable         = 2008
+	UnableToEvaluateExpression     = 2009
+	UnableToHalt                   = 2010
+	UnableToGetExceptionInfo       = 2011
+	UnableToSetVariable            = 2012
+	UnableToDisassemble            = 2013
+	UnableToListRegisters          = 2014
+	UnableToRunDlvCommand          = 2015
+	UnableToGetDataBreakpointInfo  = 2016
+	UnableToReadMemory             = 2017
+	UnableToWriteMemory            = 2018
+	UnableToGetGotoTargets         = 2019
+	UnableToGoto                   = 2020
+	UnableToGetStepInTargets       = 2021
+	UnableToStepIn                 = 2022
+	UnableToRestartFrame           = 2023
+	RequestCancelled               = 2024
+	UnableToGetBreakpointLocations = 2025
 
 	// Add more codes as we support more requests
 
@@ -11,11 +11,15 @@ package dap
 import (
 	"bufio"
 	"bytes"
+	"debug/buildinfo"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"go/ast"
 	"go/constant"
 	"go/parser"
+	"go/token"
 	"io"
 	"math"
 	"net"
@@ -26,17 +30,21 @@ import (
 	"regexp"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-delve/delve/pkg/gobuild"
 	"github.com/go-delve/delve/pkg/goversion"
 	"github.com/go-delve/delve/pkg/locspec"
 	"github.com/go-delve/delve/pkg/logflags"
 	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/evalop"
 
 	"github.com/go-delve/delve/service"
 	"github.com/go-delve/delve/service/api"
@@ -160,6 +168,20 @@ type Session struct {
 	// changing the state of the running process at the same time.
 	changeStateMu sync.Mutex
 
+	// cancelMu synchronizes access to runningSeq, cancelRunning and
+	// queuedSeqs, which are also accessed by 'cancel' requests as soon as
+	// they are read.
+	cancelMu sync.Mutex
+	// runningSeq is the sequence number of the request being handled by the
+	// request loop, or 0 if there is none.
+	runningSeq int
+	// cancelRunning interrupts the operation in progress for the request
+	// being handled, it is nil if there is no debugger.
+	cancelRunning func()
+	// queuedSeqs contains the sequence numbers of the requests read but not
+	// yet handled, mapped to true if they have been canceled.
+	queuedSeqs map[int]bool
+
 	// stdoutReader the program's stdout.
 	stdoutReader io.ReadCloser
 
@@ -168,6 +190,44 @@ type Session struct {
 
 	// preTerminatedWG the WaitGroup that needs to wait before sending a terminated event.
 	preTerminatedWG sync.WaitGroup
+
+	// knownSources is the set of source files already sent to the client
+	// in a loadedSources response or event. It is nil until the client sends
+	// a loadedSources request.
+	knownSources   map[string]struct{}
+	knownSourcesMu sync.Mutex
+
+	// gotoTargets are the destinations returned by 'gotoTargets' requests
+	// since the last stop, indexed by target id minus one.
+	gotoTargets []gotoTarget
+
+	// stepInTargets are the calls returned by 'stepInTargets' requests
+	// since the last stop, indexed by target id minus one.
+	stepInTargets []stepInTarget
+
+	// nextCommand holds the arguments of the next command resumed by
+	// resumeOnce, it is set by 'stepIn' requests with a target and by
+	// 'restartFrame' requests on recordings.
+	nextCommand *api.DebuggerCommand
+
+	// restartFrameConfirm is the id of the frame that a previous
+	// 'restartFrame' request refused to restart because some of its code
+	// had already been executed. A second request for the same frame
+	// restarts it anyway.
+	restartFrameConfirm int
+}
+
+// stepInTarget is a call that a 'stepIn' request can step into.
+type stepInTarget struct {
+	goroutineID int64
+	pc          uint64
+}
+
+// gotoTarget is a destination for a 'goto' request.
+type gotoTarget struct {
+	goid int64 // goroutine the target was validated for
+	file string
+	line int
 }
 
 // Config is all the information needed to start the debugger, handle
@@ -266,6 +326,8 @@ type dapClientCapabilities struct {
 	supportsRunInTerminalRequest bool
 	supportsMemoryReferences     bool
 	supportsProgressReporting    bool
+	supportsMemoryEvent          bool
+	supportsInvalidatedEvent     bool
 }
 
 // DefaultLoadConfig controls how variables are loaded from the target's memory.
@@ -538,8 +600,62 @@ func (s *Session) ServeDAPCodec() {
 		}
 	}()
 	reader := bufio.NewReader(s.conn)
+
+	// Requests are read on a separate goroutine so that 'cancel' requests
+	// can be handled while a previous request is still being processed.
+	// All other requests are queued and handled here, one at a time, in the
+	// order they are read.
+	type readResult struct {
+		request dap.Message
+		err     error
+	}
+	var (
+		queueMu sync.Mutex
+		queue   []readResult
+	)
+	queued := make(chan struct{}, 1)
+	go func() {
+		for {
+			request, err := dap.ReadProtocolMessage(reader)
+			if err == nil {
+				if request, ok := request.(*dap.CancelRequest); ok {
+					jsonmsg, _ := json.Marshal(request)
+					s.config.log.Debug("[<- from client]", string(jsonmsg))
+					s.onCancelRequest(request)
+					continue
+				}
+				s.queueRequest(request.GetSeq())
+			}
+			queueMu.Lock()
+			queue = append(queue, readResult{request, err})
+			queueMu.Unlock()
+			select {
+			case queued <- struct{}{}:
+			default:
+			}
+			var decodeErr *dap.DecodeProtocolMessageFieldError
+			if err != nil && !errors.As(err, &decodeErr) {
+				return
+			}
+		}
+	}()
+	next := func() readResult {
+		for {
+			queueMu.Lock()
+			if len(queue) > 0 {
+				r := queue[0]
+				queue = queue[1:]
+				queueMu.Unlock()
+				return r
+			}
+			queueMu.Unlock()
+			<-queued
+		}
+	}
+
 	for {
-		request, err := dap.ReadProtocolMessage(reader)
+		r := next()
+		request, err := r.request, r.err
 		// Handle dap.DecodeProtocolMessageFieldError errors gracefully by responding with an ErrorResponse.
 		// For example:
 		// -- "Request command 'foo' is not supported" means we
@@ -566,7 +682,10 @@ func (s *Session) ServeDAPCodec() {
 			}
 			return
 		}
-		s.handleRequest(request)
+		if s.startRequest(request) {
+			s.handleRequest(request)
+			s.endRequest()
+		}
 
 		if _, ok := request.(*dap.DisconnectRequest); ok {
 			// disconnect already shut things down and triggered stopping
@@ -575,6 +694,50 @@ func (s *Session) ServeDAPCodec() {
 	}
 }
 
+// queueRequest records that the request with sequence number seq has been
+// read and is waiting to be handled.
+func (s *Session) queueRequest(seq int) {
+	s.cancelMu.Lock()
+	defer s.cancelMu.Unlock()
+	if s.queuedSeqs == nil {
+		s.queuedSeqs = make(map[int]bool)
+	}
+	s.queuedSeqs[seq] = false
+}
+
+// startRequest records that request is being handled. If the request was
+// canceled while waiting to be handled an error response is sent and false
+// is returned.
+func (s *Session) startRequest(request dap.Message) bool {
+	s.cancelMu.Lock()
+	canceled := s.queuedSeqs[request.GetSeq()]
+	delete(s.queuedSeqs, request.GetSeq())
+	if !canceled {
+		s.runningSeq = request.GetSeq()
+		s.cancelRunning = nil
+		if s.debugger != nil {
+			s.cancelRunning = s.debugger.CancelOperation
+		}
+	}
+	s.cancelMu.Unlock()
+	if canceled {
+		if r, ok := request.(dap.RequestMessage); ok {
+			s.sendErrorResponse(*r.GetRequest(), RequestCancelled, "cancelled", "the request was cancelled before it was handled")
+			return false
+		}
+	}
+	return true
+}
+
+// endRequest records that the request started by startRequest has been
+// handled.
+func (s *Session) endRequest() {
+	s.cancelMu.Lock()
+	s.runningSeq = 0
+	s.cancelRunning = nil
+	s.cancelMu.Unlock()
+}
+
 // In case a handler panics, we catch the panic to avoid crashing both
 // the server and the target. We send an error response back, but
 // in case it's a dup and ignored by the client, we also log the error.
@@ -632,6 +795,9 @@ func (s *Session) handleRequest(request dap.Message) {
 	case *dap.RestartRequest: // Optional (capability 'supportsRestartRequest')
 		/*TODO*/ s.onRestartRequest(request) // not yet implemented
 		return
+	case *dap.CancelRequest: // Optional (capability 'supportsCancelRequest')
+		s.onCancelRequest(request)
+		return
 	}
 
 	// Most requests cannot be processed while the debuggee is running.
@@ -685,6 +851,16 @@ func (s *Session) handleRequest(request dap.Message) {
 				return
 			}
 			s.onSetFunctionBreakpointsRequest(request)
+		case *dap.SetExceptionBreakpointsRequest: // Optional (capability 'exceptionBreakpointFilters')
+			s.changeStateMu.Lock()
+			defer s.changeStateMu.Unlock()
+			s.config.log.Debug("halting execution to set breakpoints")
+			_, err := s.halt()
+			if err != nil {
+				s.sendErrorResponse(request.Request, UnableToSetBreakpoints, "Unable to set or clear breakpoints", err.Error())
+				return
+			}
+			s.onSetExceptionBreakpointsRequest(request)
 		default:
 			r := request.(dap.RequestMessage).GetRequest()
 			s.sendErrorResponse(*r, DebuggeeIsRunning, fmt.Sprintf("Unable to process `%s`", r.Command), "debuggee is running")
@@ -756,6 +932,10 @@ func (s *Session) handleRequest(request dap.Message) {
 		s.onSetInstructionBreakpointsRequest(request)
 	case *dap.SetExceptionBreakpointsRequest: // Optional (capability 'exceptionBreakpointFilters')
 		s.onSetExceptionBreakpointsRequest(request)
+	case *dap.DataBreakpointInfoRequest: // Optional (capability 'supportsDataBreakpoints')
+		s.onDataBreakpointInfoRequest(request)
+	case *dap.SetDataBreakpointsRequest: // Optional (capability 'supportsDataBreakpoints')
+		s.onSetDataBreakpointsRequest(request)
 	case *dap.ThreadsRequest: // Required
 		s.onThreadsRequest(request)
 	case *dap.StackTraceRequest: // Required
@@ -772,38 +952,38 @@ func (s *Session) handleRequest(request dap.Message) {
 		s.onExceptionInfoRequest(request)
 	case *dap.DisassembleRequest: // Optional (capability 'supportsDisassembleRequest')
 		s.onDisassembleRequest(request)
+	case *dap.ReadMemoryRequest: // Optional (capability 'supportsReadMemoryRequest')
+		s.onReadMemoryRequest(request)
+	case *dap.LoadedSourcesRequest: // Optional (capability 'supportsLoadedSourcesRequest')
+		s.onLoadedSourcesRequest(request)
+	case *dap.WriteMemoryRequest: // Optional (capability 'supportsWriteMemoryRequest')
+		s.onWriteMemoryRequest(request)
+	case *dap.GotoTargetsRequest: // Optional (capability 'supportsGotoTargetsRequest')
+		s.onGotoTargetsRequest(request)
+	case *dap.CompletionsRequest: // Optional (capability 'supportsCompletionsRequest')
+		s.onCompletionsRequest(request)
+	case *dap.RestartFrameRequest: // Optional (capability 'supportsRestartFrame')
+		go func() {
+			defer s.recoverPanic(request)
+			s.onRestartFrameRequest(request, resumeRequestLoop)
+		}()
+		resumeRequestLoop.wait()
+	case *dap.StepInTargetsRequest: // Optional (capability 'supportsStepInTargetsRequest')
+		s.onStepInTargetsRequest(request)
+	case *dap.GotoRequest: // Optional (capability 'supportsGotoTargetsRequest')
+		s.onGotoRequest(request)
+	case *dap.BreakpointLocationsRequest: // Optional (capability 'supportsBreakpointLocationsRequest')
+		s.onBreakpointLocationsRequest(request)
 	//--- Requests that we may want to support ---
 	case *dap.SourceRequest: // Required
 		/*TODO*/ s.sendUnsupportedErrorResponse(request.Request) // https://github.com/go-delve/delve/issues/2851
 	case *dap.SetExpressionRequest: // Optional (capability 'supportsSetExpression')
 		/*TODO*/ s.onSetExpressionRequest(request) // Not yet implemented
-	case *dap.LoadedSourcesRequest: // Optional (capability 'supportsLoadedSourcesRequest')
-		/*TODO*/ s.onLoadedSourcesRequest(request) // Not yet implemented
-	case *dap.ReadMemoryRequest: // Optional (capability 'supportsReadMemoryRequest')
-		/*TODO*/ s.onReadMemoryRequest(request) // Not yet implemented
-	case *dap.CancelRequest: // Optional (capability 'supportsCancelRequest')
-		/*TODO*/ s.onCancelRequest(request) // Not yet implemented (does this make sense?)
 	case *dap.ModulesRequest: // Optional (capability 'supportsModulesRequest')
 		/*TODO*/ s.sendUnsupportedErrorResponse(request.Request) // Not yet implemented (does this make sense?)
 	//--- Requests that we do not plan to support ---
-	case *dap.RestartFrameRequest: // Optional (capability 'supportsRestartFrame')
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.GotoRequest: // Optional (capability 'supportsGotoTargetsRequest')
-		s.sendUnsupportedErrorResponse(request.Request)
 	case *dap.TerminateThreadsRequest: // Optional (capability 'supportsTerminateThreadsRequest')
 		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.StepInTargetsRequest: // Optional (capability 'supportsStepInTargetsRequest')
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.GotoTargetsRequest: // Optional (capability 'supportsGotoTargetsRequest')
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.CompletionsRequest: // Optional (capability 'supportsCompletionsRequest')
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.DataBreakpointInfoRequest: // Optional (capability 'supportsDataBreakpoints')
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.SetDataBreakpointsRequest: // Optional (capability 'supportsDataBreakpoints')
-		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.BreakpointLocationsRequest: // Optional (capability 'supportsBreakpointLocationsRequest')
-		s.sendUnsupportedErrorResponse(request.Request)
 	default:
 		// This is a DAP message that go-dap has a struct for, so
 		// decoding succeeded, but this function does not know how
@@ -869,6 +1049,19 @@ func (s *Session) onInitializeRequest(request *dap.InitializeRequest) {
 	response.Body.SupportsSteppingGranularity = true
 	response.Body.SupportsLogPoints = true
 	response.Body.SupportsDisassembleRequest = true
+	response.Body.SupportsDataBreakpoints = true
+	response.Body.SupportsReadMemoryRequest = true
+	response.Body.SupportsWriteMemoryRequest = true
+	response.Body.SupportsLoadedSourcesRequest = true
+	response.Body.SupportsGotoTargetsRequest = true
+	response.Body.SupportsStepInTargetsRequest = true
+	response.Body.SupportsRestartFrame = true
+	response.Body.SupportsCompletionsRequest = true
+	response.Body.CompletionTriggerCharacters = []string{"."}
+	response.Body.SupportsCancelRequest = true
+	response.Body.SupportsBreakpointLocationsRequest = true
+	response.Body.ExceptionBreakpointFilters = exceptionBreakpointFilters
+	response.Body.SupportsExceptionFilterOptions = true
 	// To be enabled by CapabilitiesEvent based on launch configuration
 	response.Body.SupportsStepBack = false
 	response.Body.SupportTerminateDebuggee = false
@@ -876,9 +1069,6 @@ func (s *Session) onInitializeRequest(request *dap.InitializeRequest) {
 	response.Body.SupportsTerminateRequest = false
 	response.Body.SupportsRestartRequest = false
 	response.Body.SupportsSetExpression = false
-	response.Body.SupportsLoadedSourcesRequest = false
-	response.Body.SupportsReadMemoryRequest = false
-	response.Body.SupportsCancelRequest = false
 	s.send(response)
 }
 
@@ -888,6 +1078,8 @@ func (s *Session) setClientCapabilities(args dap.InitializeRequestArguments) {
 	s.clientCapabilities.supportsRunInTerminalRequest = args.SupportsRunInTerminalRequest
 	s.clientCapabilities.supportsVariablePaging = args.SupportsVariablePaging
 	s.clientCapabilities.supportsVariableType = args.SupportsVariableType
+	s.clientCapabilities.supportsMemoryEvent = args.SupportsMemoryEvent
+	s.clientCapabilities.supportsInvalidatedEvent = args.SupportsInvalidatedEvent
 }
 
 func cleanExeName(name string) string {
@@ -1422,6 +1614,13 @@ func (s *Session) onSetBreakpointsRequest(request *dap.SetBreakpointsRequest) {
 	// Get all existing breakpoints that match for this source.
 	sourceRequestPrefix := fmt.Sprintf("sourceBp Path=%q ", request.Arguments.Source.Path)
 
+	// columns are the columns that new breakpoints were set on, zero for
+	// line breakpoints and -1 for existing breakpoints.
+	columns := make([]int, len(request.Arguments.Breakpoints))
+	for i := range columns {
+		columns[i] = -1
+	}
+
 	breakpoints := s.setBreakpoints(sourceRequestPrefix, len(request.Arguments.Breakpoints), func(i int) *bpMetadata {
 		want := request.Arguments.Breakpoints[i]
 		return &bpMetadata{
@@ -1432,11 +1631,27 @@ func (s *Session) onSetBreakpointsRequest(request *dap.SetBreakpointsRequest) {
 		}
 	}, func(i int) (*bpLocation, error) {
 		want := request.Arguments.Breakpoints[i]
+		columns[i] = 0
+		if want.Column > 0 {
+			if addrs, column := columnBreakpointAddrs(s.breakpointLocations(serverPath, want.Line, want.Line), want.Column); len(addrs) > 0 {
+				columns[i] = column
+				return &bpLocation{addrs: addrs}, nil
+			}
+		}
 		return &bpLocation{
 			file: serverPath,
 			line: want.Line,
 		}, nil
 	})
+	for i, want := range request.Arguments.Breakpoints {
+		if !breakpoints[i].Verified || want.Column <= 0 {
+			continue
+		}
+		if columns[i] < 0 {
+			_, columns[i] = columnBreakpointAddrs(s.breakpointLocations(serverPath, want.Line, want.Line), want.Column)
+		}
+		breakpoints[i].Column = columns[i]
+	}
 
 	response := &dap.SetBreakpointsResponse{Response: *newResponse(request.Request)}
 	response.Body.Breakpoints = breakpoints
@@ -1444,6 +1659,170 @@ func (s *Session) onSetBreakpointsRequest(request *dap.SetBreakpointsRequest) {
 	s.send(response)
 }
 
+// columnBreakpointAddrs returns the addresses of a breakpoint set on column
+// of the line of positions and the column it is moved to: the last position
+// starting at or before column, or the first position of the line if there
+// is none. Returns no addresses if the positions do not have columns, the
+// breakpoint should then be set on the whole line.
+func columnBreakpointAddrs(positions []proc.StatementPosition, column int) ([]uint64, int) {
+	found := 0
+	for _, pos := range positions {
+		if pos.Column == 0 {
+			continue
+		}
+		if found == 0 || pos.Column <= column {
+			found = pos.Column
+		}
+	}
+	if found == 0 {
+		return nil, 0
+	}
+	var addrs []uint64
+	for _, pos := range positions {
+		if pos.Column == found {
+			addrs = append(addrs, pos.PCs[0])
+		}
+	}
+	return addrs, found
+}
+
+// breakpointLocations returns the positions of the statements of file
+// between startLine and endLine (both included), sorted by line and column.
+//
+// The Go compiler does not record columns in debug_line: when a line
+// contains statements of more than one function the columns of the function
+// literals defined on it are found by parsing file, so that the client can
+// show a location for each function. If file can not be read the positions
+// are returned without columns and breakpoints apply to the whole line.
+func (s *Session) breakpointLocations(file string, startLine, endLine int) []proc.StatementPosition {
+	positions := s.debugger.BreakpointLocations(file, startLine, endLine)
+	tgrp, unlock := s.debugger.LockTargetGroup()
+	assignFuncLitColumns(tgrp.Selected.BinInfo(), file, positions)
+	unlock()
+	sort.SliceStable(positions, func(i, j int) bool {
+		if positions[i].Line != positions[j].Line {
+			return positions[i].Line < positions[j].Line
+		}
+		return positions[i].Column < positions[j].Column
+	})
+	return positions
+}
+
+// closureNameRx matches the suffix the compiler appends to the name of the
+// enclosing function to name a function literal.
+var closureNameRx = regexp.MustCompile(`\.func(\d+(?:\.\d+)*)$`)
+
+// assignFuncLitColumns sets the column of the positions, without a column,
+// of lines containing statements of more than one function. Function
+// literals starting on the line get the column of their func keyword, the
+// enclosing function gets the column of the first token of the line.
+func assignFuncLitColumns(bi *proc.BinaryInfo, file string, positions []proc.StatementPosition) {
+	byLine := map[int][]int{}
+	for i := range positions {
+		if positions[i].Column == 0 {
+			byLine[positions[i].Line] = append(byLine[positions[i].Line], i)
+		}
+	}
+	for line, idxs := range byLine {
+		if !slices.ContainsFunc(idxs, func(i int) bool { return positions[i].Fn != positions[idxs[0]].Fn }) {
+			delete(byLine, line)
+		}
+	}
+	if len(byLine) == 0 {
+		return
+	}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, file, src, parser.SkipObjectResolution)
+	if f == nil {
+		return
+	}
+	funcLitColumns := map[int][]int{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok {
+			pos := fset.Position(lit.Pos())
+			funcLitColumns[pos.Line] = append(funcLitColumns[pos.Line], pos.Column)
+		}
+		return true
+	})
+	tf := fset.File(f.Pos())
+
+	for line, idxs := range byLine {
+		cols := funcLitColumns[line]
+		if len(cols) == 0 {
+			continue
+		}
+
+		// Function literals starting on this line, identified by their name
+		// without type parameters since generic functions can have more than
+		// one instantiation of each literal.
+		litIdx := map[string][]int{}
+		var lits []string
+		var others []int
+		for _, i := range idxs {
+			fn := positions[i].Fn
+			name := fn.NameWithoutTypeParams()
+			if closureNameRx.MatchString(name) {
+				if _, l, _ := bi.PCToLine(fn.Entry); l == line {
+					if litIdx[name] == nil {
+						lits = append(lits, name)
+					}
+					litIdx[name] = append(litIdx[name], i)
+					continue
+				}
+			}
+			others = append(others, i)
+		}
+		if len(lits) != len(cols) {
+			continue
+		}
+
+		// The compiler numbers function literals in the order they appear in
+		// the source, nested literals after the one containing them.
+		slices.SortFunc(lits, func(a, b string) int {
+			return slices.Compare(closureNumbers(a), closureNumbers(b))
+		})
+		for k, name := range lits {
+			for _, i := range litIdx[name] {
+				positions[i].Column = cols[k]
+			}
+		}
+
+		if line > tf.LineCount() {
+			continue
+		}
+		off := tf.Offset(tf.LineStart(line))
+		col := 1
+		for off+col-1 < len(src) && (src[off+col-1] == ' ' || src[off+col-1] == '\t') {
+			col++
+		}
+		if col < cols[0] {
+			for _, i := range others {
+				positions[i].Column = col
+			}
+		}
+	}
+}
+
+// closureNumbers returns the numbers in the suffix the compiler appends to
+// the name of a function literal, for example [1 2] for main.main.func1.2.
+func closureNumbers(name string) []int {
+	m := closureNameRx.FindStringSubmatch(name)
+	if m == nil {
+		return nil
+	}
+	var r []int
+	for _, s := range strings.Split(m[1], ".") {
+		n, _ := strconv.Atoi(s)
+		r = append(r, n)
+	}
+	return r
+}
+
 type bpMetadata struct {
 	name         string
 	condition    string
@@ -1456,6 +1835,10 @@ type bpLocation struct {
 	line  int
 	addr  uint64
 	addrs []uint64
+	// watch is the expression watched by a data breakpoint, nil for
+	// all other breakpoints.
+	watch     *dataBreakpointID
+	watchType api.WatchType
 }
 
 // setBreakpoints is a helper function for setting source, function and instruction
@@ -1533,7 +1916,11 @@ func (s *Session) setBreakpoints(prefix string, totalBps int, metadataFunc func(
 				err = setLogMessage(bp, want.logMessage)
 				if err == nil {
 					// Create new breakpoints.
-					got, err = s.debugger.CreateBreakpoint(bp, "", nil, false)
+					if wantLoc.watch != nil {
+						got, err = s.createWatchpoint(bp, wantLoc)
+					} else {
+						got, err = s.debugger.CreateBreakpoint(bp, "", nil, false)
+					}
 				}
 			}
 		}
@@ -1560,8 +1947,12 @@ func (s *Session) updateBreakpointsResponse(breakpoints []dap.Breakpoint, i int,
 	if err != nil {
 		breakpoints[i].Message = err.Error()
 	} else {
-		path := s.toClientPath(got.File)
 		breakpoints[i].Id = got.ID
+		if got.WatchExpr != "" {
+			// Data breakpoints do not have a source location.
+			return
+		}
+		path := s.toClientPath(got.File)
 		breakpoints[i].Line = got.Line
 		breakpoints[i].Source = &dap.Source{Name: filepath.Base(path), Path: path}
 	}
@@ -1648,6 +2039,156 @@ func (s *Session) onSetInstructionBreakpointsRequest(request *dap.SetInstruction
 	s.send(response)
 }
 
+// dataBpPrefix is the prefix of bp.Name for every breakpoint bp set
+// in setDataBreakpoints request.
+const dataBpPrefix = "dataBreakpoint"
+
+// dataBreakpointID identifies the expression watched by a data breakpoint
+// and the scope it must be evaluated in. Its string form is used as the
+// opaque dataId exchanged with the client.
+type dataBreakpointID struct {
+	goroutineID int
+	frameIndex  int
+	expr        string
+}
+
+func (id dataBreakpointID) String() string {
+	return fmt.Sprintf("%d,%d,%s", id.goroutineID, id.frameIndex, id.expr)
+}
+
+func parseDataBreakpointID(dataID string) (*dataBreakpointID, error) {
+	fields := strings.SplitN(dataID, ",", 3)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid dataId %q", dataID)
+	}
+	goid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid dataId %q: %v", dataID, err)
+	}
+	frame, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid dataId %q: %v", dataID, err)
+	}
+	return &dataBreakpointID{goroutineID: goid, frameIndex: frame, expr: fields[2]}, nil
+}
+
+// watchTypeToAccessTypes converts a set of watch types to the list of
+// access types of data breakpoints that can be set with them.
+func watchTypeToAccessTypes(wtype api.WatchType) []dap.DataBreakpointAccessType {
+	var r []dap.DataBreakpointAccessType
+	if wtype&api.WatchRead != 0 {
+		r = append(r, "read")
+	}
+	if wtype&api.WatchWrite != 0 {
+		r = append(r, "write")
+	}
+	if wtype&api.WatchRead != 0 && wtype&api.WatchWrite != 0 {
+		r = append(r, "readWrite")
+	}
+	return r
+}
+
+func accessTypeToWatchType(accessType dap.DataBreakpointAccessType) (api.WatchType, error) {
+	switch accessType {
+	case "read":
+		return api.WatchRead, nil
+	case "write", "": // DAP does not specify a default, write is the most common case.
+		return api.WatchWrite, nil
+	case "readWrite":
+		return api.WatchRead | api.WatchWrite, nil
+	default:
+		return 0, fmt.Errorf("unknown access type %q", accessType)
+	}
+}
+
+// onDataBreakpointInfoRequest handles 'dataBreakpointInfo' requests.
+// Capability 'supportsDataBreakpoints' is set in 'initialize' response.
+func (s *Session) onDataBreakpointInfoRequest(request *dap.DataBreakpointInfoRequest) {
+	goid, frame := -1, 0
+	expr := request.Arguments.Name
+	if ref := request.Arguments.VariablesReference; ref != 0 {
+		// Name refers to a child of the referenced variable. As for
+		// setVariable requests, the variable is evaluated in the topmost
+		// frame of the current goroutine.
+		v, ok := s.variableHandles.get(ref)
+		if !ok {
+			s.sendErrorResponse(request.Request, UnableToGetDataBreakpointInfo, "Unable to get data breakpoint info", fmt.Sprintf("unknown reference %d", ref))
+			return
+		}
+		var err error
+		expr, err = s.computeEvaluateName(v, request.Arguments.Name)
+		if err != nil {
+			s.sendErrorResponse(request.Request, UnableToGetDataBreakpointInfo, "Unable to get data breakpoint info", err.Error())
+			return
+		}
+	} else if sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId); ok {
+		goid = sf.goroutineID
+		frame = sf.frameIndex
+	}
+
+	response := &dap.DataBreakpointInfoResponse{Response: *newResponse(request.Request)}
+	wtype, err := s.debugger.WatchpointTypes(int64(goid), frame, 0, expr)
+	if err != nil {
+		// A null dataId tells the client that no data breakpoint can be
+		// set, the description explains why.
+		response.Body.Description = err.Error()
+		s.send(response)
+		return
+	}
+	response.Body.DataId = dataBreakpointID{goroutineID: goid, frameIndex: frame, expr: expr}.String()
+	response.Body.Description = expr
+	response.Body.AccessTypes = watchTypeToAccessTypes(wtype)
+	s.send(response)
+}
+
+// onSetDataBreakpointsRequest handles 'setDataBreakpoints' requests.
+// Capability 'supportsDataBreakpoints' is set in 'initialize' response.
+func (s *Session) onSetDataBreakpointsRequest(request *dap.SetDataBreakpointsRequest) {
+	breakpoints := s.setBreakpoints(dataBpPrefix, len(request.Arguments.Breakpoints), func(i int) *bpMetadata {
+		want := request.Arguments.Breakpoints[i]
+		return &bpMetadata{
+			name:         fmt.Sprintf("%s DataId=%s AccessType=%s", dataBpPrefix, want.DataId, want.AccessType),
+			condition:    want.Condition,
+			hitCondition: want.HitCondition,
+			logMessage:   "",
+		}
+	}, func(i int) (*bpLocation, error) {
+		want := request.Arguments.Breakpoints[i]
+		id, err := parseDataBreakpointID(want.DataId)
+		if err != nil {
+			return nil, err
+		}
+		wtype, err := accessTypeToWatchType(want.AccessType)
+		if err != nil {
+			return nil, err
+		}
+		return &bpLocation{watch: id, watchType: wtype}, nil
+	})
+
+	response := &dap.SetDataBreakpointsResponse{Response: *newResponse(request.Request)}
+	response.Body.Breakpoints = breakpoints
+	s.send(response)
+}
+
+// createWatchpoint creates the watchpoint described by loc and gives it
+// the name and conditions of requested.
+func (s *Session) createWatchpoint(requested *api.Breakpoint, loc *bpLocation) (*api.Breakpoint, error) {
+	got, err := s.debugger.CreateWatchpoint(int64(loc.watch.goroutineID), loc.watch.frameIndex, 0, loc.watch.expr, loc.watchType)
+	if err != nil {
+		return nil, err
+	}
+	got.Name = requested.Name
+	got.Cond = requested.Cond
+	got.HitCond = requested.HitCond
+	if err := s.debugger.AmendBreakpoint(got); err != nil {
+		if _, err := s.debugger.ClearBreakpoint(got); err != nil {
+			s.config.log.Errorf("could not clear watchpoint %d: %v", got.ID, err)
+		}
+		return nil, err
+	}
+	return got, nil
+}
+
 func (s *Session) clearBreakpoints(existingBps map[string]*api.Breakpoint, amendedBps map[string]struct{}) error {
 	for req, bp := range existingBps {
 		if _, ok := amendedBps[req]; ok {
@@ -1678,30 +2219,179 @@ func (s *Session) getMatchingBreakpoints(prefix string) map[string]*api.Breakpoi
 	return matchingBps
 }
 
+// Exception breakpoint filters, returned in the 'initialize' response.
+const (
+	unrecoveredPanicFilter = "unrecoveredPanic"
+	fatalErrorFilter       = "fatalError"
+	allPanicsFilter        = "allPanics"
+	panicConditionFilter   = "panicCondition"
+)
+
+var exceptionBreakpointFilters = []dap.ExceptionBreakpointsFilter{
+	{
+		Filter:      unrecoveredPanicFilter,
+		Label:       "Unrecovered panics",
+		Description: "Stop when a panic is not recovered and is about to terminate the program.",
+		Default:     true,
+	},
+	{
+		Filter:      fatalErrorFilter,
+		Label:       "Fatal errors",
+		Description: "Stop on fatal runtime errors, such as concurrent map writes or deadlocks.",
+		Default:     true,
+	},
+	{
+		Filter:      allPanicsFilter,
+		Label:       "All panics (including recovered)",
+		Description: "Stop every time panic is called, even if the panic will be recovered.",
+	},
+	{
+		Filter:               panicConditionFilter,
+		Label:                "Panics with value matching condition",
+		Description:          "Stop when panic is called with a value for which the condition is true, even if the panic will be recovered.",
+		SupportsCondition:    true,
+		ConditionDescription: "Expression evaluated when panic is called, the panic value is available as 'e' (e.g. e == \"unexpected\")",
+	},
+}
+
+// panicExceptionBpName is the name of the breakpoint set on runtime.gopanic
+// by the allPanics and panicCondition exception filters.
+const panicExceptionBpName = exceptionBpPrefix + " panic"
+
+// exceptionBpPrefix is the prefix of bp.Name for every breakpoint bp set
+// by a 'setExceptionBreakpoints' request.
+const exceptionBpPrefix = "exceptionBreakpoint"
+
+// onSetExceptionBreakpointsRequest handles 'setExceptionBreakpoints'
+// requests. The unrecoveredPanic and fatalError filters enable the
+// breakpoints that delve always sets on unrecovered panics and fatal
+// errors, the allPanics and panicCondition filters set a breakpoint on
+// runtime.gopanic, with the condition of panicCondition if allPanics is
+// not also enabled.
+// Capability 'exceptionBreakpointFilters' is set in 'initialize' response.
 func (s *Session) onSetExceptionBreakpointsRequest(request *dap.SetExceptionBreakpointsRequest) {
-	// Unlike what DAP documentation claims, this request is always sent
-	// even though we specified no filters at initialization. Handle as no-op.
-	s.send(&dap.SetExceptionBreakpointsResponse{Response: *newResponse(request.Request)})
-}
+	response := &dap.SetExceptionBreakpointsResponse{Response: *newResponse(request.Request)}
+	args := request.Arguments
+	if args.Filters == nil && args.FilterOptions == nil {
+		// Clients that do not know about our filters send this request
+		// without any, keep the default behavior.
+		s.send(response)
+		return
+	}
 
-func closeIfOpen(ch chan struct{}) {
-	if ch != nil {
-		select {
-		case <-ch:
-			// already closed
-		default:
-			close(ch)
+	enabled := make(map[string]bool)
+	var condition string
+	filters := append([]string{}, args.Filters...)
+	for _, opt := range args.FilterOptions {
+		filters = append(filters, opt.FilterId)
+		if opt.FilterId == panicConditionFilter {
+			condition = opt.Condition
 		}
 	}
-}
+	for _, filter := range filters {
+		enabled[filter] = true
+	}
 
-// onConfigurationDoneRequest handles 'configurationDone' request.
-// This is an optional request enabled by capability 'supportsConfigurationDoneRequest'.
-// It gets triggered after all the debug requests that follow initialized event,
-// so the s.debugger is guaranteed to be set. Expects the target to be halted.
-func (s *Session) onConfigurationDoneRequest(request *dap.ConfigurationDoneRequest, allowNextStateChange *syncflag) {
-	defer allowNextStateChange.raise()
-	if s.args.stopOnEntry {
+	errs := make(map[string]error)
+	errs[unrecoveredPanicFilter] = s.setInternalBreakpointEnabled(proc.UnrecoveredPanic, enabled[unrecoveredPanicFilter])
+	errs[fatalErrorFilter] = s.setInternalBreakpointEnabled(proc.FatalThrow, enabled[fatalErrorFilter])
+
+	var panicBps []*bpMetadata
+	switch {
+	case enabled[allPanicsFilter]:
+		panicBps = append(panicBps, &bpMetadata{name: panicExceptionBpName})
+	case enabled[panicConditionFilter]:
+		if condition == "" {
+			errs[panicConditionFilter] = errors.New("a condition is required")
+			break
+		}
+		panicBps = append(panicBps, &bpMetadata{name: panicExceptionBpName, condition: condition})
+	}
+	bps := s.setBreakpoints(exceptionBpPrefix, len(panicBps), func(i int) *bpMetadata {
+		return panicBps[i]
+	}, func(i int) (*bpLocation, error) {
+		const panicFn = "runtime.gopanic"
+		spec, err := locspec.Parse(panicFn)
+		if err != nil {
+			return nil, err
+		}
+		locs, err := s.debugger.FindLocationSpec(-1, 0, 0, panicFn, spec, true, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(locs) == 0 {
+			return nil, fmt.Errorf("could not find %s", panicFn)
+		}
+		return &bpLocation{addr: locs[0].PC, addrs: locs[0].PCs}, nil
+	})
+	if len(bps) > 0 && !bps[0].Verified {
+		if enabled[allPanicsFilter] {
+			errs[allPanicsFilter] = errors.New(bps[0].Message)
+		} else {
+			errs[panicConditionFilter] = errors.New(bps[0].Message)
+		}
+	}
+
+	// The response contains a breakpoint for each filter, in the same order
+	// as the request.
+	response.Body.Breakpoints = make([]dap.Breakpoint, len(filters))
+	for i, filter := range filters {
+		err := errs[filter]
+		if !isExceptionBreakpointFilter(filter) {
+			err = fmt.Errorf("unknown exception filter %q", filter)
+		}
+		response.Body.Breakpoints[i].Verified = err == nil
+		if err != nil {
+			response.Body.Breakpoints[i].Message = err.Error()
+		}
+	}
+	s.send(response)
+}
+
+func isExceptionBreakpointFilter(filter string) bool {
+	for _, f := range exceptionBreakpointFilters {
+		if f.Filter == filter {
+			return true
+		}
+	}
+	return false
+}
+
+// setInternalBreakpointEnabled enables or disables the breakpoint that
+// delve sets on unrecovered panics or fatal errors, identified by name.
+func (s *Session) setInternalBreakpointEnabled(name string, enabled bool) error {
+	bp := s.debugger.FindBreakpointByName(name)
+	if bp == nil {
+		if !enabled {
+			return nil
+		}
+		return fmt.Errorf("could not find the %s breakpoint", name)
+	}
+	if bp.Disabled == !enabled {
+		return nil
+	}
+	bp.Disabled = !enabled
+	return s.debugger.AmendBreakpoint(bp)
+}
+
+func closeIfOpen(ch chan struct{}) {
+	if ch != nil {
+		select {
+		case <-ch:
+			// already closed
+		default:
+			close(ch)
+		}
+	}
+}
+
+// onConfigurationDoneRequest handles 'configurationDone' request.
+// This is an optional request enabled by capability 'supportsConfigurationDoneRequest'.
+// It gets triggered after all the debug requests that follow initialized event,
+// so the s.debugger is guaranteed to be set. Expects the target to be halted.
+func (s *Session) onConfigurationDoneRequest(request *dap.ConfigurationDoneRequest, allowNextStateChange *syncflag) {
+	defer allowNextStateChange.raise()
+	if s.args.stopOnEntry {
 		e := &dap.StoppedEvent{
 			Event: *newEvent("stopped"),
 			Body:  dap.StoppedEventBody{Reason: "entry", ThreadId: 1, AllThreadsStopped: true},
@@ -1769,8 +2459,13 @@ func (s *Session) onThreadsRequest(request *dap.ThreadsRequest) {
 	var err error
 	var gs []*proc.G
 	var next int
+	var canceled bool
 	if s.debugger != nil {
 		gs, next, err = s.debugger.Goroutines(0, maxGoroutines)
+		if errors.Is(err, proc.ErrCanceled) {
+			// Return the goroutines loaded before the request was cancelled.
+			canceled, err = true, nil
+		}
 		if err == nil {
 			// Parse the goroutine arguments.
 			filters, _, _, _, _, _, _, parseErr := api.ParseGoroutineArgs(s.args.GoroutineFilters)
@@ -1814,7 +2509,11 @@ func (s *Session) onThreadsRequest(request *dap.ThreadsRequest) {
 		}
 
 		if next >= 0 {
-			s.logToConsole(fmt.Sprintf("Too many goroutines, only loaded %d", len(gs)))
+			if canceled {
+				s.logToConsole(fmt.Sprintf("Loading of goroutines cancelled, only loaded %d", len(gs)))
+			} else {
+				s.logToConsole(fmt.Sprintf("Too many goroutines, only loaded %d", len(gs)))
+			}
 
 			// Make sure the selected goroutine is included in the list of threads
 			// to return.
@@ -2029,6 +2728,14 @@ func (s *Session) onNextRequest(request *dap.NextRequest, allowNextStateChange *
 // onStepInRequest handles 'stepIn' request
 // This is a mandatory request to support.
 func (s *Session) onStepInRequest(request *dap.StepInRequest, allowNextStateChange *syncflag) {
+	if id := request.Arguments.TargetId; id != 0 {
+		if id < 0 || id > len(s.stepInTargets) || s.stepInTargets[id-1].goroutineID != int64(request.Arguments.ThreadId) {
+			s.sendErrorResponse(request.Request, UnableToStepIn, "Unable to step in", fmt.Sprintf("unknown target %d for goroutine %d", id, request.Arguments.ThreadId))
+			allowNextStateChange.raise()
+			return
+		}
+		s.nextCommand = &api.DebuggerCommand{Name: api.Step, TargetPC: s.stepInTargets[id-1].pc}
+	}
 	s.sendStepResponse(request.Arguments.ThreadId, &dap.StepInResponse{Response: *newResponse(request.Request)})
 	s.stepUntilStopAndNotify(api.Step, request.Arguments.ThreadId, request.Arguments.Granularity, allowNextStateChange)
 }
@@ -2175,10 +2882,17 @@ func (s *Session) onStackTraceRequest(request *dap.StackTraceRequest) {
 	// the requested depth and then slice them here per
 	// `supportsDelayedStackTraceLoading` capability.
 	frames, err := s.debugger.Stacktrace(int64(goroutineID), start+levels-1, 0)
-	if err != nil {
+	if err != nil && !errors.Is(err, proc.ErrCanceled) {
 		s.sendErrorResponse(request.Request, UnableToProduceStackTrace, "Unable to produce stack trace", err.Error())
 		return
 	}
+	// If the request was cancelled the last frame records the error and
+	// the frames before it are returned.
+	canceled := err != nil
+	if len(frames) > 0 && errors.Is(frames[len(frames)-1].Err, proc.ErrCanceled) {
+		canceled = true
+		frames = frames[:len(frames)-1]
+	}
 
 	// Determine if the goroutine is a system goroutine.
 	isSystemGoroutine := true
@@ -2208,7 +2922,16 @@ func (s *Session) onStackTraceRequest(request *dap.StackTraceRequest) {
 	}
 
 	totalFrames := len(frames)
-	if len(frames) >= start+levels && !frames[len(frames)-1].Bottom {
+	if canceled {
+		if len(frames) < start+levels {
+			stackFrames = append(stackFrames, dap.StackFrame{
+				Id:               s.stackFrameHandles.create(stackFrame{goroutineID, len(frames)}),
+				Name:             "(stack unwinding cancelled)",
+				PresentationHint: "label",
+			})
+			totalFrames++
+		}
+	} else if len(frames) >= start+levels && !frames[len(frames)-1].Bottom {
 		// We don't know the exact number of available stack frames, so
 		// add an arbitrary number so the client knows to request additional
 		// frames.
@@ -2406,6 +3129,9 @@ func (s *Session) maybeLoadResliced(v *fullyQualifiedVariable, start, count int)
 // number of children returned, since a key-value pair may be split
 // into two separate children.
 func getIndexedVariableCount(v *proc.Variable) int {
+	if hasFormattedChildren(v) {
+		return 0
+	}
 	indexedVars := 0
 	switch v.Kind {
 	case reflect.Array, reflect.Slice, reflect.Map:
@@ -2421,6 +3147,25 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 	// compute evaluate names when this is called from onSetVariableRequest.
 	children := []dap.Variable{} // must return empty array, not null, if no children
 
+	if hasFormattedChildren(v.Variable) {
+		// Synthetic children produced by a pretty printer, they can not be
+		// accessed with an expression.
+		for i := range v.FormattedChildren {
+			c := &v.FormattedChildren[i]
+			cvalue, cvarref := s.convertVariable(c, "")
+			children = append(children, dap.Variable{
+				Name:               c.Name,
+				Type:               s.getTypeIfSupported(c),
+				Value:              cvalue,
+				VariablesReference: cvarref,
+				IndexedVariables:   getIndexedVariableCount(c),
+				NamedVariables:     getNamedVariableCount(c),
+				MemoryReference:    s.getMemoryReferenceIfSupported(c),
+			})
+		}
+		return children
+	}
+
 	switch v.Kind {
 	case reflect.Map:
 		for i := 0; i < len(v.Children); i += 2 {
@@ -2457,6 +3202,7 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 					VariablesReference: keyref,
 					IndexedVariables:   getIndexedVariableCount(keyv),
 					NamedVariables:     getNamedVariableCount(keyv),
+					MemoryReference:    s.getMemoryReferenceIfSupported(keyv),
 				}
 				valvar := dap.Variable{
 					Name:               fmt.Sprintf("[val %d]", v.startIndex+kvIndex),
@@ -2466,6 +3212,7 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 					VariablesReference: valref,
 					IndexedVariables:   getIndexedVariableCount(valv),
 					NamedVariables:     getNamedVariableCount(valv),
+					MemoryReference:    s.getMemoryReferenceIfSupported(valv),
 				}
 				children = append(children, keyvar, valvar)
 			} else { // At least one is a scalar
@@ -2474,10 +3221,11 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 					keyValType = fmt.Sprintf("%s: %s", keyType, valType)
 				}
 				kvvar := dap.Variable{
-					Name:         key,
-					EvaluateName: valexpr,
-					Type:         keyValType,
-					Value:        val,
+					Name:            key,
+					EvaluateName:    valexpr,
+					Type:            keyValType,
+					Value:           val,
+					MemoryReference: s.getMemoryReferenceIfSupported(valv),
 				}
 				if keyref != 0 { // key is a type to be expanded
 					if len(key) > maxMapKeyValueLen {
@@ -2509,6 +3257,7 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 				VariablesReference: cvarref,
 				IndexedVariables:   getIndexedVariableCount(&v.Children[i]),
 				NamedVariables:     getNamedVariableCount(&v.Children[i]),
+				MemoryReference:    s.getMemoryReferenceIfSupported(&v.Children[i]),
 			}
 		}
 	default:
@@ -2561,13 +3310,23 @@ func (s *Session) childrenToDAPVariables(v *fullyQualifiedVariable) []dap.Variab
 				VariablesReference: cvarref,
 				IndexedVariables:   getIndexedVariableCount(c),
 				NamedVariables:     getNamedVariableCount(c),
+				MemoryReference:    s.getMemoryReferenceIfSupported(c),
 			}
 		}
 	}
 	return children
 }
 
+// hasFormattedChildren returns true if v was formatted by a pretty printer
+// that replaced its children with synthetic ones.
+func hasFormattedChildren(v *proc.Variable) bool {
+	return v.Flags&proc.VariableFormatted != 0 && v.FormattedChildren != nil
+}
+
 func getNamedVariableCount(v *proc.Variable) int {
+	if hasFormattedChildren(v) {
+		return len(v.FormattedChildren)
+	}
 	namedVars := 0
 	if v.Kind == reflect.Map && v.Len > 0 {
 		// len
@@ -2645,6 +3404,29 @@ func (s *Session) getTypeIfSupported(v *proc.Variable) string {
 	return v.TypeString()
 }
 
+// getMemoryReferenceIfSupported returns the memory reference of the memory
+// the variable refers to: the pointed-to memory for pointers, the backing
+// array for strings and slices and the variable's own memory otherwise.
+func (s *Session) getMemoryReferenceIfSupported(v *proc.Variable) string {
+	if !s.clientCapabilities.supportsMemoryReferences || v.Unreadable != nil {
+		return ""
+	}
+	addr := v.Addr
+	switch v.Kind {
+	case reflect.Ptr, reflect.UnsafePointer:
+		addr = 0
+		if len(v.Children) > 0 {
+			addr = v.Children[0].Addr
+		}
+	case reflect.String, reflect.Slice:
+		addr = v.Base
+	}
+	if addr == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%#x", addr)
+}
+
 // convertVariable converts proc.Variable to dap.Variable value and reference
 // while keeping track of the full qualified name or load expression.
 // Variable reference is used to keep track of the children associated with each
@@ -2724,6 +3506,13 @@ func (s *Session) convertVariableWithOpts(v *proc.Variable, qualifiedNameOrExpr
 		return value
 	}
 
+	if hasFormattedChildren(v) {
+		if len(v.FormattedChildren) > 0 {
+			variablesReference = maybeCreateVariableHandle(v)
+		}
+		return value, variablesReference
+	}
+
 	switch v.Kind {
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		n, _ := strconv.ParseUint(api.ExtractIntValue(api.ConvertVar(v).Value), 10, 64)
@@ -2926,7 +3715,7 @@ func (s *Session) onEvaluateRequest(request *dap.EvaluateRequest) {
 			opts |= showFullValue
 		}
 		exprVal, exprRef := s.convertVariableWithOpts(exprVar, fmt.Sprintf("(%s)", request.Arguments.Expression), opts)
-		response.Body = dap.EvaluateResponseBody{Result: exprVal, Type: s.getTypeIfSupported(exprVar), VariablesReference: exprRef, IndexedVariables: getIndexedVariableCount(exprVar), NamedVariables: getNamedVariableCount(exprVar)}
+		response.Body = dap.EvaluateResponseBody{Result: exprVal, Type: s.getTypeIfSupported(exprVar), VariablesReference: exprRef, IndexedVariables: getIndexedVariableCount(exprVar), NamedVariables: getNamedVariableCount(exprVar), MemoryReference: s.getMemoryReferenceIfSupported(exprVar)}
 	}
 	s.send(response)
 }
@@ -2997,7 +3786,7 @@ func (s *Session) doCall(goid, frame int, expr string) (*api.DebuggerState, []*p
 	// If the above Call command passed but the expression is not a valid
 	// go expression, we just handled a variable assignment request.
 	isAssignment := false
-	if _, err := parser.ParseExpr(expr); err != nil {
+	if _, err := evalop.ParseExpr(expr); err != nil {
 		isAssignment = true
 	}
 
@@ -3112,12 +3901,29 @@ func (s *Session) onSetVariableRequest(request *dap.SetVariableRequest) {
 	switch evaluated.Kind {
 	case reflect.String:
 		useFnCall = true
+	case reflect.Slice, reflect.Map:
+		// Slice and map literals need to be allocated in the target.
+		if expr, err := evalop.ParseExpr(arg.Value); err == nil {
+			_, useFnCall = expr.(*ast.CompositeLit)
+		}
 	default:
 		// TODO(hyangah): it's possible to set a non-string variable using (`call i = fn()`)
 		// and we don't support it through the Set Variable request yet.
 		// If we want to support it for non-string types, we need to parse arg.Value.
 	}
 
+	if !useFnCall {
+		err := s.debugger.SetVariableInScope(int64(goid), frame, 0, evaluateName, arg.Value)
+		// Assignments that need memory allocated in the target program, for
+		// example of a value to an interface or of a map element, are done
+		// with call injection.
+		useFnCall = errors.Is(err, proc.ErrFuncCallNotAllowedLitAlloc) || errors.Is(err, proc.ErrFuncCallNotAllowedStrAlloc)
+		if err != nil && !useFnCall {
+			s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to set variable", err.Error())
+			return
+		}
+	}
+
 	if useFnCall {
 		// TODO(hyangah): function call injection currently allows to assign return values of
 		// a function call to variables. So, curious users would find set variable
@@ -3144,11 +3950,6 @@ func (s *Session) onSetVariableRequest(request *dap.SetVariableRequest) {
 			s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to set variable", msg)
 			return
 		}
-	} else {
-		if err := s.debugger.SetVariableInScope(int64(goid), frame, 0, evaluateName, arg.Value); err != nil {
-			s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to set variable", err.Error())
-			return
-		}
 	}
 	// * Note on inconsistent state after set variable:
 	//
@@ -3174,22 +3975,591 @@ func (s *Session) onSetVariableRequest(request *dap.SetVariableRequest) {
 	s.send(response)
 }
 
+// onCompletionsRequest handles 'completions' requests.
+// Capability 'supportsCompletionsRequest' is set in 'initialize' response.
+// If the text starts with 'dlv ' the names of the dlv commands, and of
+// their arguments, are returned. Otherwise the word before the cursor is
+// completed with the variables, functions and packages visible in the
+// selected frame or, if it follows a selector, with the fields and methods
+// of the expression to the left of the dot.
+func (s *Session) onCompletionsRequest(request *dap.CompletionsRequest) {
+	response := &dap.CompletionsResponse{Response: *newResponse(request.Request)}
+	response.Body.Targets = []dap.CompletionItem{}
+
+	lines := strings.Split(request.Arguments.Text, "\n")
+	line := request.Arguments.Line
+	if line == 0 {
+		line = 1
+	}
+	if line < 1 || line > len(lines) {
+		s.send(response)
+		return
+	}
+	text := lines[line-1]
+	text = text[:utf16OffsetToByte(text, request.Arguments.Column-1)]
+
+	wordStart := len(text)
+	for wordStart > 0 {
+		r, sz := utf8.DecodeLastRuneInString(text[:wordStart])
+		if !isIdentRune(r) {
+			break
+		}
+		wordStart -= sz
+	}
+	word := text[wordStart:]
+
+	var targets []dap.CompletionItem
+	if dlvcmd, isDlv := strings.CutPrefix(strings.TrimLeftFunc(text, unicode.IsSpace), "dlv "); isDlv {
+		wordStart = len(text) - len(dlvcmd)
+		if i := strings.LastIndexFunc(dlvcmd, unicode.IsSpace); i >= 0 {
+			wordStart += i + 1
+		}
+		word = text[wordStart:]
+		for _, name := range s.dlvCompletions(dlvcmd) {
+			targets = append(targets, dap.CompletionItem{Label: name, Type: "keyword"})
+		}
+	} else {
+		var operand string
+		if strings.HasSuffix(text[:wordStart], ".") {
+			operand = selectorOperand(text[:wordStart-1])
+			if operand == "" {
+				s.send(response)
+				return
+			}
+		}
+		goid, frame := -1, 0
+		if sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId); ok {
+			goid = sf.goroutineID
+			frame = sf.frameIndex
+		}
+		completions, err := s.debugger.Completions(int64(goid), frame, 0, operand, word)
+		if err != nil {
+			s.config.log.Debugf("completions of %q: %v", text, err)
+		}
+		for _, c := range completions {
+			targets = append(targets, dap.CompletionItem{Label: c.Name, Type: completionItemType(c.Kind)})
+		}
+	}
+
+	start := byteOffsetToUTF16(text, wordStart) + 1
+	length := byteOffsetToUTF16(word, len(word))
+	for i := range targets {
+		targets[i].Start = start
+		targets[i].Length = length
+	}
+	if targets != nil {
+		response.Body.Targets = targets
+	}
+	s.send(response)
+}
+
+func completionItemType(kind proc.CompletionKind) dap.CompletionItemType {
+	switch kind {
+	case proc.CompletionField:
+		return "field"
+	case proc.CompletionMethod:
+		return "method"
+	case proc.CompletionFunction:
+		return "function"
+	case proc.CompletionType:
+		return "class"
+	case proc.CompletionPackage:
+		return "module"
+	default:
+		return "variable"
+	}
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// selectorOperand returns the operand of the selector expression that
+// ends at the end of text, for example "a.b[i].c" for "x + a.b[i].c".
+// Returns the empty string if text does not end with an operand.
+func selectorOperand(text string) string {
+	start := len(text)
+	depth := 0
+	for start > 0 {
+		r, sz := utf8.DecodeLastRuneInString(text[:start])
+		switch {
+		case r == ')' || r == ']':
+			depth++
+		case r == '(' || r == '[':
+			if depth == 0 {
+				return text[start:]
+			}
+			depth--
+		case depth > 0 || isIdentRune(r) || r == '.':
+			// part of the operand
+		default:
+			return text[start:]
+		}
+		start -= sz
+	}
+	if depth != 0 {
+		return ""
+	}
+	return text
+}
+
+// utf16OffsetToByte converts an offset in UTF-16 code units, as used by
+// DAP columns, into a byte offset in s.
+func utf16OffsetToByte(s string, off int) int {
+	n := 0
+	for i, r := range s {
+		if n >= off {
+			return i
+		}
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	return len(s)
+}
+
+// byteOffsetToUTF16 converts a byte offset in s into an offset in UTF-16
+// code units.
+func byteOffsetToUTF16(s string, off int) int {
+	n := 0
+	for _, r := range s[:off] {
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	return n
+}
+
 // onSetExpression sends a not-yet-implemented error response.
 // Capability 'supportsSetExpression' is not set 'initialize' response.
 func (s *Session) onSetExpressionRequest(request *dap.SetExpressionRequest) {
 	s.sendNotYetImplementedErrorResponse(request.Request)
 }
 
-// onLoadedSourcesRequest sends a not-yet-implemented error response.
-// Capability 'supportsLoadedSourcesRequest' is not set 'initialize' response.
+// onLoadedSourcesRequest handles 'loadedSources' requests.
+// Capability 'supportsLoadedSourcesRequest' is set in 'initialize' response.
 func (s *Session) onLoadedSourcesRequest(request *dap.LoadedSourcesRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	response := &dap.LoadedSourcesResponse{Response: *newResponse(request.Request)}
+	s.knownSourcesMu.Lock()
+	s.knownSources = make(map[string]struct{})
+	response.Body.Sources = s.newLoadedSources()
+	s.knownSourcesMu.Unlock()
+	s.send(response)
+}
+
+// sendLoadedSourceEvents notifies the client of source files that were
+// added since the last loadedSources response or event, for example by
+// a plugin or a newly attached child process.
+func (s *Session) sendLoadedSourceEvents() {
+	s.knownSourcesMu.Lock()
+	if s.knownSources == nil {
+		// The client is not interested in loaded sources.
+		s.knownSourcesMu.Unlock()
+		return
+	}
+	sources := s.newLoadedSources()
+	s.knownSourcesMu.Unlock()
+	for _, source := range sources {
+		s.send(&dap.LoadedSourceEvent{
+			Event: *newEvent("loadedSource"),
+			Body:  dap.LoadedSourceEventBody{Reason: "new", Source: source},
+		})
+	}
 }
 
-// onReadMemoryRequest sends a not-yet-implemented error response.
-// Capability 'supportsReadMemoryRequest' is not set 'initialize' response.
+// newLoadedSources returns the source files of all targets that are not
+// in s.knownSources and adds them to it. Sources are grouped by module and
+// package: the origin of each source names the package it belongs to and
+// its module and sources are sorted by origin.
+// knownSourcesMu must be held when calling newLoadedSources.
+func (s *Session) newLoadedSources() []dap.Source {
+	type loadedSource struct {
+		path, pkg, module string
+	}
+	var files []loadedSource
+
+	tgrp, unlock := s.debugger.LockTargetGroup()
+	t := proc.ValidTargets{Group: tgrp}
+	for t.Next() {
+		var pkgs map[string]*sourcePackage
+		for _, path := range t.BinInfo().Sources {
+			if _, ok := s.knownSources[path]; ok {
+				continue
+			}
+			s.knownSources[path] = struct{}{}
+			if pkgs == nil {
+				pkgs = sourcePackages(t.BinInfo())
+			}
+			file := loadedSource{path: path}
+			if pkg := pkgs[path]; pkg != nil {
+				file.pkg, file.module = pkg.importPath, pkg.module
+			}
+			files = append(files, file)
+		}
+	}
+	unlock()
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].module != files[j].module {
+			return files[i].module < files[j].module
+		}
+		if files[i].pkg != files[j].pkg {
+			return files[i].pkg < files[j].pkg
+		}
+		return files[i].path < files[j].path
+	})
+
+	sources := make([]dap.Source, len(files))
+	for i, file := range files {
+		path := s.toClientPath(file.path)
+		sources[i] = dap.Source{Name: filepath.Base(path), Path: path}
+		switch {
+		case file.pkg != "" && file.module != "":
+			sources[i].Origin = fmt.Sprintf("%s (%s)", file.pkg, file.module)
+		case file.pkg != "":
+			sources[i].Origin = file.pkg
+		}
+	}
+	return sources
+}
+
+// sourcePackage describes the package a source file belongs to.
+type sourcePackage struct {
+	importPath string
+	// module is the path and version of the module containing the package,
+	// "std" for the standard library or empty if unknown.
+	module string
+}
+
+// sourcePackages maps each source file of the executable described by bi
+// to its package.
+func sourcePackages(bi *proc.BinaryInfo) map[string]*sourcePackage {
+	var modules []*debug.Module
+	if info, err := buildinfo.ReadFile(bi.Images[0].Path); err == nil {
+		modules = append(modules, &info.Main)
+		modules = append(modules, info.Deps...)
+	}
+	pbis := bi.ListPackagesBuildInfo(true)
+
+	// Packages of the standard library are compiled from GOROOT/src, which
+	// is where the runtime package is.
+	stdlibDir := ""
+	for _, pbi := range pbis {
+		if pbi.ImportPath == "runtime" {
+			stdlibDir = filepath.Dir(pbi.DirectoryPath) + string(filepath.Separator)
+		}
+	}
+
+	moduleOf := func(pbi *proc.PackageBuildInfo) string {
+		if stdlibDir != "" && strings.HasPrefix(pbi.DirectoryPath, stdlibDir) {
+			return "std"
+		}
+		importPath := pbi.ImportPath
+		var found *debug.Module
+		if importPath == "main" && len(modules) > 0 {
+			found = modules[0]
+		}
+		for _, mod := range modules {
+			if mod.Path == "" || (importPath != mod.Path && !strings.HasPrefix(importPath, mod.Path+"/")) {
+				continue
+			}
+			if found == nil || len(mod.Path) > len(found.Path) {
+				found = mod
+			}
+		}
+		switch {
+		case found == nil:
+			return ""
+		case found.Replace != nil && found.Replace.Version != "":
+			return found.Replace.Path + "@" + found.Replace.Version
+		case found.Version != "" && found.Version != "(devel)":
+			return found.Path + "@" + found.Version
+		default:
+			return found.Path
+		}
+	}
+
+	r := make(map[string]*sourcePackage)
+	for _, pbi := range pbis {
+		pkg := &sourcePackage{importPath: pbi.ImportPath, module: moduleOf(pbi)}
+		for file := range pbi.Files {
+			r[file] = pkg
+		}
+	}
+	return r
+}
+
+// maxReadMemoryCount is the maximum number of bytes returned by a single
+// readMemory request. Clients are expected to request more if needed.
+const maxReadMemoryCount = 1 << 16
+
+// parseMemoryReference returns the address corresponding to a memory
+// reference and offset. Memory references are the hexadecimal addresses
+// produced by getMemoryReferenceIfSupported.
+func parseMemoryReference(memoryReference string, offset int) (uint64, error) {
+	addr, err := strconv.ParseUint(memoryReference, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory reference %q", memoryReference)
+	}
+	return addr + uint64(offset), nil
+}
+
+// onReadMemoryRequest handles 'readMemory' requests.
+// Capability 'supportsReadMemoryRequest' is set in 'initialize' response.
 func (s *Session) onReadMemoryRequest(request *dap.ReadMemoryRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	addr, err := parseMemoryReference(request.Arguments.MemoryReference, request.Arguments.Offset)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToReadMemory, "Unable to read memory", err.Error())
+		return
+	}
+	count := request.Arguments.Count
+	if count < 0 {
+		s.sendErrorResponse(request.Request, UnableToReadMemory, "Unable to read memory", fmt.Sprintf("invalid count %d", count))
+		return
+	}
+	if count > maxReadMemoryCount {
+		count = maxReadMemoryCount
+	}
+
+	data, err := s.debugger.ExamineMemory(addr, count)
+	if err != nil {
+		// Find the longest readable prefix of the requested range, the rest
+		// is reported as unreadable.
+		data = nil
+		lo, hi := 0, count
+		for lo < hi {
+			n := (lo + hi + 1) / 2
+			if d, err := s.debugger.ExamineMemory(addr, n); err == nil {
+				data, lo = d, n
+			} else {
+				hi = n - 1
+			}
+		}
+	}
+
+	response := &dap.ReadMemoryResponse{Response: *newResponse(request.Request)}
+	response.Body.Address = fmt.Sprintf("%#x", addr)
+	response.Body.Data = base64.StdEncoding.EncodeToString(data)
+	response.Body.UnreadableBytes = count - len(data)
+	s.send(response)
+}
+
+// onWriteMemoryRequest handles 'writeMemory' requests.
+// Capability 'supportsWriteMemoryRequest' is set in 'initialize' response.
+func (s *Session) onWriteMemoryRequest(request *dap.WriteMemoryRequest) {
+	addr, err := parseMemoryReference(request.Arguments.MemoryReference, request.Arguments.Offset)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToWriteMemory, "Unable to write memory", err.Error())
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(request.Arguments.Data)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToWriteMemory, "Unable to write memory", fmt.Sprintf("invalid data: %v", err))
+		return
+	}
+
+	n, err := s.debugger.WriteMemory(addr, data)
+	if err != nil && (n == 0 || !request.Arguments.AllowPartial) {
+		s.sendErrorResponse(request.Request, UnableToWriteMemory, "Unable to write memory", err.Error())
+		return
+	}
+
+	response := &dap.WriteMemoryResponse{Response: *newResponse(request.Request)}
+	if request.Arguments.AllowPartial {
+		response.Body.BytesWritten = n
+	}
+	s.send(response)
+
+	// Memory views and variables showing the modified range are now stale.
+	if s.clientCapabilities.supportsMemoryEvent {
+		s.send(&dap.MemoryEvent{
+			Event: *newEvent("memory"),
+			Body: dap.MemoryEventBody{
+				MemoryReference: fmt.Sprintf("%#x", addr),
+				Count:           n,
+			},
+		})
+	}
+	if s.clientCapabilities.supportsInvalidatedEvent {
+		s.send(&dap.InvalidatedEvent{
+			Event: *newEvent("invalidated"),
+			Body:  dap.InvalidatedEventBody{Areas: []dap.InvalidatedAreas{"variables"}},
+		})
+	}
+}
+
+// onGotoTargetsRequest handles 'gotoTargets' requests.
+// Capability 'supportsGotoTargetsRequest' is set in 'initialize' response.
+// A line has at most one target: its first statement, provided that the
+// program counter of the current goroutine can be moved there. Targets can
+// only be used to move the goroutine they were computed for.
+func (s *Session) onGotoTargetsRequest(request *dap.GotoTargetsRequest) {
+	path := s.toServerPath(request.Arguments.Source.Path)
+	line := request.Arguments.Line
+	state, err := s.debugger.State( /*nowait*/ true)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToGetGotoTargets, "Unable to get goto targets", err.Error())
+		return
+	}
+	goid := stoppedGoroutineID(state)
+	pc, err := s.debugger.JumpDestination(goid, path, line)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToGetGotoTargets, "Unable to get goto targets", err.Error())
+		return
+	}
+	s.gotoTargets = append(s.gotoTargets, gotoTarget{goid: goid, file: path, line: line})
+	response := &dap.GotoTargetsResponse{Response: *newResponse(request.Request)}
+	response.Body.Targets = []dap.GotoTarget{{
+		Id:                          len(s.gotoTargets),
+		Label:                       fmt.Sprintf("%s:%d", filepath.Base(path), line),
+		Line:                        line,
+		InstructionPointerReference: fmt.Sprintf("%#x", pc),
+	}}
+	s.send(response)
+}
+
+// onGotoRequest handles 'goto' requests.
+// Capability 'supportsGotoTargetsRequest' is set in 'initialize' response.
+// The program counter of the goroutine is moved without resuming the
+// program, a stopped event with reason 'goto' is sent afterwards.
+func (s *Session) onGotoRequest(request *dap.GotoRequest) {
+	id := request.Arguments.TargetId
+	if id <= 0 || id > len(s.gotoTargets) {
+		s.sendErrorResponse(request.Request, UnableToGoto, "Unable to goto", fmt.Sprintf("unknown target %d", id))
+		return
+	}
+	target := s.gotoTargets[id-1]
+	goid := int64(request.Arguments.ThreadId)
+	if goid != target.goid {
+		s.sendErrorResponse(request.Request, UnableToGoto, "Unable to goto", fmt.Sprintf("target %d can only be used with goroutine %d", id, target.goid))
+		return
+	}
+	if _, err := s.debugger.SetPCToLine(goid, target.file, target.line); err != nil {
+		s.sendErrorResponse(request.Request, UnableToGoto, "Unable to goto", err.Error())
+		return
+	}
+	s.send(&dap.GotoResponse{Response: *newResponse(request.Request)})
+
+	s.resetHandlesForStoppedEvent()
+	stopped := &dap.StoppedEvent{Event: *newEvent("stopped")}
+	stopped.Body.Reason = "goto"
+	stopped.Body.ThreadId = int(goid)
+	stopped.Body.AllThreadsStopped = true
+	s.send(stopped)
+}
+
+// onRestartFrameRequest handles 'restartFrame' requests.
+// Capability 'supportsRestartFrame' is set in 'initialize' response.
+// On recordings execution is rewound to the call that created the frame.
+// On live targets the goroutine is moved back to the entry point of the
+// function, if some of the code of the frame has already been executed the
+// request fails and the client has to send it again to confirm.
+// A stopped event with reason 'restart' is sent once the frame has been
+// restarted.
+func (s *Session) onRestartFrameRequest(request *dap.RestartFrameRequest, allowNextStateChange *syncflag) {
+	id := request.Arguments.FrameId
+	sf, ok := s.stackFrameHandles.get(id)
+	if !ok {
+		s.sendErrorResponse(request.Request, UnableToRestartFrame, "Unable to restart frame", fmt.Sprintf("unknown frame id %d", id))
+		allowNextStateChange.raise()
+		return
+	}
+	cmd := &api.DebuggerCommand{Name: api.RestartFrame, GoroutineID: int64(sf.goroutineID), Frame: sf.frameIndex}
+
+	if recorded, _ := s.debugger.Recorded(); recorded {
+		s.nextCommand = cmd
+		s.sendStepResponse(sf.goroutineID, &dap.RestartFrameResponse{Response: *newResponse(request.Request)})
+		s.runUntilStopAndNotify(api.RestartFrame, allowNextStateChange)
+		return
+	}
+
+	cmd.Force = s.restartFrameConfirm == id
+	s.changeStateMu.Lock()
+	_, err := s.debugger.Command(cmd, nil, s.conn.closedChan)
+	s.changeStateMu.Unlock()
+	allowNextStateChange.raise()
+	if errors.Is(err, proc.ErrRestartFrameSideEffects) {
+		s.restartFrameConfirm = id
+		s.sendErrorResponse(request.Request, UnableToRestartFrame, "Unable to restart frame", err.Error()+", restart the frame again to execute it a second time")
+		return
+	}
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToRestartFrame, "Unable to restart frame", err.Error())
+		return
+	}
+	s.send(&dap.RestartFrameResponse{Response: *newResponse(request.Request)})
+
+	s.resetHandlesForStoppedEvent()
+	stopped := &dap.StoppedEvent{Event: *newEvent("stopped")}
+	stopped.Body.Reason = "restart"
+	stopped.Body.ThreadId = sf.goroutineID
+	stopped.Body.AllThreadsStopped = true
+	s.send(stopped)
+}
+
+// onBreakpointLocationsRequest handles 'breakpointLocations' requests.
+// Capability 'supportsBreakpointLocationsRequest' is set in 'initialize' response.
+// The locations are the positions of the statements in the requested range,
+// lines containing function literals have a location for each function.
+func (s *Session) onBreakpointLocationsRequest(request *dap.BreakpointLocationsRequest) {
+	if request.Arguments == nil || request.Arguments.Source.Path == "" {
+		s.sendErrorResponse(request.Request, UnableToGetBreakpointLocations, "Unable to get breakpoint locations", "empty file path")
+		return
+	}
+	args := request.Arguments
+	endLine := args.EndLine
+	if endLine < args.Line {
+		endLine = args.Line
+	}
+
+	response := &dap.BreakpointLocationsResponse{Response: *newResponse(request.Request)}
+	response.Body.Breakpoints = []dap.BreakpointLocation{}
+	for _, pos := range s.breakpointLocations(s.toServerPath(args.Source.Path), args.Line, endLine) {
+		if pos.Column != 0 && ((pos.Line == args.Line && pos.Column < args.Column) || (pos.Line == endLine && args.EndColumn > 0 && pos.Column > args.EndColumn)) {
+			continue
+		}
+		loc := dap.BreakpointLocation{Line: pos.Line, Column: pos.Column}
+		if n := len(response.Body.Breakpoints); n > 0 && response.Body.Breakpoints[n-1] == loc {
+			continue
+		}
+		response.Body.Breakpoints = append(response.Body.Breakpoints, loc)
+	}
+	s.send(response)
+}
+
+// onStepInTargetsRequest handles 'stepInTargets' requests.
+// Capability 'supportsStepInTargetsRequest' is set in 'initialize' response.
+// The targets are the function calls on the current line of the goroutine
+// that have not been executed yet, only the topmost frame has targets.
+func (s *Session) onStepInTargetsRequest(request *dap.StepInTargetsRequest) {
+	sf, ok := s.stackFrameHandles.get(request.Arguments.FrameId)
+	if !ok {
+		s.sendErrorResponse(request.Request, UnableToGetStepInTargets, "Unable to get step in targets", fmt.Sprintf("unknown frame id %d", request.Arguments.FrameId))
+		return
+	}
+	response := &dap.StepInTargetsResponse{Response: *newResponse(request.Request)}
+	response.Body.Targets = []dap.StepInTarget{}
+	if sf.frameIndex != 0 {
+		s.send(response)
+		return
+	}
+	goid := int64(sf.goroutineID)
+	targets, err := s.debugger.StepInTargets(goid)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToGetStepInTargets, "Unable to get step in targets", err.Error())
+		return
+	}
+	for _, target := range targets {
+		s.stepInTargets = append(s.stepInTargets, stepInTarget{goroutineID: goid, pc: target.PC})
+		label := fmt.Sprintf("dynamic call at %#x", target.PC)
+		if target.Function != nil {
+			label = target.Function.Name()
+		}
+		response.Body.Targets = append(response.Body.Targets, dap.StepInTarget{Id: len(s.stepInTargets), Label: label})
+	}
+	s.send(response)
 }
 
 var invalidInstruction = dap.DisassembledInstruction{
@@ -3254,13 +4624,20 @@ func (s *Session) onDisassembleRequest(request *dap.DisassembleRequest) {
 
 	// Disassemble the instructions
 	procInstructions, err := s.debugger.Disassemble(-1, start, end)
-	if err != nil {
+	canceled := errors.Is(err, proc.ErrCanceled)
+	if err != nil && !canceled {
 		s.sendErrorResponse(request.Request, UnableToDisassemble, "Unable to disassemble", err.Error())
 		return
 	}
 
-	// Find the section of instructions that were requested.
+	// Find the section of instructions that were requested. If the request
+	// was cancelled the instructions that were not disassembled are returned
+	// as invalid instructions.
 	procInstructions, offset, err := findInstructions(procInstructions, addr, request.Arguments.InstructionOffset, request.Arguments.InstructionCount)
+	if err != nil && canceled {
+		s.sendErrorResponse(request.Request, RequestCancelled, "cancelled", "disassembly was cancelled before reaching the requested address")
+		return
+	}
 	if err != nil {
 		s.sendErrorResponse(request.Request, UnableToDisassemble, "Unable to disassemble", err.Error())
 		return
@@ -3420,10 +4797,27 @@ func checkOutOfAddressSpace(pc uint64, bi *proc.BinaryInfo) (bool, uint64) {
 	return false, pc
 }
 
-// onCancelRequest sends a not-yet-implemented error response.
-// Capability 'supportsCancelRequest' is not set 'initialize' response.
+// onCancelRequest handles 'cancel' requests. Unlike other requests these
+// are handled as soon as they are read, while the request to cancel could
+// be waiting to be handled or be in progress.
+// A request that is waiting fails with a "cancelled" error when its turn
+// comes. If the request is in progress the variable loading, goroutine
+// listing, stack unwinding or disassembly it is doing is interrupted and
+// the partial result collected until then is returned.
+// Cancelling progress is not supported.
+// Capability 'supportsCancelRequest' is set in 'initialize' response.
 func (s *Session) onCancelRequest(request *dap.CancelRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	if request.Arguments != nil && request.Arguments.RequestId != 0 {
+		seq := request.Arguments.RequestId
+		s.cancelMu.Lock()
+		if _, queued := s.queuedSeqs[seq]; queued {
+			s.queuedSeqs[seq] = true
+		} else if seq == s.runningSeq && s.cancelRunning != nil {
+			s.cancelRunning()
+		}
+		s.cancelMu.Unlock()
+	}
+	s.send(&dap.CancelResponse{Response: *newResponse(request.Request)})
 }
 
 // onExceptionInfoRequest handles 'exceptionInfo' requests.
@@ -3447,7 +4841,7 @@ func (s *Session) onExceptionInfoRequest(request *dap.ExceptionInfoRequest) {
 	}
 	// Check if this goroutine ID is stopped at a breakpoint.
 	includeStackTrace := true
-	if bpState != nil && bpState.Breakpoint != nil && bpState.Breakpoint.Logical != nil && (bpState.Breakpoint.Logical.Name == proc.FatalThrow || bpState.Breakpoint.Logical.Name == proc.UnrecoveredPanic) {
+	if bpState != nil && bpState.Breakpoint != nil && bpState.Breakpoint.Logical != nil && (bpState.Breakpoint.Logical.Name == proc.FatalThrow || bpState.Breakpoint.Logical.Name == proc.UnrecoveredPanic || bpState.Breakpoint.Logical.Name == panicExceptionBpName) {
 		switch bpState.Breakpoint.Logical.Name {
 		case proc.FatalThrow:
 			body.ExceptionId = "fatal error"
@@ -3467,6 +4861,12 @@ func (s *Session) onExceptionInfoRequest(request *dap.ExceptionInfoRequest) {
 			if err != nil {
 				body.Description = fmt.Sprintf("Error getting panic message: %s", err.Error())
 			}
+		case panicExceptionBpName:
+			body.ExceptionId = "panic"
+			body.Description, err = s.panicValue(goroutineID)
+			if err != nil {
+				body.Description = fmt.Sprintf("Error getting panic value: %s", err.Error())
+			}
 		}
 	} else {
 		// If this thread is not stopped on a breakpoint, then a runtime error must have occurred.
@@ -3547,6 +4947,22 @@ func (s *Session) panicReason(goroutineID int64) (string, error) {
 	return s.getExprString("(*msgs).arg.(data)", goroutineID, 0)
 }
 
+// panicValue returns the value passed to panic by a goroutine stopped at
+// the entry point of runtime.gopanic.
+func (s *Session) panicValue(goroutineID int64) (string, error) {
+	v, err := s.debugger.EvalVariableInScope(goroutineID, 0, 0, "e", DefaultLoadConfig)
+	if err != nil {
+		return "", err
+	}
+	if v.Unreadable != nil {
+		return "", v.Unreadable
+	}
+	if v.Kind == reflect.Interface && len(v.Children) > 0 {
+		v = &v.Children[0]
+	}
+	return api.ConvertVar(v).SinglelineStringWithShortTypes(), nil
+}
+
 func (s *Session) getExprString(expr string, goroutineID int64, frame int) (string, error) {
 	exprVar, err := s.debugger.EvalVariableInScope(goroutineID, frame, 0, expr, DefaultLoadConfig)
 	if err != nil {
@@ -3646,6 +5062,9 @@ func (s *Session) resetHandlesForStoppedEvent() {
 	s.stackFrameHandles.reset()
 	s.variableHandles.reset()
 	s.exceptionErr = nil
+	s.gotoTargets = nil
+	s.stepInTargets = nil
+	s.restartFrameConfirm = 0
 }
 
 func processExited(state *api.DebuggerState, err error) bool {
@@ -3697,10 +5116,16 @@ func (s *Session) resumeOnce(command string, allowNextStateChange *syncflag) (bo
 	// stopped. If this happened, do not resume execution of
 	// the program.
 	if s.checkHaltRequested() {
+		s.nextCommand = nil
 		state, err := s.debugger.State(false)
 		return false, state, err
 	}
-	state, err := s.debugger.Command(&api.DebuggerCommand{Name: command}, asyncSetupDone, s.conn.closedChan)
+	cmd := &api.DebuggerCommand{Name: command}
+	if s.nextCommand != nil && s.nextCommand.Name == command {
+		cmd = s.nextCommand
+	}
+	s.nextCommand = nil
+	state, err := s.debugger.Command(cmd, asyncSetupDone, s.conn.closedChan)
 	return true, state, err
 }
 
@@ -3748,15 +5173,27 @@ func (s *Session) runUntilStopAndNotify(command string, allowNextStateChange *sy
 		switch stopReason {
 		case proc.StopNextFinished:
 			stopped.Body.Reason = "step"
+			if command == api.RestartFrame {
+				stopped.Body.Reason = "restart"
+			}
 		case proc.StopManual: // triggered by halt
 			stopped.Body.Reason = "pause"
 		case proc.StopUnknown: // can happen while terminating
 			stopped.Body.Reason = "unknown"
 		case proc.StopWatchpoint:
 			stopped.Body.Reason = "data breakpoint"
+			if _, bp := s.stoppedOnBreakpointGoroutineID(state); bp != nil && bp.ID > 0 {
+				stopped.Body.HitBreakpointIds = []int{bp.ID}
+			}
 		default:
 			stopped.Body.Reason = "breakpoint"
 			goid, bp := s.stoppedOnBreakpointGoroutineID(state)
+			if len(state.WatchOutOfScope) > 0 && len(s.stoppedGs(state)) == 0 {
+				// The only reason for this stop is that a data breakpoint
+				// went out of scope.
+				stopped.Body.Reason = "data breakpoint"
+				goid = stoppedGoroutineID(state)
+			}
 			stopped.Body.ThreadId = int(goid)
 			if bp != nil {
 				switch bp.Name {
@@ -3768,6 +5205,10 @@ func (s *Session) runUntilStopAndNotify(command string, allowNextStateChange *sy
 					stopped.Body.Reason = "exception"
 					stopped.Body.Description = "panic"
 					stopped.Body.Text, _ = s.panicReason(int64(stopped.Body.ThreadId))
+				case panicExceptionBpName:
+					stopped.Body.Reason = "exception"
+					stopped.Body.Description = "panic"
+					stopped.Body.Text, _ = s.panicValue(int64(stopped.Body.ThreadId))
 				}
 				if strings.HasPrefix(bp.Name, functionBpPrefix) {
 					stopped.Body.Reason = "function breakpoint"
@@ -3776,7 +5217,8 @@ func (s *Session) runUntilStopAndNotify(command string, allowNextStateChange *sy
 					stopped.Body.Reason = "instruction breakpoint"
 				}
 				// Filter out internal delve breakpoints (panic, fatal, hardcoded, etc.)
-				if bp.ID > 0 {
+				// and the breakpoints set by exception filters.
+				if bp.ID > 0 && !strings.HasPrefix(bp.Name, exceptionBpPrefix) {
 					stopped.Body.HitBreakpointIds = []int{bp.ID}
 				} else {
 					stopped.Body.HitBreakpointIds = []int{}
@@ -3815,6 +5257,26 @@ func (s *Session) runUntilStopAndNotify(command string, allowNextStateChange *sy
 		}
 	}
 
+	s.sendLoadedSourceEvents()
+
+	// Stack watchpoints are cleared when the variable they watch goes out
+	// of scope, let the client know that they are gone.
+	if state != nil {
+		for _, bp := range state.WatchOutOfScope {
+			s.send(&dap.BreakpointEvent{
+				Event: *newEvent("breakpoint"),
+				Body: dap.BreakpointEventBody{
+					Reason: "removed",
+					Breakpoint: dap.Breakpoint{
+						Id:       bp.ID,
+						Verified: false,
+						Message:  fmt.Sprintf("%s went out of scope and was cleared", bp.WatchExpr),
+					},
+				},
+			})
+		}
+	}
+
 	// NOTE: If we happen to be responding to another request with an is-running
 	// error while this one completes, it is possible that the error response
 	// will arrive after this stopped event.
@@ -3861,8 +5323,9 @@ func (s *Session) resumeOnceAndCheckStop(command string, allowNextStateChange *s
 
 	switch s.debugger.StopReason() {
 	case proc.StopBreakpoint, proc.StopManual:
-		// Make sure a real manual stop was requested or a real breakpoint was hit.
-		if len(gsOnBp) > 0 || s.checkHaltRequested() {
+		// Make sure a real manual stop was requested, a real breakpoint was hit
+		// or a data breakpoint went out of scope.
+		if len(gsOnBp) > 0 || s.checkHaltRequested() || len(state.WatchOutOfScope) > 0 {
 			s.setRunningCmd(false)
 		}
 	default:
@@ -3,6 +3,8 @@ package dap
 import (
 	"bufio"
 	"bytes"
+	"encoding/base64"
+	"encoding/binary"
 	"flag"
 	"fmt"
 	"io"
@@ -3005,6 +3007,155 @@ func TestSetInstructionBreakpoint(t *testing.T) {
 	})
 }
 
+// skipIfNoWatchpoints skips the test on platforms where hardware
+// watchpoints are not implemented.
+func skipIfNoWatchpoints(t *testing.T) {
+	switch runtime.GOARCH {
+	case "386", "ppc64le", "riscv64", "loong64":
+		t.Skip("watchpoints not implemented on " + runtime.GOARCH)
+	}
+	switch runtime.GOOS {
+	case "freebsd":
+		t.Skip("watchpoints not implemented on " + runtime.GOOS)
+	case "windows":
+		t.Skip("see https://github.com/go-delve/delve/issues/2768")
+	}
+}
+
+func TestSetDataBreakpoints(t *testing.T) {
+	skipIfNoWatchpoints(t)
+	runTest(t, "databpeasy", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{15},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.main", 15)
+
+					// Expressions that do not have an address can not be watched.
+					client.DataBreakpointInfoRequest(0, "globalvar1 + 1", 1000)
+					info := client.ExpectDataBreakpointInfoResponse(t)
+					if info.Body.DataId != nil || info.Body.Description == "" {
+						t.Errorf("got %#v, want DataId=nil and a description", info)
+					}
+
+					client.DataBreakpointInfoRequest(0, "globalvar1", 1000)
+					info = client.ExpectDataBreakpointInfoResponse(t)
+					dataID, ok := info.Body.DataId.(string)
+					if !ok || info.Body.Description != "globalvar1" {
+						t.Fatalf("got %#v, want DataId=<string> Description=globalvar1", info)
+					}
+					wantAccessTypes := []dap.DataBreakpointAccessType{"read", "write", "readWrite"}
+					if !reflect.DeepEqual(info.Body.AccessTypes, wantAccessTypes) {
+						t.Errorf("got AccessTypes=%v, want %v", info.Body.AccessTypes, wantAccessTypes)
+					}
+
+					client.SetDataBreakpointsRequest([]dap.DataBreakpoint{{DataId: dataID, AccessType: "write"}, {DataId: "invalid"}})
+					bps := client.ExpectSetDataBreakpointsResponse(t).Body.Breakpoints
+					if len(bps) != 2 || !bps[0].Verified || bps[0].Id <= 0 || bps[1].Verified {
+						t.Fatalf("got %#v, want one verified and one unverified breakpoint", bps)
+					}
+					watchID := bps[0].Id
+
+					client.ContinueRequest(1)
+					client.ExpectContinueResponse(t)
+					se := client.ExpectStoppedEvent(t)
+					if se.Body.Reason != "data breakpoint" || !reflect.DeepEqual(se.Body.HitBreakpointIds, []int{watchID}) {
+						t.Errorf("got %#v, want Reason=\"data breakpoint\" HitBreakpointIds=[%d]", se, watchID)
+					}
+					checkStop(t, client, 1, "main.main", []int{18, 19})
+
+					// Sending the same data breakpoint again keeps it.
+					client.SetDataBreakpointsRequest([]dap.DataBreakpoint{{DataId: dataID, AccessType: "write"}})
+					bps = client.ExpectSetDataBreakpointsResponse(t).Body.Breakpoints
+					if len(bps) != 1 || !bps[0].Verified || bps[0].Id != watchID {
+						t.Errorf("got %#v, want Id=%d Verified=true", bps, watchID)
+					}
+
+					// Clear all data breakpoints.
+					client.SetDataBreakpointsRequest([]dap.DataBreakpoint{})
+					if bps := client.ExpectSetDataBreakpointsResponse(t).Body.Breakpoints; len(bps) != 0 {
+						t.Errorf("got %#v, want no breakpoints", bps)
+					}
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+func TestSetDataBreakpointsStack(t *testing.T) {
+	skipIfNoWatchpoints(t)
+	runTest(t, "databpstack", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{11},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.f", 11)
+
+					// Stack allocated variables can only be watched for writes.
+					client.DataBreakpointInfoRequest(0, "w", 1000)
+					info := client.ExpectDataBreakpointInfoResponse(t)
+					dataID, ok := info.Body.DataId.(string)
+					if !ok || !reflect.DeepEqual(info.Body.AccessTypes, []dap.DataBreakpointAccessType{"write"}) {
+						t.Fatalf("got %#v, want DataId=<string> AccessTypes=[write]", info)
+					}
+
+					// The dataId can also be obtained through a variables reference.
+					client.ScopesRequest(1000)
+					client.ExpectScopesResponse(t)
+					client.DataBreakpointInfoRequest(localsScope, "w", 0)
+					if got := client.ExpectDataBreakpointInfoResponse(t); got.Body.DataId == nil || got.Body.Description != "w" {
+						t.Errorf("got %#v, want DataId=<string> Description=w", got)
+					}
+
+					client.SetDataBreakpointsRequest([]dap.DataBreakpoint{{DataId: dataID, AccessType: "read"}})
+					bps := client.ExpectSetDataBreakpointsResponse(t).Body.Breakpoints
+					if len(bps) != 1 || bps[0].Verified {
+						t.Errorf("got %#v, want unverified breakpoint", bps)
+					}
+
+					client.SetDataBreakpointsRequest([]dap.DataBreakpoint{{DataId: dataID}})
+					bps = client.ExpectSetDataBreakpointsResponse(t).Body.Breakpoints
+					if len(bps) != 1 || !bps[0].Verified {
+						t.Fatalf("got %#v, want verified breakpoint", bps)
+					}
+					watchID := bps[0].Id
+
+					client.ContinueRequest(1)
+					client.ExpectContinueResponse(t)
+					se := client.ExpectStoppedEvent(t)
+					if se.Body.Reason != "data breakpoint" {
+						t.Errorf("got %#v, want Reason=\"data breakpoint\"", se)
+					}
+					checkStop(t, client, 1, "main.g", []int{16, 17})
+
+					// The watchpoint is removed when w goes out of scope.
+					client.ContinueRequest(1)
+					client.ExpectContinueResponse(t)
+					be := client.ExpectBreakpointEvent(t)
+					if be.Body.Reason != "removed" || be.Body.Breakpoint.Id != watchID || be.Body.Breakpoint.Verified {
+						t.Errorf("got %#v, want Reason=\"removed\" Id=%d Verified=false", be, watchID)
+					}
+					se = client.ExpectStoppedEvent(t)
+					if se.Body.Reason != "data breakpoint" || se.Body.ThreadId != 1 {
+						t.Errorf("got %#v, want Reason=\"data breakpoint\" ThreadId=1", se)
+					}
+					checkStop(t, client, 1, "main.main", []int{23, 24})
+				},
+				disconnect: false,
+			}})
+	})
+}
+
 func TestPauseAtStop(t *testing.T) {
 	runTest(t, "loopprog", func(client *daptest.Client, fixture protest.Fixture) {
 		runDebugSessionWithBPs(t, client, "launch",
@@ -6014,36 +6165,12 @@ func TestUnsupportedCommandResponses(t *testing.T) {
 			seqCnt++
 		}
 
-		client.RestartFrameRequest()
-		expectUnsupportedCommand("restartFrame")
-
-		client.GotoRequest()
-		expectUnsupportedCommand("goto")
-
 		client.SourceRequest()
 		expectUnsupportedCommand("source")
 
 		client.TerminateThreadsRequest()
 		expectUnsupportedCommand("terminateThreads")
 
-		client.StepInTargetsRequest()
-		expectUnsupportedCommand("stepInTargets")
-
-		client.GotoTargetsRequest()
-		expectUnsupportedCommand("gotoTargets")
-
-		client.CompletionsRequest()
-		expectUnsupportedCommand("completions")
-
-		client.DataBreakpointInfoRequest()
-		expectUnsupportedCommand("dataBreakpointInfo")
-
-		client.SetDataBreakpointsRequest()
-		expectUnsupportedCommand("setDataBreakpoints")
-
-		client.BreakpointLocationsRequest()
-		expectUnsupportedCommand("breakpointLocations")
-
 		client.ModulesRequest()
 		expectUnsupportedCommand("modules")
 
@@ -6163,7 +6290,8 @@ func TestSetVariable(t *testing.T) {
 					tester.expectSetVariable(a4Ref, "[1]", "-7")
 					tester.evaluate("a4", "[2]int [1,-7]", hasChildren)
 
-					tester.failSetVariable(localsScope, "a4", "[2]int{3, 4}", "not implemented")
+					tester.expectSetVariable(localsScope, "a4", "[2]int{3, 4}")
+					tester.evaluate("a4", "[2]int [3,4]", hasChildren)
 
 					// slice of int
 					a5Ref := checkVarExact(t, locals, -1, "a5", "a5", "[]int len: 5, cap: 5, [1,2,3,4,5]", "[]int", hasChildren)
@@ -6328,6 +6456,14 @@ func TestSetVariableWithCall(t *testing.T) {
 					tester.expectSetVariable(a6Ref, "Bur", `"sentence"`)
 					tester.evaluate("a6", `main.FooBar {Baz: 8, Bur: "sentence"}`, hasChildren)
 
+					// slice literals are allocated in the target
+					checkVarExact(t, locals, -1, "a5", "a5", "[]int len: 5, cap: 5, [1,2,3,4,5]", "[]int", hasChildren)
+					tester.expectSetVariable(localsScope, "a5", "[]int{9, 8}")
+					tester.evaluate("a5", "[]int len: 2, cap: 2, [9,8]", hasChildren)
+
+					tester.expectSetVariable(localsScope, "a9", "&main.FooBar{Baz: 3}")
+					tester.evaluate("a9", `*main.FooBar {Baz: 3, Bur: ""}`, hasChildren)
+
 					// stop inside main.barfoo
 					client.ContinueRequest(1)
 					client.ExpectContinueResponse(t)
@@ -6377,6 +6513,10 @@ func TestSetVariableWithCall(t *testing.T) {
 					tester.expectSetVariable(localsScope, "str", `callstacktrace()`)
 					tester.evaluateRegex("str", `.*in main.callstacktrace at.*`, noChildren)
 
+					// assignments that need memory allocated in the target use a function call.
+					tester.expectSetVariable(localsScope, "iface", `"boxed"`)
+					tester.evaluate("iface", `interface {}(string) "boxed"`, hasChildren)
+
 					tester.failSetVariableAndStop(localsScope, "str", `callpanic()`, `callpanic panicked`)
 					checkStop(t, client, 1, "main.main", -1)
 
@@ -6415,15 +6555,6 @@ func TestOptionalNotYetImplementedResponses(t *testing.T) {
 		client.SetExpressionRequest()
 		expectNotYetImplemented("setExpression")
 
-		client.LoadedSourcesRequest()
-		expectNotYetImplemented("loadedSources")
-
-		client.ReadMemoryRequest()
-		expectNotYetImplemented("readMemory")
-
-		client.CancelRequest()
-		expectNotYetImplemented("cancel")
-
 		client.DisconnectRequest()
 		client.ExpectDisconnectResponse(t)
 	})
@@ -7223,6 +7354,164 @@ func TestParseLogPoint(t *testing.T) {
 	}
 }
 
+func TestLoadedSources(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		clientDir := filepath.Join(string(filepath.Separator), "path", "that", "does", "not", "exist")
+		if runtime.GOOS == "windows" {
+			clientDir = "C:" + clientDir
+		}
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequestWithArgs(map[string]interface{}{
+					"mode": "exec", "program": fixture.Path, "stopOnEntry": false,
+					"substitutePath": []map[string]string{{"from": clientDir, "to": filepath.Dir(fixture.Source)}},
+				})
+			},
+			// Set breakpoints
+			filepath.Join(clientDir, filepath.Base(fixture.Source)), []int{17},
+			[]onBreakpoint{{
+				execute: func() {
+					client.LoadedSourcesRequest()
+					sources := client.ExpectLoadedSourcesResponse(t).Body.Sources
+
+					var mainSource, runtimeSource *dap.Source
+					for i := range sources {
+						switch {
+						case sources[i].Path == filepath.Join(clientDir, "increment.go"):
+							mainSource = &sources[i]
+						case strings.HasSuffix(filepath.ToSlash(sources[i].Path), "/src/runtime/proc.go"):
+							runtimeSource = &sources[i]
+						case sources[i].Path == fixture.Source:
+							t.Errorf("substitutePath rules were not applied to %q", sources[i].Path)
+						}
+					}
+					if mainSource == nil || mainSource.Name != "increment.go" || !strings.HasPrefix(mainSource.Origin, "main") {
+						t.Errorf("got %#v, want Name=increment.go Origin=main...", mainSource)
+					}
+					if runtimeSource == nil || runtimeSource.Name != "proc.go" || runtimeSource.Origin != "runtime (std)" {
+						t.Errorf("got %#v, want Name=proc.go Origin=\"runtime (std)\"", runtimeSource)
+					}
+
+					// Sources are grouped by module and package.
+					seen := map[string]bool{}
+					for i := range sources {
+						if i > 0 && sources[i].Origin != sources[i-1].Origin && seen[sources[i].Origin] {
+							t.Errorf("sources of %q are not grouped together", sources[i].Origin)
+							break
+						}
+						seen[sources[i].Origin] = true
+					}
+
+					// No new sources are loaded after the next stop.
+					client.NextRequest(1)
+					client.ExpectNextResponse(t)
+					client.ExpectStoppedEvent(t)
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+// TestReadWriteMemory checks memory references of variables and that
+// readMemory and writeMemory requests access the memory they refer to.
+func TestReadWriteMemory(t *testing.T) {
+	runTest(t, "testvariables2", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequestWithArgs(dap.InitializeRequestArguments{
+			AdapterID:                "go",
+			PathFormat:               "path",
+			LinesStartAt1:            true,
+			ColumnsStartAt1:          true,
+			SupportsMemoryReferences: true,
+			SupportsInvalidatedEvent: true,
+		})
+		client.ExpectInitializeResponseAndCapabilities(t)
+		client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+		client.ExpectProcessEvent(t)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+		client.ExpectStoppedEvent(t)
+
+		client.StackTraceRequest(1, 0, 0)
+		client.ExpectStackTraceResponse(t)
+
+		memoryReference := func(expr string) string {
+			t.Helper()
+			client.EvaluateRequest(expr, 1000, "repl")
+			got := client.ExpectEvaluateResponse(t)
+			if got.Body.MemoryReference == "" {
+				t.Fatalf("%s: got %#v, want MemoryReference", expr, got)
+			}
+			return got.Body.MemoryReference
+		}
+		readMemory := func(memoryReference string, offset, count int) ([]byte, int) {
+			t.Helper()
+			client.ReadMemoryRequest(memoryReference, offset, count)
+			got := client.ExpectReadMemoryResponse(t)
+			data, err := base64.StdEncoding.DecodeString(got.Body.Data)
+			if err != nil {
+				t.Fatalf("could not decode %q: %v", got.Body.Data, err)
+			}
+			return data, got.Body.UnreadableBytes
+		}
+
+		// Pointers refer to the memory they point to.
+		i1Ref := memoryReference("i1")
+		if p1Ref := memoryReference("p1"); p1Ref != i1Ref {
+			t.Errorf("got MemoryReference=%s for p1, want %s", p1Ref, i1Ref)
+		}
+		if data, unreadable := readMemory(i1Ref, 0, 8); unreadable != 0 || binary.LittleEndian.Uint64(data) != 1 {
+			t.Errorf("got data=%v unreadable=%d, want i1=1", data, unreadable)
+		}
+
+		// Strings and slices refer to their backing array.
+		str1Ref := memoryReference("str1")
+		if data, _ := readMemory(str1Ref, 0, 11); string(data) != "01234567890" {
+			t.Errorf("got %q, want \"01234567890\"", data)
+		}
+		if data, _ := readMemory(str1Ref, 2, 3); string(data) != "234" {
+			t.Errorf("got %q with offset 2, want \"234\"", data)
+		}
+		if data, _ := readMemory(memoryReference("byteslice"), 0, 5); !bytes.Equal(data, []byte{116, 195, 168, 115, 116}) {
+			t.Errorf("got %v, want [116 195 168 115 116]", data)
+		}
+
+		// Unreadable memory is reported as such.
+		if data, unreadable := readMemory("0x0", 0, 16); len(data) != 0 || unreadable != 16 {
+			t.Errorf("got data=%v unreadable=%d, want no data and 16 unreadable bytes", data, unreadable)
+		}
+		client.ReadMemoryRequest("i1", 0, 8)
+		if er := client.ExpectErrorResponse(t); er.Body.Error == nil || er.Body.Error.Id != UnableToReadMemory {
+			t.Errorf("got %#v, want Id=%d", er, UnableToReadMemory)
+		}
+
+		// Write to i1 through its memory reference.
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, 42)
+		client.WriteMemoryRequest(i1Ref, 0, false, base64.StdEncoding.EncodeToString(buf))
+		client.ExpectWriteMemoryResponse(t)
+		if ie := client.ExpectInvalidatedEvent(t); !reflect.DeepEqual(ie.Body.Areas, []dap.InvalidatedAreas{"variables"}) {
+			t.Errorf("got %#v, want Areas=[variables]", ie)
+		}
+		client.EvaluateRequest("i1", 1000, "repl")
+		if got := client.ExpectEvaluateResponse(t); got.Body.Result != "42" {
+			t.Errorf("got %#v, want Result=42", got)
+		}
+
+		client.WriteMemoryRequest("0x0", 0, false, base64.StdEncoding.EncodeToString(buf))
+		if er := client.ExpectErrorResponse(t); er.Body.Error == nil || er.Body.Error.Id != UnableToWriteMemory {
+			t.Errorf("got %#v, want Id=%d", er, UnableToWriteMemory)
+		}
+
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectOutputEventDetachingKill(t)
+		client.ExpectDisconnectResponse(t)
+		client.ExpectTerminatedEvent(t)
+	})
+}
+
 func TestDisassemble(t *testing.T) {
 	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
 		runDebugSessionWithBPs(t, client, "launch",
@@ -7763,3 +8052,428 @@ func checkErrorMessageFormat(er *dap.ErrorMessage, fmt string) bool {
 	}
 	return er != nil && er.Format == fmt
 }
+
+// TestGotoTargets checks that gotoTargets and goto requests move the
+// program counter within the current function.
+func TestGotoTargets(t *testing.T) {
+	runTest(t, "jump", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{7},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.skip", 7)
+
+					// Lines of a different function are not valid targets.
+					client.GotoTargetsRequest(fixture.Source, 22)
+					er := client.ExpectErrorResponse(t)
+					if !checkErrorMessageId(er.Body.Error, UnableToGetGotoTargets) {
+						t.Errorf("got %#v, want Id=%d", er, UnableToGetGotoTargets)
+					}
+
+					client.GotoTargetsRequest(fixture.Source, 9)
+					targets := client.ExpectGotoTargetsResponse(t).Body.Targets
+					if len(targets) != 1 || targets[0].Line != 9 || targets[0].InstructionPointerReference == "" {
+						t.Fatalf("got %#v, want one target at line 9", targets)
+					}
+
+					// Targets can only move the goroutine they were computed for.
+					client.GotoRequest(2, targets[0].Id)
+					er = client.ExpectErrorResponse(t)
+					if !checkErrorMessageId(er.Body.Error, UnableToGoto) {
+						t.Errorf("got %#v, want Id=%d", er, UnableToGoto)
+					}
+
+					client.GotoRequest(1, targets[0].Id)
+					client.ExpectGotoResponse(t)
+					se := client.ExpectStoppedEvent(t)
+					if se.Body.Reason != "goto" || se.Body.ThreadId != 1 || !se.Body.AllThreadsStopped {
+						t.Errorf("got %#v, want Reason=\"goto\" ThreadId=1 AllThreadsStopped=true", se)
+					}
+					checkStop(t, client, 1, "main.skip", 9)
+
+					// Targets are invalidated by the stop.
+					client.GotoRequest(1, targets[0].Id)
+					er = client.ExpectErrorResponse(t)
+					if !checkErrorMessageId(er.Body.Error, UnableToGoto) {
+						t.Errorf("got %#v, want Id=%d", er, UnableToGoto)
+					}
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+// TestStepInTargets checks that stepInTargets lists the calls on the
+// current line and that stepIn requests can step into one of them.
+func TestStepInTargets(t *testing.T) {
+	runTest(t, "stepintargets", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{19},
+			[]onBreakpoint{{
+				execute: func() {
+					client.StackTraceRequest(1, 0, 20)
+					frames := client.ExpectStackTraceResponse(t).Body.StackFrames
+					if len(frames) < 2 || frames[0].Name != "main.main" {
+						t.Fatalf("got %#v, want main.main on top", frames)
+					}
+
+					// Only the topmost frame has targets.
+					client.StepInTargetsRequest(frames[1].Id)
+					if targets := client.ExpectStepInTargetsResponse(t).Body.Targets; len(targets) != 0 {
+						t.Errorf("got %#v, want no targets", targets)
+					}
+
+					client.StepInTargetsRequest(frames[0].Id)
+					targets := client.ExpectStepInTargetsResponse(t).Body.Targets
+					var labels []string
+					for _, target := range targets {
+						labels = append(labels, target.Label)
+					}
+					if !reflect.DeepEqual(labels, []string{"main.g", "main.h", "main.f"}) {
+						t.Fatalf("got %v, want [main.g main.h main.f]", labels)
+					}
+
+					client.StepInTargetRequest(1, targets[1].Id)
+					client.ExpectStepInResponse(t)
+					client.ExpectStoppedEvent(t)
+					checkStop(t, client, 1, "main.h", 9)
+
+					// Targets are invalidated by the stop.
+					client.StepInTargetRequest(1, targets[2].Id)
+					er := client.ExpectErrorResponse(t)
+					if !checkErrorMessageId(er.Body.Error, UnableToStepIn) {
+						t.Errorf("got %#v, want Id=%d", er, UnableToStepIn)
+					}
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+func TestBreakpointLocations(t *testing.T) {
+	runTest(t, "breakpointlocations", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{6},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.apply", 6)
+
+					client.BreakpointLocationsRequest(fixture.Source, 10, 14)
+					got := client.ExpectBreakpointLocationsResponse(t).Body.Breakpoints
+					// Each function defined on line 10 has its own column.
+					want := []dap.BreakpointLocation{{Line: 10, Column: 2}, {Line: 10, Column: 13}, {Line: 10, Column: 62}, {Line: 11}, {Line: 13}, {Line: 14}}
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("got %#v, want %#v", got, want)
+					}
+
+					client.BreakpointLocationsRequest(fixture.Source, 12, 0)
+					if got := client.ExpectBreakpointLocationsResponse(t).Body.Breakpoints; len(got) != 0 {
+						t.Errorf("got %#v, want no locations on an empty line", got)
+					}
+
+					// A breakpoint between two locations is moved to the one before it.
+					client.SetSourceBreakpointsRequest(fixture.Source, []dap.SourceBreakpoint{{Line: 10, Column: 70}})
+					bps := client.ExpectSetBreakpointsResponse(t).Body.Breakpoints
+					if len(bps) != 1 || !bps[0].Verified || bps[0].Line != 10 || bps[0].Column != 62 {
+						t.Fatalf("got %#v, want verified breakpoint at 10:62", bps)
+					}
+
+					client.ContinueRequest(1)
+					client.ExpectContinueResponse(t)
+					client.ExpectStoppedEvent(t)
+					checkStop(t, client, 1, "main.main.func2", 10)
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+func TestAssignFuncLitColumnsUnreadableFile(t *testing.T) {
+	// Without the source file the positions keep their zero column and
+	// breakpoints are set on the whole line.
+	positions := []proc.StatementPosition{
+		{Line: 10, Fn: &proc.Function{Name: "main.main"}, PCs: []uint64{0x1000}},
+		{Line: 10, Fn: &proc.Function{Name: "main.main.func1"}, PCs: []uint64{0x2000}},
+	}
+	assignFuncLitColumns(nil, filepath.Join(t.TempDir(), "missing.go"), positions)
+	for _, pos := range positions {
+		if pos.Column != 0 {
+			t.Errorf("%s: got column %d, want 0", pos.Fn.Name, pos.Column)
+		}
+	}
+	if addrs, column := columnBreakpointAddrs(positions, 13); addrs != nil || column != 0 {
+		t.Errorf("got %#v %d, want no addresses", addrs, column)
+	}
+}
+
+func TestRestartFrame(t *testing.T) {
+	runTest(t, "restartframe", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{8},
+			[]onBreakpoint{{
+				execute: func() {
+					client.StackTraceRequest(1, 0, 20)
+					frames := client.ExpectStackTraceResponse(t).Body.StackFrames
+					if len(frames) < 3 || frames[0].Name != "main.add" || frames[1].Name != "main.outer" {
+						t.Fatalf("got %#v, want main.add and main.outer on top", frames)
+					}
+
+					client.RestartFrameRequest(-1)
+					er := client.ExpectErrorResponse(t)
+					if !checkErrorMessageId(er.Body.Error, UnableToRestartFrame) {
+						t.Errorf("got %#v, want Id=%d", er, UnableToRestartFrame)
+					}
+
+					// Part of add has already been executed, the first request
+					// fails and the second one confirms the restart.
+					client.RestartFrameRequest(frames[0].Id)
+					er = client.ExpectErrorResponse(t)
+					if !checkErrorMessageId(er.Body.Error, UnableToRestartFrame) {
+						t.Errorf("got %#v, want Id=%d", er, UnableToRestartFrame)
+					}
+					client.RestartFrameRequest(frames[0].Id)
+					client.ExpectRestartFrameResponse(t)
+					se := client.ExpectStoppedEvent(t)
+					if se.Body.Reason != "restart" || se.Body.ThreadId != 1 || !se.Body.AllThreadsStopped {
+						t.Errorf("got %#v, want Reason=\"restart\" ThreadId=1 AllThreadsStopped=true", se)
+					}
+					checkStop(t, client, 1, "main.add", 5)
+
+					client.ContinueRequest(1)
+					client.ExpectContinueResponse(t)
+					client.ExpectStoppedEvent(t)
+					checkStop(t, client, 1, "main.add", 8)
+					client.EvaluateRequest("c", 1000, "watch")
+					checkEval(t, client.ExpectEvaluateResponse(t), "12", noChildren)
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+func TestCompletions(t *testing.T) {
+	runTest(t, "completions", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{29},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.main", 29)
+
+					for _, tc := range []struct {
+						text   string
+						column int
+						labels []string
+						start  int
+						length int
+					}{
+						{"item", 5, []string{"item", "itemCount", "itemTotal"}, 1, 4},
+						{"x + item.I", 11, []string{"ID", "Identify"}, 10, 1},
+						{"boxed.", 7, []string{"Base", "Count", "ID", "Identify", "Label", "Name"}, 7, 0},
+						{"len(item.Base.)", 15, []string{"ID", "Identify"}, 15, 0},
+						{"main.It + 1", 8, []string{"Item"}, 6, 2},
+						{"dlv con", 8, []string{"config"}, 5, 3},
+						{"dlv config -list showG", 23, []string{"showGlobalVariables"}, 18, 5},
+						{"nonexistent.", 13, []string{}, 0, 0},
+					} {
+						client.CompletionsRequest(tc.text, tc.column, 1000)
+						got := client.ExpectCompletionsResponse(t)
+						labels := []string{}
+						for _, target := range got.Body.Targets {
+							labels = append(labels, target.Label)
+							if target.Start != tc.start || target.Length != tc.length {
+								t.Errorf("%q: got %#v, want Start=%d Length=%d", tc.text, target, tc.start, tc.length)
+							}
+						}
+						if !reflect.DeepEqual(labels, tc.labels) {
+							t.Errorf("%q: got %v, want %v", tc.text, labels, tc.labels)
+						}
+					}
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+func TestCancelRequest(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{8},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.Increment", 8)
+
+					// Cancelling a request that was already handled, or that
+					// does not exist, has no effect.
+					client.CancelRequest(client.Seq() - 1)
+					client.ExpectCancelResponse(t)
+					client.CancelRequest(1000)
+					client.ExpectCancelResponse(t)
+
+					// A cancelled stackTrace request returns either all the
+					// frames, some of them followed by a label or a
+					// "cancelled" error depending on when it is cancelled.
+					seq := client.Seq()
+					client.StackTraceRequest(1, 0, 20)
+					client.CancelRequest(seq)
+					var gotStackTrace, gotCancel bool
+					for !gotStackTrace || !gotCancel {
+						m, err := client.ReadMessage()
+						if err != nil {
+							t.Fatal(err)
+						}
+						switch m := m.(type) {
+						case *dap.CancelResponse:
+							gotCancel = true
+						case *dap.StackTraceResponse:
+							gotStackTrace = true
+							frames := m.Body.StackFrames
+							if len(frames) == 0 {
+								t.Errorf("got no frames")
+							} else if last := frames[len(frames)-1]; last.PresentationHint == "label" {
+								if last.Name != "(stack unwinding cancelled)" {
+									t.Errorf("got label frame %q", last.Name)
+								}
+							} else if frames[0].Name != "main.Increment" {
+								t.Errorf("got first frame %q", frames[0].Name)
+							}
+						case *dap.ErrorResponse:
+							gotStackTrace = true
+							if m.Command != "stackTrace" || m.Message != "cancelled" {
+								t.Errorf("got error response %#v", m)
+							}
+						default:
+							t.Fatalf("got unexpected message %#v", m)
+						}
+					}
+
+					// Requests that follow are not affected.
+					client.StackTraceRequest(1, 0, 20)
+					st := client.ExpectStackTraceResponse(t)
+					if len(st.Body.StackFrames) == 0 || st.Body.StackFrames[0].Name != "main.Increment" {
+						t.Errorf("got %#v", st)
+					}
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+func TestExceptionBreakpointFilters(t *testing.T) {
+	expectPanicStop := func(t *testing.T, client *daptest.Client, text string) {
+		t.Helper()
+		client.ContinueRequest(1)
+		client.ExpectContinueResponse(t)
+		se := client.ExpectStoppedEvent(t)
+		if se.Body.Reason != "exception" || se.Body.Description != "panic" || se.Body.Text != text || len(se.Body.HitBreakpointIds) != 0 {
+			t.Errorf("\ngot  %#v\nwant Reason=\"exception\" Description=\"panic\" Text=%q", se, text)
+		}
+		client.ExceptionInfoRequest(se.Body.ThreadId)
+		eInfo := client.ExpectExceptionInfoResponse(t)
+		if eInfo.Body.ExceptionId != "panic" || eInfo.Body.Description != text {
+			t.Errorf("\ngot  %#v\nwant ExceptionId=\"panic\" Description=%q", eInfo, text)
+		}
+	}
+
+	checkBreakpoints := func(t *testing.T, client *daptest.Client, verified ...bool) {
+		t.Helper()
+		resp := client.ExpectSetExceptionBreakpointsResponse(t)
+		if len(resp.Body.Breakpoints) != len(verified) {
+			t.Fatalf("got %#v, want %d breakpoints", resp.Body.Breakpoints, len(verified))
+		}
+		for i := range verified {
+			if resp.Body.Breakpoints[i].Verified != verified[i] {
+				t.Errorf("breakpoint %d: got %#v, want Verified=%v", i, resp.Body.Breakpoints[i], verified[i])
+			}
+		}
+	}
+
+	t.Run("allPanics", func(t *testing.T) {
+		runTest(t, "panicfilters", func(client *daptest.Client, fixture protest.Fixture) {
+			runDebugSessionWithBPs(t, client, "launch",
+				// Launch
+				func() {
+					client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+				},
+				// Set breakpoints
+				fixture.Source, []int{13},
+				[]onBreakpoint{{
+					execute: func() {
+						checkStop(t, client, 1, "main.main", 13)
+
+						client.SetExceptionBreakpointsRequestWithArgs([]string{"allPanics", "unrecoveredPanic", "nonexistent"}, nil)
+						checkBreakpoints(t, client, true, true, false)
+
+						expectPanicStop(t, client, "\"first\"")
+						expectPanicStop(t, client, "*errors.errorString {s: \"second\"}")
+						expectPanicStop(t, client, "\"third\"")
+						expectPanicStop(t, client, "\"unrecovered\"")
+						// The unrecovered panic is also reported by the unrecoveredPanic filter.
+						expectPanicStop(t, client, "\"unrecovered\"")
+					},
+					disconnect: true,
+				}})
+		})
+	})
+
+	t.Run("panicCondition", func(t *testing.T) {
+		runTest(t, "panicfilters", func(client *daptest.Client, fixture protest.Fixture) {
+			runDebugSessionWithBPs(t, client, "launch",
+				// Launch
+				func() {
+					client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+				},
+				// Set breakpoints
+				fixture.Source, []int{13},
+				[]onBreakpoint{{
+					execute: func() {
+						checkStop(t, client, 1, "main.main", 13)
+
+						client.SetExceptionBreakpointsRequestWithArgs([]string{}, []dap.ExceptionFilterOptions{{FilterId: "panicCondition"}})
+						checkBreakpoints(t, client, false)
+
+						client.SetExceptionBreakpointsRequestWithArgs([]string{}, []dap.ExceptionFilterOptions{{FilterId: "panicCondition", Condition: "e == \"third\""}})
+						checkBreakpoints(t, client, true)
+
+						expectPanicStop(t, client, "\"third\"")
+
+						// With the unrecoveredPanic filter disabled the program
+						// terminates without stopping.
+						client.ContinueRequest(1)
+						client.ExpectContinueResponse(t)
+						client.ExpectTerminatedEvent(t)
+					},
+					disconnect: false,
+				}})
+		})
+	})
+}
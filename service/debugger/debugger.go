@@ -20,13 +20,16 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
 	"github.com/go-delve/delve/pkg/dwarf/op"
 	"github.com/go-delve/delve/pkg/gobuild"
 	"github.com/go-delve/delve/pkg/goversion"
 	"github.com/go-delve/delve/pkg/locspec"
 	"github.com/go-delve/delve/pkg/logflags"
+	"github.com/go-delve/delve/pkg/prettyprint"
 	"github.com/go-delve/delve/pkg/proc"
 	"github.com/go-delve/delve/pkg/proc/core"
+	"github.com/go-delve/delve/pkg/proc/evalop"
 	"github.com/go-delve/delve/pkg/proc/gdbserial"
 	"github.com/go-delve/delve/pkg/proc/native"
 	"github.com/go-delve/delve/service/api"
@@ -78,7 +81,29 @@ type Debugger struct {
 
 	dumpState proc.DumpState
 
+	// cancelOp is closed by CancelOperation to interrupt the cancellable
+	// operation in progress, it is nil if there is no such operation.
+	cancelOp      chan struct{}
+	cancelOpMutex sync.Mutex
+
 	breakpointIDCounter int
+
+	prettyPrinters *prettyprint.Registry
+
+	watchExprs         []*watchExpression
+	watchExprIDCounter int
+}
+
+// watchExpression is a watch expression, its value is updated every time
+// the target stops.
+type watchExpression struct {
+	api.WatchExpression
+	// last is the value of the expression at the last stop, formatted so
+	// that it can be compared with the next one.
+	last string
+	// evaluated is true if the expression was evaluated since it was
+	// created or the target was restarted.
+	evaluated bool
 }
 
 type ExecuteKind int
@@ -157,6 +182,14 @@ type Config struct {
 	DisableASLR bool
 
 	RrOnProcessPid int
+
+	// PrettyPrinters is a list of Starlark scripts defining pretty printers
+	// for the types of the target program.
+	PrettyPrinters []string
+
+	// DisableBuiltinPrettyPrinters disables the built-in pretty printers
+	// for types of the standard library.
+	DisableBuiltinPrettyPrinters bool
 }
 
 // New creates a new Debugger. ProcessArgs specify the commandline arguments for the
@@ -169,6 +202,12 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 		log:         logger,
 	}
 
+	var err error
+	d.prettyPrinters, err = prettyprint.New(config.PrettyPrinters, config.DisableBuiltinPrettyPrinters)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the process by either attaching or launching.
 	switch {
 	case d.config.AttachPid > 0 || d.config.AttachWaitFor != "":
@@ -481,6 +520,7 @@ func (d *Debugger) Restart(rerecord bool, pos string, resetArgs bool, newArgs []
 	recorded, _ := d.target.Recorded()
 	if recorded && !rerecord {
 		d.target.ResumeNotify(nil)
+		d.resetWatchExpressions()
 		return nil, d.target.Restart(pos)
 	}
 
@@ -547,6 +587,7 @@ func (d *Debugger) Restart(rerecord bool, pos string, resetArgs bool, newArgs []
 		discarded = append(discarded, api.DiscardedBreakpoint{Breakpoint: api.ConvertLogicalBreakpoint(oldBp), Reason: err.Error()})
 	})
 	d.target = grp
+	d.resetWatchExpressions()
 	return discarded, nil
 }
 
@@ -636,6 +677,8 @@ func (d *Debugger) state(retLoadCfg *proc.LoadConfig, withBreakpointInfo bool) (
 		}
 	}
 
+	state.WatchExpressions = d.watchExpressions()
+
 	return state, nil
 }
 
@@ -1009,6 +1052,72 @@ func (d *Debugger) CreateWatchpoint(goid int64, frame, deferredCall int, expr st
 	return d.convertBreakpoint(bp.Logical), nil
 }
 
+// JumpDestination returns the address the program counter of the
+// specified goroutine would be moved to by SetPCToLine.
+func (d *Debugger) JumpDestination(goid int64, file string, line int) (uint64, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	g, err := proc.FindGoroutine(d.target.Selected, goid)
+	if err != nil {
+		return 0, err
+	}
+	return d.target.Selected.JumpDestination(g, file, line)
+}
+
+// SetPCToLine moves the program counter of the specified goroutine to
+// file:line, which must be in the function the goroutine is executing.
+func (d *Debugger) SetPCToLine(goid int64, file string, line int) (*api.DebuggerState, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	g, err := proc.FindGoroutine(d.target.Selected, goid)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.target.Selected.SetPCToLine(g, file, line); err != nil {
+		return nil, err
+	}
+	return d.state(nil, false)
+}
+
+// StepInTargets returns the function calls on the current line of the
+// specified goroutine that can be stepped into.
+func (d *Debugger) StepInTargets(goid int64) ([]api.StepInTarget, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	g, err := proc.FindGoroutine(d.target.Selected, goid)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := d.target.Selected.StepInTargets(g)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.StepInTarget, len(targets))
+	for i := range targets {
+		r[i] = api.StepInTarget{PC: targets[i].PC, Function: api.ConvertFunction(targets[i].Fn)}
+	}
+	return r, nil
+}
+
+// WatchpointTypes returns the types of watchpoint that can be created on
+// the specified expression.
+func (d *Debugger) WatchpointTypes(goid int64, frame, deferredCall int, expr string) (api.WatchType, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	p := d.target.Selected
+
+	s, err := proc.ConvertEvalScope(p, goid, frame, deferredCall)
+	if err != nil {
+		return 0, err
+	}
+	wtype, err := p.WatchpointTypes(s, expr)
+	return api.WatchType(wtype), err
+}
+
 // Threads returns the threads of the target process.
 func (d *Debugger) Threads() ([]proc.Thread, error) {
 	d.targetMutex.Lock()
@@ -1140,7 +1249,11 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 		if err := d.target.ChangeDirection(proc.Forward); err != nil {
 			return nil, err
 		}
-		err = d.target.Step()
+		if command.TargetPC != 0 {
+			err = d.target.StepInTarget(command.TargetPC)
+		} else {
+			err = d.target.Step()
+		}
 	case api.ReverseStep:
 		d.log.Debug("reverse stepping")
 		if err := d.target.ChangeDirection(proc.Backward); err != nil {
@@ -1202,6 +1315,18 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 			err = d.target.Selected.SwitchGoroutine(g)
 		}
 		withBreakpointInfo = false
+	case api.RestartFrame:
+		d.log.Debugf("restarting frame %d", command.Frame)
+		if command.GoroutineID > 0 {
+			var g *proc.G
+			g, err = proc.FindGoroutine(d.target.Selected, command.GoroutineID)
+			if err == nil {
+				err = d.target.Selected.SwitchGoroutine(g)
+			}
+		}
+		if err == nil {
+			err = d.target.RestartFrame(command.Frame, command.Force)
+		}
 	case api.Halt:
 		// RequestManualStop already called
 		withBreakpointInfo = false
@@ -1220,6 +1345,9 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 		}
 		return nil, err
 	}
+	if command.Name != api.SwitchGoroutine && command.Name != api.SwitchThread {
+		d.updateWatchExpressions()
+	}
 	state, stateErr := d.state(api.LoadConfigToProc(command.ReturnInfoLoadConfig), withBreakpointInfo)
 	if stateErr != nil {
 		return state, stateErr
@@ -1339,6 +1467,15 @@ func (d *Debugger) Sources(filter string) ([]string, error) {
 	return files, nil
 }
 
+// BreakpointLocations returns the positions of the statements of file
+// between startLine and endLine (both included), sorted by line and column.
+func (d *Debugger) BreakpointLocations(file string, startLine, endLine int) []proc.StatementPosition {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	return d.target.Selected.BinInfo().StatementPositions(file, startLine, endLine)
+}
+
 // Functions returns a list of functions in the target process.
 func (d *Debugger) Functions(filter string, followCalls int) ([]string, error) {
 	d.targetMutex.Lock()
@@ -1479,6 +1616,9 @@ func (d *Debugger) PackageVariables(filter string, cfg proc.LoadConfig) ([]*proc
 	if err != nil {
 		return nil, err
 	}
+	var done func()
+	cfg.Cancel, done = d.startCancellable()
+	defer done()
 	pv, err := scope.PackageVariables(cfg)
 	if err != nil {
 		return nil, err
@@ -1489,6 +1629,7 @@ func (d *Debugger) PackageVariables(filter string, cfg proc.LoadConfig) ([]*proc
 			pvr = append(pvr, pv[i])
 		}
 	}
+	d.prettyPrint(scope, pvr...)
 	return pvr, nil
 }
 
@@ -1520,6 +1661,20 @@ func (d *Debugger) ScopeRegisters(goid int64, frame, deferredCall int) (*op.Dwar
 	return &s.Regs, d.target.Selected.BinInfo().Arch.DwarfRegisterToString, nil
 }
 
+// Completions returns the names starting with prefix that can follow
+// operand in an expression, if operand is empty the names are the
+// variables, functions and packages visible in the scope.
+func (d *Debugger) Completions(goid int64, frame, deferredCall int, operand, prefix string) ([]proc.Completion, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target.Selected, goid, frame, deferredCall)
+	if err != nil {
+		return nil, err
+	}
+	return s.Completions(operand, prefix)
+}
+
 // LocalVariables returns a list of the local variables.
 func (d *Debugger) LocalVariables(goid int64, frame, deferredCall int, cfg proc.LoadConfig) ([]*proc.Variable, error) {
 	d.targetMutex.Lock()
@@ -1529,7 +1684,12 @@ func (d *Debugger) LocalVariables(goid int64, frame, deferredCall int, cfg proc.
 	if err != nil {
 		return nil, err
 	}
-	return s.LocalVariables(cfg)
+	var done func()
+	cfg.Cancel, done = d.startCancellable()
+	defer done()
+	vars, err := s.LocalVariables(cfg)
+	d.prettyPrint(s, vars...)
+	return vars, err
 }
 
 // FunctionArguments returns the arguments to the current function.
@@ -1541,7 +1701,12 @@ func (d *Debugger) FunctionArguments(goid int64, frame, deferredCall int, cfg pr
 	if err != nil {
 		return nil, err
 	}
-	return s.FunctionArguments(cfg)
+	var done func()
+	cfg.Cancel, done = d.startCancellable()
+	defer done()
+	vars, err := s.FunctionArguments(cfg)
+	d.prettyPrint(s, vars...)
+	return vars, err
 }
 
 // Function returns the current function.
@@ -1566,7 +1731,148 @@ func (d *Debugger) EvalVariableInScope(goid int64, frame, deferredCall int, expr
 	if err != nil {
 		return nil, err
 	}
-	return s.EvalExpression(expr, cfg)
+	var done func()
+	cfg.Cancel, done = d.startCancellable()
+	defer done()
+	v, err := s.EvalExpression(expr, cfg)
+	d.prettyPrint(s, v)
+	return v, err
+}
+
+// MethodSetInScope returns the method set of the type of the value of
+// expr, evaluated in the given scope. If the value is an interface the
+// method set of its concrete type is returned.
+func (d *Debugger) MethodSetInScope(goid int64, frame, deferredCall int, expr string) ([]proc.Method, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target.Selected, goid, frame, deferredCall)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.EvalExpression(expr, proc.LoadConfig{})
+	if err != nil {
+		return nil, err
+	}
+	return s.MethodSet(v)
+}
+
+// AddWatchExpression adds expr to the list of watch expressions, the
+// expression will be evaluated in scope every time the target stops and
+// its value loaded using cfg, if cfg is nil a default configuration is
+// used.
+func (d *Debugger) AddWatchExpression(expr string, scope api.EvalScope, cfg *api.LoadConfig) (*api.WatchExpression, error) {
+	if _, err := evalop.ParseExpr(expr); err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+	}
+
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	d.watchExprIDCounter++
+	w := &watchExpression{WatchExpression: api.WatchExpression{ID: d.watchExprIDCounter, Expr: expr, Scope: scope, Cfg: *cfg}}
+	if _, err := d.target.Valid(); err == nil {
+		cancel, done := d.startCancellable()
+		d.evalWatchExpression(w, cancel)
+		done()
+	}
+	d.watchExprs = append(d.watchExprs, w)
+	r := w.WatchExpression
+	return &r, nil
+}
+
+// RemoveWatchExpression removes the watch expression with the specified ID.
+func (d *Debugger) RemoveWatchExpression(id int) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	for i, w := range d.watchExprs {
+		if w.ID == id {
+			d.watchExprs = slices.Delete(d.watchExprs, i, i+1)
+			return nil
+		}
+	}
+	return fmt.Errorf("no watch expression with id %d", id)
+}
+
+// WatchExpressions returns the list of watch expressions, with their
+// values at the last stop.
+func (d *Debugger) WatchExpressions() []*api.WatchExpression {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.watchExpressions()
+}
+
+func (d *Debugger) watchExpressions() []*api.WatchExpression {
+	if len(d.watchExprs) == 0 {
+		return nil
+	}
+	r := make([]*api.WatchExpression, len(d.watchExprs))
+	for i := range d.watchExprs {
+		w := d.watchExprs[i].WatchExpression
+		r[i] = &w
+	}
+	return r
+}
+
+// updateWatchExpressions evaluates all watch expressions, it is called
+// every time the target stops.
+func (d *Debugger) updateWatchExpressions() {
+	if len(d.watchExprs) == 0 {
+		return
+	}
+	if _, err := d.target.Valid(); err != nil {
+		// The target exited, keep the values from the last stop.
+		return
+	}
+	cancel, done := d.startCancellable()
+	defer done()
+	for _, w := range d.watchExprs {
+		d.evalWatchExpression(w, cancel)
+	}
+}
+
+// resetWatchExpressions forgets the values of all watch expressions.
+func (d *Debugger) resetWatchExpressions() {
+	for _, w := range d.watchExprs {
+		w.Value, w.Err, w.Changed = nil, "", false
+		w.last, w.evaluated = "", false
+	}
+}
+
+// evalWatchExpression evaluates w and compares its new value with the
+// value it had at the previous stop.
+func (d *Debugger) evalWatchExpression(w *watchExpression, cancel <-chan struct{}) {
+	w.Value, w.Err = nil, ""
+	var cur string
+	v, err := d.evalWatchExpressionValue(w, cancel)
+	if err != nil {
+		w.Err = err.Error()
+		cur = "error: " + w.Err
+	} else {
+		w.Value = api.ConvertVar(v)
+		cur = w.Value.SinglelineString()
+	}
+	w.Changed = w.evaluated && cur != w.last
+	w.last, w.evaluated = cur, true
+}
+
+func (d *Debugger) evalWatchExpressionValue(w *watchExpression, cancel <-chan struct{}) (*proc.Variable, error) {
+	s, err := proc.ConvertEvalScope(d.target.Selected, w.Scope.GoroutineID, w.Scope.Frame, w.Scope.DeferredCall)
+	if err != nil {
+		return nil, err
+	}
+	cfg := api.LoadConfigToProc(&w.Cfg)
+	cfg.Cancel = cancel
+	v, err := s.EvalExpression(w.Expr, *cfg)
+	if err != nil {
+		return nil, err
+	}
+	d.prettyPrint(s, v)
+	return v, nil
 }
 
 // LoadResliced will attempt to 'reslice' a map, array or slice so that the values
@@ -1574,7 +1880,20 @@ func (d *Debugger) EvalVariableInScope(goid int64, frame, deferredCall int, expr
 func (d *Debugger) LoadResliced(v *proc.Variable, start int, cfg proc.LoadConfig) (*proc.Variable, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
-	return v.LoadResliced(start, cfg)
+	var done func()
+	cfg.Cancel, done = d.startCancellable()
+	defer done()
+	v, err := v.LoadResliced(start, cfg)
+	d.prettyPrint(nil, v)
+	return v, err
+}
+
+// prettyPrint runs the pretty printers on vars, scope is used to load the
+// parts of the variables that the pretty printers need.
+func (d *Debugger) prettyPrint(scope *proc.EvalScope, vars ...*proc.Variable) {
+	for _, v := range vars {
+		d.prettyPrinters.Apply(scope, v)
+	}
 }
 
 // SetVariableInScope will set the value of the variable represented by
@@ -1590,13 +1909,116 @@ func (d *Debugger) SetVariableInScope(goid int64, frame, deferredCall int, symbo
 	return s.SetVariable(symbol, value)
 }
 
+// SetLiveConvenienceVariable sets the convenience variable $name to the
+// live expression expr, which is evaluated every time the variable is used.
+func (d *Debugger) SetLiveConvenienceVariable(name, expr string) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.ConvenienceVariables.SetLive(name, expr)
+}
+
 // Goroutines will return a list of goroutines in the target process.
+// If the listing is interrupted by CancelOperation the goroutines found
+// until then are returned along with proc.ErrCanceled.
 func (d *Debugger) Goroutines(start, count int) ([]*proc.G, int, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
+	_, done := d.startCancellable()
+	defer done()
 	return proc.GoroutinesInfo(d.target.Selected, start, count)
 }
 
+// HeapObjects returns the objects allocated in the heap of the selected
+// target, grouped by type and sorted by the memory they use. At most top
+// types are returned, all of them if top is 0.
+// If typ is not empty only the objects of type typ, and the arrays of
+// elements of type typ, are selected and at most max of them are returned,
+// sorted by address, all of them if max is 0. Count and bytes are the
+// number of selected objects and the memory they use.
+func (d *Debugger) HeapObjects(typ string, top, max int) (types []api.HeapType, objs []proc.HeapObject, count, bytes int64, err error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	_, done := d.startCancellable()
+	defer done()
+	all, err := proc.HeapObjects(d.target.Selected)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+
+	byType := make(map[string]*api.HeapType)
+	for i := range all {
+		obj := &all[i]
+		if typ != "" && !heapObjectHasType(obj, typ) {
+			continue
+		}
+		count++
+		bytes += obj.Size
+		name := api.HeapTypeName(obj.Type)
+		ht := byType[name]
+		if ht == nil {
+			ht = &api.HeapType{Type: name}
+			byType[name] = ht
+		}
+		ht.Count++
+		ht.Bytes += obj.Size
+		if typ != "" && (max == 0 || len(objs) < max) {
+			objs = append(objs, *obj)
+		}
+	}
+
+	types = make([]api.HeapType, 0, len(byType))
+	for _, ht := range byType {
+		types = append(types, *ht)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].Bytes != types[j].Bytes {
+			return types[i].Bytes > types[j].Bytes
+		}
+		return types[i].Type < types[j].Type
+	})
+	if top > 0 && len(types) > top {
+		types = types[:top]
+	}
+	return types, objs, count, bytes, nil
+}
+
+// heapObjectHasType returns true if obj has type typ or is an array of
+// elements of type typ.
+func heapObjectHasType(obj *proc.HeapObject, typ string) bool {
+	if obj.Type == nil {
+		return false
+	}
+	if api.PrettyTypeName(obj.Type) == typ || api.HeapTypeName(obj.Type) == typ {
+		return true
+	}
+	atyp, isarray := obj.Type.(*godwarf.ArrayType)
+	return isarray && api.PrettyTypeName(atyp.Type) == typ
+}
+
+// ObjectReferencesInScope evaluates expr in the specified scope and
+// returns the heap object it references, and the pointers to it.
+// See proc.ReferencedObjectAddress and proc.ObjectReferences.
+func (d *Debugger) ObjectReferencesInScope(goid int64, frame, deferredCall int, expr string) (proc.HeapObject, []proc.ObjectReference, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target.Selected, goid, frame, deferredCall)
+	if err != nil {
+		return proc.HeapObject{}, nil, err
+	}
+	v, err := s.EvalExpression(expr, proc.LoadConfig{})
+	if err != nil {
+		return proc.HeapObject{}, nil, err
+	}
+	addr, err := proc.ReferencedObjectAddress(v)
+	if err != nil {
+		return proc.HeapObject{}, nil, err
+	}
+	_, done := d.startCancellable()
+	defer done()
+	return proc.ObjectReferences(d.target.Selected, addr)
+}
+
 // FilterGoroutines returns the goroutines in gs that satisfy the specified filters.
 func (d *Debugger) FilterGoroutines(gs []*proc.G, filters []api.ListGoroutinesFilter) []*proc.G {
 	if len(filters) == 0 {
@@ -1737,6 +2159,8 @@ func (d *Debugger) GroupGoroutines(gs []*proc.G, group *api.GoroutineGroupingOpt
 func (d *Debugger) Stacktrace(goroutineID int64, depth int, opts api.StacktraceOptions) ([]proc.Stackframe, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
+	_, done := d.startCancellable()
+	defer done()
 	return d.stacktrace(goroutineID, depth, opts)
 }
 
@@ -1984,6 +2408,9 @@ func (d *Debugger) Disassemble(goroutineID int64, addr1, addr2 uint64) ([]proc.A
 		addr1 = fn.Entry
 		addr2 = fn.End
 	}
+	if addr1 > addr2 {
+		return nil, fmt.Errorf("start address(%x) should be less than end address(%x)", addr1, addr2)
+	}
 
 	g, err := proc.FindGoroutine(d.target.Selected, goroutineID)
 	if err != nil {
@@ -1996,9 +2423,46 @@ func (d *Debugger) Disassemble(goroutineID int64, addr1, addr2 uint64) ([]proc.A
 	}
 	regs, _ := curthread.Registers()
 
-	return proc.Disassemble(d.target.Selected.Memory(), regs, d.target.Selected.Breakpoints(), d.target.Selected.BinInfo(), addr1, addr2)
+	cancel, done := d.startCancellable()
+	defer done()
+
+	// Disassemble in chunks so that the operation can be canceled, each
+	// chunk is extended by the maximum instruction length so that the last
+	// instruction of the chunk is not truncated.
+	p := d.target.Selected
+	maxInstLen := uint64(p.BinInfo().Arch.MaxInstructionLength())
+	r := []proc.AsmInstruction{}
+	for pc := addr1; pc < addr2; {
+		select {
+		case <-cancel:
+			return r, proc.ErrCanceled
+		default:
+		}
+		chunkEnd := min(pc+disassembleChunkSize, addr2)
+		insts, err := proc.Disassemble(p.Memory(), regs, p.Breakpoints(), p.BinInfo(), pc, min(chunkEnd+maxInstLen, addr2))
+		if err != nil {
+			return nil, err
+		}
+		next := pc
+		for _, inst := range insts {
+			if inst.Loc.PC >= chunkEnd {
+				break
+			}
+			r = append(r, inst)
+			next = inst.Loc.PC + uint64(inst.Size)
+		}
+		if next == pc {
+			break
+		}
+		pc = next
+	}
+	return r, nil
 }
 
+// disassembleChunkSize is the size of the chunks of memory disassembled
+// between checks for cancellation.
+const disassembleChunkSize = 4096
+
 func (d *Debugger) AsmInstructionText(inst *proc.AsmInstruction, flavour proc.AssemblyFlavour) string {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
@@ -2027,7 +2491,9 @@ func (d *Debugger) FindThreadReturnValues(id int, cfg proc.LoadConfig) ([]*proc.
 		return nil, fmt.Errorf("could not find thread %d", id)
 	}
 
-	return thread.Common().ReturnValues(cfg), nil
+	vars := thread.Common().ReturnValues(cfg)
+	d.prettyPrint(nil, vars...)
+	return vars, nil
 }
 
 // Checkpoint will set a checkpoint specified by the locspec.
@@ -2077,6 +2543,15 @@ func (d *Debugger) ExamineMemory(address uint64, length int) ([]byte, error) {
 	return data, nil
 }
 
+// WriteMemory writes data to the memory of the selected target starting
+// at address and returns the number of bytes written.
+func (d *Debugger) WriteMemory(address uint64, data []byte) (int, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	return d.target.Selected.Memory().WriteMemory(address, data)
+}
+
 func (d *Debugger) GetVersion(out *api.GetVersionOut) error {
 	if d.config.CoreFile != "" {
 		if d.config.Backend == "rr" {
@@ -2141,7 +2616,7 @@ func (d *Debugger) LockTargetGroup() (*proc.TargetGroup, func()) {
 }
 
 // DumpStart starts a core dump to dest.
-func (d *Debugger) DumpStart(dest string) error {
+func (d *Debugger) DumpStart(dest string, opts proc.DumpOptions) error {
 	d.targetMutex.Lock()
 	// targetMutex will only be unlocked when the dump is done
 
@@ -2177,7 +2652,7 @@ func (d *Debugger) DumpStart(dest string) error {
 	d.dumpState.Err = nil
 	go func() {
 		defer d.targetMutex.Unlock()
-		d.target.Selected.Dump(fh, 0, &d.dumpState)
+		d.target.Selected.Dump(fh, opts, &d.dumpState)
 	}()
 
 	return nil
@@ -2205,6 +2680,49 @@ func (d *Debugger) DumpWait(wait time.Duration) *proc.DumpState {
 	return &d.dumpState
 }
 
+// startCancellable marks the start of an operation that can be
+// interrupted by CancelOperation, it must be called with targetMutex held.
+// It returns the channel that will be closed if the operation is canceled
+// and a function that must be called when the operation ends.
+func (d *Debugger) startCancellable() (<-chan struct{}, func()) {
+	cancel := make(chan struct{})
+	d.cancelOpMutex.Lock()
+	d.cancelOp = cancel
+	d.cancelOpMutex.Unlock()
+	p := d.target.Selected
+	p.SetCancel(cancel)
+	return cancel, func() {
+		p.SetCancel(nil)
+		d.cancelOpMutex.Lock()
+		if d.cancelOp == cancel {
+			d.cancelOp = nil
+		}
+		d.cancelOpMutex.Unlock()
+	}
+}
+
+// CancelOperation interrupts the variable loading, goroutine listing,
+// stack unwinding or disassembly in progress, if any. The interrupted
+// operation returns the results collected until then:
+//   - variables that could not be loaded are marked with the
+//     proc.VariableLoadCanceled flag
+//   - stacktraces end with a frame whose Err field is proc.ErrCanceled
+//   - goroutine listings and disassemblies are returned along with
+//     proc.ErrCanceled
+//
+// Operations are not associated with the client that requested them, the
+// operation in progress is interrupted whichever client sends the request.
+// If no operation is in progress CancelOperation does nothing, it does not
+// affect the next operation.
+func (d *Debugger) CancelOperation() {
+	d.cancelOpMutex.Lock()
+	defer d.cancelOpMutex.Unlock()
+	if d.cancelOp != nil {
+		close(d.cancelOp)
+		d.cancelOp = nil
+	}
+}
+
 // DumpCancel cancels a dump in progress
 func (d *Debugger) DumpCancel() error {
 	d.dumpState.Mutex.Lock()
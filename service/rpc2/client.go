@@ -164,6 +164,18 @@ func (c *RPCClient) Step() (*api.DebuggerState, error) {
 	return &out.State, err
 }
 
+func (c *RPCClient) StepInTarget(pc uint64) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Step, ReturnInfoLoadConfig: c.retValLoadCfg, TargetPC: pc}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) StepInTargets(goroutineID int64) ([]api.StepInTarget, error) {
+	var out StepInTargetsOut
+	err := c.call("StepInTargets", StepInTargetsIn{goroutineID}, &out)
+	return out.Targets, err
+}
+
 func (c *RPCClient) ReverseStep() (*api.DebuggerState, error) {
 	var out CommandOut
 	err := c.call("Command", api.DebuggerCommand{Name: api.ReverseStep, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
@@ -198,6 +210,12 @@ func (c *RPCClient) StepInstruction(skipCalls bool) (*api.DebuggerState, error)
 	return &out.State, err
 }
 
+func (c *RPCClient) Jump(goroutineID int64, file string, line int) (*api.DebuggerState, error) {
+	var out JumpOut
+	err := c.call("Jump", JumpIn{goroutineID, file, line}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) ReverseStepInstruction(skipCalls bool) (*api.DebuggerState, error) {
 	var out CommandOut
 	name := api.ReverseStepInstruction
@@ -341,11 +359,51 @@ func (c *RPCClient) EvalVariable(scope api.EvalScope, expr string, cfg api.LoadC
 	return out.Variable, err
 }
 
+func (c *RPCClient) ListMethods(scope api.EvalScope, expr string) ([]api.Method, error) {
+	var out ListMethodsOut
+	err := c.call("ListMethods", ListMethodsIn{scope, expr}, &out)
+	return out.Methods, err
+}
+
+func (c *RPCClient) ListHeapObjects(typ string, top, max int) ([]api.HeapType, []api.HeapObject, int64, int64, error) {
+	var out ListHeapObjectsOut
+	err := c.call("ListHeapObjects", ListHeapObjectsIn{typ, top, max}, &out)
+	return out.Types, out.Objects, out.Count, out.Bytes, err
+}
+
+func (c *RPCClient) ListObjectReferences(scope api.EvalScope, expr string) (*api.HeapObject, []api.ObjectReference, error) {
+	var out ListObjectReferencesOut
+	err := c.call("ListObjectReferences", ListObjectReferencesIn{scope, expr}, &out)
+	return &out.Object, out.References, err
+}
+
+func (c *RPCClient) AddWatchExpression(expr string, scope api.EvalScope, cfg *api.LoadConfig) (*api.WatchExpression, error) {
+	var out AddWatchExpressionOut
+	err := c.call("AddWatchExpression", AddWatchExpressionIn{expr, scope, cfg}, &out)
+	return &out.WatchExpression, err
+}
+
+func (c *RPCClient) RemoveWatchExpression(id int) error {
+	var out RemoveWatchExpressionOut
+	return c.call("RemoveWatchExpression", RemoveWatchExpressionIn{id}, &out)
+}
+
+func (c *RPCClient) ListWatchExpressions() ([]*api.WatchExpression, error) {
+	var out ListWatchExpressionsOut
+	err := c.call("ListWatchExpressions", ListWatchExpressionsIn{}, &out)
+	return out.WatchExpressions, err
+}
+
 func (c *RPCClient) SetVariable(scope api.EvalScope, symbol, value string) error {
 	out := new(SetOut)
 	return c.call("Set", SetIn{scope, symbol, value}, out)
 }
 
+func (c *RPCClient) SetLiveVariable(name, expr string) error {
+	out := new(SetLiveOut)
+	return c.call("SetLive", SetLiveIn{name, expr}, out)
+}
+
 func (c *RPCClient) ListSources(filter string) ([]string, error) {
 	sources := new(ListSourcesOut)
 	err := c.call("ListSources", ListSourcesIn{filter}, sources)
@@ -538,6 +596,12 @@ func (c *RPCClient) CoreDumpStart(dest string) (api.DumpState, error) {
 	return out.State, err
 }
 
+func (c *RPCClient) CoreDumpStartWithOptions(dest string, opts api.DumpOptions) (api.DumpState, error) {
+	out := &DumpStartOut{}
+	err := c.call("DumpStart", DumpStartIn{Destination: dest, Options: opts}, out)
+	return out.State, err
+}
+
 func (c *RPCClient) CoreDumpWait(msec int) api.DumpState {
 	out := &DumpWaitOut{}
 	_ = c.call("DumpWait", DumpWaitIn{Wait: msec}, out)
@@ -549,6 +613,14 @@ func (c *RPCClient) CoreDumpCancel() error {
 	return c.call("DumpCancel", DumpCancelIn{}, out)
 }
 
+// CancelOperation interrupts the variable loading, goroutine listing, stack
+// unwinding or disassembly in progress, even if it was requested by a
+// different client.
+func (c *RPCClient) CancelOperation() error {
+	out := &CancelOperationOut{}
+	return c.call("CancelOperation", CancelOperationIn{}, out)
+}
+
 // ListTargets returns the current list of debug targets.
 func (c *RPCClient) ListTargets() ([]api.Target, error) {
 	out := &ListTargetsOut{}
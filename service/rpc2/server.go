@@ -544,6 +544,78 @@ func (s *RPCServer) Eval(arg EvalIn, out *EvalOut) error {
 	return nil
 }
 
+type ListMethodsIn struct {
+	Scope api.EvalScope
+	Expr  string
+}
+
+type ListMethodsOut struct {
+	Methods []api.Method
+}
+
+// ListMethods lists the methods of the type of the value of an expression,
+// if the value is an interface the methods of its concrete type are listed.
+// The methods are read from the runtime type information of the target.
+func (s *RPCServer) ListMethods(arg ListMethodsIn, out *ListMethodsOut) error {
+	methods, err := s.debugger.MethodSetInScope(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Expr)
+	if err != nil {
+		return err
+	}
+	out.Methods = api.ConvertMethods(methods)
+	return nil
+}
+
+type AddWatchExpressionIn struct {
+	Expr  string
+	Scope api.EvalScope
+	Cfg   *api.LoadConfig
+}
+
+type AddWatchExpressionOut struct {
+	WatchExpression api.WatchExpression
+}
+
+// AddWatchExpression adds a watch expression. Watch expressions are
+// evaluated every time the target stops, their values are returned in the
+// WatchExpressions field of DebuggerState.
+// If Scope.GoroutineID is -1 the expression is evaluated on the goroutine
+// selected when the target stops.
+// If Cfg is nil a default load configuration is used.
+func (s *RPCServer) AddWatchExpression(arg AddWatchExpressionIn, out *AddWatchExpressionOut) error {
+	w, err := s.debugger.AddWatchExpression(arg.Expr, arg.Scope, arg.Cfg)
+	if err != nil {
+		return err
+	}
+	out.WatchExpression = *w
+	return nil
+}
+
+type RemoveWatchExpressionIn struct {
+	ID int
+}
+
+type RemoveWatchExpressionOut struct {
+}
+
+// RemoveWatchExpression removes a watch expression.
+func (s *RPCServer) RemoveWatchExpression(arg RemoveWatchExpressionIn, out *RemoveWatchExpressionOut) error {
+	return s.debugger.RemoveWatchExpression(arg.ID)
+}
+
+type ListWatchExpressionsIn struct {
+}
+
+type ListWatchExpressionsOut struct {
+	WatchExpressions []*api.WatchExpression
+}
+
+// ListWatchExpressions lists the watch expressions with their values at
+// the last stop.
+func (s *RPCServer) ListWatchExpressions(arg ListWatchExpressionsIn, out *ListWatchExpressionsOut) error {
+	out.WatchExpressions = s.debugger.WatchExpressions()
+	return nil
+}
+
 type SetIn struct {
 	Scope  api.EvalScope
 	Symbol string
@@ -559,6 +631,65 @@ func (s *RPCServer) Set(arg SetIn, out *SetOut) error {
 	return s.debugger.SetVariableInScope(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Symbol, arg.Value)
 }
 
+type SetLiveIn struct {
+	// Name of the convenience variable, without the leading '$'.
+	Name string
+	Expr string
+}
+
+type SetLiveOut struct {
+}
+
+// SetLive sets the convenience variable $Name to the live expression Expr,
+// which is evaluated every time the variable is used.
+// Convenience variables holding a snapshot of a value are set with Set,
+// using $Name as the symbol.
+func (s *RPCServer) SetLive(arg SetLiveIn, out *SetLiveOut) error {
+	return s.debugger.SetLiveConvenienceVariable(arg.Name, arg.Expr)
+}
+
+type JumpIn struct {
+	GoroutineID int64
+	File        string
+	Line        int
+}
+
+type JumpOut struct {
+	State api.DebuggerState
+}
+
+// Jump moves the program counter of the goroutine GoroutineID to File:Line,
+// without executing any of the code in between. The destination must be in
+// the function the goroutine is currently executing.
+func (s *RPCServer) Jump(arg JumpIn, out *JumpOut) error {
+	state, err := s.debugger.SetPCToLine(arg.GoroutineID, arg.File, arg.Line)
+	if err != nil {
+		return err
+	}
+	out.State = *state
+	return nil
+}
+
+type StepInTargetsIn struct {
+	GoroutineID int64
+}
+
+type StepInTargetsOut struct {
+	Targets []api.StepInTarget
+}
+
+// StepInTargets returns the function calls on the current line of the
+// goroutine GoroutineID that have not been executed yet. Pass the PC of one
+// of them as the TargetPC field of a step command to step into it.
+func (s *RPCServer) StepInTargets(arg StepInTargetsIn, out *StepInTargetsOut) error {
+	targets, err := s.debugger.StepInTargets(arg.GoroutineID)
+	if err != nil {
+		return err
+	}
+	out.Targets = targets
+	return nil
+}
+
 type ListSourcesIn struct {
 	Filter string
 }
@@ -629,6 +760,9 @@ type ListGoroutinesOut struct {
 	Nextg         int
 	Groups        []api.GoroutineGroup
 	TooManyGroups bool
+	// Canceled is true if the listing was interrupted by CancelOperation,
+	// Nextg can be used to continue it.
+	Canceled bool
 }
 
 // ListGoroutines lists all goroutines.
@@ -698,6 +832,10 @@ func (s *RPCServer) ListGoroutines(arg ListGoroutinesIn, out *ListGoroutinesOut)
 
 	if !gsLoaded {
 		gs, nextg, err = s.debugger.Goroutines(arg.Start, arg.Count)
+		if errors.Is(err, proc.ErrCanceled) {
+			out.Canceled = true
+			err = nil
+		}
 	}
 	if err != nil {
 		return err
@@ -711,6 +849,77 @@ func (s *RPCServer) ListGoroutines(arg ListGoroutinesIn, out *ListGoroutinesOut)
 	return nil
 }
 
+type ListHeapObjectsIn struct {
+	// Type, if not empty, selects the objects of this type and the arrays
+	// of elements of this type.
+	Type string
+	// Top is the maximum number of types returned, 0 means all types.
+	Top int
+	// Max is the maximum number of objects returned, 0 means all objects.
+	// Objects are only returned if Type is set.
+	Max int
+}
+
+type ListHeapObjectsOut struct {
+	// Types lists the selected objects grouped by type, sorted by the
+	// memory they use.
+	Types []api.HeapType
+	// Objects lists the selected objects, sorted by address.
+	Objects []api.HeapObject
+	// Count and Bytes are the number of selected objects and the memory
+	// they use.
+	Count int64
+	Bytes int64
+}
+
+// ListHeapObjects lists the objects allocated in the heap of the target.
+// The types of the objects are determined from the runtime type
+// information and by following typed pointers from global variables and
+// from the local variables of all goroutines.
+func (s *RPCServer) ListHeapObjects(arg ListHeapObjectsIn, out *ListHeapObjectsOut) error {
+	types, objs, count, bytes, err := s.debugger.HeapObjects(arg.Type, arg.Top, arg.Max)
+	if err != nil {
+		return err
+	}
+	out.Types = types
+	for i := range objs {
+		out.Objects = append(out.Objects, api.ConvertHeapObject(&objs[i]))
+	}
+	out.Count = count
+	out.Bytes = bytes
+	return nil
+}
+
+type ListObjectReferencesIn struct {
+	Scope api.EvalScope
+	Expr  string
+}
+
+type ListObjectReferencesOut struct {
+	// Object is the heap object referenced by Expr.
+	Object api.HeapObject
+	// References lists the pointers to Object.
+	References []api.ObjectReference
+}
+
+// ListObjectReferences lists the pointers to the heap object referenced by
+// an expression, that is the object it points to, if the expression is a
+// pointer, the object at the address it evaluates to, if it is an integer,
+// or the object containing it otherwise.
+// The roots of the garbage collector (global variables, live local
+// variables of goroutines and finalizers) and the heap objects are scanned
+// for pointers into the object, for each pointer the shortest retention
+// path from a root is returned.
+func (s *RPCServer) ListObjectReferences(arg ListObjectReferencesIn, out *ListObjectReferencesOut) error {
+	obj, refs, err := s.debugger.ObjectReferencesInScope(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Expr)
+	if err != nil {
+		return err
+	}
+	out.Object = api.ConvertHeapObject(&obj)
+	out.References = api.ConvertObjectReferences(refs)
+	return nil
+}
+
 type AttachedToExistingProcessIn struct {
 }
 
@@ -771,6 +980,9 @@ type DisassembleIn struct {
 
 type DisassembleOut struct {
 	Disassemble api.AsmInstructions
+	// Canceled is true if the disassembly was interrupted by
+	// CancelOperation, only the first instructions were disassembled.
+	Canceled bool
 }
 
 // Disassemble code.
@@ -782,6 +994,10 @@ type DisassembleOut struct {
 // Disassemble will also try to calculate the destination address of an absolute indirect CALL if it happens to be the instruction the selected goroutine is stopped at.
 func (s *RPCServer) Disassemble(arg DisassembleIn, out *DisassembleOut) error {
 	insts, err := s.debugger.Disassemble(arg.Scope.GoroutineID, arg.StartPC, arg.EndPC)
+	if errors.Is(err, proc.ErrCanceled) {
+		out.Canceled = true
+		err = nil
+	}
 	if err != nil {
 		return err
 	}
@@ -1011,6 +1227,9 @@ func (s *RPCServer) StopRecording(arg StopRecordingIn, cb service.RPCCallback) {
 
 type DumpStartIn struct {
 	Destination string
+	// Options controls which memory is included in the core dump and its
+	// compression, the zero value dumps all memory without compression.
+	Options api.DumpOptions
 }
 
 type DumpStartOut struct {
@@ -1019,7 +1238,11 @@ type DumpStartOut struct {
 
 // DumpStart starts a core dump to arg.Destination.
 func (s *RPCServer) DumpStart(arg DumpStartIn, out *DumpStartOut) error {
-	err := s.debugger.DumpStart(arg.Destination)
+	opts, err := api.DumpOptionsToProc(&arg.Options)
+	if err != nil {
+		return err
+	}
+	err = s.debugger.DumpStart(arg.Destination, opts)
 	if err != nil {
 		return err
 	}
@@ -1054,6 +1277,28 @@ func (s *RPCServer) DumpCancel(arg DumpCancelIn, out *DumpCancelOut) error {
 	return s.debugger.DumpCancel()
 }
 
+type CancelOperationIn struct {
+}
+
+type CancelOperationOut struct {
+}
+
+// CancelOperation interrupts the variable loading, goroutine listing, stack
+// unwinding or disassembly in progress. Unlike other methods it is executed
+// as soon as it is received, without waiting for the previous calls to
+// return.
+// The interrupted call returns the results collected until then: variables
+// that could not be loaded are marked with the VariableLoadCanceled flag,
+// stacktraces end with a frame with an error, ListGoroutines and
+// Disassemble set the Canceled field of their output.
+// The operation is interrupted regardless of which client started it: when
+// multiple clients are connected (see --accept-multiclient) a client can
+// interrupt an operation requested by another client.
+func (s *RPCServer) CancelOperation(arg CancelOperationIn, out *CancelOperationOut) error {
+	s.debugger.CancelOperation()
+	return nil
+}
+
 type CreateWatchpointIn struct {
 	Scope api.EvalScope
 	Expr  string
@@ -207,20 +207,21 @@ func (s *ServerImpl) serveJSONCodec(conn io.ReadWriteCloser) {
 
 	sending := new(sync.Mutex)
 	codec := jsonrpc.NewServerCodec(conn)
-	var req rpc.Request
-	var resp rpc.Response
-	for {
-		req = rpc.Request{}
-		err := codec.ReadRequestHeader(&req)
-		if err != nil {
-			if err != io.EOF {
-				s.log.Error("rpc:", err)
-			}
-			break
-		}
 
+	// Request headers are read on a separate goroutine so that
+	// CancelOperation can interrupt the request being handled, all other
+	// requests are handled here, one at a time, in the order they are
+	// received.
+	headers := make(chan rpc.Request)
+	bodyRead := make(chan struct{})
+	defer close(bodyRead)
+	go s.readJSONRequestHeaders(codec, sending, headers, bodyRead)
+
+	var resp rpc.Response
+	for req := range headers {
 		mtype, ok := s.methodMaps[s.config.APIVersion-1][req.ServiceMethod]
 		if !ok {
+			bodyRead <- struct{}{}
 			s.log.Errorf("rpc: can't find method %s", req.ServiceMethod)
 			s.sendResponse(sending, &req, &rpc.Response{}, nil, codec, fmt.Sprintf("unknown method: %s", req.ServiceMethod))
 			continue
@@ -237,9 +238,10 @@ func (s *ServerImpl) serveJSONCodec(conn io.ReadWriteCloser) {
 			argIsValue = true
 		}
 		// argv guaranteed to be a pointer now.
-		if err = codec.ReadRequestBody(argv.Interface()); err != nil {
+		if err := codec.ReadRequestBody(argv.Interface()); err != nil {
 			return
 		}
+		bodyRead <- struct{}{}
 		if argIsValue {
 			argv = argv.Elem()
 		}
@@ -298,6 +300,47 @@ func (s *ServerImpl) serveJSONCodec(conn io.ReadWriteCloser) {
 	codec.Close()
 }
 
+// readJSONRequestHeaders reads the headers of the requests from codec and
+// sends them to headers, which is closed when the connection is closed.
+// After sending a header it waits for the body of the request to be read
+// from bodyRead, it returns when bodyRead is closed.
+// CancelOperation requests are handled as soon as they are read, without
+// waiting for the request being handled to finish.
+func (s *ServerImpl) readJSONRequestHeaders(codec rpc.ServerCodec, sending *sync.Mutex, headers chan<- rpc.Request, bodyRead <-chan struct{}) {
+	defer close(headers)
+	for {
+		req := rpc.Request{}
+		err := codec.ReadRequestHeader(&req)
+		if err != nil {
+			if err != io.EOF {
+				s.log.Error("rpc:", err)
+			}
+			return
+		}
+
+		if req.ServiceMethod == "RPCServer.CancelOperation" {
+			if err := codec.ReadRequestBody(nil); err != nil {
+				return
+			}
+			if logflags.RPC() {
+				s.log.Debugf("<- %s", req.ServiceMethod)
+			}
+			s.debugger.CancelOperation()
+			s.sendResponse(sending, &req, &rpc.Response{}, rpc2.CancelOperationOut{}, codec, "")
+			continue
+		}
+
+		select {
+		case headers <- req:
+		case <-bodyRead:
+			return
+		}
+		if _, ok := <-bodyRead; !ok {
+			return
+		}
+	}
+}
+
 // A value sent as a placeholder for the server's response value when the server
 // receives an invalid request. It is never decoded by the client since the Response
 // contains an error when it is used.
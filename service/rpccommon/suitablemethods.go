@@ -8,11 +8,13 @@ import (
 )
 
 func suitableMethods2(s *rpc2.RPCServer, methods map[string]*methodType) {
+	methods["RPCServer.AddWatchExpression"] = &methodType{method: reflect.ValueOf(s.AddWatchExpression)}
 	methods["RPCServer.AmendBreakpoint"] = &methodType{method: reflect.ValueOf(s.AmendBreakpoint)}
 	methods["RPCServer.Ancestors"] = &methodType{method: reflect.ValueOf(s.Ancestors)}
 	methods["RPCServer.AttachedToExistingProcess"] = &methodType{method: reflect.ValueOf(s.AttachedToExistingProcess)}
 	methods["RPCServer.BuildID"] = &methodType{method: reflect.ValueOf(s.BuildID)}
 	methods["RPCServer.CancelNext"] = &methodType{method: reflect.ValueOf(s.CancelNext)}
+	methods["RPCServer.CancelOperation"] = &methodType{method: reflect.ValueOf(s.CancelOperation)}
 	methods["RPCServer.Checkpoint"] = &methodType{method: reflect.ValueOf(s.Checkpoint)}
 	methods["RPCServer.ClearBreakpoint"] = &methodType{method: reflect.ValueOf(s.ClearBreakpoint)}
 	methods["RPCServer.ClearCheckpoint"] = &methodType{method: reflect.ValueOf(s.ClearCheckpoint)}
@@ -37,6 +39,7 @@ func suitableMethods2(s *rpc2.RPCServer, methods map[string]*methodType) {
 	methods["RPCServer.GetThread"] = &methodType{method: reflect.ValueOf(s.GetThread)}
 	methods["RPCServer.GuessSubstitutePath"] = &methodType{method: reflect.ValueOf(s.GuessSubstitutePath)}
 	methods["RPCServer.IsMulticlient"] = &methodType{method: reflect.ValueOf(s.IsMulticlient)}
+	methods["RPCServer.Jump"] = &methodType{method: reflect.ValueOf(s.Jump)}
 	methods["RPCServer.LastModified"] = &methodType{method: reflect.ValueOf(s.LastModified)}
 	methods["RPCServer.ListBreakpoints"] = &methodType{method: reflect.ValueOf(s.ListBreakpoints)}
 	methods["RPCServer.ListCheckpoints"] = &methodType{method: reflect.ValueOf(s.ListCheckpoints)}
@@ -44,7 +47,10 @@ func suitableMethods2(s *rpc2.RPCServer, methods map[string]*methodType) {
 	methods["RPCServer.ListFunctionArgs"] = &methodType{method: reflect.ValueOf(s.ListFunctionArgs)}
 	methods["RPCServer.ListFunctions"] = &methodType{method: reflect.ValueOf(s.ListFunctions)}
 	methods["RPCServer.ListGoroutines"] = &methodType{method: reflect.ValueOf(s.ListGoroutines)}
+	methods["RPCServer.ListHeapObjects"] = &methodType{method: reflect.ValueOf(s.ListHeapObjects)}
 	methods["RPCServer.ListLocalVars"] = &methodType{method: reflect.ValueOf(s.ListLocalVars)}
+	methods["RPCServer.ListMethods"] = &methodType{method: reflect.ValueOf(s.ListMethods)}
+	methods["RPCServer.ListObjectReferences"] = &methodType{method: reflect.ValueOf(s.ListObjectReferences)}
 	methods["RPCServer.ListPackageVars"] = &methodType{method: reflect.ValueOf(s.ListPackageVars)}
 	methods["RPCServer.ListPackagesBuildInfo"] = &methodType{method: reflect.ValueOf(s.ListPackagesBuildInfo)}
 	methods["RPCServer.ListRegisters"] = &methodType{method: reflect.ValueOf(s.ListRegisters)}
@@ -52,12 +58,16 @@ func suitableMethods2(s *rpc2.RPCServer, methods map[string]*methodType) {
 	methods["RPCServer.ListTargets"] = &methodType{method: reflect.ValueOf(s.ListTargets)}
 	methods["RPCServer.ListThreads"] = &methodType{method: reflect.ValueOf(s.ListThreads)}
 	methods["RPCServer.ListTypes"] = &methodType{method: reflect.ValueOf(s.ListTypes)}
+	methods["RPCServer.ListWatchExpressions"] = &methodType{method: reflect.ValueOf(s.ListWatchExpressions)}
 	methods["RPCServer.ProcessPid"] = &methodType{method: reflect.ValueOf(s.ProcessPid)}
 	methods["RPCServer.Recorded"] = &methodType{method: reflect.ValueOf(s.Recorded)}
+	methods["RPCServer.RemoveWatchExpression"] = &methodType{method: reflect.ValueOf(s.RemoveWatchExpression)}
 	methods["RPCServer.Restart"] = &methodType{method: reflect.ValueOf(s.Restart)}
 	methods["RPCServer.Set"] = &methodType{method: reflect.ValueOf(s.Set)}
+	methods["RPCServer.SetLive"] = &methodType{method: reflect.ValueOf(s.SetLive)}
 	methods["RPCServer.Stacktrace"] = &methodType{method: reflect.ValueOf(s.Stacktrace)}
 	methods["RPCServer.State"] = &methodType{method: reflect.ValueOf(s.State)}
+	methods["RPCServer.StepInTargets"] = &methodType{method: reflect.ValueOf(s.StepInTargets)}
 	methods["RPCServer.StopRecording"] = &methodType{method: reflect.ValueOf(s.StopRecording)}
 	methods["RPCServer.ToggleBreakpoint"] = &methodType{method: reflect.ValueOf(s.ToggleBreakpoint)}
 }
@@ -209,6 +209,136 @@ func TestRestart_duringStop(t *testing.T) {
 	})
 }
 
+func TestRestart_convenienceVariables(t *testing.T) {
+	withTestClient2Extended("issue305", t, 0, [3]string{}, nil, func(c service.Client, fixture protest.Fixture) {
+		bp, err := c.CreateBreakpoint(&api.Breakpoint{File: fixture.Source, Line: 5})
+		assertNoError(err, t, "CreateBreakpoint()")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		scope := api.EvalScope{GoroutineID: -1}
+		assertNoError(c.SetVariable(scope, "$n", "i + 3"), t, "SetVariable($n)")
+		assertNoError(c.SetVariable(scope, "$str", `"hello"`), t, "SetVariable($str)")
+		assertNoError(c.SetVariable(scope, "$p", "&i"), t, "SetVariable($p)")
+		assertNoError(c.SetLiveVariable("cur", "i"), t, "SetLiveVariable($cur)")
+
+		bp.Cond = "i == $n"
+		assertNoError(c.AmendBreakpoint(bp), t, "AmendBreakpoint()")
+
+		checkVar := func(expr, tgt string) {
+			t.Helper()
+			v, err := c.EvalVariable(scope, expr, normalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", expr))
+			if v.Value != tgt {
+				t.Errorf("%s: expected %q got %q", expr, tgt, v.Value)
+			}
+		}
+
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		checkVar("$cur", "3")
+		checkVar("*$p", "3")
+
+		_, err = c.Restart(false)
+		assertNoError(err, t, "Restart()")
+
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		checkVar("i", "3")
+		checkVar("$n", "3")
+		checkVar("$cur", "3")
+		checkVar("$str", "hello")
+
+		// $p pointed to the memory of the previous process
+		_, err = c.EvalVariable(scope, "$p", normalLoadConfig)
+		if err == nil {
+			t.Fatal("$p was preserved across restart")
+		}
+	})
+}
+
+func TestWatchExpressions(t *testing.T) {
+	withTestClient2Extended("issue305", t, 0, [3]string{}, nil, func(c service.Client, fixture protest.Fixture) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{File: fixture.Source, Line: 5})
+		assertNoError(err, t, "CreateBreakpoint()")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+
+		scope := api.EvalScope{GoroutineID: -1}
+		wi, err := c.AddWatchExpression("i", scope, nil)
+		assertNoError(err, t, "AddWatchExpression(i)")
+		if wi.Value == nil || wi.Value.Value != "0" || wi.Changed {
+			t.Fatalf("wrong initial value for i: %#v", wi)
+		}
+		_, err = c.AddWatchExpression("i / 2", scope, nil)
+		assertNoError(err, t, "AddWatchExpression(i / 2)")
+		_, err = c.AddWatchExpression("nonexistent", scope, nil)
+		assertNoError(err, t, "AddWatchExpression(nonexistent)")
+		_, err = c.AddWatchExpression("i +", scope, nil)
+		if err == nil {
+			t.Fatal("AddWatchExpression accepted a syntax error")
+		}
+
+		checkWatch := func(ws []*api.WatchExpression, expr, tgt string, changed bool) {
+			t.Helper()
+			for _, w := range ws {
+				if w.Expr != expr {
+					continue
+				}
+				if tgt == "" {
+					if w.Err == "" || w.Changed != changed {
+						t.Errorf("%s: expected error (changed %v), got %#v", expr, changed, w)
+					}
+					return
+				}
+				if w.Value == nil || w.Value.Value != tgt || w.Changed != changed {
+					t.Errorf("%s: expected %s (changed %v), got %#v", expr, tgt, changed, w)
+				}
+				return
+			}
+			t.Errorf("%s: watch expression not found", expr)
+		}
+
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		checkWatch(state.WatchExpressions, "i", "1", true)
+		checkWatch(state.WatchExpressions, "i / 2", "0", false)
+		checkWatch(state.WatchExpressions, "nonexistent", "", false)
+
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		checkWatch(state.WatchExpressions, "i", "2", true)
+		checkWatch(state.WatchExpressions, "i / 2", "1", true)
+
+		// asking for the state again does not reevaluate watch expressions
+		state, err = c.GetState()
+		assertNoError(err, t, "GetState()")
+		checkWatch(state.WatchExpressions, "i / 2", "1", true)
+
+		assertNoError(c.RemoveWatchExpression(wi.ID), t, "RemoveWatchExpression()")
+		if c.RemoveWatchExpression(wi.ID) == nil {
+			t.Error("removed the same watch expression twice")
+		}
+		ws, err := c.ListWatchExpressions()
+		assertNoError(err, t, "ListWatchExpressions()")
+		if len(ws) != 2 {
+			t.Errorf("wrong number of watch expressions: %d", len(ws))
+		}
+		checkWatch(ws, "i / 2", "1", true)
+
+		// the values from the last stop are kept after the target exits
+		_, err = c.ClearBreakpoint(state.CurrentThread.Breakpoint.ID)
+		assertNoError(err, t, "ClearBreakpoint()")
+		state = <-c.Continue()
+		if !state.Exited {
+			t.Fatalf("target did not exit: %#v", state)
+		}
+		ws, err = c.ListWatchExpressions()
+		assertNoError(err, t, "ListWatchExpressions()")
+		checkWatch(ws, "i / 2", "1", true)
+	})
+}
+
 // This source is a slightly modified version of
 // _fixtures/testenv.go. The only difference is that
 // the name of the environment variable we are trying to
@@ -2087,6 +2217,89 @@ func TestAcceptMulticlient(t *testing.T) {
 	<-serverDone
 }
 
+func TestCancelOperationMulticlient(t *testing.T) {
+	// CancelOperation is not scoped to the client that sends it, it is
+	// handled immediately by the server and only interrupts the operation
+	// in progress, if any. Requests queued behind a running Command are
+	// not affected.
+	if testBackend == "rr" {
+		t.Skip("recording not allowed for TestCancelOperationMulticlient")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("couldn't start listener: %s\n", err)
+	}
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		defer listener.Close()
+		disconnectChan := make(chan struct{})
+		server := rpccommon.NewServer(&service.Config{
+			Listener:       listener,
+			ProcessArgs:    []string{protest.BuildFixture(t, "loopprog", 0).Path},
+			AcceptMulti:    true,
+			DisconnectChan: disconnectChan,
+			Debugger: debugger.Config{
+				Backend:     testBackend,
+				ExecuteKind: debugger.ExecutingGeneratedTest,
+			},
+		})
+		if err := server.Run(); err != nil {
+			panic(err)
+		}
+		<-disconnectChan
+		server.Stop()
+	}()
+	client1 := rpc2.NewClient(listener.Addr().String())
+	client2 := rpc2.NewClient(listener.Addr().String())
+
+	bp, err := client1.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.loop"})
+	assertNoError(err, t, "CreateBreakpoint()")
+	state := <-client1.Continue()
+	assertNoError(state.Err, t, "Continue()")
+	_, err = client1.ClearBreakpoint(bp.ID)
+	assertNoError(err, t, "ClearBreakpoint()")
+
+	stateChan := client1.Continue()
+	for {
+		state, err := client2.GetStateNonBlocking()
+		assertNoError(err, t, "GetStateNonBlocking()")
+		if state.Running {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	type stacktraceResult struct {
+		frames []api.Stackframe
+		err    error
+	}
+	stackChan := make(chan stacktraceResult)
+	go func() {
+		frames, err := client2.Stacktrace(-1, 50, 0, nil)
+		stackChan <- stacktraceResult{frames, err}
+	}()
+
+	assertNoError(client1.CancelOperation(), t, "CancelOperation() (client1)")
+	assertNoError(client2.CancelOperation(), t, "CancelOperation() (client2)")
+
+	_, err = client1.Halt()
+	assertNoError(err, t, "Halt()")
+	<-stateChan
+
+	res := <-stackChan
+	assertNoError(res.err, t, "Stacktrace()")
+	for _, frame := range res.frames {
+		if frame.Err == proc.ErrCanceled.Error() {
+			t.Errorf("stacktrace queued before the cancel requests was interrupted: %v", res.frames)
+		}
+	}
+
+	client2.Disconnect(false)
+	client1.Detach(true)
+	<-serverDone
+}
+
 func TestForceStopWhileContinue(t *testing.T) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -3339,3 +3552,31 @@ func TestFollowExecFindLocation(t *testing.T) {
 		assertNoError(err, t, "FindLocation(spawn.go:19)")
 	})
 }
+
+func TestBuiltinPrettyPrinters(t *testing.T) {
+	withTestClient2("prettyprint", t, func(c service.Client) {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue()")
+		for _, tc := range []struct{ expr, tgt string }{
+			{"d", "1m30s"},
+			{"*n", "-1180591620717411303424"},
+			{"ip4", "192.168.0.1"},
+			{"ip6", "2001:db8::1%eth0"},
+			{"ip4in6", "::ffff:10.0.0.1"},
+			{"ipzero", "invalid IP"},
+			{"ap", "[::1]:8080"},
+			{"pfx", "10.0.0.0/8"},
+			{"cnt", "42"},
+			{"flag", "true"},
+		} {
+			v, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, tc.expr, normalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("EvalVariable(%s)", tc.expr))
+			if v.Flags&api.VariableFormatted == 0 {
+				t.Errorf("%s: not formatted", tc.expr)
+			}
+			if v.Value != tc.tgt {
+				t.Errorf("%s: got %q expected %q", tc.expr, v.Value, tc.tgt)
+			}
+		}
+	})
+}